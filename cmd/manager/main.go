@@ -12,6 +12,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"go.uber.org/zap/zapcore"
@@ -27,11 +28,13 @@ import (
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/controller"
 	"github.com/stolostron/managedcluster-import-controller/pkg/controller/agentregistration"
+	"github.com/stolostron/managedcluster-import-controller/pkg/controller/clusterdeployment"
 	"github.com/stolostron/managedcluster-import-controller/pkg/controller/importconfig"
 	"github.com/stolostron/managedcluster-import-controller/pkg/features"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
 	"github.com/stolostron/managedcluster-import-controller/pkg/source"
+	importwebhook "github.com/stolostron/managedcluster-import-controller/pkg/webhook"
 
 	klusterletconfigclient "github.com/stolostron/cluster-lifecycle-api/client/klusterletconfig/clientset/versioned"
 	klusterletconfiginformer "github.com/stolostron/cluster-lifecycle-api/client/klusterletconfig/informers/externalversions"
@@ -53,6 +56,7 @@ import (
 	"k8s.io/apimachinery/pkg/fields"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
 	k8sscheme "k8s.io/client-go/kubernetes/scheme"
 	utilflag "k8s.io/component-base/cli/flag"
@@ -61,11 +65,18 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
 // Change below variables to serve metrics on different host or port.
 var metricsPort = 8383
 
+// Change below variables to serve the webhooks on different port or with different certificates.
+var (
+	webhookPort    = 9443
+	webhookCertDir = "/webhook-server"
+)
+
 var (
 	scheme   = k8sruntime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
@@ -84,6 +95,10 @@ func init() {
 func main() {
 	var leaderElectionNamespace string = ""
 	pflag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "", "required when the process is not running in cluster")
+	pflag.StringSliceVar(&clusterdeployment.PropagatedLabelKeys, "clusterdeployment-propagated-labels", nil,
+		"the keys of the labels that are copied from the clusterdeployment to the managed cluster")
+	pflag.StringVar(&helpers.EventsNamespace, "events-namespace", "",
+		"the dedicated namespace for the events of the controllers, the namespace of this component is used if it is empty")
 	pflag.CommandLine.SetNormalizeFunc(utilflag.WordSepNormalizeFunc)
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 	features.DefaultMutableFeatureGate.AddFlag(pflag.CommandLine)
@@ -101,6 +116,13 @@ func main() {
 		}
 	}))
 
+	for _, key := range clusterdeployment.PropagatedLabelKeys {
+		if errs := validation.IsQualifiedName(key); len(errs) != 0 {
+			setupLog.Error(fmt.Errorf("%s", strings.Join(errs, ";")), "invalid propagated label key", "key", key)
+			os.Exit(1)
+		}
+	}
+
 	ctx := ctrl.SetupSignalHandler()
 
 	// Get a config to talk to the kube-apiserver
@@ -109,6 +131,10 @@ func main() {
 		setupLog.Error(err, "failed to get kube config")
 		os.Exit(1)
 	}
+	// slow down all of the controllers when the hub is overloaded, the leader election keeps using the original
+	// config, so the lease can still be renewed in time
+	leaderElectionCfg := cfg
+	cfg = helpers.DefaultHubThrottle.ThrottledConfig(cfg)
 
 	kubeClient, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
@@ -201,6 +227,37 @@ func main() {
 		},
 	)
 
+	componentNamespace, err := helpers.GetComponentNamespace()
+	if err != nil {
+		setupLog.Error(err, "failed to get the component namespace")
+		os.Exit(1)
+	}
+
+	// load the condition message templates from the configmap in the component namespace
+	conditionMessageTemplatesInformerF := informers.NewFilteredSharedInformerFactory(
+		kubeClient,
+		10*time.Minute,
+		componentNamespace, func(listOptions *metav1.ListOptions) {
+			listOptions.FieldSelector = fields.OneTermEqualSelector(
+				"metadata.name", helpers.ConditionMessageTemplatesConfigMapName).String()
+		},
+	)
+	if _, err := conditionMessageTemplatesInformerF.Core().V1().ConfigMaps().Informer().AddEventHandler(
+		helpers.ConditionMessageTemplatesEventHandler()); err != nil {
+		setupLog.Error(err, "failed to add the condition message templates event handler")
+		os.Exit(1)
+	}
+
+	// watch the hub root CA in the component namespace, the import secrets are regenerated once it is rotated
+	hubRootCAInformerF := informers.NewFilteredSharedInformerFactory(
+		kubeClient,
+		10*time.Minute,
+		componentNamespace, func(listOptions *metav1.ListOptions) {
+			listOptions.FieldSelector = fields.OneTermEqualSelector(
+				"metadata.name", importconfig.HubRootCAConfigMapName).String()
+		},
+	)
+
 	klusterletconfigInformerF := klusterletconfiginformer.NewSharedInformerFactory(klusterletconfigClient, 10*time.Minute)
 	klusterletconfigLister := klusterletconfigInformerF.Config().V1alpha1().KlusterletConfigs().Lister()
 
@@ -224,6 +281,11 @@ func main() {
 		LeaderElection:          true,
 		LeaderElectionID:        "managedcluster-import-controller.open-cluster-management.io",
 		LeaderElectionNamespace: leaderElectionNamespace,
+		LeaderElectionConfig:    leaderElectionCfg,
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Port:    webhookPort,
+			CertDir: webhookCertDir,
+		}),
 	})
 	if err != nil {
 		setupLog.Error(err, "failed to create manager")
@@ -254,6 +316,7 @@ func main() {
 			HostedWorkLister:         hostedWorksInformerF.Work().V1().ManifestWorks().Lister(),
 			KlusterletConfigLister:   klusterletconfigLister,
 			ManagedClusterInformer:   managedclusterInformer,
+			HubRootCAInformer:        hubRootCAInformerF.Core().V1().ConfigMaps().Informer(),
 		},
 	); err != nil {
 		setupLog.Error(err, "failed to register controller")
@@ -266,6 +329,8 @@ func main() {
 	hostedWorksInformerF.Start(ctx.Done())
 	klusterletconfigInformerF.Start(ctx.Done())
 	managedclusterInformerF.Start(ctx.Done())
+	conditionMessageTemplatesInformerF.Start(ctx.Done())
+	hubRootCAInformerF.Start(ctx.Done())
 
 	importSecertInformerF.WaitForCacheSync(ctx.Done())
 	autoimportSecretInformerF.WaitForCacheSync(ctx.Done())
@@ -273,6 +338,8 @@ func main() {
 	hostedWorksInformerF.WaitForCacheSync(ctx.Done())
 	klusterletconfigInformerF.WaitForCacheSync(ctx.Done())
 	managedclusterInformerF.WaitForCacheSync(ctx.Done())
+	conditionMessageTemplatesInformerF.WaitForCacheSync(ctx.Done())
+	hubRootCAInformerF.WaitForCacheSync(ctx.Done())
 
 	// Start the agent-registratioin server
 	if features.DefaultMutableFeatureGate.Enabled(features.AgentRegistration) {
@@ -284,6 +351,13 @@ func main() {
 		}()
 	}
 
+	if features.DefaultMutableFeatureGate.Enabled(features.AutoImportSecretWebhook) {
+		setupLog.Info("Registering Webhooks")
+		mgr.GetWebhookServer().Register(importwebhook.AutoImportSecretValidatingPath, &webhook.Admission{
+			Handler: importwebhook.NewAutoImportSecretValidator(mgr.GetClient()),
+		})
+	}
+
 	setupLog.Info("Starting Controller Manager")
 	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "failed to start manager")
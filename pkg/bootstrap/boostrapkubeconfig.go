@@ -6,20 +6,24 @@ package bootstrap
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"net/url"
+	"os"
 	"reflect"
 	"strings"
 
 	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apiserver/pkg/storage/names"
 	"k8s.io/client-go/kubernetes"
 	certutil "k8s.io/client-go/util/cert"
@@ -38,9 +42,13 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
+// DefaultBootstrapKubeConfigContextName is the context name of the bootstrap kubeconfig if it is not customized
+const DefaultBootstrapKubeConfigContextName = "default-context"
+
 // create kubeconfig for bootstrap
 func CreateBootstrapKubeConfig(ctx context.Context, clientHolder *helpers.ClientHolder, saName string, ns string,
-	tokenExpirationSeconds int64, klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig) ([]byte, []byte, error) {
+	tokenExpirationSeconds int64, contextName string,
+	klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig) ([]byte, []byte, error) {
 	token, expiration, err := getBootstrapToken(ctx, clientHolder.KubeClient, saName, ns, tokenExpirationSeconds)
 	if err != nil {
 		return nil, nil, err
@@ -75,12 +83,12 @@ func CreateBootstrapKubeConfig(ctx context.Context, clientHolder *helpers.Client
 			Token: string(token),
 		}},
 		// Define a context that connects the auth info and cluster, and set it as the default
-		Contexts: map[string]*clientcmdapi.Context{"default-context": {
+		Contexts: map[string]*clientcmdapi.Context{contextName: {
 			Cluster:   "default-cluster",
 			AuthInfo:  "default-auth",
 			Namespace: "default",
 		}},
-		CurrentContext: "default-context",
+		CurrentContext: contextName,
 	}
 
 	boostrapConfigData, err := runtime.Encode(clientcmdlatest.Codec, &bootstrapConfig)
@@ -91,16 +99,52 @@ func CreateBootstrapKubeConfig(ctx context.Context, clientHolder *helpers.Client
 	return boostrapConfigData, expiration, err
 }
 
-const bootstrapSASuffix = "bootstrap-sa"
+// GetBootstrapKubeConfigContextName gets the context name of the bootstrap kubeconfig from the managed
+// cluster annotations, if the annotation is not set, the default context name is returned.
+func GetBootstrapKubeConfigContextName(clusterAnnotations map[string]string) (string, error) {
+	contextName, ok := clusterAnnotations[constants.KubeconfigContextNameAnnotation]
+	if !ok {
+		return DefaultBootstrapKubeConfigContextName, nil
+	}
+
+	if errMsgs := validation.IsDNS1123Subdomain(contextName); len(errMsgs) != 0 {
+		return "", fmt.Errorf("invalid kubeconfig context name annotation %s: %s",
+			contextName, strings.Join(errMsgs, ";"))
+	}
+
+	return contextName, nil
+}
 
+const (
+	bootstrapSASuffix = "bootstrap-sa"
+
+	// bootstrapSANameHashLength is the length of the cluster name hash that is appended to the truncated bootstrap
+	// service account name
+	bootstrapSANameHashLength = 8
+)
+
+// GetBootstrapSAName returns the name of the bootstrap service account of the managed cluster. If the name exceeds
+// 63 characters, the cluster name is truncated and a hash of the full cluster name is appended, so the long cluster
+// names that share a prefix do not collide in the shared bootstrap service account namespace.
 func GetBootstrapSAName(clusterName string) string {
 	bootstrapSAName := fmt.Sprintf("%s-%s", clusterName, bootstrapSASuffix)
 	if len(bootstrapSAName) > 63 {
-		return fmt.Sprintf("%s-%s", clusterName[:63-len("-"+bootstrapSASuffix)], bootstrapSASuffix)
+		hash := fmt.Sprintf("%x", sha256.Sum256([]byte(clusterName)))[:bootstrapSANameHashLength]
+		prefixLength := 63 - len("-"+hash+"-"+bootstrapSASuffix)
+		return fmt.Sprintf("%s-%s-%s", clusterName[:prefixLength], hash, bootstrapSASuffix)
 	}
 	return bootstrapSAName
 }
 
+// GetBootstrapSANamespace returns the namespace of the bootstrap service account of the managed cluster, the
+// namespace is specified by the BOOTSTRAP_SA_NAMESPACE env, by default, it is the managed cluster namespace.
+func GetBootstrapSANamespace(clusterName string) string {
+	if namespace := os.Getenv(constants.BootstrapSANamespaceEnvVarName); len(namespace) != 0 {
+		return namespace
+	}
+	return clusterName
+}
+
 // getBootstrapToken lists the secrets from the managed cluster namespace to look for the managed cluster
 // bootstrap token firstly (compatibility with the ocp that version is less than 4.11), if there is no
 // token found, uses tokenrequest to request token.
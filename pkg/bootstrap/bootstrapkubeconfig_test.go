@@ -15,6 +15,7 @@ import (
 	configv1 "github.com/openshift/api/config/v1"
 	hivev1 "github.com/openshift/hive/apis/hive/v1"
 	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
 	testinghelpers "github.com/stolostron/managedcluster-import-controller/pkg/helpers/testing"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
@@ -28,6 +29,7 @@ import (
 
 	authv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	kubefake "k8s.io/client-go/kubernetes/fake"
@@ -327,7 +329,7 @@ func TestCreateBootstrapKubeConfig(t *testing.T) {
 				KubeClient: fakeKubeClinet,
 			}
 
-			kubeconfigData, _, err := CreateBootstrapKubeConfig(context.Background(), clientHolder, GetBootstrapSAName(cluster.Name), cluster.Name, 8640*3600, tt.klusterletConfig)
+			kubeconfigData, _, err := CreateBootstrapKubeConfig(context.Background(), clientHolder, GetBootstrapSAName(cluster.Name), cluster.Name, 8640*3600, DefaultBootstrapKubeConfigContextName, tt.klusterletConfig)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("createKubeconfigData() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -746,7 +748,12 @@ func TestGetBootstrapSAName(t *testing.T) {
 		{
 			name:           "long name",
 			clusterName:    "123456789-123456789-123456789-123456789-123456789-123456789",
-			expectedSAName: "123456789-123456789-123456789-123456789-123456789--bootstrap-sa",
+			expectedSAName: "123456789-123456789-123456789-123456789-1-dbef41d8-bootstrap-sa",
+		},
+		{
+			name:           "long names with the same prefix",
+			clusterName:    "123456789-123456789-123456789-123456789-123456789-987654321",
+			expectedSAName: "123456789-123456789-123456789-123456789-1-18a59bf9-bootstrap-sa",
 		},
 	}
 
@@ -759,6 +766,34 @@ func TestGetBootstrapSAName(t *testing.T) {
 	}
 }
 
+func TestGetBootstrapSANamespace(t *testing.T) {
+	if namespace := GetBootstrapSANamespace("cluster1"); namespace != "cluster1" {
+		t.Errorf("expected the managed cluster namespace, but got %s", namespace)
+	}
+
+	t.Setenv(constants.BootstrapSANamespaceEnvVarName, "bootstrap-sas")
+	if namespace := GetBootstrapSANamespace("cluster1"); namespace != "bootstrap-sas" {
+		t.Errorf("expected the namespace bootstrap-sas, but got %s", namespace)
+	}
+
+	objs, err := GenerateHubBootstrapRBACObjects("cluster1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *corev1.ServiceAccount:
+			if o.Namespace != "bootstrap-sas" {
+				t.Errorf("expected the bootstrap sa in the namespace bootstrap-sas, but got %s", o.Namespace)
+			}
+		case *rbacv1.ClusterRoleBinding:
+			if o.Subjects[0].Namespace != "bootstrap-sas" {
+				t.Errorf("expected the subject in the namespace bootstrap-sas, but got %s", o.Subjects[0].Namespace)
+			}
+		}
+	}
+}
+
 func TestGetProxySettings(t *testing.T) {
 	tests := []struct {
 		name             string
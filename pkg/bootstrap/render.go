@@ -6,11 +6,15 @@ package bootstrap
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"embed"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"io/fs"
 	"os"
 	"strings"
+	"time"
 
 	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
@@ -18,14 +22,51 @@ import (
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	operatorv1 "open-cluster-management.io/api/operator/v1"
 )
 
 //go:embed manifests
 var ManifestFiles embed.FS
 
+// klusterletManifestsVersion is the hash of the embedded klusterlet manifest templates
+var klusterletManifestsVersion = mustHashManifests("manifests/klusterlet")
+
+// KlusterletManifestsVersion returns the schema version of the klusterlet manifests in the import secret, it is
+// the hash of the klusterlet manifest templates, so it is changed whenever the templates are changed.
+func KlusterletManifestsVersion() string {
+	return klusterletManifestsVersion
+}
+
+func mustHashManifests(dir string) string {
+	h := sha256.New()
+	// the files are walked in lexical order, so the hash is stable
+	if err := fs.WalkDir(ManifestFiles, dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := ManifestFiles.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		h.Write([]byte(path))
+		h.Write([]byte{0})
+		h.Write(data)
+		h.Write([]byte{0})
+		return nil
+	}); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
 const managedClusterImagePullSecretName = "open-cluster-management-image-pull-credentials"
 
+const (
+	klusterletNamespacePrefix = "open-cluster-management-"
+	addonNamespaceSuffix      = "-addon"
+)
+
 const (
 	klusterletCrdsV1File      = "manifests/klusterlet/crds/klusterlets.crd.v1.yaml"
 	klusterletCrdsV1beta1File = "manifests/klusterlet/crds/klusterlets.crd.v1beta1.yaml"
@@ -47,6 +88,24 @@ var klusterletOperatorFiles = []string{
 	"manifests/klusterlet/operator.yaml",
 }
 
+// klusterletAgentNamespaceFile is used to create the agent namespace if the agent and the operator are
+// deployed in different namespaces, the agent namespace is required by the bootstrap secret
+const klusterletAgentNamespaceFile = "manifests/klusterlet/agent_namespace.yaml"
+
+// klusterletPDBFiles are used to create the PodDisruptionBudgets of the klusterlet agent pods if the minAvailable
+// is specified, the PodDisruptionBudgets are created after the klusterlet to make sure the agent namespace exists.
+// The registration agent and the work agent are deployed separately in the Default mode, and they are deployed as
+// one klusterlet agent in the Singleton mode.
+var klusterletPDBFiles = map[operatorv1.InstallMode][]string{
+	operatorv1.InstallModeDefault: {
+		"manifests/klusterlet/pod_disruption_budget_registration_agent.yaml",
+		"manifests/klusterlet/pod_disruption_budget_work_agent.yaml",
+	},
+	operatorv1.InstallModeSingleton: {
+		"manifests/klusterlet/pod_disruption_budget_agent.yaml",
+	},
+}
+
 var klusterletFiles = []string{
 	"manifests/klusterlet/bootstrap_secret.yaml",
 	"manifests/klusterlet/klusterlet.yaml",
@@ -60,6 +119,7 @@ type RenderConfig struct {
 // KlusterletRenderConfig defines variables used in the klusterletFiles.
 type KlusterletRenderConfig struct {
 	KlusterletNamespace       string
+	OperatorNamespace         string
 	ManagedClusterNamespace   string
 	BootstrapKubeconfig       string
 	RegistrationOperatorImage string
@@ -68,6 +128,13 @@ type KlusterletRenderConfig struct {
 	ImageName                 string
 	NodeSelector              map[string]string
 	Tolerations               []corev1.Toleration
+	PodLabels                 map[string]string
+	NamespaceLabels           map[string]string
+	ExtraVolumes              []helpers.KlusterletVolume
+	MetricsPort               int32
+	PDBMinAvailable           string
+	ClientCertExpiration      int32
+	ClusterRoleLabels         map[string]string
 	InstallMode               string
 	ClusterAnnotations        map[string]string
 }
@@ -85,6 +152,7 @@ type KlusterletManifestsConfig struct {
 
 	ClusterName                  string
 	KlusterletNamespace          string
+	KlusterletOperatorNamespace  string
 	KlusterletClusterAnnotations map[string]string
 	BootstrapKubeconfig          []byte
 
@@ -114,6 +182,13 @@ func (c *KlusterletManifestsConfig) WithKlusterletClusterAnnotations(a map[strin
 	return c
 }
 
+// WithKlusterletOperatorNamespace sets the namespace of the klusterlet operator, if it is empty, the operator
+// is deployed in the klusterlet namespace.
+func (c *KlusterletManifestsConfig) WithKlusterletOperatorNamespace(ns string) *KlusterletManifestsConfig {
+	c.KlusterletOperatorNamespace = ns
+	return c
+}
+
 // WithManagedClusterAnnotations sets the managed cluster annotations.
 // The managed cluster annotations contains information like: image pull secret, nodeSelector, tolerations, etc.
 // We need to extract these information from the managed cluster annotations to render the klusterlet manifests.
@@ -146,6 +221,18 @@ func (b *KlusterletManifestsConfig) Generate(ctx context.Context, clientHolder *
 		return nil, fmt.Errorf("invalid install mode: %s", b.InstallMode)
 	}
 
+	operatorNamespace := b.KlusterletNamespace
+	if len(b.KlusterletOperatorNamespace) != 0 && b.InstallMode != operatorv1.InstallModeHosted {
+		if err := validateKlusterletOperatorNamespace(b.KlusterletOperatorNamespace, b.KlusterletNamespace); err != nil {
+			return nil, err
+		}
+		operatorNamespace = b.KlusterletOperatorNamespace
+	}
+	if operatorNamespace != b.KlusterletNamespace {
+		// the agent namespace should be created before the bootstrap secret
+		files = append([]string{files[0], klusterletAgentNamespaceFile}, files[1:]...)
+	}
+
 	// For image, image pull secret, nodeplacement, we use configurations in klusterletconfg over configurations in managed cluster annotations.
 	var kcRegistries []klusterletconfigv1alpha1.Registries
 	var kcNodePlacement *operatorv1.NodePlacement
@@ -175,6 +262,39 @@ func (b *KlusterletManifestsConfig) Generate(ctx context.Context, clientHolder *
 		return nil, err
 	}
 
+	// The per-component images take precedence over the registries
+	klusterletImages, err := helpers.GetKlusterletImagesFromManagedClusterAnnotations(b.ManagedClusterAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("Get klusterlet images for cluster %s failed: %v", b.ClusterName, err)
+	}
+	if err := helpers.ValidateKlusterletImages(klusterletImages); err != nil {
+		return nil, fmt.Errorf("invalid klusterlet images annotation %v", err)
+	}
+	if image, ok := klusterletImages[constants.KlusterletImageComponentRegistrationOperator]; ok {
+		registrationOperatorImageName = image
+	}
+	if image, ok := klusterletImages[constants.KlusterletImageComponentRegistration]; ok {
+		registrationImageName = image
+	}
+	if image, ok := klusterletImages[constants.KlusterletImageComponentWork]; ok {
+		workImageName = image
+	}
+
+	// ImageDigestPolicy, the final images must be referenced by digest if the digest is required
+	imageDigestPolicy, err := helpers.GetImageDigestPolicyFromManagedClusterAnnotations(b.ManagedClusterAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("invalid klusterlet image digest policy annotation %v", err)
+	}
+	if imageDigestPolicy == constants.ImageDigestPolicyRequired {
+		if err := helpers.ValidateImagesDigestPinned(map[string]string{
+			constants.KlusterletImageComponentRegistrationOperator: registrationOperatorImageName,
+			constants.KlusterletImageComponentRegistration:         registrationImageName,
+			constants.KlusterletImageComponentWork:                 workImageName,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
 	// NodeSelector
 	var nodeSelector map[string]string
 	if kcNodePlacement != nil && len(kcNodePlacement.NodeSelector) != 0 {
@@ -203,10 +323,164 @@ func (b *KlusterletManifestsConfig) Generate(ctx context.Context, clientHolder *
 		return nil, fmt.Errorf("invalid tolerations annotation %v", err)
 	}
 
+	// PodLabels
+	podLabels, err := helpers.GetPodLabelsFromManagedClusterAnnotations(b.ManagedClusterAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("Get klusterlet pod labels for cluster %s failed: %v", b.ClusterName, err)
+	}
+	if err := helpers.ValidatePodLabels(podLabels); err != nil {
+		return nil, fmt.Errorf("invalid klusterlet pod labels annotation %v", err)
+	}
+
+	// NamespaceLabels
+	namespaceLabels, err := helpers.GetKlusterletNamespaceLabelsFromManagedClusterAnnotations(
+		b.ManagedClusterAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("Get klusterlet namespace labels for cluster %s failed: %v", b.ClusterName, err)
+	}
+	if err := helpers.ValidateNamespaceLabels(namespaceLabels); err != nil {
+		return nil, fmt.Errorf("invalid klusterlet namespace labels annotation %v", err)
+	}
+
+	// ExtraVolumes
+	extraVolumes, err := helpers.GetKlusterletExtraVolumesFromManagedClusterAnnotations(b.ManagedClusterAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("Get klusterlet extra volumes for cluster %s failed: %v", b.ClusterName, err)
+	}
+	if err := helpers.ValidateKlusterletVolumes(extraVolumes); err != nil {
+		return nil, fmt.Errorf("invalid klusterlet extra volumes annotation %v", err)
+	}
+
+	// MetricsPort
+	metricsPort, err := helpers.GetKlusterletMetricsPortFromManagedClusterAnnotations(b.ManagedClusterAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("Get klusterlet metrics port for cluster %s failed: %v", b.ClusterName, err)
+	}
+
+	// PDBMinAvailable, the klusterlet agents are not running on the managed cluster in the Hosted mode
+	pdbMinAvailable, err := helpers.GetKlusterletPDBMinAvailableFromManagedClusterAnnotations(
+		b.ManagedClusterAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("Get klusterlet pdb minAvailable for cluster %s failed: %v", b.ClusterName, err)
+	}
+	if len(pdbMinAvailable) != 0 {
+		files = append(files, klusterletPDBFiles[b.InstallMode]...)
+	}
+
+	// ClientCertExpiration
+	clientCertExpiration, err := helpers.GetClientCertExpirationFromManagedClusterAnnotations(
+		b.ManagedClusterAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("Get client cert expiration for cluster %s failed: %v", b.ClusterName, err)
+	}
+
+	// ClusterRoleLabels
+	clusterRoleLabels, err := helpers.GetClusterRoleAggregationLabelFromManagedClusterAnnotations(
+		b.ManagedClusterAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("invalid klusterlet clusterrole aggregation label annotation %v", err)
+	}
+
+	// ClusterClaims, they are rendered into the klusterlet cluster annotations
+	clusterClaims, err := helpers.GetClusterClaimsFromManagedClusterAnnotations(b.ManagedClusterAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("Get cluster claims for cluster %s failed: %v", b.ClusterName, err)
+	}
+	if err := helpers.ValidateClusterClaims(clusterClaims); err != nil {
+		return nil, fmt.Errorf("invalid cluster claims annotation %v", err)
+	}
+
+	// KubeDistribution, it is rendered into the klusterlet cluster annotations as a cluster claim
+	kubeDistribution, err := helpers.GetKubeDistributionFromManagedClusterAnnotations(b.ManagedClusterAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kube distribution annotation %v", err)
+	}
+	if _, ok := clusterClaims[constants.ProductClusterClaim]; !ok && len(kubeDistribution) != 0 {
+		if clusterClaims == nil {
+			clusterClaims = map[string]string{}
+		}
+		clusterClaims[constants.ProductClusterClaim] = kubeDistribution
+	}
+
+	// OpenShiftVersion, it is rendered into the klusterlet cluster annotations as a cluster claim
+	openshiftVersion, err := helpers.GetOpenShiftVersionFromManagedClusterAnnotations(b.ManagedClusterAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("invalid openshift version annotation %v", err)
+	}
+	if _, ok := clusterClaims[constants.OpenShiftVersionClusterClaim]; !ok && len(openshiftVersion) != 0 {
+		if clusterClaims == nil {
+			clusterClaims = map[string]string{}
+		}
+		clusterClaims[constants.OpenShiftVersionClusterClaim] = openshiftVersion
+	}
+
+	// CapacityClaims, they are rendered into the klusterlet cluster annotations as cluster claims
+	capacities, err := helpers.GetCapacityClaimsFromManagedClusterAnnotations(b.ManagedClusterAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("Get capacity claims for cluster %s failed: %v", b.ClusterName, err)
+	}
+	if err := helpers.ValidateCapacityClaims(capacities); err != nil {
+		return nil, fmt.Errorf("invalid capacity claims annotation %v", err)
+	}
+	for name, quantity := range capacities {
+		claimName := name + constants.CapacityClusterClaimSuffix
+		if _, ok := clusterClaims[claimName]; ok {
+			continue
+		}
+		if clusterClaims == nil {
+			clusterClaims = map[string]string{}
+		}
+		clusterClaims[claimName] = quantity.String()
+	}
+
+	// CSRApproval, it is rendered into the klusterlet cluster annotations
+	csrApproval, err := helpers.GetCSRApprovalFromManagedClusterAnnotations(b.ManagedClusterAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("invalid klusterlet CSR approval annotation %v", err)
+	}
+
+	// ClusterProxyHint, it is rendered into the klusterlet cluster annotations
+	clusterProxyHint, err := helpers.GetClusterProxyHintFromManagedClusterAnnotations(b.ManagedClusterAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster-proxy hint annotation %v", err)
+	}
+
+	// RenewalLeadTime, it is rendered into the klusterlet cluster annotations, the bounds are validated when the
+	// bootstrap kubeconfig is generated
+	renewalLeadTime := ""
+	if leadTime, ok := b.ManagedClusterAnnotations[constants.BootstrapKubeConfigRenewalLeadTimeAnnotation]; ok {
+		duration, err := time.ParseDuration(leadTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bootstrap kubeconfig renewal lead time annotation %v", err)
+		}
+		renewalLeadTime = duration.String()
+	}
+
+	clusterAnnotations := b.KlusterletClusterAnnotations
+	if len(clusterClaims) != 0 || len(csrApproval) != 0 || len(clusterProxyHint) != 0 || len(renewalLeadTime) != 0 {
+		clusterAnnotations = map[string]string{}
+		for key, value := range b.KlusterletClusterAnnotations {
+			clusterAnnotations[key] = value
+		}
+		for name, value := range clusterClaims {
+			clusterAnnotations[constants.ClusterClaimAnnotationPrefix+name] = value
+		}
+		if len(csrApproval) != 0 {
+			clusterAnnotations[constants.CSRApprovalClusterAnnotation] = csrApproval
+		}
+		if len(clusterProxyHint) != 0 {
+			clusterAnnotations[constants.ClusterProxyHintClusterAnnotation] = clusterProxyHint
+		}
+		if len(renewalLeadTime) != 0 {
+			clusterAnnotations[constants.BootstrapKubeConfigRenewalLeadTimeClusterAnnotation] = renewalLeadTime
+		}
+	}
+
 	renderConfig := RenderConfig{
 		KlusterletRenderConfig: KlusterletRenderConfig{
 			ManagedClusterNamespace: b.ClusterName,
 			KlusterletNamespace:     b.KlusterletNamespace,
+			OperatorNamespace:       operatorNamespace,
 			InstallMode:             string(b.InstallMode),
 
 			// BootstrapKubeConfig
@@ -222,8 +496,29 @@ func (b *KlusterletManifestsConfig) Generate(ctx context.Context, clientHolder *
 			NodeSelector: nodeSelector,
 			Tolerations:  tolerations,
 
+			// PodLabels
+			PodLabels: podLabels,
+
+			// NamespaceLabels
+			NamespaceLabels: namespaceLabels,
+
+			// ExtraVolumes
+			ExtraVolumes: extraVolumes,
+
+			// MetricsPort
+			MetricsPort: metricsPort,
+
+			// PDBMinAvailable
+			PDBMinAvailable: pdbMinAvailable,
+
+			// ClientCertExpiration
+			ClientCertExpiration: clientCertExpiration,
+
+			// ClusterRoleLabels
+			ClusterRoleLabels: clusterRoleLabels,
+
 			// KlusterletClusterAnnotations
-			ClusterAnnotations: b.KlusterletClusterAnnotations,
+			ClusterAnnotations: clusterAnnotations,
 		},
 	}
 
@@ -257,6 +552,26 @@ func (b *KlusterletManifestsConfig) Generate(ctx context.Context, clientHolder *
 	return manifestsBytes, nil
 }
 
+// validateKlusterletOperatorNamespace validates the klusterlet operator namespace, the operator namespace must
+// have the klusterlet namespace prefix, and it cannot be the addon namespace (<klusterlet namespace>-addon),
+// otherwise the operator will be removed together with the addons.
+func validateKlusterletOperatorNamespace(operatorNamespace, klusterletNamespace string) error {
+	if errMsgs := validation.IsDNS1123Label(operatorNamespace); len(errMsgs) != 0 {
+		return fmt.Errorf("invalid klusterlet operator namespace %s: %s", operatorNamespace, strings.Join(errMsgs, ";"))
+	}
+
+	if !strings.HasPrefix(operatorNamespace, klusterletNamespacePrefix) {
+		return fmt.Errorf("invalid klusterlet operator namespace %s, the namespace must have a prefix of %s",
+			operatorNamespace, klusterletNamespacePrefix)
+	}
+
+	if operatorNamespace == klusterletNamespace+addonNamespaceSuffix {
+		return fmt.Errorf("the klusterlet operator namespace %s collides with the addon namespace", operatorNamespace)
+	}
+
+	return nil
+}
+
 func GenerateKlusterletCRDsV1() ([]byte, error) {
 	return filesToTemplateBytes([]string{klusterletCrdsV1File}, nil)
 }
@@ -267,13 +582,13 @@ func GenerateKlusterletCRDsV1Beta1() ([]byte, error) {
 
 func GenerateHubBootstrapRBACObjects(managedClusterName string) ([]runtime.Object, error) {
 	return filesToObjects(hubFiles, struct {
-		ManagedClusterName          string
-		ManagedClusterNamespace     string
-		BootstrapServiceAccountName string
+		ManagedClusterName               string
+		BootstrapServiceAccountName      string
+		BootstrapServiceAccountNamespace string
 	}{
-		ManagedClusterName:          managedClusterName,
-		ManagedClusterNamespace:     managedClusterName,
-		BootstrapServiceAccountName: GetBootstrapSAName(managedClusterName),
+		ManagedClusterName:               managedClusterName,
+		BootstrapServiceAccountName:      GetBootstrapSAName(managedClusterName),
+		BootstrapServiceAccountNamespace: GetBootstrapSANamespace(managedClusterName),
 	})
 }
 
@@ -3,6 +3,7 @@ package bootstrap
 import (
 	"context"
 	"os"
+	"reflect"
 	"testing"
 
 	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
@@ -11,6 +12,8 @@ import (
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
 	appv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	kubefake "k8s.io/client-go/kubernetes/fake"
@@ -178,6 +181,801 @@ func TestKlusterletConfigGenerate(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "default customized with klusterlet pod labels",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+			},
+			config: NewKlusterletManifestsConfig(
+				operatorv1.InstallModeDefault,
+				"test", // cluster name
+				"test", // klusterlet namespace
+				[]byte("bootstrap kubeconfig"),
+			).WithManagedClusterAnnotations(map[string]string{
+				constants.KlusterletPodLabelsAnnotation: "{\"network-policy/egress\":\"allow\"}",
+			}),
+			validateFunc: func(t *testing.T, objects []runtime.Object) {
+				operater, ok := objects[6].(*appv1.Deployment)
+				if !ok {
+					t.Fatal("the operater is not deployment")
+				}
+
+				labels := operater.Spec.Template.Labels
+				if labels["network-policy/egress"] != "allow" {
+					t.Errorf("the operater pod label %s is not %s", labels["network-policy/egress"], "allow")
+				}
+				if labels["app"] != "klusterlet" {
+					t.Errorf("the operater pod label %s is not %s", labels["app"], "klusterlet")
+				}
+			},
+		},
+		{
+			name: "default with klusterlet metrics port",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+			},
+			config: NewKlusterletManifestsConfig(
+				operatorv1.InstallModeDefault,
+				"test", // cluster name
+				"test", // klusterlet namespace
+				[]byte("bootstrap kubeconfig"),
+			).WithManagedClusterAnnotations(map[string]string{
+				constants.KlusterletMetricsPortAnnotation: "9443",
+			}),
+			validateFunc: func(t *testing.T, objects []runtime.Object) {
+				operater, ok := objects[6].(*appv1.Deployment)
+				if !ok {
+					t.Fatal("the operater is not deployment")
+				}
+
+				container := operater.Spec.Template.Spec.Containers[0]
+				listenArg := false
+				for _, arg := range container.Args {
+					if arg == "--listen=0.0.0.0:9443" {
+						listenArg = true
+					}
+				}
+				if !listenArg {
+					t.Errorf("the operater listen arg is not rendered, args: %v", container.Args)
+				}
+				if container.LivenessProbe.HTTPGet.Port.IntValue() != 9443 {
+					t.Errorf("the operater liveness probe port %s is not 9443", container.LivenessProbe.HTTPGet.Port.String())
+				}
+				if container.ReadinessProbe.HTTPGet.Port.IntValue() != 9443 {
+					t.Errorf("the operater readiness probe port %s is not 9443", container.ReadinessProbe.HTTPGet.Port.String())
+				}
+			},
+		},
+		{
+			name: "default with klusterlet extra volumes",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+			},
+			config: NewKlusterletManifestsConfig(
+				operatorv1.InstallModeDefault,
+				"test", // cluster name
+				"test", // klusterlet namespace
+				[]byte("bootstrap kubeconfig"),
+			).WithManagedClusterAnnotations(map[string]string{
+				constants.KlusterletExtraVolumesAnnotation: `[` +
+					`{"name":"custom-ca","configMap":"custom-ca","mountPath":"/etc/custom-ca"},` +
+					`{"name":"custom-creds","secret":"custom-creds","mountPath":"/etc/custom-creds"}]`,
+			}),
+			validateFunc: func(t *testing.T, objects []runtime.Object) {
+				operater, ok := objects[6].(*appv1.Deployment)
+				if !ok {
+					t.Fatal("the operater is not deployment")
+				}
+
+				expectedVolumes := []corev1.Volume{
+					{
+						Name:         "tmpdir",
+						VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+					},
+					{
+						Name: "custom-ca",
+						VolumeSource: corev1.VolumeSource{
+							ConfigMap: &corev1.ConfigMapVolumeSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "custom-ca"},
+							},
+						},
+					},
+					{
+						Name: "custom-creds",
+						VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{SecretName: "custom-creds"},
+						},
+					},
+				}
+				if !reflect.DeepEqual(operater.Spec.Template.Spec.Volumes, expectedVolumes) {
+					t.Errorf("expected volumes %v, but got %v", expectedVolumes, operater.Spec.Template.Spec.Volumes)
+				}
+
+				expectedMounts := []corev1.VolumeMount{
+					{Name: "tmpdir", MountPath: "/tmp"},
+					{Name: "custom-ca", MountPath: "/etc/custom-ca", ReadOnly: true},
+					{Name: "custom-creds", MountPath: "/etc/custom-creds", ReadOnly: true},
+				}
+				container := operater.Spec.Template.Spec.Containers[0]
+				if !reflect.DeepEqual(container.VolumeMounts, expectedMounts) {
+					t.Errorf("expected volume mounts %v, but got %v", expectedMounts, container.VolumeMounts)
+				}
+			},
+		},
+		{
+			name: "default with klusterlet pdb",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+			},
+			config: NewKlusterletManifestsConfig(
+				operatorv1.InstallModeDefault,
+				"test", // cluster name
+				"test", // klusterlet namespace
+				[]byte("bootstrap kubeconfig"),
+			).WithManagedClusterAnnotations(map[string]string{
+				constants.KlusterletPDBMinAvailableAnnotation: "50%",
+			}),
+			validateFunc: func(t *testing.T, objects []runtime.Object) {
+				pdbs := map[string]*policyv1.PodDisruptionBudget{}
+				for _, obj := range objects {
+					if p, ok := obj.(*policyv1.PodDisruptionBudget); ok {
+						pdbs[p.Spec.Selector.MatchLabels["app"]] = p
+					}
+				}
+				// the pdbs select the registration agent and the work agent in the Default mode
+				for _, app := range []string{"klusterlet-registration-agent", "klusterlet-manifestwork-agent"} {
+					pdb, ok := pdbs[app]
+					if !ok {
+						t.Fatalf("the klusterlet pdb of %s is not rendered, got %v", app, pdbs)
+					}
+					if pdb.Namespace != "test" {
+						t.Errorf("the klusterlet pdb namespace %s is not test", pdb.Namespace)
+					}
+					if pdb.Spec.MinAvailable == nil || pdb.Spec.MinAvailable.String() != "50%" {
+						t.Errorf("the klusterlet pdb minAvailable %v is not 50%%", pdb.Spec.MinAvailable)
+					}
+				}
+				if len(pdbs) != 2 {
+					t.Errorf("expected 2 klusterlet pdbs, but got %v", pdbs)
+				}
+			},
+		},
+		{
+			name: "singleton with klusterlet pdb",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+			},
+			config: NewKlusterletManifestsConfig(
+				operatorv1.InstallModeSingleton,
+				"test", // cluster name
+				"test", // klusterlet namespace
+				[]byte("bootstrap kubeconfig"),
+			).WithManagedClusterAnnotations(map[string]string{
+				constants.KlusterletPDBMinAvailableAnnotation: "1",
+			}),
+			validateFunc: func(t *testing.T, objects []runtime.Object) {
+				pdbs := []*policyv1.PodDisruptionBudget{}
+				for _, obj := range objects {
+					if p, ok := obj.(*policyv1.PodDisruptionBudget); ok {
+						pdbs = append(pdbs, p)
+					}
+				}
+				if len(pdbs) != 1 {
+					t.Fatalf("expected 1 klusterlet pdb, but got %v", pdbs)
+				}
+				if pdbs[0].Spec.Selector.MatchLabels["app"] != "klusterlet-agent" {
+					t.Errorf("the klusterlet pdb selector %v is not app=klusterlet-agent",
+						pdbs[0].Spec.Selector.MatchLabels)
+				}
+			},
+		},
+		{
+			name: "default with client cert expiration",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+			},
+			config: NewKlusterletManifestsConfig(
+				operatorv1.InstallModeDefault,
+				"test", // cluster name
+				"test", // klusterlet namespace
+				[]byte("bootstrap kubeconfig"),
+			).WithManagedClusterAnnotations(map[string]string{
+				constants.KlusterletClientCertExpirationAnnotation: "86400",
+			}),
+			validateFunc: func(t *testing.T, objects []runtime.Object) {
+				klusterlet, ok := objects[8].(*operatorv1.Klusterlet)
+				if !ok {
+					t.Fatal("the klusterlet is not klusterlet")
+				}
+				if klusterlet.Spec.RegistrationConfiguration == nil {
+					t.Fatal("the klusterlet registration configuration is not rendered")
+				}
+				if klusterlet.Spec.RegistrationConfiguration.ClientCertExpirationSeconds != 86400 {
+					t.Errorf("the klusterlet client cert expiration %d is not %d",
+						klusterlet.Spec.RegistrationConfiguration.ClientCertExpirationSeconds, 86400)
+				}
+			},
+		},
+		{
+			name: "default with clusterrole aggregation label",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+			},
+			config: NewKlusterletManifestsConfig(
+				operatorv1.InstallModeDefault,
+				"test", // cluster name
+				"test", // klusterlet namespace
+				[]byte("bootstrap kubeconfig"),
+			).WithManagedClusterAnnotations(map[string]string{
+				constants.KlusterletClusterRoleAggregationLabelAnnotation: "rbac.example.com/aggregate-to-agent=true",
+			}),
+			validateFunc: func(t *testing.T, objects []runtime.Object) {
+				for _, index := range []int{2, 3} {
+					clusterRole, ok := objects[index].(*rbacv1.ClusterRole)
+					if !ok {
+						t.Fatalf("the object %d is not clusterrole", index)
+					}
+					if len(clusterRole.Labels) != 0 {
+						t.Errorf("expected no labels on the clusterrole %s, but got %v", clusterRole.Name, clusterRole.Labels)
+					}
+				}
+
+				aggregateClusterRole, ok := objects[4].(*rbacv1.ClusterRole)
+				if !ok {
+					t.Fatal("the object 4 is not clusterrole")
+				}
+				expectedLabels := map[string]string{
+					"rbac.authorization.k8s.io/aggregate-to-admin": "true",
+					"rbac.example.com/aggregate-to-agent":          "true",
+				}
+				if !reflect.DeepEqual(aggregateClusterRole.Labels, expectedLabels) {
+					t.Errorf("expected labels %v on the clusterrole %s, but got %v",
+						expectedLabels, aggregateClusterRole.Name, aggregateClusterRole.Labels)
+				}
+			},
+		},
+		{
+			name: "default with cluster claims",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+			},
+			config: NewKlusterletManifestsConfig(
+				operatorv1.InstallModeDefault,
+				"test", // cluster name
+				"test", // klusterlet namespace
+				[]byte("bootstrap kubeconfig"),
+			).WithKlusterletClusterAnnotations(map[string]string{
+				"agent.open-cluster-management.io/test": "test",
+			}).WithManagedClusterAnnotations(map[string]string{
+				constants.ClusterClaimsAnnotation: "{\"platform.open-cluster-management.io\":\"AWS\"," +
+					"\"region.open-cluster-management.io\":\"us-east-1\"}",
+			}),
+			validateFunc: func(t *testing.T, objects []runtime.Object) {
+				klusterlet, ok := objects[8].(*operatorv1.Klusterlet)
+				if !ok {
+					t.Fatal("the klusterlet is not klusterlet")
+				}
+
+				expected := map[string]string{
+					"agent.open-cluster-management.io/test":                                      "test",
+					"agent.open-cluster-management.io/claim-platform.open-cluster-management.io": "AWS",
+					"agent.open-cluster-management.io/claim-region.open-cluster-management.io":   "us-east-1",
+				}
+				if !reflect.DeepEqual(klusterlet.Spec.RegistrationConfiguration.ClusterAnnotations, expected) {
+					t.Errorf("expected cluster annotations %v, but got %v",
+						expected, klusterlet.Spec.RegistrationConfiguration.ClusterAnnotations)
+				}
+			},
+		},
+		{
+			name: "default with kube distribution",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+			},
+			config: NewKlusterletManifestsConfig(
+				operatorv1.InstallModeDefault,
+				"test", // cluster name
+				"test", // klusterlet namespace
+				[]byte("bootstrap kubeconfig"),
+			).WithManagedClusterAnnotations(map[string]string{
+				constants.KubeDistributionAnnotation: "k3s",
+			}),
+			validateFunc: func(t *testing.T, objects []runtime.Object) {
+				klusterlet, ok := objects[8].(*operatorv1.Klusterlet)
+				if !ok {
+					t.Fatal("the klusterlet is not klusterlet")
+				}
+
+				expected := map[string]string{
+					constants.ClusterClaimAnnotationPrefix + constants.ProductClusterClaim: "K3s",
+				}
+				if !reflect.DeepEqual(klusterlet.Spec.RegistrationConfiguration.ClusterAnnotations, expected) {
+					t.Errorf("expected cluster annotations %v, but got %v",
+						expected, klusterlet.Spec.RegistrationConfiguration.ClusterAnnotations)
+				}
+			},
+		},
+		{
+			name: "default with openshift version",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+			},
+			config: NewKlusterletManifestsConfig(
+				operatorv1.InstallModeDefault,
+				"test", // cluster name
+				"test", // klusterlet namespace
+				[]byte("bootstrap kubeconfig"),
+			).WithManagedClusterAnnotations(map[string]string{
+				constants.OpenShiftVersionAnnotation: "4.14.3",
+				constants.ClusterClaimsAnnotation:    `{"platform.open-cluster-management.io":"AWS"}`,
+			}),
+			validateFunc: func(t *testing.T, objects []runtime.Object) {
+				klusterlet, ok := objects[8].(*operatorv1.Klusterlet)
+				if !ok {
+					t.Fatal("the klusterlet is not klusterlet")
+				}
+
+				expected := map[string]string{
+					constants.ClusterClaimAnnotationPrefix + constants.OpenShiftVersionClusterClaim: "4.14.3",
+					constants.ClusterClaimAnnotationPrefix + "platform.open-cluster-management.io":  "AWS",
+				}
+				if !reflect.DeepEqual(klusterlet.Spec.RegistrationConfiguration.ClusterAnnotations, expected) {
+					t.Errorf("expected cluster annotations %v, but got %v",
+						expected, klusterlet.Spec.RegistrationConfiguration.ClusterAnnotations)
+				}
+			},
+		},
+		{
+			name: "default with capacity claims",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+			},
+			config: NewKlusterletManifestsConfig(
+				operatorv1.InstallModeDefault,
+				"test", // cluster name
+				"test", // klusterlet namespace
+				[]byte("bootstrap kubeconfig"),
+			).WithManagedClusterAnnotations(map[string]string{
+				constants.CapacityClaimsAnnotation: `{"gpu":"4","storage":"0.5Ti"}`,
+				constants.ClusterClaimsAnnotation:  `{"storage.capacity.open-cluster-management.io":"1Ti"}`,
+			}),
+			validateFunc: func(t *testing.T, objects []runtime.Object) {
+				klusterlet, ok := objects[8].(*operatorv1.Klusterlet)
+				if !ok {
+					t.Fatal("the klusterlet is not klusterlet")
+				}
+
+				expected := map[string]string{
+					constants.ClusterClaimAnnotationPrefix + "gpu" + constants.CapacityClusterClaimSuffix:     "4",
+					constants.ClusterClaimAnnotationPrefix + "storage" + constants.CapacityClusterClaimSuffix: "1Ti",
+				}
+				if !reflect.DeepEqual(klusterlet.Spec.RegistrationConfiguration.ClusterAnnotations, expected) {
+					t.Errorf("expected cluster annotations %v, but got %v",
+						expected, klusterlet.Spec.RegistrationConfiguration.ClusterAnnotations)
+				}
+			},
+		},
+		{
+			name: "default with csr approval",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+			},
+			config: NewKlusterletManifestsConfig(
+				operatorv1.InstallModeDefault,
+				"test", // cluster name
+				"test", // klusterlet namespace
+				[]byte("bootstrap kubeconfig"),
+			).WithManagedClusterAnnotations(map[string]string{
+				constants.KlusterletCSRApprovalAnnotation: constants.CSRApprovalManual,
+			}),
+			validateFunc: func(t *testing.T, objects []runtime.Object) {
+				klusterlet, ok := objects[8].(*operatorv1.Klusterlet)
+				if !ok {
+					t.Fatal("the klusterlet is not klusterlet")
+				}
+
+				expected := map[string]string{
+					constants.CSRApprovalClusterAnnotation: constants.CSRApprovalManual,
+				}
+				if !reflect.DeepEqual(klusterlet.Spec.RegistrationConfiguration.ClusterAnnotations, expected) {
+					t.Errorf("expected cluster annotations %v, but got %v",
+						expected, klusterlet.Spec.RegistrationConfiguration.ClusterAnnotations)
+				}
+			},
+		},
+		{
+			name: "default with bootstrap kubeconfig renewal lead time",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+			},
+			config: NewKlusterletManifestsConfig(
+				operatorv1.InstallModeDefault,
+				"test", // cluster name
+				"test", // klusterlet namespace
+				[]byte("bootstrap kubeconfig"),
+			).WithManagedClusterAnnotations(map[string]string{
+				constants.BootstrapKubeConfigRenewalLeadTimeAnnotation: "720h",
+			}),
+			validateFunc: func(t *testing.T, objects []runtime.Object) {
+				klusterlet, ok := objects[8].(*operatorv1.Klusterlet)
+				if !ok {
+					t.Fatal("the klusterlet is not klusterlet")
+				}
+
+				expected := map[string]string{
+					constants.BootstrapKubeConfigRenewalLeadTimeClusterAnnotation: "720h0m0s",
+				}
+				if !reflect.DeepEqual(klusterlet.Spec.RegistrationConfiguration.ClusterAnnotations, expected) {
+					t.Errorf("expected cluster annotations %v, but got %v",
+						expected, klusterlet.Spec.RegistrationConfiguration.ClusterAnnotations)
+				}
+			},
+		},
+		{
+			name: "default with cluster-proxy hint",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+			},
+			config: NewKlusterletManifestsConfig(
+				operatorv1.InstallModeDefault,
+				"test", // cluster name
+				"test", // klusterlet namespace
+				[]byte("bootstrap kubeconfig"),
+			).WithManagedClusterAnnotations(map[string]string{
+				constants.ClusterProxyHintAnnotation: constants.ClusterProxyHintTunnel,
+			}),
+			validateFunc: func(t *testing.T, objects []runtime.Object) {
+				klusterlet, ok := objects[8].(*operatorv1.Klusterlet)
+				if !ok {
+					t.Fatal("the klusterlet is not klusterlet")
+				}
+
+				expected := map[string]string{
+					constants.ClusterProxyHintClusterAnnotation: constants.ClusterProxyHintTunnel,
+				}
+				if !reflect.DeepEqual(klusterlet.Spec.RegistrationConfiguration.ClusterAnnotations, expected) {
+					t.Errorf("expected cluster annotations %v, but got %v",
+						expected, klusterlet.Spec.RegistrationConfiguration.ClusterAnnotations)
+				}
+			},
+		},
+		{
+			name: "default with a separate klusterlet operator namespace",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+			},
+			config: NewKlusterletManifestsConfig(
+				operatorv1.InstallModeDefault,
+				"test",                          // cluster name
+				"open-cluster-management-agent", // klusterlet namespace
+				[]byte("bootstrap kubeconfig"),
+			).WithKlusterletOperatorNamespace("open-cluster-management-operator"),
+			validateFunc: func(t *testing.T, objects []runtime.Object) {
+				if len(objects) != 11 {
+					t.Fatalf("Expected 11 objects, but got %d", len(objects))
+				}
+
+				operatorNamespace, ok := objects[0].(*corev1.Namespace)
+				if !ok || operatorNamespace.Name != "open-cluster-management-operator" {
+					t.Errorf("the first element is not the operator namespace")
+				}
+				agentNamespace, ok := objects[1].(*corev1.Namespace)
+				if !ok || agentNamespace.Name != "open-cluster-management-agent" {
+					t.Errorf("the second element is not the agent namespace")
+				}
+
+				operater, ok := objects[7].(*appv1.Deployment)
+				if !ok {
+					t.Fatal("the operater is not deployment")
+				}
+				if operater.Namespace != "open-cluster-management-operator" {
+					t.Errorf("the operater namespace %s is not %s", operater.Namespace, "open-cluster-management-operator")
+				}
+
+				bootstrapSecret, ok := objects[8].(*corev1.Secret)
+				if !ok {
+					t.Fatal("the bootstrap secret is not secret")
+				}
+				if bootstrapSecret.Namespace != "open-cluster-management-agent" {
+					t.Errorf("the bootstrap secret namespace %s is not %s",
+						bootstrapSecret.Namespace, "open-cluster-management-agent")
+				}
+
+				klusterlet, ok := objects[9].(*operatorv1.Klusterlet)
+				if !ok {
+					t.Fatal("the klusterlet is not klusterlet")
+				}
+				if klusterlet.Spec.Namespace != "open-cluster-management-agent" {
+					t.Errorf("the klusterlet namespace %s is not %s",
+						klusterlet.Spec.Namespace, "open-cluster-management-agent")
+				}
+			},
+		},
+		{
+			name: "default with klusterlet namespace labels",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+			},
+			config: NewKlusterletManifestsConfig(
+				operatorv1.InstallModeDefault,
+				"test",                          // cluster name
+				"open-cluster-management-agent", // klusterlet namespace
+				[]byte("bootstrap kubeconfig"),
+			).WithKlusterletOperatorNamespace("open-cluster-management-operator").
+				WithManagedClusterAnnotations(map[string]string{
+					constants.KlusterletNamespaceLabelsAnnotation: "{\"team\":\"platform\"}",
+				}),
+			validateFunc: func(t *testing.T, objects []runtime.Object) {
+				for i, name := range []string{"open-cluster-management-operator", "open-cluster-management-agent"} {
+					namespace, ok := objects[i].(*corev1.Namespace)
+					if !ok || namespace.Name != name {
+						t.Fatalf("the element %d is not the namespace %s", i, name)
+					}
+					if namespace.Labels["team"] != "platform" {
+						t.Errorf("the namespace %s label %s is not %s", name, namespace.Labels["team"], "platform")
+					}
+					if namespace.Annotations["workload.openshift.io/allowed"] != "management" {
+						t.Errorf("the namespace %s annotations are not kept: %v", name, namespace.Annotations)
+					}
+				}
+			},
+		},
 		{
 			name: "default customized with klusterletconfig",
 			clientObjs: []runtimeclient.Object{
@@ -263,6 +1061,74 @@ func TestKlusterletConfigGenerate(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "default with per-component klusterlet images",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+			},
+			config: NewKlusterletManifestsConfig(
+				operatorv1.InstallModeDefault,
+				"test", // cluster name
+				"test", // klusterlet namespace
+				[]byte("bootstrap kubeconfig"),
+			).WithKlusterletConfig(&klusterletconfigv1alpha1.KlusterletConfig{
+				Spec: klusterletconfigv1alpha1.KlusterletConfigSpec{
+					Registries: []klusterletconfigv1alpha1.Registries{
+						{
+							Source: "quay.io/open-cluster-management",
+							Mirror: "quay.io/rhacm2",
+						},
+					},
+				},
+			}).WithManagedClusterAnnotations(map[string]string{
+				constants.KlusterletImagesAnnotation: `{"registration-operator":"mirror.example.com/ocm/operator:v1",` +
+					`"work":"mirror.example.com/ocm/work:v1"}`,
+			}),
+			validateFunc: func(t *testing.T, objects []runtime.Object) {
+				klusterlet, ok := objects[8].(*operatorv1.Klusterlet)
+				if !ok {
+					t.Fatal("the klusterlet is not klusterlet")
+				}
+				if klusterlet.Spec.ImagePullSpec != "mirror.example.com/ocm/operator:v1" {
+					t.Errorf("the klusterlet image pull spec %s is not %s",
+						klusterlet.Spec.ImagePullSpec, "mirror.example.com/ocm/operator:v1")
+				}
+				if klusterlet.Spec.WorkImagePullSpec != "mirror.example.com/ocm/work:v1" {
+					t.Errorf("the klusterlet work image pull spec %s is not %s",
+						klusterlet.Spec.WorkImagePullSpec, "mirror.example.com/ocm/work:v1")
+				}
+				// the component without an override is still overridden by the registries
+				if klusterlet.Spec.RegistrationImagePullSpec != "quay.io/rhacm2/registration:latest" {
+					t.Errorf("the klusterlet registration image pull spec %s is not %s",
+						klusterlet.Spec.RegistrationImagePullSpec, "quay.io/rhacm2/registration:latest")
+				}
+
+				operater, ok := objects[6].(*appv1.Deployment)
+				if !ok {
+					t.Fatal("the operater is not deployment")
+				}
+				if operater.Spec.Template.Spec.Containers[0].Image != "mirror.example.com/ocm/operator:v1" {
+					t.Errorf("the operater image %s is not %s",
+						operater.Spec.Template.Spec.Containers[0].Image, "mirror.example.com/ocm/operator:v1")
+				}
+			},
+		},
 	}
 
 	for _, testcase := range testcases {
@@ -285,3 +1151,149 @@ func TestKlusterletConfigGenerate(t *testing.T) {
 		})
 	}
 }
+
+func TestKlusterletImageDigestPolicy(t *testing.T) {
+	digest := "@sha256:2d4b8d3b2bc1e4f5a6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9"
+
+	cases := []struct {
+		name                string
+		annotations         map[string]string
+		expectedErr         bool
+		expectedNotPinned   bool
+		expectedWorkImage   string
+		expectedOperatorImg string
+	}{
+		{
+			name: "tag based images are allowed without the policy",
+			annotations: map[string]string{
+				constants.KlusterletImageDigestPolicyAnnotation: constants.ImageDigestPolicyOptional,
+			},
+			expectedWorkImage:   "quay.io/open-cluster-management/work:latest",
+			expectedOperatorImg: "quay.io/open-cluster-management/registration-operator:latest",
+		},
+		{
+			name: "tag based images are rejected under the policy",
+			annotations: map[string]string{
+				constants.KlusterletImageDigestPolicyAnnotation: constants.ImageDigestPolicyRequired,
+				constants.KlusterletImagesAnnotation: `{"registration-operator":"mirror.example.com/ocm/operator` +
+					digest + `"}`,
+			},
+			expectedErr:       true,
+			expectedNotPinned: true,
+		},
+		{
+			name: "digest pinned images are allowed under the policy",
+			annotations: map[string]string{
+				constants.KlusterletImageDigestPolicyAnnotation: constants.ImageDigestPolicyRequired,
+				constants.KlusterletImagesAnnotation: `{"registration-operator":"mirror.example.com/ocm/operator` +
+					digest + `","registration":"mirror.example.com/ocm/registration` + digest +
+					`","work":"mirror.example.com/ocm/work:v1` + digest + `"}`,
+			},
+			expectedWorkImage:   "mirror.example.com/ocm/work:v1" + digest,
+			expectedOperatorImg: "mirror.example.com/ocm/operator" + digest,
+		},
+		{
+			name: "invalid policy",
+			annotations: map[string]string{
+				constants.KlusterletImageDigestPolicyAnnotation: "Always",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			clientHolder := &helpers.ClientHolder{
+				KubeClient:          kubeClient,
+				RuntimeClient:       fake.NewClientBuilder().WithScheme(testscheme).Build(),
+				ImageRegistryClient: imageregistry.NewClient(kubeClient),
+			}
+			manifestsBytes, err := NewKlusterletManifestsConfig(
+				operatorv1.InstallModeDefault,
+				"test", // cluster name
+				"test", // klusterlet namespace
+				[]byte("bootstrap kubeconfig"),
+			).WithManagedClusterAnnotations(c.annotations).Generate(context.Background(), clientHolder)
+			if c.expectedErr {
+				if err == nil {
+					t.Fatalf("expected an error, but got nil")
+				}
+				if helpers.IsImagesNotDigestPinned(err) != c.expectedNotPinned {
+					t.Errorf("expected images not digest pinned %v, but got %v", c.expectedNotPinned, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			objs := []runtime.Object{}
+			for _, yaml := range helpers.SplitYamls(manifestsBytes) {
+				objs = append(objs, helpers.MustCreateObject(yaml))
+			}
+			klusterlet, ok := objs[8].(*operatorv1.Klusterlet)
+			if !ok {
+				t.Fatal("the klusterlet is not klusterlet")
+			}
+			if klusterlet.Spec.ImagePullSpec != c.expectedOperatorImg {
+				t.Errorf("the klusterlet image pull spec %s is not %s",
+					klusterlet.Spec.ImagePullSpec, c.expectedOperatorImg)
+			}
+			if klusterlet.Spec.WorkImagePullSpec != c.expectedWorkImage {
+				t.Errorf("the klusterlet work image pull spec %s is not %s",
+					klusterlet.Spec.WorkImagePullSpec, c.expectedWorkImage)
+			}
+		})
+	}
+}
+
+func TestValidateKlusterletOperatorNamespace(t *testing.T) {
+	cases := []struct {
+		name                string
+		operatorNamespace   string
+		klusterletNamespace string
+		expectedErr         bool
+	}{
+		{
+			name:                "valid operator namespace",
+			operatorNamespace:   "open-cluster-management-operator",
+			klusterletNamespace: "open-cluster-management-agent",
+		},
+		{
+			name:                "operator namespace without prefix",
+			operatorNamespace:   "klusterlet-operator",
+			klusterletNamespace: "open-cluster-management-agent",
+			expectedErr:         true,
+		},
+		{
+			name:                "operator namespace collides with the addon namespace",
+			operatorNamespace:   "open-cluster-management-agent-addon",
+			klusterletNamespace: "open-cluster-management-agent",
+			expectedErr:         true,
+		},
+		{
+			name:                "operator namespace collides with the addon namespace of a customized klusterlet namespace",
+			operatorNamespace:   "open-cluster-management-test-addon",
+			klusterletNamespace: "open-cluster-management-test",
+			expectedErr:         true,
+		},
+		{
+			name:                "operator namespace does not collide with the addon namespace of a customized klusterlet namespace",
+			operatorNamespace:   "open-cluster-management-agent-addon",
+			klusterletNamespace: "open-cluster-management-test",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := validateKlusterletOperatorNamespace(c.operatorNamespace, c.klusterletNamespace)
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -14,8 +14,8 @@ const (
 	// AutoImportRetryName is the secret data key of auto import retry
 	AutoImportRetryName string = "autoImportRetry"
 
-	// AnnotationAutoImportCurrentRetry is the annotation key of auto import secret used to indicate
-	// the current retry times of auto importing a managed cluster
+	// AnnotationAutoImportCurrentRetry is the annotation key of auto import secret (or the clusterdeployment) used to
+	// indicate the current retry times of auto importing a managed cluster
 	AnnotationAutoImportCurrentRetry = "managedcluster-import-controller.open-cluster-management.io/current-retry"
 
 	// AnnotationKeepingAutoImportSecret is the annotation key of auto import secret used to indicate
@@ -24,6 +24,15 @@ const (
 
 	// LabelAutoImportRestore is the label key of auto import secret used for backup restore case
 	LabelAutoImportRestore = "cluster.open-cluster-management.io/restore-auto-import-secret"
+
+	// AutoImportStrategyKey is the secret data key of auto import strategy, if it is not set, the klusterlet is
+	// deployed with the auto import secret
+	AutoImportStrategyKey string = "autoImportStrategy"
+
+	// AutoImportStrategyToken is the auto import strategy for the managed clusters that register themselves with
+	// a deployed klusterlet, the auto import secret contains a server and a bootstrap token that has the limited
+	// permissions, only the bootstrap hub kubeconfig secret of the klusterlet is applied with the token
+	AutoImportStrategyToken = "token"
 )
 
 /* #nosec */
@@ -36,8 +45,19 @@ const (
 
 const PodNamespaceEnvVarName = "POD_NAMESPACE"
 
+// BootstrapSANamespaceEnvVarName is the env var name of the namespace where the bootstrap service accounts of
+// the managed clusters are placed on the hub, if it is not set, the managed cluster namespace is used.
+const BootstrapSANamespaceEnvVarName = "BOOTSTRAP_SA_NAMESPACE"
+
 const ImportFinalizer string = "managedcluster-import-controller.open-cluster-management.io/cleanup"
 
+// LegacyImportFinalizers are the finalizers that were set by the previous releases on the managed clusters
+// and clusterdeployments, they are not used anymore and will be removed by the controllers
+var LegacyImportFinalizers = []string{
+	"rcm-api.cluster",
+	"managedcluster-import-controller/cleanup",
+}
+
 const SelfManagedLabel string = "local-cluster"
 
 const (
@@ -46,14 +66,28 @@ const (
 	HostedClusterLabel       = "import.open-cluster-management.io/hosted-cluster"
 )
 
+// KlusterletWorksHubIdentityAnnotation records the identity of the hub that creates the klusterlet manifestworks
+const KlusterletWorksHubIdentityAnnotation = "import.open-cluster-management.io/hub-identity"
+
 const (
 	CreatedViaAnnotation = "open-cluster-management/created-via"
 	CreatedViaAI         = "assisted-installer"
 	CreatedViaHive       = "hive"
 	CreatedViaDiscovery  = "discovery"
 	CreatedViaHypershift = "hypershift"
+	CreatedViaOther      = "other"
+
+	// CreatedViaImmutableAnnotation prevents the created-via annotation of a managed cluster from being overridden
+	// by the import controller if its value is "true", it is used by the clusters that are provisioned by a custom
+	// pipeline and have their own provenance in the created-via annotation.
+	CreatedViaImmutableAnnotation = "import.open-cluster-management.io/created-via-immutable"
 )
 
+// ImportSecretNameSuffixEnvVarName is the env var name of the suffix of the import secret name, the import secret
+// is named <cluster name>-<suffix>, if it is not set, the ImportSecretNameSuffix is used. It allows multiple
+// controllers on the same hub to generate their own import secrets without colliding.
+const ImportSecretNameSuffixEnvVarName = "IMPORT_SECRET_NAME_SUFFIX"
+
 /* #nosec */
 const (
 	ImportSecretNameSuffix         = "import"
@@ -62,6 +96,16 @@ const (
 	ImportSecretCRDSV1YamlKey      = "crdsv1.yaml"
 	ImportSecretCRDSV1beta1YamlKey = "crdsv1beta1.yaml"
 	ImportSecretTokenExpiration    = "expiration"
+
+	// ImportSecretImportYamlGzipKey is the key of the gzip compressed import.yaml, the import.yaml is compressed
+	// only when the import secret exceeds the size limit, in that case, the ImportSecretImportYamlKey is not set
+	ImportSecretImportYamlGzipKey = "import.yaml.gz"
+
+	// ImportSecretControllerVersionAnnotation is the annotation key of the import secret that records the schema
+	// version of the klusterlet manifests in the import secret. An import secret with a different version is
+	// generated by another controller version, its klusterlet manifests are regenerated to the current schema on
+	// reconcile, and its bootstrap kubeconfig is kept if it is still valid.
+	ImportSecretControllerVersionAnnotation = "import.open-cluster-management.io/controller-version"
 )
 
 const (
@@ -70,6 +114,12 @@ const (
 	// we use HostingClusterNameAnnotation to determine where to deploy the registration-agent and work-agent.
 	KlusterletDeployModeAnnotation string = "import.open-cluster-management.io/klusterlet-deploy-mode"
 
+	// HostedKonnectivityEndpointAnnotation is used to specify the konnectivity proxy endpoint of a Hosted mode
+	// managed cluster whose kube-apiserver is only reachable through konnectivity, e.g. http://konnectivity:8090.
+	// The scheme must be http, https or socks5, the endpoint is set as the proxy-url of the clusters in the
+	// external managed kubeconfig that is delivered to the hosting cluster.
+	HostedKonnectivityEndpointAnnotation string = "import.open-cluster-management.io/hosted-konnectivity-endpoint"
+
 	// HostingClusterNameAnnotation is required in Hosted mode, and the hosting cluster MUST be one
 	// of the managed cluster of the hub. The value of the annotation should be the ManagedCluster name of
 	// the hosting cluster.
@@ -81,8 +131,262 @@ const (
 	// In the Hosted mode, this namespace still exists on the managed cluster to contain
 	// necessary resources, like service accounts, roles and rolebindings.
 	KlusterletNamespaceAnnotation string = "import.open-cluster-management.io/klusterlet-namespace"
+
+	// KlusterletOperatorNamespaceAnnotation is used to customize the namespace to deploy the klusterlet operator
+	// on the managed cluster, if it is not set, the operator is deployed in the same namespace as the agent.
+	// The namespace must have a prefix of "open-cluster-management-" and cannot be the addon namespace
+	// "<klusterlet namespace>-addon". This annotation is ignored in the Hosted mode.
+	KlusterletOperatorNamespaceAnnotation string = "import.open-cluster-management.io/klusterlet-operator-namespace"
+
+	// KubeconfigContextNameAnnotation is used to customize the context name of the bootstrap hub kubeconfig
+	// that is consumed by the klusterlet, if it is not set, the context name "default-context" is used.
+	KubeconfigContextNameAnnotation string = "import.open-cluster-management.io/kubeconfig-context-name"
+
+	// KlusterletPodLabelsAnnotation is used to add extra labels to the pods of the klusterlet deployment,
+	// e.g. the labels that are required by the network policies of the managed cluster to allow the egress
+	// traffic. The value is a json map, e.g. {"network-policy/egress":"allow"}
+	KlusterletPodLabelsAnnotation string = "import.open-cluster-management.io/klusterlet-pod-labels"
+
+	// KlusterletNamespaceLabelsAnnotation is used to add extra labels to the klusterlet namespaces on the managed
+	// cluster, e.g. the labels that are used by the namespace based team isolation of the managed cluster. The
+	// value is a json map, e.g. {"team":"platform"}. This annotation is ignored in the Hosted mode.
+	KlusterletNamespaceLabelsAnnotation string = "import.open-cluster-management.io/klusterlet-namespace-labels"
+
+	// KlusterletExtraVolumesAnnotation is used to mount extra volumes from the existing configmaps or secrets on the
+	// managed cluster into the klusterlet deployment, e.g. the custom configurations of the agent. The value is a
+	// json list, e.g. [{"name":"custom-ca","configMap":"custom-ca","mountPath":"/etc/custom-ca"}], each volume
+	// references either a configmap or a secret and is mounted read-only.
+	KlusterletExtraVolumesAnnotation string = "import.open-cluster-management.io/klusterlet-extra-volumes"
+
+	// KlusterletMetricsPortAnnotation is used to specify the port that the klusterlet operator serves the metrics
+	// and the health checks on, it is used to avoid the port conflicts on the managed cluster, by default it is 8443.
+	// The port must be between 1024 and 65535, the klusterlet operator is not allowed to bind the privileged ports.
+	KlusterletMetricsPortAnnotation string = "import.open-cluster-management.io/klusterlet-metrics-port"
+
+	// KlusterletPDBMinAvailableAnnotation is used to create a PodDisruptionBudget for the klusterlet agent pods with
+	// the given minAvailable, it is used to keep the HA klusterlet agents available during the node drains. The value
+	// is a positive integer or a percentage between 1% and 100%, e.g. 1 or 50%. This annotation is ignored in the
+	// Hosted mode.
+	KlusterletPDBMinAvailableAnnotation string = "import.open-cluster-management.io/klusterlet-pdb-min-available"
+
+	// KlusterletClientCertExpirationAnnotation is used to specify the expiration seconds of the registration agent
+	// client certificate, the registration agent rotates its client certificate before it expires, so this
+	// controls the rotation interval of the registration agent credentials
+	KlusterletClientCertExpirationAnnotation string = "import.open-cluster-management.io/klusterlet-client-cert-expiration-seconds"
+
+	// BootstrapKubeConfigRenewalLeadTimeAnnotation is used to specify how long before the bootstrap token expires
+	// the bootstrap kubeconfig is renewed, the value is a duration, e.g. 720h. The hub renews the bootstrap
+	// kubeconfig in the import secret with it, and it is rendered into the klusterlet cluster annotations with the
+	// key BootstrapKubeConfigRenewalLeadTimeClusterAnnotation.
+	BootstrapKubeConfigRenewalLeadTimeAnnotation string = "import.open-cluster-management.io/bootstrap-kubeconfig-renewal-lead-time"
+
+	// BootstrapKubeConfigRenewalLeadTimeClusterAnnotation is the key of the klusterlet cluster annotation of the
+	// bootstrap kubeconfig renewal lead time. The klusterlet API has no field for it, the registration agent only
+	// passes it through to the managed cluster.
+	BootstrapKubeConfigRenewalLeadTimeClusterAnnotation string = "agent.open-cluster-management.io/bootstrap-kubeconfig-renewal-lead-time"
+
+	// KlusterletClusterRoleAggregationLabelAnnotation is used to specify an aggregation label that is added to the
+	// klusterlet admin aggregate clusterrole, the value format is <key>=<value>, e.g.
+	// rbac.example.com/aggregate-to-agent=true. The keys with the rbac.authorization.k8s.io/ prefix are not allowed.
+	KlusterletClusterRoleAggregationLabelAnnotation string = "import.open-cluster-management.io/klusterlet-clusterrole-aggregation-label"
+
+	// KlusterletWorksDeleteOptionAnnotation is used to specify the delete propagation policy of the klusterlet
+	// manifestworks, the value can be Foreground (default), Orphan or SelectivelyOrphan. It controls whether the
+	// klusterlet is cleaned up or kept on the managed cluster when the managed cluster is detached.
+	KlusterletWorksDeleteOptionAnnotation string = "import.open-cluster-management.io/klusterlet-works-delete-option"
+
+	// KlusterletWorksServerSideApplyAnnotation is used to specify whether the work agent applies the resources of
+	// the klusterlet manifestworks with the server side apply, the value is a boolean, by default it is false and
+	// the resources are updated. The server side apply reduces the conflicts with other agents that change the
+	// klusterlet resources on the managed cluster.
+	KlusterletWorksServerSideApplyAnnotation string = "import.open-cluster-management.io/klusterlet-works-server-side-apply"
+
+	// KlusterletWorksPartialApplyAnnotation is used to specify whether the klusterlet manifestworks are applied
+	// independently, the value is a boolean, by default it is false and a failed manifestwork fails the whole apply.
+	// If it is true and only the klusterlet manifestwork fails to apply, the import proceeds with the applied CRDs
+	// manifestwork, the managed cluster has the PartialImport condition and only the failed manifestwork is retried.
+	// A failed CRDs manifestwork still fails the whole apply.
+	KlusterletWorksPartialApplyAnnotation string = "import.open-cluster-management.io/klusterlet-works-partial-apply"
+
+	// ImportDryRunAnnotation is used to validate the import of a managed cluster without applying anything, if the
+	// value is true, the klusterlet manifests of the import secret are applied on the managed cluster with the
+	// server-side dry-run only, and the result is reported with the ImportDryRunSucceeded or ImportDryRunFailed
+	// reason of the ManagedClusterImportSucceeded condition. It is honored by the import with the auto import secret
+	// and the import with the admin kubeconfig of the clusterdeployment, the auto import secret is kept and the
+	// managed cluster is not changed by the clusterdeployment controller in the dry-run mode.
+	ImportDryRunAnnotation string = "import.open-cluster-management.io/dry-run"
+
+	// ImporterAnnotation is used to specify the name of the importer that imports the agent of a managed cluster,
+	// the importer must be registered by helpers.RegisterImporter. If it is not set or the value is klusterlet,
+	// the klusterlet is imported.
+	ImporterAnnotation string = "import.open-cluster-management.io/importer"
+
+	// KlusterletWorksAvailabilityPolicyAnnotation is used to specify when the managed cluster is considered as
+	// imported if only part of the klusterlet manifestworks are available, the value can be WaitForAll (default),
+	// the cluster is imported after all of the klusterlet manifestworks are available, or ProceedOnFirst, the
+	// cluster is imported once one of the klusterlet manifestworks is available.
+	KlusterletWorksAvailabilityPolicyAnnotation string = "import.open-cluster-management.io/klusterlet-works-availability-policy"
+
+	// ImportDelayAnnotation is used to delay the import of a cluster that is claimed from a hive cluster pool, e.g.
+	// the claimed cluster is configured by another operator before it can be imported. The value is a duration, e.g.
+	// 5m, the cluster is imported after the duration since it was claimed. It can be set on the ClusterDeployment or
+	// the ManagedCluster, the annotation on the ManagedCluster takes precedence.
+	ImportDelayAnnotation string = "import.open-cluster-management.io/import-delay"
+
+	// ImportRetryAnnotation is used to retry the import of a hive managed cluster with its admin kubeconfig, like the
+	// autoImportRetry of the auto import secret. The value is the total number of the import attempts, it is set on
+	// the ClusterDeployment, the cluster is imported once if it is not set. Once all of the attempts are used up, the
+	// import is failed with the reason ImportRetryExhausted.
+	ImportRetryAnnotation string = "import.open-cluster-management.io/import-retry"
+
+	// ReconcileTraceAnnotation is used to enable the reconcile trace of the managed cluster for debugging, if its
+	// value is "true", the decisions of the import reconciles (e.g. skipped-not-installed, waiting-works, applied
+	// or failed) are recorded into the import-controller-reconcile-trace configmap in the managed cluster namespace,
+	// the configmap is deleted once the annotation is removed
+	ReconcileTraceAnnotation string = "import.open-cluster-management.io/reconcile-trace"
+
+	// ImportFailedAttemptsAnnotation records how many reconciles have failed to import the managed cluster, it is
+	// increased by the controllers that import the managed cluster, e.g. the autoimport controller, only when the
+	// import fails, and is removed once the managed cluster is imported successfully. It is used to track the flaky
+	// imports.
+	ImportFailedAttemptsAnnotation string = "import.open-cluster-management.io/import-failed-attempts"
+
+	// LastAppliedHashAnnotation records the hash of the import secret data and the credential data (the auto import
+	// secret or the admin kubeconfig secret) that are used by the last successful import, the import with the admin
+	// kubeconfig secret is skipped if neither of them is changed since then, the last import succeeded and the
+	// managed cluster is available. The import with the auto import secret is never skipped.
+	LastAppliedHashAnnotation string = "import.open-cluster-management.io/last-applied-hash"
+
+	// ForceImportAnnotation is used to force the import secret to be re-applied on the managed cluster even if it
+	// is not changed since the last successful import, e.g. the klusterlet is removed from the managed cluster
+	// manually. Adding the annotation triggers the import, it is removed once the import secret is re-applied.
+	ForceImportAnnotation string = "import.open-cluster-management.io/force-import"
+
+	// ClusterClaimsAnnotation is used to specify the initial infrastructure claims of the managed cluster, e.g.
+	// the cloud provider or the region. The value is a json map of the claim name to the claim value, e.g.
+	// {"platform.open-cluster-management.io":"AWS"}. The claims are rendered into the klusterlet cluster
+	// annotations with the prefix ClusterClaimAnnotationPrefix, so they are set on the managed cluster when
+	// the managed cluster is registered.
+	ClusterClaimsAnnotation string = "import.open-cluster-management.io/cluster-claims"
+
+	// ClusterClaimAnnotationPrefix is the prefix of the klusterlet cluster annotations of the cluster claims
+	ClusterClaimAnnotationPrefix string = "agent.open-cluster-management.io/claim-"
+
+	// KubeDistributionAnnotation is used to specify the kubernetes distribution of a non-OpenShift managed cluster,
+	// e.g. K3s or MicroK8s. It is rendered into the klusterlet cluster annotations as the ProductClusterClaim claim,
+	// the claim in the ClusterClaimsAnnotation takes precedence over this annotation.
+	KubeDistributionAnnotation string = "import.open-cluster-management.io/kube-distribution"
+
+	// ProductClusterClaim is the name of the cluster claim of the kubernetes distribution of the managed cluster
+	ProductClusterClaim string = "product.open-cluster-management.io"
+
+	// OpenShiftVersionAnnotation is used to specify the OpenShift version of an OpenShift managed cluster for the
+	// version aware placement, e.g. 4.14.3. It is rendered into the klusterlet cluster annotations as the
+	// OpenShiftVersionClusterClaim claim, the claim in the ClusterClaimsAnnotation takes precedence over this
+	// annotation.
+	OpenShiftVersionAnnotation string = "import.open-cluster-management.io/openshift-version"
+
+	// OpenShiftVersionClusterClaim is the name of the cluster claim of the OpenShift version of the managed cluster
+	OpenShiftVersionClusterClaim string = "version.openshift.io"
+
+	// CapacityClaimsAnnotation is used to specify the custom capacity claims of the managed cluster for the capacity
+	// aware placement, e.g. the number of GPUs. The value is a json map of the capacity name to the quantity, e.g.
+	// {"gpu":"4","storage":"500Gi"}. Each capacity is rendered into the klusterlet cluster annotations as a cluster
+	// claim named <capacity name>.capacity.open-cluster-management.io, the claim in the ClusterClaimsAnnotation takes
+	// precedence over the capacity claim with the same name.
+	CapacityClaimsAnnotation string = "import.open-cluster-management.io/capacity-claims"
+
+	// CapacityClusterClaimSuffix is the suffix of the cluster claim names of the custom capacities
+	CapacityClusterClaimSuffix string = ".capacity.open-cluster-management.io"
+
+	// KlusterletCSRApprovalAnnotation is used to specify how the client certificate signing requests of the
+	// registration agent are expected to be approved on the hub, the value is Auto or Manual. It is rendered into
+	// the klusterlet cluster annotations with the key CSRApprovalClusterAnnotation, so the expectation is set on the
+	// managed cluster when the managed cluster is registered. The annotation is only passed through, neither this
+	// controller nor the klusterlet agents act on it, it is a hint for the CSR approver on the hub.
+	KlusterletCSRApprovalAnnotation string = "import.open-cluster-management.io/klusterlet-csr-approval"
+
+	// CSRApprovalClusterAnnotation is the key of the klusterlet cluster annotation of the CSR approval expectation
+	CSRApprovalClusterAnnotation string = "agent.open-cluster-management.io/csr-approval"
+
+	// ClusterProxyHintAnnotation is used to hint the cluster-proxy addon how to connect to the managed cluster, the
+	// value is Tunnel or Direct. Tunnel means the managed cluster is not reachable from the hub, so the addon should
+	// establish a tunnel from the managed cluster, Direct means the hub can reach the managed cluster directly. It is
+	// rendered into the klusterlet cluster annotations with the key ClusterProxyHintClusterAnnotation. The annotation
+	// is only passed through to the managed cluster by the registration agent, neither this controller nor the
+	// klusterlet agents act on it, it is up to the cluster-proxy addon to read it.
+	ClusterProxyHintAnnotation string = "import.open-cluster-management.io/cluster-proxy-hint"
+
+	// ClusterProxyHintClusterAnnotation is the key of the klusterlet cluster annotation of the cluster-proxy hint
+	ClusterProxyHintClusterAnnotation string = "agent.open-cluster-management.io/cluster-proxy-hint"
+
+	// KlusterletNodePlacementAnnotation is used to specify the node placement of the klusterlet agents that run on
+	// the hosting cluster in the Hosted mode, e.g. run the agents on the tainted infra nodes of the hosting cluster.
+	// The value is a json object, e.g. {"nodeSelector":{"node-role.kubernetes.io/infra":""},"tolerations":[{"key":
+	// "node-role.kubernetes.io/infra","operator":"Exists","effect":"NoSchedule"}]}. It is only injected into the
+	// klusterlet of the hosted klusterlet manifestwork and overrides the node placement of the import secret.
+	KlusterletNodePlacementAnnotation string = "import.open-cluster-management.io/klusterlet-node-placement"
+
+	// KlusterletImagesAnnotation is used to override the image of each klusterlet component, the value is a json map
+	// of the component to the image, e.g. {"registration-operator":"mirror.example.com/ocm/registration-operator:v1"}.
+	// The supported components are KlusterletImageComponents, the image of a component in this annotation takes
+	// precedence over the image that is overridden by the registries of the klusterletconfig or the image
+	// registries annotation.
+	KlusterletImagesAnnotation string = "import.open-cluster-management.io/klusterlet-images"
+
+	// KlusterletImageDigestPolicyAnnotation is used to specify the digest pinning policy of the klusterlet images,
+	// the value is Required or Optional. Required means all the klusterlet images must be referenced by digest, the
+	// import fails with the reason ImagesNotDigestPinned if any klusterlet image is referenced by tag. The import
+	// secret is deleted if the images are rejected or the value is invalid, so the klusterlet works that reference
+	// the previous images are not applied anymore.
+	KlusterletImageDigestPolicyAnnotation string = "import.open-cluster-management.io/klusterlet-image-digest-policy"
+)
+
+// The supported kubernetes distributions of the KubeDistributionAnnotation
+var KubeDistributions = []string{"K3s", "MicroK8s", "RKE2", "KinD", "Minikube", "EKS", "AKS", "GKE", "IKS"}
+
+// ImportSecretNotGeneratedReasons are the reasons of the ImportSucceeded condition that are set by the importconfig
+// controller when it does not generate the import secret, the other controllers must not overwrite them with a
+// generic reason, e.g. ImportSecretNotGenerated
+var ImportSecretNotGeneratedReasons = []string{
+	ConditionReasonInvalidDeployMode,
+	ConditionReasonKlusterletNamespaceInvalid,
+	ConditionReasonHubBootstrapMisconfigured,
+	ConditionReasonImportSecretTooLarge,
+	ConditionReasonImagesNotDigestPinned,
+	ConditionReasonImageDigestPolicyInvalid,
+	ConditionReasonBootstrapKubeConfigRenewalLeadTimeInvalid,
+	ConditionReasonKubeconfigContextNameInvalid,
+}
+
+const (
+	CSRApprovalAuto   = "Auto"
+	CSRApprovalManual = "Manual"
+)
+
+const (
+	ImageDigestPolicyRequired = "Required"
+	ImageDigestPolicyOptional = "Optional"
+)
+
+const (
+	ClusterProxyHintTunnel = "Tunnel"
+	ClusterProxyHintDirect = "Direct"
+)
+
+// The klusterlet components of the KlusterletImagesAnnotation
+const (
+	KlusterletImageComponentRegistrationOperator = "registration-operator"
+	KlusterletImageComponentRegistration         = "registration"
+	KlusterletImageComponentWork                 = "work"
 )
 
+var KlusterletImageComponents = []string{
+	KlusterletImageComponentRegistrationOperator,
+	KlusterletImageComponentRegistration,
+	KlusterletImageComponentWork,
+}
+
 const (
 	// HostedManifestworkSuffix is a suffix of the hosted mode klusterlet manifestwork name.
 	HostedKlusterletManifestworkSuffix = "hosted-klusterlet"
@@ -93,6 +397,14 @@ const (
 	// ManifestWorkFinalizer is used to delete all manifestworks before deleting a managed cluster.
 	ManifestWorkFinalizer = "managedcluster-import-controller.open-cluster-management.io/manifestwork-cleanup"
 
+	// KlusterletCleanupGateAnnotation is used to guarantee the klusterlet is cleaned up from the managed cluster
+	// before the managed cluster is deleted, if the value is "true", the manifestworks of the managed cluster are
+	// not force deleted even if the managed cluster is unavailable, so the ManifestWorkFinalizer is removed only
+	// after all of the manifestworks are removed by the work agent. The gate does not apply if the klusterlet is
+	// kept on the managed cluster by the Orphan or SelectivelyOrphan KlusterletWorksDeleteOptionAnnotation.
+	// Removing this annotation from a deleting managed cluster releases the gate.
+	KlusterletCleanupGateAnnotation = "import.open-cluster-management.io/klusterlet-cleanup-gate"
+
 	// PostponeDeletionAnnotation is used to delete the manifest work with this annotation until 10 min after the cluster is deleted.
 	PostponeDeletionAnnotation = "open-cluster-management/postpone-delete"
 
@@ -114,4 +426,156 @@ const (
 	ConditionReasonManagedClusterImporting        = "ManagedClusterImporting"
 	ConditionReasonManagedClusterImportFailed     = "ManagedClusterImportFailed"
 	ConditionReasonManagedClusterImported         = "ManagedClusterImported"
+	ConditionReasonSpokeVersionUnsupported        = "SpokeVersionUnsupported"
+	ConditionReasonHubBootstrapMisconfigured      = "HubBootstrapMisconfigured"
+	ConditionReasonAutoImportSecretWrongType      = "AutoImportSecretWrongType"
+	ConditionReasonHubNamespaceRBACPending        = "HubNamespaceRBACPending"
+	ConditionReasonImportSecretNotGenerated       = "ImportSecretNotGenerated"
+	ConditionReasonInvalidDeployMode              = "InvalidDeployMode"
+	ConditionReasonAdminKubeconfigPendingDeletion = "AdminKubeconfigPendingDeletion"
+	ConditionReasonImportSecretTooLarge           = "ImportSecretTooLarge"
+	ConditionReasonImagesNotDigestPinned          = "ImagesNotDigestPinned"
+
+	// ConditionReasonImageDigestPolicyInvalid indicates the value of the KlusterletImageDigestPolicyAnnotation of
+	// the managed cluster is neither Required nor Optional
+	ConditionReasonImageDigestPolicyInvalid = "ImageDigestPolicyInvalid"
+
+	// ConditionReasonUnexpectedKlusterletWorks indicates there are manifestworks that have the klusterlet works
+	// label but are not the klusterlet manifestworks in the managed cluster namespace
+	ConditionReasonUnexpectedKlusterletWorks = "UnexpectedKlusterletWorks"
+
+	// ConditionReasonWaitingForKlusterletWorks indicates the klusterlet manifestworks of the managed cluster have
+	// been missing for too long, the manifestwork controller may fail to create them
+	ConditionReasonWaitingForKlusterletWorks = "WaitingForKlusterletWorks"
+
+	ConditionReasonImportDryRunSucceeded = "ImportDryRunSucceeded"
+	ConditionReasonImportDryRunFailed    = "ImportDryRunFailed"
+
+	// ConditionReasonImportRetryExhausted indicates the managed cluster failed to be imported after all of
+	// the retries specified by the autoImportRetry of the auto import secret or the import retry annotation of the
+	// clusterdeployment are used up
+	ConditionReasonImportRetryExhausted = "ImportRetryExhausted"
+
+	// ConditionReasonExternalManagedKubeconfigInvalid indicates the external managed kubeconfig provided by the
+	// auto import secret of a hosted mode managed cluster is malformed, e.g. it has no server or credentials
+	ConditionReasonExternalManagedKubeconfigInvalid = "ExternalManagedKubeconfigInvalid"
+
+	// ConditionReasonKlusterletNamespaceInvalid indicates the klusterlet namespace that is specified by the
+	// KlusterletNamespaceAnnotation of the managed cluster is not a valid DNS-1123 label with the prefix
+	// "open-cluster-management-"
+	ConditionReasonKlusterletNamespaceInvalid = "KlusterletNamespaceInvalid"
+
+	// ConditionReasonKubeconfigContextNameInvalid indicates the KubeconfigContextNameAnnotation of the managed cluster
+	// is not a valid DNS-1123 subdomain
+	ConditionReasonKubeconfigContextNameInvalid = "KubeconfigContextNameInvalid"
+
+	// ConditionReasonBootstrapKubeConfigRenewalLeadTimeInvalid indicates the BootstrapKubeConfigRenewalLeadTimeAnnotation
+	// of the managed cluster is not a valid duration or is out of the allowed range
+	ConditionReasonBootstrapKubeConfigRenewalLeadTimeInvalid = "BootstrapKubeConfigRenewalLeadTimeInvalid"
+
+	// ConditionReasonSpokeMissingKlusterletCRDs indicates the klusterlet CRDs cannot be resolved on the managed
+	// cluster after they are applied
+	ConditionReasonSpokeMissingKlusterletCRDs = "SpokeMissingKlusterletCRDs"
+
+	// ConditionReasonInvalidNodePlacement indicates the KlusterletNodePlacementAnnotation of a hosted mode managed
+	// cluster is not a valid node placement
+	ConditionReasonInvalidNodePlacement = "InvalidNodePlacement"
+)
+
+const (
+	// ConditionManagedClusterImportAndRegistrationReady is the condition type of managed cluster to summarize
+	// whether the managed cluster is imported successfully and its registration is healthy, the registration is
+	// healthy when the managed cluster is accepted by the hub, joined the hub and available.
+	ConditionManagedClusterImportAndRegistrationReady = "ImportAndRegistrationReady"
+
+	ConditionReasonImportAndRegistrationReady = "ImportAndRegistrationReady"
+	ConditionReasonManagedClusterNotImported  = "ManagedClusterNotImported"
+	ConditionReasonManagedClusterNotAccepted  = "ManagedClusterNotAccepted"
+	ConditionReasonManagedClusterNotJoined    = "ManagedClusterNotJoined"
+	ConditionReasonManagedClusterNotAvailable = "ManagedClusterNotAvailable"
+)
+
+const (
+	// ConditionKlusterletWorksApplyStuck is the condition type of managed cluster to indicate whether the klusterlet
+	// manifestworks are stuck in not applied, the reason of the condition is from the Applied condition of the work.
+	ConditionKlusterletWorksApplyStuck = "KlusterletWorksApplyStuck"
+
+	ConditionReasonKlusterletWorksNotStuck   = "KlusterletWorksNotStuck"
+	ConditionReasonKlusterletWorksNotApplied = "KlusterletWorksNotApplied"
+)
+
+const (
+	// ConditionPartialImport is the condition type of managed cluster to indicate whether some of the klusterlet
+	// manifestworks failed to apply while the others are applied, it is set only when the klusterlet manifestworks
+	// are applied independently with the KlusterletWorksPartialApplyAnnotation.
+	ConditionPartialImport = "PartialImport"
+
+	ConditionReasonKlusterletWorksPartiallyApplied     = "KlusterletWorksPartiallyApplied"
+	ConditionReasonKlusterletWorksApplied              = "KlusterletWorksApplied"
+	ConditionReasonKlusterletWorksPartialApplyDisabled = "KlusterletWorksPartialApplyDisabled"
+)
+
+const (
+	KlusterletWorksAvailabilityPolicyWaitForAll     = "WaitForAll"
+	KlusterletWorksAvailabilityPolicyProceedOnFirst = "ProceedOnFirst"
+)
+
+const (
+	// ConditionKlusterletWorksAvailable is the condition type of managed cluster to indicate whether the klusterlet
+	// manifestworks are available with the klusterlet works availability policy of the managed cluster.
+	ConditionKlusterletWorksAvailable = "KlusterletWorksAvailable"
+
+	ConditionReasonKlusterletWorksAllAvailable       = "KlusterletWorksAllAvailable"
+	ConditionReasonKlusterletWorksPartiallyAvailable = "KlusterletWorksPartiallyAvailable"
+	ConditionReasonKlusterletWorksNotAvailable       = "KlusterletWorksNotAvailable"
+)
+
+const (
+	// ConditionLastImportSucceeded is the condition type of managed cluster to record when the managed cluster was
+	// imported successfully last time, the time is recorded in its message only when the managed cluster becomes
+	// imported, so it is kept when the managed cluster keeps imported.
+	ConditionLastImportSucceeded = "LastImportSucceeded"
+
+	ConditionReasonLastImportSucceeded = "LastImportSucceeded"
+)
+
+const (
+	// ConditionBootstrapCARotated is the condition type of managed cluster to indicate whether the import secret of
+	// the managed cluster is being regenerated because the hub CA is rotated, it is true during the transition and
+	// turns false once the import secret embeds the current hub CA.
+	ConditionBootstrapCARotated = "BootstrapCARotated"
+
+	ConditionReasonBootstrapCARotated  = "BootstrapCARotated"
+	ConditionReasonBootstrapCAUpToDate = "BootstrapCAUpToDate"
+)
+
+const (
+	// ConditionHostedKlusterletWorksAvailable is the condition type of a Hosted mode managed cluster to indicate
+	// whether both of the hosted klusterlet manifestwork and the managed kubeconfig manifestwork are applied and
+	// available on the hosting cluster.
+	ConditionHostedKlusterletWorksAvailable = "HostedKlusterletWorksAvailable"
+
+	ConditionReasonHostedKlusterletWorksAvailable   = "HostedKlusterletWorksAvailable"
+	ConditionReasonHostedKlusterletWorkNotAvailable = "HostedKlusterletWorkNotAvailable"
+	ConditionReasonHostedKubeconfigWorkNotAvailable = "HostedKubeconfigWorkNotAvailable"
+)
+
+const (
+	// ConditionManagedClusterPermanentlyUnreachable is the condition type of managed cluster to indicate whether the
+	// managed cluster has the unreachable taint for a long time, in that case, the managed cluster is suggested to
+	// be detached or imported again.
+	ConditionManagedClusterPermanentlyUnreachable = "ManagedClusterPermanentlyUnreachable"
+
+	ConditionReasonManagedClusterReachable       = "ManagedClusterReachable"
+	ConditionReasonManagedClusterUnreachable     = "ManagedClusterUnreachable"
+	ConditionReasonManagedClusterDetachSuggested = "ManagedClusterDetachSuggested"
+)
+
+const (
+	// ConditionForeignKlusterletWorksPresent is the condition type of managed cluster to indicate whether there are
+	// klusterlet manifestworks that are created by another hub in the managed cluster namespace.
+	ConditionForeignKlusterletWorksPresent = "ForeignKlusterletWorksPresent"
+
+	ConditionReasonForeignKlusterletWorksFound    = "ForeignKlusterletWorksFound"
+	ConditionReasonForeignKlusterletWorksNotFound = "ForeignKlusterletWorksNotFound"
 )
@@ -8,8 +8,10 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/openshift/library-go/pkg/operator/events"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -48,7 +50,7 @@ func NewReconcileAutoImport(
 		kubeClient:     kubeClient,
 		informerHolder: informerHolder,
 		recorder:       recorder,
-		importHelper:   helpers.NewImportHelper(informerHolder, recorder, log),
+		importHelper:   helpers.NewImportHelper(informerHolder, recorder, log).WithRuntimeClient(client),
 	}
 }
 
@@ -61,24 +63,28 @@ var _ reconcile.Reconciler = &ReconcileAutoImport{}
 // Note: The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
 func (r *ReconcileAutoImport) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	start := time.Now()
 	reqLogger := log.WithValues("Request.Namespace", request.Namespace)
 
 	managedClusterName := request.Namespace
 	managedCluster := &clusterv1.ManagedCluster{}
 	err := r.client.Get(ctx, types.NamespacedName{Name: managedClusterName}, managedCluster)
 	if errors.IsNotFound(err) {
-		// the managed cluster could have been deleted, do nothing
+		// the managed cluster could have been deleted, forget its failed imports
+		r.importHelper.ResetBackoff(managedClusterName)
 		return reconcile.Result{}, nil
 	}
 	if err != nil {
 		return reconcile.Result{}, err
 	}
 
-	if helpers.DetermineKlusterletMode(managedCluster) == operatorv1.InstallModeHosted {
+	deployMode := helpers.DetermineKlusterletMode(managedCluster)
+	if deployMode == operatorv1.InstallModeHosted {
 		return reconcile.Result{}, nil
 	}
 
 	if !managedCluster.DeletionTimestamp.IsZero() {
+		r.importHelper.ResetBackoff(managedClusterName)
 		return reconcile.Result{}, nil
 	}
 
@@ -93,6 +99,26 @@ func (r *ReconcileAutoImport) Reconcile(ctx context.Context, request reconcile.R
 
 	reqLogger.V(5).Info("Reconciling auto import secret")
 
+	// the auto import secret must be an opaque secret, the secret type is immutable, so the secret with a wrong
+	// type has to be recreated
+	if autoImportSecret.Type != corev1.SecretTypeOpaque && len(autoImportSecret.Type) != 0 {
+		if err := helpers.UpdateManagedClusterStatus(
+			r.client,
+			managedClusterName,
+			helpers.NewManagedClusterImportSucceededCondition(
+				metav1.ConditionFalse,
+				constants.ConditionReasonAutoImportSecretWrongType,
+				fmt.Sprintf("The type of the auto import secret %s/%s is %s, expected %s; please delete it and "+
+					"create it again with the type %s", autoImportSecret.Namespace, autoImportSecret.Name,
+					autoImportSecret.Type, corev1.SecretTypeOpaque, corev1.SecretTypeOpaque),
+			),
+		); err != nil {
+			return reconcile.Result{}, err
+		}
+		// auto import secret type is wrong, stop retrying
+		return reconcile.Result{}, nil
+	}
+
 	lastRetry := 0
 	totalRetry := 1
 	if len(autoImportSecret.Annotations[constants.AnnotationAutoImportCurrentRetry]) != 0 {
@@ -122,17 +148,35 @@ func (r *ReconcileAutoImport) Reconcile(ctx context.Context, request reconcile.R
 		}
 	}
 
+	if helpers.IsImportDryRun(managedCluster.GetAnnotations()) {
+		// only validate the import on the managed cluster, nothing is applied and the auto import secret is kept,
+		// the managed cluster is imported once the dry-run annotation is removed
+		reqLogger.V(5).Info("Dry run the import with the auto import secret")
+		condition, err := r.importHelper.DryRunImport(ctx, managedClusterName, autoImportSecret)
+		if uErr := helpers.UpdateManagedClusterStatus(r.client, managedClusterName, condition); uErr != nil {
+			return reconcile.Result{}, uErr
+		}
+		return reconcile.Result{}, err
+	}
+
 	backupRestore := false
 	if v, ok := autoImportSecret.Labels[constants.LabelAutoImportRestore]; ok && strings.EqualFold(v, "true") {
 		backupRestore = true
 	}
 
 	result, condition, modified, currentRetry, iErr := r.importHelper.Import(
-		backupRestore, managedClusterName, autoImportSecret, lastRetry, totalRetry)
+		ctx, backupRestore, managedCluster, autoImportSecret, lastRetry, totalRetry)
+	helpers.RecordReconcileTrace(ctx, r.kubeClient, managedCluster, controllerName,
+		helpers.ImportReconcileDecision(&condition, iErr), condition.Message)
+	if err := helpers.IncreaseImportFailedAttempts(ctx, r.client, managedCluster, &condition, iErr); err != nil {
+		return reconcile.Result{}, err
+	}
 	// if resources are applied but NOT modified, will not update the condition, keep the original condition.
 	// This check is to prevent the current controller and import status controller from modifying the
-	// ManagedClusterImportSucceeded condition of the managed cluster in a loop
-	if !helpers.ImportingResourcesApplied(&condition) || modified {
+	// ManagedClusterImportSucceeded condition of the managed cluster in a loop. The WaitingForKlusterletWorks
+	// condition is always cleared after the klusterlet manifest works appear
+	if !helpers.ImportingResourcesApplied(&condition) || modified ||
+		helpers.IsWaitingForKlusterletWorks(managedCluster) {
 		if err := helpers.UpdateManagedClusterStatus(
 			r.client,
 			managedClusterName,
@@ -140,13 +184,13 @@ func (r *ReconcileAutoImport) Reconcile(ctx context.Context, request reconcile.R
 		); err != nil {
 			return reconcile.Result{}, err
 		}
+		helpers.ObserveImportResult(deployMode, managedCluster, &condition, start)
 	}
 
 	reqLogger.V(5).Info("Import result", "importError", iErr, "condition", condition,
 		"current", currentRetry, "result", result, "modified", modified)
 
-	if condition.Reason == constants.ConditionReasonManagedClusterImportFailed ||
-		helpers.ImportingResourcesApplied(&condition) {
+	if helpers.IsImportFailed(&condition) || helpers.ImportingResourcesApplied(&condition) {
 		// delete secret
 		if err := helpers.DeleteAutoImportSecret(ctx, r.kubeClient, autoImportSecret, r.recorder); err != nil {
 			return reconcile.Result{}, err
@@ -6,13 +6,16 @@ package autoimport
 import (
 	"context"
 	operatorv1 "open-cluster-management.io/api/operator/v1"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
 	testinghelpers "github.com/stolostron/managedcluster-import-controller/pkg/helpers/testing"
 	"github.com/stolostron/managedcluster-import-controller/pkg/source"
 
+	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -153,6 +156,32 @@ func TestReconcile(t *testing.T) {
 			expectedConditionStatus: metav1.ConditionFalse,
 			expectedConditionReason: constants.ConditionReasonManagedClusterImportFailed,
 		},
+		{
+			name: "auto-import-secret with wrong type",
+			objs: []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: managedClusterName,
+					},
+				},
+			},
+			works: []runtime.Object{},
+			secrets: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "auto-import-secret",
+						Namespace: managedClusterName,
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("{}"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+			},
+			expectedErr:             false,
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonAutoImportSecretWrongType,
+		},
 		{
 			name: "auto-import-secret current retry annotation invalid",
 			objs: []client.Object{
@@ -347,6 +376,56 @@ func TestReconcile(t *testing.T) {
 			expectedConditionStatus: metav1.ConditionFalse,
 			expectedConditionReason: constants.ConditionReasonManagedClusterImportFailed,
 		},
+		{
+			name: "import cluster with auto-import secret retry exhausted",
+			objs: []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: managedClusterName,
+					},
+				},
+			},
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet-crds",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+			},
+			secrets: []runtime.Object{
+				testinghelpers.GetImportSecret(managedClusterName),
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "auto-import-secret",
+						Namespace: managedClusterName,
+						Annotations: map[string]string{
+							constants.AnnotationAutoImportCurrentRetry: "2",
+						},
+					},
+					Data: map[string][]byte{
+						"autoImportRetry": []byte("3"),
+						"server":          []byte(config.Host),
+						// no auth info
+					},
+				},
+			},
+			expectedErr:             false,
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonImportRetryExhausted,
+		},
 		{
 			name: "only update the bootstrap secret",
 			objs: []client.Object{
@@ -573,3 +652,142 @@ func TestReconcile(t *testing.T) {
 		})
 	}
 }
+
+func TestReconcileTrace(t *testing.T) {
+	managedClusterName := "cluster-trace"
+	objs := []client.Object{
+		&clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: managedClusterName,
+				Annotations: map[string]string{
+					constants.ReconcileTraceAnnotation: "true",
+				},
+			},
+		},
+	}
+	secrets := []runtime.Object{
+		testinghelpers.GetImportSecret(managedClusterName),
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "auto-import-secret",
+				Namespace: managedClusterName,
+			},
+			Data: map[string][]byte{
+				"autoImportRetry": []byte("0"),
+				"kubeconfig":      []byte("invalid"),
+			},
+		},
+	}
+
+	kubeClient := kubefake.NewSimpleClientset(secrets...)
+	kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+	secretInformer := kubeInformerFactory.Core().V1().Secrets().Informer()
+	for _, secret := range secrets {
+		secretInformer.GetStore().Add(secret)
+	}
+	workInformerFactory := workinformers.NewSharedInformerFactory(workfake.NewSimpleClientset(), 10*time.Minute)
+
+	r := NewReconcileAutoImport(
+		fake.NewClientBuilder().WithScheme(testscheme).WithObjects(objs...).WithStatusSubresource(objs...).Build(),
+		kubeClient,
+		&source.InformerHolder{
+			AutoImportSecretLister: kubeInformerFactory.Core().V1().Secrets().Lister(),
+			ImportSecretLister:     kubeInformerFactory.Core().V1().Secrets().Lister(),
+			KlusterletWorkLister:   workInformerFactory.Work().V1().ManifestWorks().Lister(),
+		},
+		eventstesting.NewTestingEventRecorder(t),
+	)
+
+	_, _ = r.Reconcile(context.TODO(),
+		reconcile.Request{NamespacedName: types.NamespacedName{Namespace: managedClusterName}})
+
+	configMap, err := kubeClient.CoreV1().ConfigMaps(managedClusterName).Get(
+		context.TODO(), helpers.ReconcileTraceConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	trace := configMap.Data[helpers.ReconcileTraceKey]
+	if !strings.Contains(trace, controllerName+": "+helpers.ReconcileDecisionFailed) {
+		t.Errorf("expected the trace records the %s decision, but got %q", helpers.ReconcileDecisionFailed, trace)
+	}
+}
+
+func TestReconcileDryRun(t *testing.T) {
+	managedClusterName := "cluster-dry-run"
+	objs := []client.Object{
+		&clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: managedClusterName,
+				Annotations: map[string]string{
+					constants.ImportDryRunAnnotation: "true",
+				},
+			},
+		},
+	}
+	secrets := []runtime.Object{
+		testinghelpers.GetImportSecret(managedClusterName),
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      constants.AutoImportSecretName,
+				Namespace: managedClusterName,
+			},
+			Data: map[string][]byte{
+				"kubeconfig": []byte("fake"),
+			},
+		},
+	}
+
+	kubeClient := kubefake.NewSimpleClientset(secrets...)
+	kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+	secretInformer := kubeInformerFactory.Core().V1().Secrets().Informer()
+	for _, secret := range secrets {
+		secretInformer.GetStore().Add(secret)
+	}
+	workInformerFactory := workinformers.NewSharedInformerFactory(workfake.NewSimpleClientset(), 10*time.Minute)
+
+	r := NewReconcileAutoImport(
+		fake.NewClientBuilder().WithScheme(testscheme).WithObjects(objs...).WithStatusSubresource(objs...).Build(),
+		kubeClient,
+		&source.InformerHolder{
+			AutoImportSecretLister: kubeInformerFactory.Core().V1().Secrets().Lister(),
+			ImportSecretLister:     kubeInformerFactory.Core().V1().Secrets().Lister(),
+			KlusterletWorkLister:   workInformerFactory.Work().V1().ManifestWorks().Lister(),
+		},
+		eventstesting.NewTestingEventRecorder(t),
+	)
+	r.importHelper = r.importHelper.
+		WithGenerateClientHolderFunc(func(secret *corev1.Secret) (*helpers.ClientHolder, meta.RESTMapper, error) {
+			return &helpers.ClientHolder{}, nil, nil
+		}).
+		WithApplyResourcesFunc(func(backupRestore bool, client *helpers.ClientHolder, restMapper meta.RESTMapper,
+			recorder events.Recorder, importSecret *corev1.Secret) (bool, error) {
+			t.Errorf("the resources should not be applied in the dry-run mode")
+			return false, nil
+		}).
+		WithDryRunImportFunc(func(ctx context.Context, client *helpers.ClientHolder, restMapper meta.RESTMapper,
+			importSecret *corev1.Secret) error {
+			return nil
+		})
+
+	_, err := r.Reconcile(context.TODO(),
+		reconcile.Request{NamespacedName: types.NamespacedName{Namespace: managedClusterName}})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	managedCluster := &clusterv1.ManagedCluster{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: managedClusterName}, managedCluster); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	condition := meta.FindStatusCondition(
+		managedCluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+	if condition == nil || condition.Reason != constants.ConditionReasonImportDryRunSucceeded {
+		t.Errorf("expected condition reason %s, but got %v", constants.ConditionReasonImportDryRunSucceeded, condition)
+	}
+
+	// the auto import secret is kept for the real import
+	if _, err := kubeClient.CoreV1().Secrets(managedClusterName).Get(
+		context.TODO(), constants.AutoImportSecretName, metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the auto import secret is kept, but got %v", err)
+	}
+}
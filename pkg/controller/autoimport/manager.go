@@ -4,8 +4,10 @@
 package autoimport
 
 import (
+	"context"
 	"strings"
 
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	workv1 "open-cluster-management.io/api/work/v1"
 
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
@@ -14,11 +16,16 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
 
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	runtimesource "sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 const controllerName = "autoimport-controller"
@@ -81,6 +88,34 @@ func Add(mgr manager.Manager, clientHolder *helpers.ClientHolder, informerHolder
 		return controllerName, err
 	}
 
+	// watch the import dry-run and force import annotations of the managed clusters, the auto import secret is in
+	// the managed cluster namespace
+	if err := c.Watch(
+		runtimesource.Kind(mgr.GetCache(), &clusterv1.ManagedCluster{}),
+		handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, o client.Object) []reconcile.Request {
+			return []reconcile.Request{
+				{
+					NamespacedName: types.NamespacedName{
+						Namespace: o.GetName(),
+						Name:      o.GetName(),
+					},
+				},
+			}
+		}),
+		predicate.Predicate(predicate.Funcs{
+			GenericFunc: func(e event.GenericEvent) bool { return false },
+			DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+			CreateFunc:  func(e event.CreateEvent) bool { return false },
+			UpdateFunc: func(e event.UpdateEvent) bool {
+				return e.ObjectOld.GetAnnotations()[constants.ImportDryRunAnnotation] !=
+					e.ObjectNew.GetAnnotations()[constants.ImportDryRunAnnotation] ||
+					helpers.ForceImportAnnotationAdded(e.ObjectOld, e.ObjectNew)
+			},
+		}),
+	); err != nil {
+		return controllerName, err
+	}
+
 	// watch the klusterlet manifest works
 	if err := c.Watch(
 		source.NewKlusterletWorkSource(informerHolder.KlusterletWorkInformer),
@@ -5,15 +5,21 @@ package clusterdeployment
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
 	"github.com/stolostron/managedcluster-import-controller/pkg/source"
 
+	"github.com/go-logr/logr"
 	hivev1 "github.com/openshift/hive/apis/hive/v1"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
@@ -33,6 +39,10 @@ type ReconcileClusterDeployment struct {
 	informerHolder *source.InformerHolder
 	recorder       events.Recorder
 	importHelper   *helpers.ImportHelper
+
+	// propagatedLabelKeys are the keys of the labels that are copied from the clusterdeployment to the managed
+	// cluster
+	propagatedLabelKeys []string
 }
 
 func NewReconcileClusterDeployment(
@@ -47,10 +57,17 @@ func NewReconcileClusterDeployment(
 		kubeClient:     kubeClient,
 		informerHolder: informerHolder,
 		recorder:       recorder,
-		importHelper:   helpers.NewImportHelper(informerHolder, recorder, log),
+		importHelper:   helpers.NewImportHelper(informerHolder, recorder, log).WithRuntimeClient(client),
 	}
 }
 
+// WithPropagatedLabelKeys sets the keys of the labels that are copied from the clusterdeployment to the managed
+// cluster
+func (r *ReconcileClusterDeployment) WithPropagatedLabelKeys(keys []string) *ReconcileClusterDeployment {
+	r.propagatedLabelKeys = keys
+	return r
+}
+
 // blank assignment to verify that ReconcileClusterDeployment implements reconcile.Reconciler
 var _ reconcile.Reconciler = &ReconcileClusterDeployment{}
 
@@ -61,9 +78,10 @@ var _ reconcile.Reconciler = &ReconcileClusterDeployment{}
 func (r *ReconcileClusterDeployment) Reconcile(
 	ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
 
-	reqLogger := log.WithValues("Request.Name", request.Name)
+	start := time.Now()
 
 	clusterName := request.Name
+	reqLogger := log.WithValues("Request.Name", request.Name, "clusterName", clusterName)
 
 	clusterDeployment := &hivev1.ClusterDeployment{}
 	err := r.client.Get(ctx, types.NamespacedName{Name: clusterName, Namespace: clusterName}, clusterDeployment)
@@ -76,18 +94,33 @@ func (r *ReconcileClusterDeployment) Reconcile(
 
 	reqLogger.Info("Reconciling clusterdeployment")
 
+	// the legacy import finalizers are not handled anymore, remove them to avoid blocking the
+	// clusterdeployment deletion
+	if err := helpers.RemoveLegacyImportFinalizers(ctx, r.client, r.recorder, clusterDeployment); err != nil {
+		return reconcile.Result{}, err
+	}
+
 	if !clusterDeployment.DeletionTimestamp.IsZero() {
 		// We do not set this finalizer anymore, but we still need to remove it for backward compatible
 		// the clusterdeployment is deleting, its managed cluster may already be detached (the managed
 		// cluster has been deleted, but the namespace is remained), if it has import finalizer, we
 		// remove its namespace
-		return reconcile.Result{}, r.removeImportFinalizer(ctx, clusterDeployment)
+		if err := r.removeImportFinalizer(ctx, reqLogger, clusterDeployment); err != nil {
+			return reconcile.Result{}, err
+		}
+
+		// the admin kubeconfig may still exist during the deprovision, but it will be deleted together with
+		// the clusterdeployment and may be already invalid, so do not import the cluster with it
+		return reconcile.Result{}, r.reportAdminKubeconfigPendingDeletion(ctx, clusterName,
+			fmt.Sprintf("The clusterdeployment %s is deleting, the admin kubeconfig will not be used to import "+
+				"the cluster", clusterName))
 	}
 
 	managedCluster := &clusterv1.ManagedCluster{}
 	err = r.client.Get(ctx, types.NamespacedName{Name: clusterName}, managedCluster)
 	if errors.IsNotFound(err) {
-		// the managed cluster could be deleted, do nothing
+		// the managed cluster could be deleted, forget its failed imports
+		r.importHelper.ResetBackoff(clusterName)
 		return reconcile.Result{}, nil
 	}
 	if err != nil {
@@ -95,31 +128,79 @@ func (r *ReconcileClusterDeployment) Reconcile(
 	}
 
 	if !managedCluster.DeletionTimestamp.IsZero() {
+		r.importHelper.ResetBackoff(clusterName)
 		return reconcile.Result{}, nil
 	}
 
+	deployMode := helpers.DetermineKlusterletMode(managedCluster)
+	reqLogger = reqLogger.WithValues("deployMode", deployMode)
+
 	if !clusterDeployment.Spec.Installed {
 		// cluster deployment is not installed yet, do nothing
-		reqLogger.Info("The hive managed cluster is not installed, skipped", "managedcluster", clusterName)
+		reqLogger.Info("The hive managed cluster is not installed, skipped")
+		helpers.RecordReconcileTrace(ctx, r.kubeClient, managedCluster, controllerName,
+			helpers.ReconcileDecisionSkippedNotInstalled, "")
 		return reconcile.Result{}, nil
 	}
 
 	if clusterDeployment.Spec.ClusterPoolRef != nil && clusterDeployment.Spec.ClusterPoolRef.ClaimedTimestamp.IsZero() {
 		// cluster deployment is not claimed yet, do nothing
-		reqLogger.Info("The hive managed cluster is not claimed, skipped", "managedcluster", clusterName)
+		reqLogger.Info("The hive managed cluster is not claimed, skipped")
+		helpers.RecordReconcileTrace(ctx, r.kubeClient, managedCluster, controllerName,
+			helpers.ReconcileDecisionSkippedNotClaimed, "")
 		return reconcile.Result{}, nil
 	}
 
-	// set managed cluster created-via annotation
-	if err := r.setCreatedViaAnnotation(ctx, clusterDeployment, managedCluster); err != nil {
-		return reconcile.Result{}, err
+	// the claimed cluster may be configured by other operators before it can be imported, wait for the import
+	// delay since the cluster is claimed
+	if clusterDeployment.Spec.ClusterPoolRef != nil {
+		delay, err := importDelay(clusterDeployment, managedCluster)
+		if err != nil {
+			reqLogger.Info("The import delay is invalid, skipped", "error", err.Error())
+			return reconcile.Result{}, helpers.UpdateManagedClusterStatus(
+				r.client,
+				clusterName,
+				helpers.NewManagedClusterImportSucceededCondition(
+					metav1.ConditionFalse,
+					constants.ConditionReasonManagedClusterImportFailed,
+					err.Error(),
+				),
+			)
+		}
+
+		claimedAge := time.Since(clusterDeployment.Spec.ClusterPoolRef.ClaimedTimestamp.Time)
+		if remaining := delay - claimedAge; remaining > 0 {
+			reqLogger.Info("The hive managed cluster is claimed recently, wait for the import delay",
+				"delay", delay, "remaining", remaining.Round(time.Second))
+			helpers.RecordReconcileTrace(ctx, r.kubeClient, managedCluster, controllerName,
+				helpers.ReconcileDecisionWaitingImportDelay, fmt.Sprintf("%s remaining", remaining.Round(time.Second)))
+			return reconcile.Result{RequeueAfter: remaining}, nil
+		}
+	}
+
+	// nothing is changed on the hub in the dry-run mode, the managed cluster is changed once the dry-run
+	// annotation is removed
+	dryRun := helpers.IsImportDryRun(managedCluster.GetAnnotations())
+	if !dryRun {
+		// set managed cluster created-via annotation
+		if err := r.setCreatedViaAnnotation(ctx, reqLogger, clusterDeployment, managedCluster); err != nil {
+			return reconcile.Result{}, err
+		}
+
+		// copy the configured labels of the clusterdeployment to the managed cluster
+		if err := r.propagateLabels(ctx, reqLogger, clusterDeployment, managedCluster); err != nil {
+			return reconcile.Result{}, err
+		}
 	}
 
 	// if there is an auto import secret in the managed cluster namespace, we will use the auto import secret
-	// to import the cluster
+	// to import the cluster, the autoimport controller also honors the dry-run mode
 	_, err = r.informerHolder.AutoImportSecretLister.Secrets(clusterName).Get(constants.AutoImportSecretName)
 	if err == nil {
-		reqLogger.Info("The hive managed cluster has auto import secret, skipped", "managedcluster", clusterName)
+		reqLogger.Info("The hive managed cluster has auto import secret, skipped",
+			"secret", constants.AutoImportSecretName)
+		helpers.RecordReconcileTrace(ctx, r.kubeClient, managedCluster, controllerName,
+			helpers.ReconcileDecisionSkippedAutoImport, "")
 		return reconcile.Result{}, nil
 	}
 	if !errors.IsNotFound(err) {
@@ -127,16 +208,59 @@ func (r *ReconcileClusterDeployment) Reconcile(
 	}
 
 	secretRefName := clusterDeployment.Spec.ClusterMetadata.AdminKubeconfigSecretRef.Name
+	reqLogger = reqLogger.WithValues("secret", secretRefName)
 	hiveSecret, err := r.kubeClient.CoreV1().Secrets(clusterName).Get(ctx, secretRefName, metav1.GetOptions{})
 	if err != nil {
+		helpers.RecordReconcileTrace(ctx, r.kubeClient, managedCluster, controllerName,
+			helpers.ReconcileDecisionFailed, err.Error())
 		return reconcile.Result{}, err
 	}
+	if !hiveSecret.DeletionTimestamp.IsZero() {
+		reqLogger.Info("The admin kubeconfig secret is deleting, skipped")
+		return reconcile.Result{}, r.reportAdminKubeconfigPendingDeletion(ctx, clusterName,
+			fmt.Sprintf("The admin kubeconfig secret %s is deleting, it will not be used to import the cluster",
+				secretRefName))
+	}
 
-	result, condition, modified, _, iErr := r.importHelper.Import(false, clusterName, hiveSecret, 0, 1)
+	if dryRun {
+		// only validate the import on the managed cluster, nothing is applied
+		reqLogger.V(5).Info("Dry run the import with the admin kubeconfig")
+		condition, err := r.importHelper.DryRunImport(ctx, clusterName, hiveSecret)
+		if uErr := helpers.UpdateManagedClusterStatus(r.client, clusterName, condition); uErr != nil {
+			return reconcile.Result{}, uErr
+		}
+		return reconcile.Result{}, err
+	}
+
+	lastRetry, totalRetry, err := importRetry(clusterDeployment)
+	if err != nil {
+		reqLogger.Info("The import retry is invalid, skipped", "error", err.Error())
+		// the import retry is invalid, stop retrying
+		return reconcile.Result{}, helpers.UpdateManagedClusterStatus(
+			r.client,
+			clusterName,
+			helpers.NewManagedClusterImportSucceededCondition(
+				metav1.ConditionFalse,
+				constants.ConditionReasonManagedClusterImportFailed,
+				err.Error(),
+			),
+		)
+	}
+
+	reqLogger.V(5).Info("Import the hive managed cluster with the admin kubeconfig")
+	result, condition, modified, currentRetry, iErr := r.importHelper.Import(
+		ctx, false, managedCluster, hiveSecret, lastRetry, totalRetry)
+	helpers.RecordReconcileTrace(ctx, r.kubeClient, managedCluster, controllerName,
+		helpers.ImportReconcileDecision(&condition, iErr), condition.Message)
+	if err := helpers.IncreaseImportFailedAttempts(ctx, r.client, managedCluster, &condition, iErr); err != nil {
+		return reconcile.Result{}, err
+	}
 	// if resources are applied but NOT modified, will not update the condition, keep the original condition.
 	// This check is to prevent the current controller and import status controller from modifying the
-	// ManagedClusterImportSucceeded condition of the managed cluster in a loop
-	if !helpers.ImportingResourcesApplied(&condition) || modified {
+	// ManagedClusterImportSucceeded condition of the managed cluster in a loop. The WaitingForKlusterletWorks
+	// condition is always cleared after the klusterlet manifest works appear
+	if !helpers.ImportingResourcesApplied(&condition) || modified ||
+		helpers.IsWaitingForKlusterletWorks(managedCluster) {
 		if err := helpers.UpdateManagedClusterStatus(
 			r.client,
 			clusterName,
@@ -144,13 +268,123 @@ func (r *ReconcileClusterDeployment) Reconcile(
 		); err != nil {
 			return reconcile.Result{}, err
 		}
+		helpers.ObserveImportResult(deployMode, managedCluster, &condition, start)
+	}
+
+	if err := r.recordImportRetry(ctx, clusterDeployment, &condition, lastRetry, currentRetry, totalRetry); err != nil {
+		return reconcile.Result{}, err
 	}
 
 	return result, iErr
 }
 
-func (r *ReconcileClusterDeployment) setCreatedViaAnnotation(
-	ctx context.Context, clusterDeployment *hivev1.ClusterDeployment, cluster *clusterv1.ManagedCluster) error {
+// importRetry returns the current and total import retry of the hive managed cluster from the annotations of the
+// clusterdeployment, the cluster is imported once if the import retry annotation is not set.
+func importRetry(clusterDeployment *hivev1.ClusterDeployment) (int, int, error) {
+	lastRetry := 0
+	totalRetry := 1
+
+	annotations := clusterDeployment.GetAnnotations()
+	if current, ok := annotations[constants.AnnotationAutoImportCurrentRetry]; ok {
+		retry, err := strconv.Atoi(current)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid current retry annotation %v", err)
+		}
+		lastRetry = retry
+	}
+
+	if total, ok := annotations[constants.ImportRetryAnnotation]; ok {
+		retry, err := strconv.Atoi(total)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid import retry annotation %v", err)
+		}
+		if retry < 1 {
+			return 0, 0, fmt.Errorf("the import retry %d should be greater than 0", retry)
+		}
+		totalRetry = retry
+	}
+
+	return lastRetry, totalRetry, nil
+}
+
+// recordImportRetry records the current import retry on the clusterdeployment, so the retries are not reset
+// after the controller restarts, the record is removed once the importing resources are applied.
+func (r *ReconcileClusterDeployment) recordImportRetry(ctx context.Context,
+	clusterDeployment *hivev1.ClusterDeployment, condition *metav1.Condition,
+	lastRetry, currentRetry, totalRetry int) error {
+	_, recorded := clusterDeployment.GetAnnotations()[constants.AnnotationAutoImportCurrentRetry]
+
+	switch {
+	case helpers.ImportingResourcesApplied(condition) && recorded:
+		patch := client.MergeFrom(clusterDeployment.DeepCopy())
+		delete(clusterDeployment.Annotations, constants.AnnotationAutoImportCurrentRetry)
+		return r.client.Patch(ctx, clusterDeployment, patch)
+	case lastRetry < currentRetry && currentRetry < totalRetry:
+		patch := client.MergeFrom(clusterDeployment.DeepCopy())
+		if clusterDeployment.Annotations == nil {
+			clusterDeployment.Annotations = map[string]string{}
+		}
+		clusterDeployment.Annotations[constants.AnnotationAutoImportCurrentRetry] = strconv.Itoa(currentRetry)
+		return r.client.Patch(ctx, clusterDeployment, patch)
+	default:
+		return nil
+	}
+}
+
+// importDelay returns how long the import of a claimed cluster is delayed since the cluster is claimed, the
+// annotation on the managed cluster takes precedence over the annotation on the clusterdeployment.
+func importDelay(clusterDeployment *hivev1.ClusterDeployment, managedCluster *clusterv1.ManagedCluster) (
+	time.Duration, error) {
+	delay, ok := managedCluster.GetAnnotations()[constants.ImportDelayAnnotation]
+	if !ok {
+		delay, ok = clusterDeployment.GetAnnotations()[constants.ImportDelayAnnotation]
+	}
+	if !ok {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(delay)
+	if err != nil {
+		return 0, fmt.Errorf("invalid import delay annotation %v", err)
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("the import delay %s should not be negative", duration)
+	}
+
+	return duration, nil
+}
+
+// reportAdminKubeconfigPendingDeletion sets the ImportSucceeded condition of the managed cluster with the
+// AdminKubeconfigPendingDeletion reason if the managed cluster is not imported yet
+func (r *ReconcileClusterDeployment) reportAdminKubeconfigPendingDeletion(
+	ctx context.Context, clusterName, message string) error {
+	managedCluster := &clusterv1.ManagedCluster{}
+	err := r.client.Get(ctx, types.NamespacedName{Name: clusterName}, managedCluster)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !managedCluster.DeletionTimestamp.IsZero() ||
+		meta.IsStatusConditionTrue(managedCluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded) {
+		return nil
+	}
+
+	return helpers.UpdateManagedClusterStatus(
+		r.client,
+		clusterName,
+		helpers.NewManagedClusterImportSucceededCondition(
+			metav1.ConditionFalse,
+			constants.ConditionReasonAdminKubeconfigPendingDeletion,
+			message,
+		),
+	)
+}
+
+func (r *ReconcileClusterDeployment) setCreatedViaAnnotation(ctx context.Context, reqLogger logr.Logger,
+	clusterDeployment *hivev1.ClusterDeployment, cluster *clusterv1.ManagedCluster) error {
 	patch := client.MergeFrom(cluster.DeepCopy())
 
 	viaAnnotation := cluster.Annotations[constants.CreatedViaAnnotation]
@@ -159,6 +393,11 @@ func (r *ReconcileClusterDeployment) setCreatedViaAnnotation(
 		return nil
 	}
 
+	if strings.EqualFold(cluster.Annotations[constants.CreatedViaImmutableAnnotation], "true") {
+		reqLogger.Info("The created-via annotation is immutable, skip overriding it", "createdVia", viaAnnotation)
+		return nil
+	}
+
 	modified := resourcemerge.BoolPtr(false)
 	if clusterDeployment.Spec.Platform.AgentBareMetal != nil {
 		resourcemerge.MergeMap(modified,
@@ -184,8 +423,39 @@ func (r *ReconcileClusterDeployment) setCreatedViaAnnotation(
 	return nil
 }
 
+// propagateLabels copies the labels of the propagated label keys from the clusterdeployment to the managed cluster,
+// the copy is additive, a label that is removed from the clusterdeployment is not removed from the managed cluster.
+func (r *ReconcileClusterDeployment) propagateLabels(ctx context.Context, reqLogger logr.Logger,
+	clusterDeployment *hivev1.ClusterDeployment, cluster *clusterv1.ManagedCluster) error {
+	labels := map[string]string{}
+	for _, key := range r.propagatedLabelKeys {
+		if value, ok := clusterDeployment.Labels[key]; ok {
+			labels[key] = value
+		}
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+
+	patch := client.MergeFrom(cluster.DeepCopy())
+	modified := resourcemerge.BoolPtr(false)
+	resourcemerge.MergeMap(modified, &cluster.Labels, labels)
+	if !*modified {
+		return nil
+	}
+
+	if err := r.client.Patch(ctx, cluster, patch); err != nil {
+		return err
+	}
+
+	reqLogger.Info("The clusterdeployment labels are propagated to the managed cluster", "labels", labels)
+	r.recorder.Eventf("ManagedClusterLabelsUpdated",
+		"The managed cluster %s labels are propagated from the clusterdeployment", cluster.Name)
+	return nil
+}
+
 func (r *ReconcileClusterDeployment) removeImportFinalizer(
-	ctx context.Context, clusterDeployment *hivev1.ClusterDeployment) error {
+	ctx context.Context, reqLogger logr.Logger, clusterDeployment *hivev1.ClusterDeployment) error {
 
 	hasImportFinalizer := false
 
@@ -198,15 +468,13 @@ func (r *ReconcileClusterDeployment) removeImportFinalizer(
 
 	if !hasImportFinalizer {
 		// the clusterdeployment does not have import finalizer, ignore it
-		log.Info("the clusterDeployment does not have import finalizer, skip it",
-			"clusterDeployment", clusterDeployment.Name)
+		reqLogger.Info("the clusterDeployment does not have import finalizer, skip it")
 		return nil
 	}
 
 	if len(clusterDeployment.Finalizers) != 1 {
 		// the clusterdeployment has other finalizers, wait hive to remove them
-		log.Info("wait hive to remove the finalizers from the clusterdeployment",
-			"clusterdeployment", clusterDeployment.Name)
+		reqLogger.Info("wait hive to remove the finalizers from the clusterdeployment")
 		return nil
 	}
 
@@ -5,6 +5,9 @@ package clusterdeployment
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,9 +17,11 @@ import (
 	workv1 "open-cluster-management.io/api/work/v1"
 
 	hivev1 "github.com/openshift/hive/apis/hive/v1"
+	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
 
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
 	testinghelpers "github.com/stolostron/managedcluster-import-controller/pkg/helpers/testing"
 	"github.com/stolostron/managedcluster-import-controller/pkg/source"
 
@@ -25,6 +30,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	kubeversion "k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
 	"k8s.io/client-go/informers"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -259,3 +266,748 @@ func TestReconcile(t *testing.T) {
 		})
 	}
 }
+
+func TestReconcileTrace(t *testing.T) {
+	objs := []client.Object{
+		&clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test",
+				Annotations: map[string]string{
+					constants.ReconcileTraceAnnotation: "true",
+				},
+			},
+		},
+		&hivev1.ClusterDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test",
+			},
+		},
+	}
+
+	kubeClient := kubefake.NewSimpleClientset()
+	kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+	workInformerFactory := workinformers.NewSharedInformerFactory(workfake.NewSimpleClientset(), 10*time.Minute)
+
+	r := NewReconcileClusterDeployment(
+		fake.NewClientBuilder().WithScheme(testscheme).WithObjects(objs...).WithStatusSubresource(objs...).Build(),
+		kubeClient,
+		&source.InformerHolder{
+			AutoImportSecretLister: kubeInformerFactory.Core().V1().Secrets().Lister(),
+			ImportSecretLister:     kubeInformerFactory.Core().V1().Secrets().Lister(),
+			KlusterletWorkLister:   workInformerFactory.Work().V1().ManifestWorks().Lister(),
+		},
+		eventstesting.NewTestingEventRecorder(t),
+	)
+
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "test"}})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	configMap, err := kubeClient.CoreV1().ConfigMaps("test").Get(
+		context.TODO(), helpers.ReconcileTraceConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	trace := configMap.Data[helpers.ReconcileTraceKey]
+	if !strings.HasSuffix(trace, controllerName+": "+helpers.ReconcileDecisionSkippedNotInstalled) {
+		t.Errorf("expected the trace records the %s decision, but got %q",
+			helpers.ReconcileDecisionSkippedNotInstalled, trace)
+	}
+}
+
+func TestReconcileImportDelay(t *testing.T) {
+	claimedTimestamp := metav1.NewTime(time.Now().Add(-1 * time.Minute))
+
+	cases := []struct {
+		name                    string
+		clusterDeploymentDelay  string
+		managedClusterDelay     string
+		expectedRequeue         bool
+		expectedConditionReason string
+	}{
+		{
+			name: "no import delay",
+		},
+		{
+			name:                   "the import delay is not elapsed",
+			clusterDeploymentDelay: "10m",
+			expectedRequeue:        true,
+		},
+		{
+			name:                   "the import delay is elapsed",
+			clusterDeploymentDelay: "30s",
+		},
+		{
+			name:                   "the import delay of the managed cluster takes precedence",
+			clusterDeploymentDelay: "30s",
+			managedClusterDelay:    "10m",
+			expectedRequeue:        true,
+		},
+		{
+			name:                    "invalid import delay",
+			clusterDeploymentDelay:  "10",
+			expectedConditionReason: constants.ConditionReasonManagedClusterImportFailed,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			managedCluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test",
+					Annotations: map[string]string{},
+				},
+			}
+			if len(c.managedClusterDelay) != 0 {
+				managedCluster.Annotations[constants.ImportDelayAnnotation] = c.managedClusterDelay
+			}
+			clusterDeployment := &hivev1.ClusterDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test",
+					Namespace:   "test",
+					Annotations: map[string]string{},
+				},
+				Spec: hivev1.ClusterDeploymentSpec{
+					Installed: true,
+					ClusterPoolRef: &hivev1.ClusterPoolReference{
+						ClaimedTimestamp: &claimedTimestamp,
+					},
+				},
+			}
+			if len(c.clusterDeploymentDelay) != 0 {
+				clusterDeployment.Annotations[constants.ImportDelayAnnotation] = c.clusterDeploymentDelay
+			}
+			objs := []client.Object{managedCluster, clusterDeployment}
+
+			// the cluster has an auto import secret, so the reconcile stops after the import delay is elapsed
+			autoImportSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      constants.AutoImportSecretName,
+					Namespace: "test",
+				},
+			}
+			kubeClient := kubefake.NewSimpleClientset(autoImportSecret)
+			kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+			kubeInformerFactory.Core().V1().Secrets().Informer().GetStore().Add(autoImportSecret)
+			workInformerFactory := workinformers.NewSharedInformerFactory(workfake.NewSimpleClientset(), 10*time.Minute)
+
+			r := NewReconcileClusterDeployment(
+				fake.NewClientBuilder().WithScheme(testscheme).WithObjects(objs...).WithStatusSubresource(objs...).Build(),
+				kubeClient,
+				&source.InformerHolder{
+					AutoImportSecretLister: kubeInformerFactory.Core().V1().Secrets().Lister(),
+					ImportSecretLister:     kubeInformerFactory.Core().V1().Secrets().Lister(),
+					KlusterletWorkLister:   workInformerFactory.Work().V1().ManifestWorks().Lister(),
+				},
+				eventstesting.NewTestingEventRecorder(t),
+			)
+
+			result, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "test"}})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if c.expectedRequeue && (result.RequeueAfter <= 8*time.Minute || result.RequeueAfter > 9*time.Minute) {
+				t.Errorf("expected requeue after about 9m, but got %v", result.RequeueAfter)
+			}
+			if !c.expectedRequeue && result.RequeueAfter != 0 {
+				t.Errorf("expected no requeue, but got %v", result.RequeueAfter)
+			}
+
+			cluster := &clusterv1.ManagedCluster{}
+			if err := r.client.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			condition := meta.FindStatusCondition(cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+			if len(c.expectedConditionReason) == 0 {
+				if condition != nil {
+					t.Errorf("expected no import condition, but got %v", condition)
+				}
+				return
+			}
+			if condition == nil || condition.Reason != c.expectedConditionReason {
+				t.Errorf("expected the condition reason %s, but got %v", c.expectedConditionReason, condition)
+			}
+		})
+	}
+}
+
+func TestReconcileWaitingForKlusterletWorks(t *testing.T) {
+	adminKubeconfigSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-admin-kubeconfig",
+			Namespace: "test",
+		},
+		Data: map[string][]byte{
+			"kubeconfig": []byte("fake"),
+		},
+	}
+	importSecret := testinghelpers.GetImportSecret("test")
+	objs := []client.Object{
+		&clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test",
+			},
+		},
+		&hivev1.ClusterDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test",
+			},
+			Spec: hivev1.ClusterDeploymentSpec{
+				Installed: true,
+				ClusterMetadata: &hivev1.ClusterMetadata{
+					AdminKubeconfigSecretRef: corev1.LocalObjectReference{
+						Name: adminKubeconfigSecret.Name,
+					},
+				},
+			},
+		},
+	}
+
+	kubeClient := kubefake.NewSimpleClientset(adminKubeconfigSecret, importSecret)
+	kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+	if err := kubeInformerFactory.Core().V1().Secrets().Informer().GetStore().Add(importSecret); err != nil {
+		t.Fatal(err)
+	}
+	workInformerFactory := workinformers.NewSharedInformerFactory(workfake.NewSimpleClientset(), 10*time.Minute)
+	workStore := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore()
+	if err := workStore.Add(&workv1.ManifestWork{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-klusterlet-crds",
+			Namespace: "test",
+			Labels: map[string]string{
+				constants.KlusterletWorksLabel: "true",
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	spokeKubeClient := kubefake.NewSimpleClientset()
+	spokeKubeClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &kubeversion.Info{
+		GitVersion: "v1.27.3",
+	}
+
+	r := NewReconcileClusterDeployment(
+		fake.NewClientBuilder().WithScheme(testscheme).WithObjects(objs...).WithStatusSubresource(objs...).Build(),
+		kubeClient,
+		&source.InformerHolder{
+			AutoImportSecretLister: kubeInformerFactory.Core().V1().Secrets().Lister(),
+			ImportSecretLister:     kubeInformerFactory.Core().V1().Secrets().Lister(),
+			KlusterletWorkLister:   workInformerFactory.Work().V1().ManifestWorks().Lister(),
+		},
+		eventstesting.NewTestingEventRecorder(t),
+	)
+	// report the missing klusterlet works immediately
+	r.importHelper = r.importHelper.
+		WithKlusterletWorksWaitingTimeout(0).
+		WithGenerateClientHolderFunc(func(secret *corev1.Secret) (*helpers.ClientHolder, meta.RESTMapper, error) {
+			return &helpers.ClientHolder{KubeClient: spokeKubeClient}, nil, nil
+		}).
+		WithApplyResourcesFunc(func(backupRestore bool, client *helpers.ClientHolder, restMapper meta.RESTMapper,
+			recorder events.Recorder, importSecret *corev1.Secret) (bool, error) {
+			return false, nil
+		})
+
+	getCondition := func() *metav1.Condition {
+		managedCluster := &clusterv1.ManagedCluster{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Name: "test"}, managedCluster); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return meta.FindStatusCondition(
+			managedCluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+	}
+
+	result, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "test"}})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if result.RequeueAfter == 0 {
+		t.Errorf("expected requeue, but got %v", result)
+	}
+	condition := getCondition()
+	if condition == nil || condition.Reason != constants.ConditionReasonWaitingForKlusterletWorks {
+		t.Fatalf("expected condition reason %s, but got %v", constants.ConditionReasonWaitingForKlusterletWorks, condition)
+	}
+	if !strings.Contains(condition.Message, "but got 1") {
+		t.Errorf("expected the condition message contains the number of the existing works, but got %q",
+			condition.Message)
+	}
+
+	// the condition is cleared once both klusterlet works appear
+	if err := workStore.Add(&workv1.ManifestWork{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-klusterlet",
+			Namespace: "test",
+			Labels: map[string]string{
+				constants.KlusterletWorksLabel: "true",
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "test"}})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	condition = getCondition()
+	if condition == nil || !helpers.ImportingResourcesApplied(condition) {
+		t.Errorf("expected the importing resources are applied, but got %v", condition)
+	}
+}
+
+func TestReconcileImportRetry(t *testing.T) {
+	adminKubeconfigSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-admin-kubeconfig",
+			Namespace: "test",
+		},
+		Data: map[string][]byte{
+			"kubeconfig": []byte("fake"),
+		},
+	}
+	importSecret := testinghelpers.GetImportSecret("test")
+	objs := []client.Object{
+		&clusterv1.ManagedCluster{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test",
+			},
+		},
+		&hivev1.ClusterDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test",
+				Annotations: map[string]string{
+					constants.ImportRetryAnnotation: "2",
+				},
+			},
+			Spec: hivev1.ClusterDeploymentSpec{
+				Installed: true,
+				ClusterMetadata: &hivev1.ClusterMetadata{
+					AdminKubeconfigSecretRef: corev1.LocalObjectReference{
+						Name: adminKubeconfigSecret.Name,
+					},
+				},
+			},
+		},
+	}
+
+	kubeClient := kubefake.NewSimpleClientset(adminKubeconfigSecret, importSecret)
+	kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+	if err := kubeInformerFactory.Core().V1().Secrets().Informer().GetStore().Add(importSecret); err != nil {
+		t.Fatal(err)
+	}
+	workInformerFactory := workinformers.NewSharedInformerFactory(workfake.NewSimpleClientset(), 10*time.Minute)
+	workStore := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore()
+	for _, name := range []string{"test-klusterlet-crds", "test-klusterlet"} {
+		if err := workStore.Add(&workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "test",
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "true",
+				},
+			},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := NewReconcileClusterDeployment(
+		fake.NewClientBuilder().WithScheme(testscheme).WithObjects(objs...).WithStatusSubresource(objs...).Build(),
+		kubeClient,
+		&source.InformerHolder{
+			AutoImportSecretLister: kubeInformerFactory.Core().V1().Secrets().Lister(),
+			ImportSecretLister:     kubeInformerFactory.Core().V1().Secrets().Lister(),
+			KlusterletWorkLister:   workInformerFactory.Work().V1().ManifestWorks().Lister(),
+		},
+		eventstesting.NewTestingEventRecorder(t),
+	)
+	// the managed cluster is unreachable
+	r.importHelper = r.importHelper.
+		WithGenerateClientHolderFunc(func(secret *corev1.Secret) (*helpers.ClientHolder, meta.RESTMapper, error) {
+			return nil, nil, fmt.Errorf("unreachable")
+		})
+
+	steps := []struct {
+		name            string
+		expectedRequeue bool
+		expectedReason  string
+		expectedRetry   string
+	}{
+		{
+			name:            "the first attempt is failed",
+			expectedRequeue: true,
+			expectedReason:  constants.ConditionReasonManagedClusterImporting,
+			expectedRetry:   "1",
+		},
+		{
+			name:           "the retries are used up",
+			expectedReason: constants.ConditionReasonImportRetryExhausted,
+			expectedRetry:  "1",
+		},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			result, err := r.Reconcile(context.TODO(),
+				reconcile.Request{NamespacedName: types.NamespacedName{Name: "test"}})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if requeue := result.RequeueAfter > 0; requeue != step.expectedRequeue {
+				t.Errorf("expected requeue %v, but got %v", step.expectedRequeue, result)
+			}
+
+			managedCluster := &clusterv1.ManagedCluster{}
+			if err := r.client.Get(context.TODO(), types.NamespacedName{Name: "test"}, managedCluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			condition := meta.FindStatusCondition(
+				managedCluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+			if condition == nil || condition.Reason != step.expectedReason {
+				t.Errorf("expected condition reason %s, but got %v", step.expectedReason, condition)
+			}
+
+			clusterDeployment := &hivev1.ClusterDeployment{}
+			if err := r.client.Get(context.TODO(),
+				types.NamespacedName{Name: "test", Namespace: "test"}, clusterDeployment); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if retry := clusterDeployment.Annotations[constants.AnnotationAutoImportCurrentRetry]; retry != step.expectedRetry {
+				t.Errorf("expected current retry %q, but got %q", step.expectedRetry, retry)
+			}
+		})
+	}
+}
+
+func TestReconcileAdminKubeconfigPendingDeletion(t *testing.T) {
+	adminKubeconfigSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-admin-kubeconfig",
+			Namespace: "test",
+		},
+		Data: map[string][]byte{
+			"kubeconfig": []byte("invalid"),
+		},
+	}
+	deletingSecret := adminKubeconfigSecret.DeepCopy()
+	deletingSecret.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+	cases := []struct {
+		name              string
+		deploymentDeleted bool
+		secret            *corev1.Secret
+	}{
+		{
+			name:              "clusterdeployment is deleting",
+			deploymentDeleted: true,
+			secret:            adminKubeconfigSecret,
+		},
+		{
+			name:   "admin kubeconfig secret is deleting",
+			secret: deletingSecret,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clusterDeployment := &hivev1.ClusterDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "test",
+				},
+				Spec: hivev1.ClusterDeploymentSpec{
+					Installed: true,
+					ClusterMetadata: &hivev1.ClusterMetadata{
+						AdminKubeconfigSecretRef: corev1.LocalObjectReference{
+							Name: adminKubeconfigSecret.Name,
+						},
+					},
+				},
+			}
+			if c.deploymentDeleted {
+				clusterDeployment.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+				clusterDeployment.Finalizers = []string{"hive.openshift.io/deprovision"}
+			}
+			objs := []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+				clusterDeployment,
+			}
+
+			kubeClient := kubefake.NewSimpleClientset(c.secret)
+			kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+			workInformerFactory := workinformers.NewSharedInformerFactory(workfake.NewSimpleClientset(), 10*time.Minute)
+
+			r := NewReconcileClusterDeployment(
+				fake.NewClientBuilder().WithScheme(testscheme).WithObjects(objs...).WithStatusSubresource(objs...).Build(),
+				kubeClient,
+				&source.InformerHolder{
+					AutoImportSecretLister: kubeInformerFactory.Core().V1().Secrets().Lister(),
+					ImportSecretLister:     kubeInformerFactory.Core().V1().Secrets().Lister(),
+					KlusterletWorkLister:   workInformerFactory.Work().V1().ManifestWorks().Lister(),
+				},
+				eventstesting.NewTestingEventRecorder(t),
+			)
+
+			_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "test"}})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			managedCluster := &clusterv1.ManagedCluster{}
+			if err := r.client.Get(context.TODO(), types.NamespacedName{Name: "test"}, managedCluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			condition := meta.FindStatusCondition(
+				managedCluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+			if condition == nil || condition.Reason != constants.ConditionReasonAdminKubeconfigPendingDeletion {
+				t.Errorf("expected condition reason %s, but got %v",
+					constants.ConditionReasonAdminKubeconfigPendingDeletion, condition)
+			}
+		})
+	}
+}
+
+func TestReconcileImportDryRun(t *testing.T) {
+	adminKubeconfigSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-admin-kubeconfig",
+			Namespace: "test",
+		},
+		Data: map[string][]byte{
+			"kubeconfig": []byte("fake"),
+		},
+	}
+	importSecret := testinghelpers.GetImportSecret("test")
+
+	cases := []struct {
+		name           string
+		dryRunErr      error
+		expectedReason string
+	}{
+		{
+			name:           "dry-run import succeeded",
+			expectedReason: constants.ConditionReasonImportDryRunSucceeded,
+		},
+		{
+			name:           "dry-run import failed",
+			dryRunErr:      fmt.Errorf("the namespace is forbidden"),
+			expectedReason: constants.ConditionReasonImportDryRunFailed,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			objs := []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							constants.ImportDryRunAnnotation: "true",
+						},
+					},
+				},
+				&hivev1.ClusterDeployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "test",
+					},
+					Spec: hivev1.ClusterDeploymentSpec{
+						Installed: true,
+						ClusterMetadata: &hivev1.ClusterMetadata{
+							AdminKubeconfigSecretRef: corev1.LocalObjectReference{
+								Name: adminKubeconfigSecret.Name,
+							},
+						},
+					},
+				},
+			}
+
+			kubeClient := kubefake.NewSimpleClientset(adminKubeconfigSecret, importSecret)
+			kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+			if err := kubeInformerFactory.Core().V1().Secrets().Informer().GetStore().Add(importSecret); err != nil {
+				t.Fatal(err)
+			}
+			workInformerFactory := workinformers.NewSharedInformerFactory(workfake.NewSimpleClientset(), 10*time.Minute)
+
+			r := NewReconcileClusterDeployment(
+				fake.NewClientBuilder().WithScheme(testscheme).WithObjects(objs...).WithStatusSubresource(objs...).Build(),
+				kubeClient,
+				&source.InformerHolder{
+					AutoImportSecretLister: kubeInformerFactory.Core().V1().Secrets().Lister(),
+					ImportSecretLister:     kubeInformerFactory.Core().V1().Secrets().Lister(),
+					KlusterletWorkLister:   workInformerFactory.Work().V1().ManifestWorks().Lister(),
+				},
+				eventstesting.NewTestingEventRecorder(t),
+			)
+			r.importHelper = r.importHelper.
+				WithGenerateClientHolderFunc(func(secret *corev1.Secret) (*helpers.ClientHolder, meta.RESTMapper, error) {
+					return &helpers.ClientHolder{}, nil, nil
+				}).
+				WithApplyResourcesFunc(func(backupRestore bool, client *helpers.ClientHolder, restMapper meta.RESTMapper,
+					recorder events.Recorder, importSecret *corev1.Secret) (bool, error) {
+					t.Errorf("the resources should not be applied in the dry-run mode")
+					return false, nil
+				}).
+				WithDryRunImportFunc(func(ctx context.Context, client *helpers.ClientHolder, restMapper meta.RESTMapper,
+					importSecret *corev1.Secret) error {
+					return c.dryRunErr
+				})
+
+			_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "test"}})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			managedCluster := &clusterv1.ManagedCluster{}
+			if err := r.client.Get(context.TODO(), types.NamespacedName{Name: "test"}, managedCluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			condition := meta.FindStatusCondition(
+				managedCluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+			if condition == nil || condition.Reason != c.expectedReason {
+				t.Errorf("expected condition reason %s, but got %v", c.expectedReason, condition)
+			}
+			// nothing is changed on the hub in the dry-run mode
+			if via, ok := managedCluster.Annotations[constants.CreatedViaAnnotation]; ok {
+				t.Errorf("expected the managed cluster is not changed in the dry-run mode, but got created-via %s", via)
+			}
+		})
+	}
+}
+
+func TestSetCreatedViaAnnotation(t *testing.T) {
+	cases := []struct {
+		name               string
+		annotations        map[string]string
+		expectedCreatedVia string
+	}{
+		{
+			name:               "no annotation",
+			expectedCreatedVia: constants.CreatedViaHive,
+		},
+		{
+			name:               "discovery",
+			annotations:        map[string]string{constants.CreatedViaAnnotation: constants.CreatedViaDiscovery},
+			expectedCreatedVia: constants.CreatedViaDiscovery,
+		},
+		{
+			name:               "custom provenance is overridden",
+			annotations:        map[string]string{constants.CreatedViaAnnotation: "custom-pipeline"},
+			expectedCreatedVia: constants.CreatedViaHive,
+		},
+		{
+			name: "immutable custom provenance",
+			annotations: map[string]string{
+				constants.CreatedViaAnnotation:          "custom-pipeline",
+				constants.CreatedViaImmutableAnnotation: "true",
+			},
+			expectedCreatedVia: "custom-pipeline",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test",
+					Annotations: c.annotations,
+				},
+			}
+			clusterDeployment := &hivev1.ClusterDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "test",
+				},
+			}
+
+			r := &ReconcileClusterDeployment{
+				client:   fake.NewClientBuilder().WithScheme(testscheme).WithObjects(cluster).Build(),
+				recorder: eventstesting.NewTestingEventRecorder(t),
+			}
+			if err := r.setCreatedViaAnnotation(context.TODO(), log, clusterDeployment, cluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			managedCluster := &clusterv1.ManagedCluster{}
+			if err := r.client.Get(context.TODO(), types.NamespacedName{Name: "test"}, managedCluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if createdVia := managedCluster.Annotations[constants.CreatedViaAnnotation]; createdVia != c.expectedCreatedVia {
+				t.Errorf("expected created-via %q, but got %q", c.expectedCreatedVia, createdVia)
+			}
+		})
+	}
+}
+
+func TestPropagateLabels(t *testing.T) {
+	cases := []struct {
+		name                    string
+		propagatedLabelKeys     []string
+		clusterDeploymentLabels map[string]string
+		clusterLabels           map[string]string
+		expectedLabels          map[string]string
+	}{
+		{
+			name:                    "no propagated label keys",
+			clusterDeploymentLabels: map[string]string{"cost-center": "1234"},
+			clusterLabels:           map[string]string{"vendor": "OpenShift"},
+			expectedLabels:          map[string]string{"vendor": "OpenShift"},
+		},
+		{
+			name:                    "copy the propagated labels",
+			propagatedLabelKeys:     []string{"cost-center", "environment"},
+			clusterDeploymentLabels: map[string]string{"cost-center": "1234", "environment": "prod", "team": "a"},
+			clusterLabels:           map[string]string{"vendor": "OpenShift", "environment": "dev"},
+			expectedLabels:          map[string]string{"vendor": "OpenShift", "cost-center": "1234", "environment": "prod"},
+		},
+		{
+			name:                    "the labels are not removed from the managed cluster",
+			propagatedLabelKeys:     []string{"cost-center", "environment"},
+			clusterDeploymentLabels: map[string]string{"cost-center": "1234"},
+			clusterLabels:           map[string]string{"environment": "prod"},
+			expectedLabels:          map[string]string{"cost-center": "1234", "environment": "prod"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "test",
+					Labels: c.clusterLabels,
+				},
+			}
+			clusterDeployment := &hivev1.ClusterDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "test",
+					Labels:    c.clusterDeploymentLabels,
+				},
+			}
+
+			r := (&ReconcileClusterDeployment{
+				client:   fake.NewClientBuilder().WithScheme(testscheme).WithObjects(cluster).Build(),
+				recorder: eventstesting.NewTestingEventRecorder(t),
+			}).WithPropagatedLabelKeys(c.propagatedLabelKeys)
+			if err := r.propagateLabels(context.TODO(), log, clusterDeployment, cluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			managedCluster := &clusterv1.ManagedCluster{}
+			if err := r.client.Get(context.TODO(), types.NamespacedName{Name: "test"}, managedCluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(managedCluster.Labels, c.expectedLabels) {
+				t.Errorf("expected labels %v, but got %v", c.expectedLabels, managedCluster.Labels)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"strings"
 
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	workv1 "open-cluster-management.io/api/work/v1"
 
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
@@ -32,13 +33,21 @@ import (
 
 const controllerName = "clusterdeployment-controller"
 
+// maxConcurrentReconcilesEnvVarName is the env to specify the max concurrent reconciles of the clusterdeployment controller,
+// the global MAX_CONCURRENT_RECONCILES env is used if it is not set
+const maxConcurrentReconcilesEnvVarName = "CLUSTERDEPLOYMENT_MAX_CONCURRENT_RECONCILES"
+
+// PropagatedLabelKeys are the keys of the labels that are copied from the clusterdeployment to the managed cluster,
+// it is set by the startup flag
+var PropagatedLabelKeys []string
+
 // Add creates a new managedcluster controller and adds it to the Manager.
 // The Manager will set fields on the Controller and Start it when the Manager is Started.
 func Add(mgr manager.Manager, clientHolder *helpers.ClientHolder, informerHolder *source.InformerHolder) (string, error) {
 
 	err := ctrl.NewControllerManagedBy(mgr).Named(controllerName).
 		WithOptions(controller.Options{
-			MaxConcurrentReconciles: helpers.GetMaxConcurrentReconciles(),
+			MaxConcurrentReconciles: helpers.GetControllerMaxConcurrentReconciles(maxConcurrentReconcilesEnvVarName),
 		}).
 		Watches( // watch the clusterdeployment
 			&hivev1.ClusterDeployment{},
@@ -53,6 +62,20 @@ func Add(mgr manager.Manager, clientHolder *helpers.ClientHolder, informerHolder
 				}
 			}),
 		).
+		Watches( // watch the import dry-run and force import annotations of the managed cluster
+			&clusterv1.ManagedCluster{},
+			&handler.EnqueueRequestForObject{},
+			builder.WithPredicates(predicate.Funcs{
+				GenericFunc: func(e event.GenericEvent) bool { return false },
+				DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+				CreateFunc:  func(e event.CreateEvent) bool { return false },
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					return e.ObjectOld.GetAnnotations()[constants.ImportDryRunAnnotation] !=
+						e.ObjectNew.GetAnnotations()[constants.ImportDryRunAnnotation] ||
+						helpers.ForceImportAnnotationAdded(e.ObjectOld, e.ObjectNew)
+				},
+			}),
+		).
 		WatchesRawSource( // watch the import secret
 			source.NewImportSecretSource(informerHolder.ImportSecretInformer),
 			&source.ManagedClusterResourceEventHandler{},
@@ -109,7 +132,8 @@ func Add(mgr manager.Manager, clientHolder *helpers.ClientHolder, informerHolder
 			clientHolder.RuntimeClient,
 			clientHolder.KubeClient,
 			informerHolder,
-			helpers.NewEventRecorder(clientHolder.KubeClient, controllerName)))
+			helpers.NewEventRecorder(clientHolder.KubeClient, controllerName)).
+			WithPropagatedLabelKeys(PropagatedLabelKeys))
 
 	return controllerName, err
 }
@@ -0,0 +1,126 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package clusterunreachable
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+var log = logf.Log.WithName(controllerName)
+
+// permanentlyUnreachableThreshold is the duration that a managed cluster can stay in unreachable, after that, the
+// managed cluster is considered permanently unreachable
+const permanentlyUnreachableThreshold = 24 * time.Hour
+
+// ReconcileClusterUnreachable reconciles the taints of the managed clusters to judge whether the managed cluster
+// is permanently unreachable
+type ReconcileClusterUnreachable struct {
+	client   client.Client
+	recorder events.Recorder
+}
+
+// blank assignment to verify that ReconcileClusterUnreachable implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileClusterUnreachable{}
+
+// Reconcile sets the managed cluster permanently unreachable condition according to its unreachable taint, the
+// managed cluster that has the unreachable taint over the permanentlyUnreachableThreshold is suggested to be
+// detached.
+func (r *ReconcileClusterUnreachable) Reconcile(
+	ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Name", request.Name)
+
+	managedCluster := &clusterv1.ManagedCluster{}
+	err := r.client.Get(ctx, types.NamespacedName{Name: request.Name}, managedCluster)
+	if errors.IsNotFound(err) {
+		// the managed cluster could have been deleted, do nothing
+		return reconcile.Result{}, nil
+	}
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if !managedCluster.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+
+	condition, recheckAfter := newPermanentlyUnreachableCondition(managedCluster, time.Now())
+	if condition == nil {
+		return reconcile.Result{}, nil
+	}
+
+	reqLogger.V(5).Info("Reconciling the managed cluster unreachable taint", "reason", condition.Reason)
+	if err := helpers.UpdateManagedClusterStatus(r.client, managedCluster.Name, *condition); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if condition.Status == metav1.ConditionTrue && !meta.IsStatusConditionTrue(
+		managedCluster.Status.Conditions, constants.ConditionManagedClusterPermanentlyUnreachable) {
+		r.recorder.Warningf("ManagedClusterPermanentlyUnreachable", "The managed cluster %s: %s",
+			managedCluster.Name, condition.Message)
+	}
+
+	return reconcile.Result{RequeueAfter: recheckAfter}, nil
+}
+
+// newPermanentlyUnreachableCondition checks how long the managed cluster has the unreachable taint, if the managed
+// cluster is unreachable but not permanently yet, the duration to recheck it is returned. No condition is returned
+// if the managed cluster does not have the unreachable taint and the condition has never been reported.
+func newPermanentlyUnreachableCondition(
+	cluster *clusterv1.ManagedCluster, now time.Time) (*metav1.Condition, time.Duration) {
+	var unreachableTaint *clusterv1.Taint
+	for i := range cluster.Spec.Taints {
+		if cluster.Spec.Taints[i].Key == clusterv1.ManagedClusterTaintUnreachable {
+			unreachableTaint = &cluster.Spec.Taints[i]
+			break
+		}
+	}
+
+	if unreachableTaint == nil {
+		if meta.FindStatusCondition(cluster.Status.Conditions,
+			constants.ConditionManagedClusterPermanentlyUnreachable) == nil {
+			return nil, 0
+		}
+
+		return &metav1.Condition{
+			Type:    constants.ConditionManagedClusterPermanentlyUnreachable,
+			Status:  metav1.ConditionFalse,
+			Reason:  constants.ConditionReasonManagedClusterReachable,
+			Message: "The managed cluster is reachable",
+		}, 0
+	}
+
+	unreachableDuration := now.Sub(unreachableTaint.TimeAdded.Time)
+	if unreachableDuration >= permanentlyUnreachableThreshold {
+		return &metav1.Condition{
+			Type:   constants.ConditionManagedClusterPermanentlyUnreachable,
+			Status: metav1.ConditionTrue,
+			Reason: constants.ConditionReasonManagedClusterDetachSuggested,
+			Message: fmt.Sprintf("The managed cluster has been unreachable since %s, consider detaching the "+
+				"managed cluster or importing it again", unreachableTaint.TimeAdded.UTC().Format(time.RFC3339)),
+		}, 0
+	}
+
+	return &metav1.Condition{
+		Type:   constants.ConditionManagedClusterPermanentlyUnreachable,
+		Status: metav1.ConditionFalse,
+		Reason: constants.ConditionReasonManagedClusterUnreachable,
+		Message: fmt.Sprintf("The managed cluster has been unreachable since %s",
+			unreachableTaint.TimeAdded.UTC().Format(time.RFC3339)),
+	}, permanentlyUnreachableThreshold - unreachableDuration
+}
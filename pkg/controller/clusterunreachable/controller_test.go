@@ -0,0 +1,151 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package clusterunreachable
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var testscheme = scheme.Scheme
+
+func init() {
+	testscheme.AddKnownTypes(clusterv1.SchemeGroupVersion, &clusterv1.ManagedCluster{})
+}
+
+func TestReconcileTaintTransition(t *testing.T) {
+	managedClusterName := "test"
+	managedCluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: managedClusterName,
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(testscheme).
+		WithObjects(managedCluster).WithStatusSubresource(managedCluster).Build()
+
+	r := &ReconcileClusterUnreachable{
+		client:   c,
+		recorder: eventstesting.NewTestingEventRecorder(t),
+	}
+
+	steps := []struct {
+		name                    string
+		taints                  []clusterv1.Taint
+		expectedCondition       bool
+		expectedConditionStatus metav1.ConditionStatus
+		expectedConditionReason string
+		expectedRequeue         bool
+	}{
+		{
+			name:              "no taints",
+			expectedCondition: false,
+		},
+		{
+			name: "unreachable recently",
+			taints: []clusterv1.Taint{
+				{
+					Key:       clusterv1.ManagedClusterTaintUnreachable,
+					Effect:    clusterv1.TaintEffectNoSelect,
+					TimeAdded: metav1.NewTime(time.Now().Add(-1 * time.Hour)),
+				},
+			},
+			expectedCondition:       true,
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonManagedClusterUnreachable,
+			expectedRequeue:         true,
+		},
+		{
+			name: "unreachable permanently",
+			taints: []clusterv1.Taint{
+				{
+					Key:       clusterv1.ManagedClusterTaintUnreachable,
+					Effect:    clusterv1.TaintEffectNoSelect,
+					TimeAdded: metav1.NewTime(time.Now().Add(-25 * time.Hour)),
+				},
+			},
+			expectedCondition:       true,
+			expectedConditionStatus: metav1.ConditionTrue,
+			expectedConditionReason: constants.ConditionReasonManagedClusterDetachSuggested,
+		},
+		{
+			name:                    "reachable again",
+			expectedCondition:       true,
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonManagedClusterReachable,
+		},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			cluster := &clusterv1.ManagedCluster{}
+			if err := c.Get(context.TODO(), types.NamespacedName{Name: managedClusterName}, cluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			cluster.Spec.Taints = step.taints
+			if err := c.Update(context.TODO(), cluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			result, err := r.Reconcile(context.TODO(), reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: managedClusterName},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if step.expectedRequeue != (result.RequeueAfter > 0) {
+				t.Errorf("expected requeue %v, but got %v", step.expectedRequeue, result.RequeueAfter)
+			}
+
+			if err := c.Get(context.TODO(), types.NamespacedName{Name: managedClusterName}, cluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			condition := meta.FindStatusCondition(cluster.Status.Conditions,
+				constants.ConditionManagedClusterPermanentlyUnreachable)
+			if !step.expectedCondition {
+				if condition != nil {
+					t.Errorf("expected no condition, but got %v", condition)
+				}
+				return
+			}
+			if condition == nil {
+				t.Fatalf("expected condition, but got nil")
+			}
+			if condition.Status != step.expectedConditionStatus || condition.Reason != step.expectedConditionReason {
+				t.Errorf("expected condition %s/%s, but got %s/%s", step.expectedConditionStatus,
+					step.expectedConditionReason, condition.Status, condition.Reason)
+			}
+		})
+	}
+}
+
+func TestTaintsChanged(t *testing.T) {
+	cluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+	tainted := cluster.DeepCopy()
+	tainted.Spec.Taints = []clusterv1.Taint{
+		{
+			Key:    clusterv1.ManagedClusterTaintUnreachable,
+			Effect: clusterv1.TaintEffectNoSelect,
+		},
+	}
+
+	if taintsChanged(cluster, cluster.DeepCopy()) {
+		t.Errorf("expected taints are not changed")
+	}
+	if !taintsChanged(cluster, tainted) {
+		t.Errorf("expected taints are changed")
+	}
+}
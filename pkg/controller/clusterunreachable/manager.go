@@ -0,0 +1,62 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package clusterunreachable
+
+import (
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/source"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const controllerName = "clusterunreachable-controller"
+
+// Add creates a new clusterunreachable controller and adds it to the Manager.
+// The Manager will set fields on the Controller and Start it when the Manager is Started.
+func Add(mgr manager.Manager, clientHolder *helpers.ClientHolder, _ *source.InformerHolder) (string, error) {
+
+	err := ctrl.NewControllerManagedBy(mgr).Named(controllerName).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: helpers.GetMaxConcurrentReconciles(),
+		}).
+		Watches(
+			&clusterv1.ManagedCluster{},
+			&handler.EnqueueRequestForObject{},
+			builder.WithPredicates(predicate.Funcs{
+				GenericFunc: func(e event.GenericEvent) bool { return false },
+				DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+				CreateFunc:  func(e event.CreateEvent) bool { return true },
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					return taintsChanged(e.ObjectOld, e.ObjectNew)
+				},
+			}),
+		).
+		Complete(&ReconcileClusterUnreachable{
+			client:   clientHolder.RuntimeClient,
+			recorder: helpers.NewEventRecorder(clientHolder.KubeClient, controllerName),
+		})
+
+	return controllerName, err
+}
+
+// taintsChanged returns true if the taints of the managed cluster are changed
+func taintsChanged(oldObj, newObj client.Object) bool {
+	oldCluster, okOld := oldObj.(*clusterv1.ManagedCluster)
+	newCluster, okNew := newObj.(*clusterv1.ManagedCluster)
+	if !okOld || !okNew {
+		return false
+	}
+
+	return !equality.Semantic.DeepEqual(oldCluster.Spec.Taints, newCluster.Spec.Taints)
+}
@@ -14,9 +14,11 @@ import (
 	"github.com/stolostron/managedcluster-import-controller/pkg/controller/autoimport"
 	"github.com/stolostron/managedcluster-import-controller/pkg/controller/clusterdeployment"
 	"github.com/stolostron/managedcluster-import-controller/pkg/controller/clusternamespacedeletion"
+	"github.com/stolostron/managedcluster-import-controller/pkg/controller/clusterunreachable"
 	"github.com/stolostron/managedcluster-import-controller/pkg/controller/csr"
 	"github.com/stolostron/managedcluster-import-controller/pkg/controller/hosted"
 	"github.com/stolostron/managedcluster-import-controller/pkg/controller/importconfig"
+	"github.com/stolostron/managedcluster-import-controller/pkg/controller/importmetrics"
 	"github.com/stolostron/managedcluster-import-controller/pkg/controller/importstatus"
 	"github.com/stolostron/managedcluster-import-controller/pkg/controller/managedcluster"
 	"github.com/stolostron/managedcluster-import-controller/pkg/controller/manifestwork"
@@ -44,6 +46,8 @@ var AddToManagerFuncs = []AddToManagerFunc{
 	clusterdeployment.Add,
 	clusternamespacedeletion.Add,
 	importstatus.Add,
+	clusterunreachable.Add,
+	importmetrics.Add,
 }
 
 // AddToManager adds all controllers to the manager
@@ -8,6 +8,7 @@ import (
 	"fmt"
 
 	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/stolostron/managedcluster-import-controller/pkg/bootstrap"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 
@@ -22,7 +23,7 @@ import (
 )
 
 const (
-	userNameSignature = "system:serviceaccount:%s:%s-bootstrap-sa"
+	userNameSignature = "system:serviceaccount:%s:%s"
 	clusterLabel      = "open-cluster-management.io/cluster-name"
 )
 
@@ -50,7 +51,8 @@ func getApprovalType(csr *certificatesv1.CertificateSigningRequest) string {
 }
 
 func validUsername(csr *certificatesv1.CertificateSigningRequest, clusterName string) bool {
-	return csr.Spec.Username == fmt.Sprintf(userNameSignature, clusterName, clusterName)
+	return csr.Spec.Username == fmt.Sprintf(userNameSignature,
+		bootstrap.GetBootstrapSANamespace(clusterName), bootstrap.GetBootstrapSAName(clusterName))
 }
 
 func csrPredicate(csr *certificatesv1.CertificateSigningRequest) bool {
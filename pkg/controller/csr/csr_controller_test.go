@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	"github.com/stolostron/managedcluster-import-controller/pkg/bootstrap"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 
@@ -40,7 +41,7 @@ func TestReconcileCSR_Reconcile(t *testing.T) {
 			},
 		},
 		Spec: certificatesv1.CertificateSigningRequestSpec{
-			Username: fmt.Sprintf(userNameSignature, clusterName, clusterName),
+			Username: fmt.Sprintf(userNameSignature, clusterName, bootstrap.GetBootstrapSAName(clusterName)),
 		},
 	}
 
@@ -158,7 +159,7 @@ func Test_getClusterName(t *testing.T) {
 			},
 		},
 		Spec: certificatesv1.CertificateSigningRequestSpec{
-			Username: fmt.Sprintf(userNameSignature, clusterName, clusterName),
+			Username: fmt.Sprintf(userNameSignature, clusterName, bootstrap.GetBootstrapSAName(clusterName)),
 		},
 	}
 
@@ -170,7 +171,7 @@ func Test_getClusterName(t *testing.T) {
 			},
 		},
 		Spec: certificatesv1.CertificateSigningRequestSpec{
-			Username: fmt.Sprintf(userNameSignature, clusterName, clusterName),
+			Username: fmt.Sprintf(userNameSignature, clusterName, bootstrap.GetBootstrapSAName(clusterName)),
 		},
 	}
 
@@ -179,7 +180,7 @@ func Test_getClusterName(t *testing.T) {
 			Name: csrNameReconcile,
 		},
 		Spec: certificatesv1.CertificateSigningRequestSpec{
-			Username: fmt.Sprintf(userNameSignature, clusterName, clusterName),
+			Username: fmt.Sprintf(userNameSignature, clusterName, bootstrap.GetBootstrapSAName(clusterName)),
 		},
 	}
 
@@ -231,7 +232,7 @@ func Test_getApproval(t *testing.T) {
 			},
 		},
 		Spec: certificatesv1.CertificateSigningRequestSpec{
-			Username: fmt.Sprintf(userNameSignature, clusterName, clusterName),
+			Username: fmt.Sprintf(userNameSignature, clusterName, bootstrap.GetBootstrapSAName(clusterName)),
 		},
 	}
 
@@ -243,7 +244,7 @@ func Test_getApproval(t *testing.T) {
 			},
 		},
 		Spec: certificatesv1.CertificateSigningRequestSpec{
-			Username: fmt.Sprintf(userNameSignature, clusterName, clusterName),
+			Username: fmt.Sprintf(userNameSignature, clusterName, bootstrap.GetBootstrapSAName(clusterName)),
 		},
 		Status: certificatesv1.CertificateSigningRequestStatus{
 			Conditions: []certificatesv1.CertificateSigningRequestCondition{
@@ -260,7 +261,7 @@ func Test_getApproval(t *testing.T) {
 			},
 		},
 		Spec: certificatesv1.CertificateSigningRequestSpec{
-			Username: fmt.Sprintf(userNameSignature, clusterName, clusterName),
+			Username: fmt.Sprintf(userNameSignature, clusterName, bootstrap.GetBootstrapSAName(clusterName)),
 		},
 		Status: certificatesv1.CertificateSigningRequestStatus{
 			Conditions: []certificatesv1.CertificateSigningRequestCondition{
@@ -317,7 +318,7 @@ func Test_validUsername(t *testing.T) {
 			},
 		},
 		Spec: certificatesv1.CertificateSigningRequestSpec{
-			Username: fmt.Sprintf(userNameSignature, clusterName, clusterName),
+			Username: fmt.Sprintf(userNameSignature, clusterName, bootstrap.GetBootstrapSAName(clusterName)),
 		},
 	}
 
@@ -377,7 +378,7 @@ func Test_csrPredicate(t *testing.T) {
 			},
 		},
 		Spec: certificatesv1.CertificateSigningRequestSpec{
-			Username: fmt.Sprintf(userNameSignature, clusterName, clusterName),
+			Username: fmt.Sprintf(userNameSignature, clusterName, bootstrap.GetBootstrapSAName(clusterName)),
 		},
 	}
 
@@ -389,7 +390,7 @@ func Test_csrPredicate(t *testing.T) {
 			},
 		},
 		Spec: certificatesv1.CertificateSigningRequestSpec{
-			Username: fmt.Sprintf(userNameSignature, clusterName, clusterName),
+			Username: fmt.Sprintf(userNameSignature, clusterName, bootstrap.GetBootstrapSAName(clusterName)),
 		},
 	}
 
@@ -7,20 +7,26 @@ import (
 	"context"
 	"embed"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/ghodss/yaml"
+	"github.com/go-logr/logr"
 	"github.com/openshift/library-go/pkg/operator/events"
 	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/clientcmd"
 	addonapiv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	operatorv1 "open-cluster-management.io/api/operator/v1"
@@ -64,9 +70,10 @@ var _ reconcile.Reconciler = &ReconcileHosted{}
 // Note: The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
 func (r *ReconcileHosted) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
-	reqLogger := log.WithValues("Request.Name", request.Name)
+	start := time.Now()
 
 	managedClusterName := request.Name
+	reqLogger := log.WithValues("Request.Name", request.Name, "clusterName", managedClusterName)
 	managedCluster := &clusterv1.ManagedCluster{}
 	err := r.clientHolder.RuntimeClient.Get(ctx, types.NamespacedName{Name: managedClusterName}, managedCluster)
 	if errors.IsNotFound(err) {
@@ -77,9 +84,11 @@ func (r *ReconcileHosted) Reconcile(ctx context.Context, request reconcile.Reque
 		return reconcile.Result{}, err
 	}
 
-	if helpers.DetermineKlusterletMode(managedCluster) != operatorv1.InstallModeHosted {
+	deployMode := helpers.DetermineKlusterletMode(managedCluster)
+	if deployMode != operatorv1.InstallModeHosted {
 		return reconcile.Result{}, nil
 	}
+	reqLogger = reqLogger.WithValues("deployMode", deployMode)
 
 	reqLogger.Info("Reconciling the manifest works of the hosted mode managed cluster")
 
@@ -94,7 +103,7 @@ func (r *ReconcileHosted) Reconcile(ctx context.Context, request reconcile.Reque
 		return reconcile.Result{}, err
 	}
 
-	result, condition, iErr := r.importCluster(ctx, managedCluster, autoImportSecret)
+	result, condition, iErr := r.importCluster(ctx, reqLogger, managedCluster, autoImportSecret)
 	if err := helpers.UpdateManagedClusterStatus(
 		r.clientHolder.RuntimeClient,
 		request.Name,
@@ -102,11 +111,27 @@ func (r *ReconcileHosted) Reconcile(ctx context.Context, request reconcile.Reque
 	); err != nil {
 		return reconcile.Result{}, err
 	}
+	helpers.ObserveImportResult(deployMode, managedCluster, &condition, start)
+
+	// summarize the availability of the hosted works, so users can tell which of the works is stuck
+	if hostingClusterName, err := helpers.GetHostingCluster(managedCluster); err == nil {
+		worksCondition, err := r.newHostedKlusterletWorksAvailableCondition(managedCluster.Name, hostingClusterName)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if err := helpers.UpdateManagedClusterStatus(
+			r.clientHolder.RuntimeClient,
+			request.Name,
+			worksCondition,
+		); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
 
 	// if the auto import secret exists and the cluster is imported successfully, delete the secret
 	if autoImportSecret != nil && condition.Status == metav1.ConditionTrue {
-		reqLogger.Info(fmt.Sprintf("External managed kubeconfig is created, try to delete its auto import secret %s/%s",
-			managedCluster.Name, constants.AutoImportSecretName))
+		reqLogger.Info("External managed kubeconfig is created, try to delete its auto import secret",
+			"secret", constants.AutoImportSecretName)
 		if err := helpers.DeleteAutoImportSecret(ctx,
 			r.clientHolder.KubeClient, autoImportSecret, r.recorder); err != nil {
 			return reconcile.Result{}, err
@@ -157,8 +182,8 @@ func (r *ReconcileHosted) cleanup(ctx context.Context,
 		r.deleteAddonsAndWorks(ctx, managedCluster, manifestWorks.Items, hostingKlusterletWorks.Items)
 }
 
-func (r *ReconcileHosted) importCluster(ctx context.Context, managedCluster *clusterv1.ManagedCluster,
-	autoImportSecret *v1.Secret) (reconcile.Result, metav1.Condition, error) {
+func (r *ReconcileHosted) importCluster(ctx context.Context, reqLogger logr.Logger,
+	managedCluster *clusterv1.ManagedCluster, autoImportSecret *v1.Secret) (reconcile.Result, metav1.Condition, error) {
 	hostedWorksSelector := labels.SelectorFromSet(map[string]string{constants.HostedClusterLabel: managedCluster.Name})
 
 	hostingClusterName, err := helpers.GetHostingCluster(managedCluster)
@@ -170,6 +195,8 @@ func (r *ReconcileHosted) importCluster(ctx context.Context, managedCluster *clu
 			nil
 	}
 
+	reqLogger = reqLogger.WithValues("hostingCluster", hostingClusterName)
+
 	hostingCluster := &clusterv1.ManagedCluster{}
 	err = r.clientHolder.RuntimeClient.Get(ctx, types.NamespacedName{Name: hostingClusterName}, hostingCluster)
 	if err != nil {
@@ -192,6 +219,24 @@ func (r *ReconcileHosted) importCluster(ctx context.Context, managedCluster *clu
 			nil
 	}
 
+	migrated, err := r.migrateHostedManifestWorks(ctx, reqLogger, managedCluster, hostingClusterName)
+	if err != nil {
+		return reconcile.Result{},
+			helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+				constants.ConditionReasonManagedClusterImporting,
+				fmt.Sprintf("Migrate hosted manifest works to the hosting cluster %s failed, error: %v",
+					hostingClusterName, err)),
+			err
+	}
+	if !migrated {
+		return reconcile.Result{RequeueAfter: 5 * time.Second},
+			helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+				constants.ConditionReasonManagedClusterImporting,
+				fmt.Sprintf("Wait for the hosted manifest works to be removed from the previous hosting cluster "+
+					"before importing on the hosting cluster %s", hostingClusterName)),
+			nil
+	}
+
 	hostedWorks, err := r.informerHolder.HostedWorkLister.ManifestWorks(hostingClusterName).List(hostedWorksSelector)
 	if err != nil {
 		return reconcile.Result{},
@@ -213,7 +258,7 @@ func (r *ReconcileHosted) importCluster(ctx context.Context, managedCluster *clu
 
 	// apply klusterlet manifest works klustelet to the management namespace from import secret
 	// to trigger the joining process.
-	importSecretName := fmt.Sprintf("%s-%s", managedCluster.Name, constants.ImportSecretNameSuffix)
+	importSecretName := helpers.GetImportSecretName(managedCluster.Name)
 	importSecret, err := r.informerHolder.ImportSecretLister.Secrets(managedCluster.Name).Get(importSecretName)
 	if errors.IsNotFound(err) {
 		// wait for the import secret to exist, do nothing
@@ -231,7 +276,8 @@ func (r *ReconcileHosted) importCluster(ctx context.Context, managedCluster *clu
 			err
 	}
 
-	if err := helpers.ValidateHostedImportSecret(importSecret); err != nil {
+	importYaml, err := helpers.GetImportYaml(importSecret)
+	if err != nil {
 		return reconcile.Result{},
 			helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
 				constants.ConditionReasonManagedClusterImportFailed,
@@ -239,7 +285,33 @@ func (r *ReconcileHosted) importCluster(ctx context.Context, managedCluster *clu
 			nil
 	}
 
-	manifestWork := createHostingManifestWork(managedCluster.Name, importSecret, hostingClusterName)
+	nodePlacement, err := helpers.GetKlusterletNodePlacementFromManagedClusterAnnotations(
+		managedCluster.GetAnnotations())
+	if err == nil && nodePlacement != nil {
+		err = utilerrors.NewAggregate([]error{
+			helpers.ValidateNodeSelector(nodePlacement.NodeSelector),
+			helpers.ValidateTolerations(nodePlacement.Tolerations),
+		})
+	}
+	if err != nil {
+		return reconcile.Result{},
+			helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+				constants.ConditionReasonInvalidNodePlacement,
+				fmt.Sprintf("The klusterlet node placement annotation %s is invalid, error: %v",
+					constants.KlusterletNodePlacementAnnotation, err)),
+			nil
+	}
+
+	manifestWork, err := createHostingManifestWork(managedCluster.Name, importYaml, hostingClusterName, nodePlacement)
+	if err != nil {
+		return reconcile.Result{},
+			helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+				constants.ConditionReasonManagedClusterImportFailed,
+				fmt.Sprintf("Import secret is invalid, error: %v", err)),
+			nil
+	}
+	reqLogger.V(5).Info("Apply the hosted klusterlet manifest work", "secret", importSecretName,
+		"manifestWork", manifestWork.Name)
 	_, err = helpers.ApplyResources(r.clientHolder, r.recorder, r.scheme, managedCluster, manifestWork)
 	if err != nil {
 		return reconcile.Result{},
@@ -268,8 +340,28 @@ func (r *ReconcileHosted) importCluster(ctx context.Context, managedCluster *clu
 
 	// if the auto import secret exists; create it on the hosting cluster by manifestwork
 	if autoImportSecret != nil {
+		konnectivityEndpoint, err := helpers.GetHostedKonnectivityEndpointFromManagedClusterAnnotations(
+			managedCluster.GetAnnotations())
+		if err != nil {
+			return reconcile.Result{},
+				helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+					constants.ConditionReasonManagedClusterImportFailed,
+					fmt.Sprintf("The konnectivity endpoint is invalid, error: %v", err)),
+				nil
+		}
+
+		if err := validateExternalManagedKubeconfig(autoImportSecret); err != nil {
+			// do not create a manifest work with a broken kubeconfig, the import is retried once the auto
+			// import secret is updated
+			return reconcile.Result{},
+				helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+					constants.ConditionReasonExternalManagedKubeconfigInvalid,
+					fmt.Sprintf("The external managed kubeconfig is invalid, error: %v", err)),
+				nil
+		}
+
 		manifestWork, err = createManagedKubeconfigManifestWork(
-			managedCluster.Name, autoImportSecret, hostingClusterName)
+			managedCluster.Name, autoImportSecret, hostingClusterName, konnectivityEndpoint)
 		if err != nil {
 			return reconcile.Result{},
 				helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
@@ -278,8 +370,9 @@ func (r *ReconcileHosted) importCluster(ctx context.Context, managedCluster *clu
 				err
 		}
 
-		_, err = helpers.ApplyResources(r.clientHolder, r.recorder, r.scheme, managedCluster, manifestWork)
-		if err != nil {
+		reqLogger.V(5).Info("Apply the managed kubeconfig manifest work", "secret", autoImportSecret.Name,
+			"manifestWork", manifestWork.Name)
+		if err := r.applyManagedKubeconfigManifestWork(ctx, managedCluster, manifestWork); err != nil {
 			return reconcile.Result{},
 				helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
 					constants.ConditionReasonManagedClusterImporting,
@@ -311,6 +404,204 @@ func (r *ReconcileHosted) importCluster(ctx context.Context, managedCluster *clu
 		nil
 }
 
+// applyManagedKubeconfigManifestWork creates the managed kubeconfig manifest work, or patches the manifests of the
+// existing work when the external managed kubeconfig is rotated. The existing work is patched in place instead of
+// being recreated, so there is no gap for the klusterlet when the kubeconfig is rotated.
+func (r *ReconcileHosted) applyManagedKubeconfigManifestWork(ctx context.Context,
+	managedCluster *clusterv1.ManagedCluster, required *workv1.ManifestWork) error {
+	existing, err := r.clientHolder.WorkClient.WorkV1().ManifestWorks(required.Namespace).Get(
+		ctx, required.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = helpers.ApplyResources(r.clientHolder, r.recorder, r.scheme, managedCluster, required)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if helpers.ManifestsEqual(existing.Spec.Workload.Manifests, required.Spec.Workload.Manifests) {
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"workload": map[string]interface{}{
+				"manifests": required.Spec.Workload.Manifests,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := r.clientHolder.WorkClient.WorkV1().ManifestWorks(required.Namespace).Patch(
+		ctx, required.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return err
+	}
+
+	r.recorder.Eventf("ManifestWorkPatched", "The external managed kubeconfig of the manifestwork %s/%s is updated",
+		required.Namespace, required.Name)
+	return nil
+}
+
+// newHostedKlusterletWorksAvailableCondition checks whether the hosted klusterlet manifest work and the managed
+// kubeconfig manifest work are applied and available on the hosting cluster, the reason of the condition names the
+// work that is not available.
+func (r *ReconcileHosted) newHostedKlusterletWorksAvailableCondition(
+	managedClusterName, hostingClusterName string) (metav1.Condition, error) {
+	works := []struct {
+		name   string
+		reason string
+	}{
+		{
+			name:   hostedKlusterletManifestWorkName(managedClusterName),
+			reason: constants.ConditionReasonHostedKlusterletWorkNotAvailable,
+		},
+		{
+			name:   hostedManagedKubeconfigManifestWorkName(managedClusterName),
+			reason: constants.ConditionReasonHostedKubeconfigWorkNotAvailable,
+		},
+	}
+
+	for _, w := range works {
+		message := ""
+		work, err := r.informerHolder.HostedWorkLister.ManifestWorks(hostingClusterName).Get(w.name)
+		switch {
+		case errors.IsNotFound(err):
+			message = fmt.Sprintf("The manifestwork %s is not created on the hosting cluster %s",
+				w.name, hostingClusterName)
+		case err != nil:
+			return metav1.Condition{}, err
+		case !meta.IsStatusConditionTrue(work.Status.Conditions, workv1.WorkApplied):
+			message = fmt.Sprintf("The manifestwork %s is not applied on the hosting cluster %s",
+				w.name, hostingClusterName)
+		case !meta.IsStatusConditionTrue(work.Status.Conditions, workv1.WorkAvailable):
+			message = fmt.Sprintf("The manifestwork %s is not available on the hosting cluster %s",
+				w.name, hostingClusterName)
+		default:
+			continue
+		}
+
+		return metav1.Condition{
+			Type:    constants.ConditionHostedKlusterletWorksAvailable,
+			Status:  metav1.ConditionFalse,
+			Reason:  w.reason,
+			Message: message,
+		}, nil
+	}
+
+	return metav1.Condition{
+		Type:    constants.ConditionHostedKlusterletWorksAvailable,
+		Status:  metav1.ConditionTrue,
+		Reason:  constants.ConditionReasonHostedKlusterletWorksAvailable,
+		Message: "The hosted klusterlet and managed kubeconfig manifestworks are available",
+	}, nil
+}
+
+// migrateHostedManifestWorks removes the hosted manifest works of the managed cluster from the previous hosting
+// clusters after the hosting cluster of the managed cluster is changed. The managed kubeconfig manifest work is
+// copied to the current hosting cluster first, because the auto import secret may have been deleted after the
+// managed cluster was imported. The klusterlet manifest work is created on the current hosting cluster only after
+// the works on the previous hosting clusters are gone, otherwise two klusterlets will manage the same cluster.
+func (r *ReconcileHosted) migrateHostedManifestWorks(ctx context.Context, reqLogger logr.Logger,
+	managedCluster *clusterv1.ManagedCluster, hostingClusterName string) (bool, error) {
+	hostedWorksSelector := labels.SelectorFromSet(map[string]string{constants.HostedClusterLabel: managedCluster.Name})
+	hostedWorks, err := r.informerHolder.HostedWorkLister.List(hostedWorksSelector)
+	if err != nil {
+		return false, err
+	}
+
+	staleWorks := map[string][]workv1.ManifestWork{}
+	for _, hostedWork := range hostedWorks {
+		if hostedWork.Namespace == hostingClusterName {
+			continue
+		}
+		staleWorks[hostedWork.Namespace] = append(staleWorks[hostedWork.Namespace], *hostedWork)
+	}
+
+	if len(staleWorks) == 0 {
+		return true, nil
+	}
+
+	kubeconfigWorkName := hostedManagedKubeconfigManifestWorkName(managedCluster.Name)
+	_, err = r.informerHolder.HostedWorkLister.ManifestWorks(hostingClusterName).Get(kubeconfigWorkName)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	kubeconfigWorkCopied := err == nil
+
+	for previousHostingClusterName, works := range staleWorks {
+		for _, work := range works {
+			if work.Name != kubeconfigWorkName || kubeconfigWorkCopied {
+				continue
+			}
+
+			kubeconfigWork := &workv1.ManifestWork{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      kubeconfigWorkName,
+					Namespace: hostingClusterName,
+					Labels: map[string]string{
+						constants.HostedClusterLabel: managedCluster.Name,
+					},
+				},
+				Spec: *work.Spec.DeepCopy(),
+			}
+			if _, err := helpers.ApplyResources(
+				r.clientHolder, r.recorder, r.scheme, managedCluster, kubeconfigWork); err != nil {
+				return false, err
+			}
+			kubeconfigWorkCopied = true
+		}
+
+		reqLogger.Info("The hosting cluster is changed, remove the hosted manifest works from the previous hosting cluster",
+			"previousHostingCluster", previousHostingClusterName)
+		if err := r.deleteHostingManifestWorks(ctx, managedCluster.Name, works); err != nil {
+			return false, err
+		}
+	}
+
+	return false, nil
+}
+
+// validateExternalManagedKubeconfig validates the external managed kubeconfig offline, the current context of the
+// kubeconfig must refer to a cluster that has a server and to a user that has credentials. The managed cluster is
+// not accessed, it may be only reachable from the hosting cluster, e.g. through the konnectivity proxy.
+func validateExternalManagedKubeconfig(secret *v1.Secret) error {
+	kubeconfig := secret.Data["kubeconfig"]
+	if len(kubeconfig) == 0 {
+		return fmt.Errorf("the field kubeconfig must exist in the secret for hosted mode")
+	}
+
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("failed to load the kubeconfig: %v", err)
+	}
+
+	kubeContext, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return fmt.Errorf("the current context %q is not found in the kubeconfig", config.CurrentContext)
+	}
+
+	cluster, ok := config.Clusters[kubeContext.Cluster]
+	if !ok || len(cluster.Server) == 0 {
+		return fmt.Errorf("the server of the cluster %q is not found in the kubeconfig", kubeContext.Cluster)
+	}
+
+	authInfo, ok := config.AuthInfos[kubeContext.AuthInfo]
+	if !ok {
+		return fmt.Errorf("the user %q is not found in the kubeconfig", kubeContext.AuthInfo)
+	}
+	hasToken := len(authInfo.Token) != 0 || len(authInfo.TokenFile) != 0
+	hasCert := (len(authInfo.ClientCertificateData) != 0 || len(authInfo.ClientCertificate) != 0) &&
+		(len(authInfo.ClientKeyData) != 0 || len(authInfo.ClientKey) != 0)
+	hasBasicAuth := len(authInfo.Username) != 0 && len(authInfo.Password) != 0
+	hasPlugin := authInfo.Exec != nil || authInfo.AuthProvider != nil
+	if !hasToken && !hasCert && !hasBasicAuth && !hasPlugin {
+		return fmt.Errorf("the credentials of the user %q are not found in the kubeconfig", kubeContext.AuthInfo)
+	}
+
+	return nil
+}
+
 func (r *ReconcileHosted) externalManagedKubeconfigCreated(
 	ctx context.Context, managedClusterName, hostingClusterName string) (bool, error) {
 	name := hostedKlusterletManifestWorkName(managedClusterName)
@@ -359,7 +650,8 @@ func (r *ReconcileHosted) deleteAddonsAndWorks(
 //  1. delete the manifest work with the postpone-delete annotation until 10 min
 //     after the cluster is deleted.
 //  2. delete the manifest works that do not include klusterlet addon works
-//  3. delete the klusterlet and managed kubeconfig manifest works
+//  3. delete the klusterlet manifest work, then delete the managed kubeconfig manifest work
+//     after the klusterlet manifest work is gone
 func (r *ReconcileHosted) deleteManifestWorks(ctx context.Context, cluster *clusterv1.ManagedCluster,
 	works, hostingWorks []workv1.ManifestWork) error {
 	err := r.deleteManagedClusterManifestWorks(ctx, cluster, works)
@@ -386,15 +678,45 @@ func (r *ReconcileHosted) deleteManifestWorks(ctx context.Context, cluster *clus
 		return nil
 	}
 
-	// delete hosting manifestWorks from the hosting cluster namespace
-	for _, hostedWork := range hostingWorks {
+	return r.deleteHostingManifestWorks(ctx, cluster.GetName(), hostingWorks)
+}
+
+// deleteHostingManifestWorks deletes the manifest works of the managed cluster from the hosting cluster namespace.
+// The managed kubeconfig manifest work is deleted after the klusterlet manifest work is gone, because the hosted
+// klusterlet requires the managed kubeconfig to clean up its resources on the managed cluster.
+func (r *ReconcileHosted) deleteHostingManifestWorks(ctx context.Context, clusterName string,
+	hostingWorks []workv1.ManifestWork) error {
+	klusterletWorkName := hostedKlusterletManifestWorkName(clusterName)
+	kubeconfigWorkName := hostedManagedKubeconfigManifestWorkName(clusterName)
+
+	klusterletWorkExists := false
+	var kubeconfigWork *workv1.ManifestWork
+	for i, hostingWork := range hostingWorks {
+		switch hostingWork.Name {
+		case kubeconfigWorkName:
+			kubeconfigWork = &hostingWorks[i]
+			continue
+		case klusterletWorkName:
+			klusterletWorkExists = true
+		}
+
 		if err := helpers.DeleteManifestWork(ctx, r.clientHolder.WorkClient, r.recorder,
-			hostedWork.Namespace, hostedWork.Name); err != nil {
+			hostingWork.Namespace, hostingWork.Name); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	if klusterletWorkExists {
+		// wait for the klusterlet manifest work to be deleted
+		return nil
+	}
+
+	if kubeconfigWork == nil {
+		return nil
+	}
+
+	return helpers.DeleteManifestWork(ctx, r.clientHolder.WorkClient, r.recorder,
+		kubeconfigWork.Namespace, kubeconfigWork.Name)
 }
 
 func (r *ReconcileHosted) deleteManagedClusterManifestWorks(ctx context.Context, cluster *clusterv1.ManagedCluster,
@@ -431,17 +753,22 @@ func (r *ReconcileHosted) deleteManagedClusterManifestWorks(ctx context.Context,
 		r.clientHolder.WorkClient, r.recorder, cluster, works, ignoreAddons)
 }
 
-// createHostingManifestWork creates the manifestwork from import secret for hosted mode cluster
-// into the hosting cluster
-func createHostingManifestWork(managedClusterName string,
-	importSecret *corev1.Secret, manifestWorkNamespace string) *workv1.ManifestWork {
+// createHostingManifestWork creates the manifestwork from the import.yaml of the import secret for hosted mode
+// cluster into the hosting cluster, the node placement is injected into the klusterlet if it is specified
+func createHostingManifestWork(managedClusterName string, importYaml []byte, manifestWorkNamespace string,
+	nodePlacement *operatorv1.NodePlacement) (*workv1.ManifestWork, error) {
 	manifests := []workv1.Manifest{}
-	importYaml := importSecret.Data[constants.ImportSecretImportYamlKey]
 	for _, yamlData := range helpers.SplitYamls(importYaml) {
 		jsonData, err := yaml.YAMLToJSON(yamlData)
 		if err != nil {
 			panic(err)
 		}
+		if nodePlacement != nil {
+			jsonData, err = setKlusterletNodePlacement(jsonData, nodePlacement)
+			if err != nil {
+				return nil, err
+			}
+		}
 		manifests = append(manifests, workv1.Manifest{
 			RawExtension: runtime.RawExtension{Raw: jsonData},
 		})
@@ -503,7 +830,42 @@ func createHostingManifestWork(managedClusterName string,
 				},
 			},
 		},
+	}, nil
+}
+
+// setKlusterletNodePlacement overrides the node selector and the tolerations of the klusterlet with the specified
+// ones, the other manifests are returned as they are
+func setKlusterletNodePlacement(jsonData []byte, nodePlacement *operatorv1.NodePlacement) ([]byte, error) {
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(jsonData); err != nil {
+		return nil, err
+	}
+	if obj.GroupVersionKind() != operatorv1.SchemeGroupVersion.WithKind("Klusterlet") {
+		return jsonData, nil
 	}
+
+	if len(nodePlacement.NodeSelector) != 0 {
+		if err := unstructured.SetNestedStringMap(obj.Object, nodePlacement.NodeSelector,
+			"spec", "nodePlacement", "nodeSelector"); err != nil {
+			return nil, err
+		}
+	}
+	if len(nodePlacement.Tolerations) != 0 {
+		tolerations := []interface{}{}
+		for i := range nodePlacement.Tolerations {
+			toleration, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&nodePlacement.Tolerations[i])
+			if err != nil {
+				return nil, err
+			}
+			tolerations = append(tolerations, toleration)
+		}
+		if err := unstructured.SetNestedSlice(obj.Object, tolerations,
+			"spec", "nodePlacement", "tolerations"); err != nil {
+			return nil, err
+		}
+	}
+
+	return obj.MarshalJSON()
 }
 
 func hostedKlusterletCRName(managedClusterName string) string {
@@ -511,12 +873,26 @@ func hostedKlusterletCRName(managedClusterName string) string {
 }
 
 func createManagedKubeconfigManifestWork(managedClusterName string, importSecret *corev1.Secret,
-	manifestWorkNamespace string) (*workv1.ManifestWork, error) {
+	manifestWorkNamespace, konnectivityEndpoint string) (*workv1.ManifestWork, error) {
 	kubeconfig := importSecret.Data["kubeconfig"]
 	if len(kubeconfig) == 0 {
 		return nil, fmt.Errorf("import secret invalid, the field kubeconfig must exist in the secret for hosted mode")
 	}
 
+	if len(konnectivityEndpoint) != 0 {
+		// route the requests of the klusterlet to the managed cluster kube-apiserver through the konnectivity proxy
+		config, err := clientcmd.Load(kubeconfig)
+		if err != nil {
+			return nil, fmt.Errorf("import secret invalid, failed to load the kubeconfig: %v", err)
+		}
+		for _, cluster := range config.Clusters {
+			cluster.ProxyURL = konnectivityEndpoint
+		}
+		if kubeconfig, err = clientcmd.Write(*config); err != nil {
+			return nil, err
+		}
+	}
+
 	config := struct {
 		KlusterletNamespace       string
 		ExternalManagedKubeconfig string
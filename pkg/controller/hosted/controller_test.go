@@ -5,12 +5,15 @@ package hosted
 
 import (
 	"context"
+	"encoding/json"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -18,6 +21,8 @@ import (
 	"k8s.io/client-go/informers"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"open-cluster-management.io/api/addon/v1alpha1"
 	addonapiv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
 	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
@@ -691,6 +696,65 @@ metadata:
 				}
 			},
 		},
+		// managedcluster is Hosted mode, but the klusterlet node placement annotation is invalid
+		{
+			name: "managedcluster is Hosted mode, but the klusterlet node placement is invalid",
+			runtimeObjs: []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							constants.KlusterletDeployModeAnnotation:    string(operatorv1.InstallModeHosted),
+							constants.HostingClusterNameAnnotation:      "cluster1",
+							constants.KlusterletNodePlacementAnnotation: `{"nodeSelector":["infra"]}`,
+						},
+					},
+				},
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "cluster1",
+					},
+				},
+			},
+			kubeObjs: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-import",
+						Namespace: "test",
+					},
+					Data: map[string][]byte{
+						constants.ImportSecretImportYamlKey: []byte(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: foo1`),
+					},
+				},
+			},
+			workObjs: []runtime.Object{},
+			request:  reconcile.Request{NamespacedName: types.NamespacedName{Name: "test"}}, // managedcluster name
+			vaildateFunc: func(t *testing.T, reconcileResult reconcile.Result, reconcileErr error, ch *helpers.ClientHolder) {
+				if reconcileErr != nil {
+					t.Errorf("unexpected error: %v", reconcileErr)
+				}
+				managedCluster := &clusterv1.ManagedCluster{}
+				err := ch.RuntimeClient.Get(context.TODO(), types.NamespacedName{Name: "test"}, managedCluster)
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+
+				condition := meta.FindStatusCondition(
+					managedCluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+				if condition.Reason != constants.ConditionReasonInvalidNodePlacement {
+					t.Errorf("unexpected condition reason: %v", condition.Reason)
+				}
+
+				_, err = ch.WorkClient.WorkV1().ManifestWorks("cluster1").Get(
+					context.TODO(), "test-hosted-klusterlet", metav1.GetOptions{})
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected the hosted klusterlet work is not created, but got %v", err)
+				}
+			},
+		},
 		// managedcluster is Hosted mode, klusterlet available
 		{
 			name: "managedcluster is Hosted mode, klusterlet available",
@@ -872,6 +936,161 @@ metadata:
 				}
 			},
 		},
+		{
+			name: "the hosting cluster is changed",
+			runtimeObjs: []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							constants.KlusterletDeployModeAnnotation: string(operatorv1.InstallModeHosted),
+							constants.HostingClusterNameAnnotation:   "cluster2",
+						},
+					},
+				},
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "cluster2",
+					},
+				},
+			},
+			kubeObjs: []runtime.Object{
+				testinghelpers.GetHostedImportSecret("test"),
+			},
+			workObjs: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "cluster1",
+						Name:      "test-hosted-klusterlet",
+						Labels: map[string]string{
+							constants.HostedClusterLabel: "test",
+						},
+					},
+				},
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "cluster1",
+						Name:      "test-hosted-kubeconfig",
+						Labels: map[string]string{
+							constants.HostedClusterLabel: "test",
+						},
+					},
+					Spec: workv1.ManifestWorkSpec{
+						Workload: workv1.ManifestsTemplate{
+							Manifests: []workv1.Manifest{
+								{RawExtension: runtime.RawExtension{Raw: []byte(`{"kind":"Secret"}`)}},
+							},
+						},
+					},
+				},
+			},
+			request: reconcile.Request{NamespacedName: types.NamespacedName{Name: "test"}}, // managedcluster name
+			vaildateFunc: func(t *testing.T, reconcileResult reconcile.Result, reconcileErr error, ch *helpers.ClientHolder) {
+				if reconcileErr != nil {
+					t.Errorf("unexpected error: %v", reconcileErr)
+				}
+				if reconcileResult.RequeueAfter == 0 {
+					t.Errorf("expected to requeue")
+				}
+
+				// the klusterlet work is removed from the previous hosting cluster
+				_, err := ch.WorkClient.WorkV1().ManifestWorks("cluster1").Get(
+					context.TODO(), "test-hosted-klusterlet", metav1.GetOptions{})
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected the klusterlet work is deleted from cluster1, but got %v", err)
+				}
+				// the managed kubeconfig work is kept until the klusterlet work is gone
+				_, err = ch.WorkClient.WorkV1().ManifestWorks("cluster1").Get(
+					context.TODO(), "test-hosted-kubeconfig", metav1.GetOptions{})
+				if err != nil {
+					t.Errorf("expected the managed kubeconfig work is kept on cluster1, but got %v", err)
+				}
+				// the managed kubeconfig work is copied to the current hosting cluster
+				kubeconfigWork, err := ch.WorkClient.WorkV1().ManifestWorks("cluster2").Get(
+					context.TODO(), "test-hosted-kubeconfig", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("expected the managed kubeconfig work is created on cluster2, but got %v", err)
+				}
+				if len(kubeconfigWork.Spec.Workload.Manifests) != 1 {
+					t.Errorf("unexpected manifests of the managed kubeconfig work: %v", kubeconfigWork.Spec.Workload.Manifests)
+				}
+				// the klusterlet work is not created on the current hosting cluster until the migration is done
+				_, err = ch.WorkClient.WorkV1().ManifestWorks("cluster2").Get(
+					context.TODO(), "test-hosted-klusterlet", metav1.GetOptions{})
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected the klusterlet work is not created on cluster2, but got %v", err)
+				}
+
+				managedCluster := &clusterv1.ManagedCluster{}
+				if err := ch.RuntimeClient.Get(context.TODO(), types.NamespacedName{Name: "test"}, managedCluster); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				condition := meta.FindStatusCondition(
+					managedCluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+				if !strings.Contains(condition.Message, "Wait for the hosted manifest works to be removed") {
+					t.Errorf("unexpected condition message: %v", condition.Message)
+				}
+			},
+		},
+		{
+			name: "the klusterlet work is removed from the previous hosting cluster",
+			runtimeObjs: []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							constants.KlusterletDeployModeAnnotation: string(operatorv1.InstallModeHosted),
+							constants.HostingClusterNameAnnotation:   "cluster2",
+						},
+					},
+				},
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "cluster2",
+					},
+				},
+			},
+			kubeObjs: []runtime.Object{
+				testinghelpers.GetHostedImportSecret("test"),
+			},
+			workObjs: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "cluster1",
+						Name:      "test-hosted-kubeconfig",
+						Labels: map[string]string{
+							constants.HostedClusterLabel: "test",
+						},
+					},
+				},
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "cluster2",
+						Name:      "test-hosted-kubeconfig",
+						Labels: map[string]string{
+							constants.HostedClusterLabel: "test",
+						},
+					},
+				},
+			},
+			request: reconcile.Request{NamespacedName: types.NamespacedName{Name: "test"}}, // managedcluster name
+			vaildateFunc: func(t *testing.T, reconcileResult reconcile.Result, reconcileErr error, ch *helpers.ClientHolder) {
+				if reconcileErr != nil {
+					t.Errorf("unexpected error: %v", reconcileErr)
+				}
+
+				_, err := ch.WorkClient.WorkV1().ManifestWorks("cluster1").Get(
+					context.TODO(), "test-hosted-kubeconfig", metav1.GetOptions{})
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected the managed kubeconfig work is deleted from cluster1, but got %v", err)
+				}
+				_, err = ch.WorkClient.WorkV1().ManifestWorks("cluster2").Get(
+					context.TODO(), "test-hosted-kubeconfig", metav1.GetOptions{})
+				if err != nil {
+					t.Errorf("expected the managed kubeconfig work is kept on cluster2, but got %v", err)
+				}
+			},
+		},
 		// TODO: add auto import secret test cases
 	}
 
@@ -911,3 +1130,508 @@ metadata:
 		})
 	}
 }
+
+func TestCreateManagedKubeconfigManifestWorkWithKonnectivity(t *testing.T) {
+	kubeconfig, err := clientcmd.Write(clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"test": {Server: "https://kube-apiserver.test.svc:6443"},
+		},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			"test": {Token: "test"},
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			"test": {Cluster: "test", AuthInfo: "test"},
+		},
+		CurrentContext: "test",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	autoImportSecret := &corev1.Secret{
+		Data: map[string][]byte{
+			"kubeconfig": kubeconfig,
+		},
+	}
+
+	mw, err := createManagedKubeconfigManifestWork("test", autoImportSecret, "cluster1",
+		"http://konnectivity.test.svc:8090")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	secret := &corev1.Secret{}
+	if err := json.Unmarshal(mw.Spec.Workload.Manifests[0].Raw, secret); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config, err := clientcmd.Load(secret.Data["kubeconfig"])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Clusters["test"].Server != "https://kube-apiserver.test.svc:6443" {
+		t.Errorf("expected the server is kept, but got %s", config.Clusters["test"].Server)
+	}
+	if config.Clusters["test"].ProxyURL != "http://konnectivity.test.svc:8090" {
+		t.Errorf("expected the konnectivity proxy url, but got %q", config.Clusters["test"].ProxyURL)
+	}
+}
+
+func TestCreateHostingManifestWorkWithNodePlacement(t *testing.T) {
+	importYaml := []byte(`apiVersion: v1
+kind: Secret
+metadata:
+  name: bootstrap-hub-kubeconfig
+  namespace: klusterlet-test
+data:
+  kubeconfig: dGVzdA==
+---
+apiVersion: operator.open-cluster-management.io/v1
+kind: Klusterlet
+metadata:
+  name: klusterlet-test
+spec:
+  deployOption:
+    mode: Hosted
+  clusterName: test
+  nodePlacement:
+    nodeSelector:
+      kubernetes.io/os: linux
+    tolerations:
+    - key: node-role.kubernetes.io/infra
+      operator: Exists
+      effect: NoSchedule
+`)
+
+	cases := []struct {
+		name                 string
+		nodePlacement        *operatorv1.NodePlacement
+		expectedNodeSelector map[string]string
+		expectedTolerations  []corev1.Toleration
+	}{
+		{
+			name:                 "no node placement",
+			expectedNodeSelector: map[string]string{"kubernetes.io/os": "linux"},
+			expectedTolerations: []corev1.Toleration{
+				{
+					Key:      "node-role.kubernetes.io/infra",
+					Operator: corev1.TolerationOpExists,
+					Effect:   corev1.TaintEffectNoSchedule,
+				},
+			},
+		},
+		{
+			name: "node placement",
+			nodePlacement: &operatorv1.NodePlacement{
+				NodeSelector: map[string]string{"node-role.kubernetes.io/infra": ""},
+				Tolerations: []corev1.Toleration{
+					{
+						Key:      "infra",
+						Operator: corev1.TolerationOpEqual,
+						Value:    "reserved",
+						Effect:   corev1.TaintEffectNoExecute,
+					},
+				},
+			},
+			expectedNodeSelector: map[string]string{"node-role.kubernetes.io/infra": ""},
+			expectedTolerations: []corev1.Toleration{
+				{
+					Key:      "infra",
+					Operator: corev1.TolerationOpEqual,
+					Value:    "reserved",
+					Effect:   corev1.TaintEffectNoExecute,
+				},
+			},
+		},
+		{
+			name: "only node selector",
+			nodePlacement: &operatorv1.NodePlacement{
+				NodeSelector: map[string]string{"node-role.kubernetes.io/infra": ""},
+			},
+			expectedNodeSelector: map[string]string{"node-role.kubernetes.io/infra": ""},
+			expectedTolerations: []corev1.Toleration{
+				{
+					Key:      "node-role.kubernetes.io/infra",
+					Operator: corev1.TolerationOpExists,
+					Effect:   corev1.TaintEffectNoSchedule,
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mw, err := createHostingManifestWork("test", importYaml, "cluster1", c.nodePlacement)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(mw.Spec.Workload.Manifests) != 2 {
+				t.Fatalf("expected 2 manifests, but got %d", len(mw.Spec.Workload.Manifests))
+			}
+
+			secret := &corev1.Secret{}
+			if err := json.Unmarshal(mw.Spec.Workload.Manifests[0].Raw, secret); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(secret.Data["kubeconfig"]) != "test" {
+				t.Errorf("expected the bootstrap secret is kept, but got %v", secret)
+			}
+
+			klusterlet := &operatorv1.Klusterlet{}
+			if err := json.Unmarshal(mw.Spec.Workload.Manifests[1].Raw, klusterlet); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !equality.Semantic.DeepEqual(klusterlet.Spec.NodePlacement.NodeSelector, c.expectedNodeSelector) {
+				t.Errorf("expected node selector %v, but got %v",
+					c.expectedNodeSelector, klusterlet.Spec.NodePlacement.NodeSelector)
+			}
+			if !equality.Semantic.DeepEqual(klusterlet.Spec.NodePlacement.Tolerations, c.expectedTolerations) {
+				t.Errorf("expected tolerations %v, but got %v",
+					c.expectedTolerations, klusterlet.Spec.NodePlacement.Tolerations)
+			}
+			if klusterlet.Spec.ClusterName != "test" {
+				t.Errorf("expected the klusterlet cluster name is kept, but got %s", klusterlet.Spec.ClusterName)
+			}
+		})
+	}
+}
+
+func TestDeleteHostingManifestWorks(t *testing.T) {
+	newHostingWork := func(name string) *workv1.ManifestWork {
+		return &workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "cluster1",
+				Name:      name,
+				Labels: map[string]string{
+					constants.HostedClusterLabel: "test",
+				},
+			},
+		}
+	}
+
+	cases := []struct {
+		name              string
+		works             []runtime.Object
+		expectedRemaining []string
+	}{
+		{
+			name: "both klusterlet and managed kubeconfig works exist",
+			works: []runtime.Object{
+				newHostingWork("test-hosted-klusterlet"),
+				newHostingWork("test-hosted-kubeconfig"),
+			},
+			expectedRemaining: []string{"test-hosted-kubeconfig"},
+		},
+		{
+			name:              "the klusterlet work is gone",
+			works:             []runtime.Object{newHostingWork("test-hosted-kubeconfig")},
+			expectedRemaining: []string{},
+		},
+		{
+			name:              "the managed kubeconfig work is gone",
+			works:             []runtime.Object{newHostingWork("test-hosted-klusterlet")},
+			expectedRemaining: []string{},
+		},
+		{
+			name:              "no works",
+			works:             []runtime.Object{},
+			expectedRemaining: []string{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			workClient := workfake.NewSimpleClientset(c.works...)
+			r := &ReconcileHosted{
+				clientHolder: &helpers.ClientHolder{
+					WorkClient: workClient,
+				},
+				recorder: eventstesting.NewTestingEventRecorder(t),
+			}
+
+			hostingWorks := []workv1.ManifestWork{}
+			for _, work := range c.works {
+				hostingWorks = append(hostingWorks, *work.(*workv1.ManifestWork))
+			}
+
+			if err := r.deleteHostingManifestWorks(context.TODO(), "test", hostingWorks); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			works, err := workClient.WorkV1().ManifestWorks("cluster1").List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			remaining := []string{}
+			for _, work := range works.Items {
+				remaining = append(remaining, work.Name)
+			}
+			if !equality.Semantic.DeepEqual(remaining, c.expectedRemaining) {
+				t.Errorf("expected remaining works %v, but got %v", c.expectedRemaining, remaining)
+			}
+		})
+	}
+}
+
+func TestValidateExternalManagedKubeconfig(t *testing.T) {
+	newSecret := func(data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      constants.AutoImportSecretName,
+				Namespace: "test",
+			},
+			Data: data,
+		}
+	}
+	newKubeconfig := func(server string, authInfo *clientcmdapi.AuthInfo) []byte {
+		kubeconfig, err := clientcmd.Write(clientcmdapi.Config{
+			Clusters: map[string]*clientcmdapi.Cluster{
+				"test": {Server: server},
+			},
+			AuthInfos: map[string]*clientcmdapi.AuthInfo{
+				"test": authInfo,
+			},
+			Contexts: map[string]*clientcmdapi.Context{
+				"test": {Cluster: "test", AuthInfo: "test"},
+			},
+			CurrentContext: "test",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return kubeconfig
+	}
+
+	cases := []struct {
+		name        string
+		secret      *corev1.Secret
+		expectedErr string
+	}{
+		{
+			name:        "no kubeconfig",
+			secret:      newSecret(map[string][]byte{"token": []byte("test"), "server": []byte("https://test")}),
+			expectedErr: "the field kubeconfig must exist in the secret for hosted mode",
+		},
+		{
+			name:        "malformed kubeconfig",
+			secret:      newSecret(map[string][]byte{"kubeconfig": []byte("invalid")}),
+			expectedErr: "failed to load the kubeconfig",
+		},
+		{
+			name: "no current context",
+			secret: newSecret(map[string][]byte{"kubeconfig": []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://test
+`)}),
+			expectedErr: "the current context \"\" is not found in the kubeconfig",
+		},
+		{
+			name: "no server",
+			secret: newSecret(map[string][]byte{
+				"kubeconfig": newKubeconfig("", &clientcmdapi.AuthInfo{Token: "test"}),
+			}),
+			expectedErr: "the server of the cluster \"test\" is not found in the kubeconfig",
+		},
+		{
+			name: "no credentials",
+			secret: newSecret(map[string][]byte{
+				"kubeconfig": newKubeconfig("https://test", &clientcmdapi.AuthInfo{}),
+			}),
+			expectedErr: "the credentials of the user \"test\" are not found in the kubeconfig",
+		},
+		{
+			name: "valid kubeconfig with token",
+			secret: newSecret(map[string][]byte{
+				"kubeconfig": newKubeconfig("https://test", &clientcmdapi.AuthInfo{Token: "test"}),
+			}),
+		},
+		{
+			name: "valid kubeconfig with client certificate",
+			secret: newSecret(map[string][]byte{
+				"kubeconfig": newKubeconfig("https://test", &clientcmdapi.AuthInfo{
+					ClientCertificateData: []byte("cert"),
+					ClientKeyData:         []byte("key"),
+				}),
+			}),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := validateExternalManagedKubeconfig(c.secret)
+			switch {
+			case len(c.expectedErr) == 0 && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case len(c.expectedErr) != 0 && (err == nil || !strings.HasPrefix(err.Error(), c.expectedErr)):
+				t.Errorf("expected error %q, but got %v", c.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestHostedKlusterletWorksAvailableCondition(t *testing.T) {
+	availableConditions := []metav1.Condition{
+		{Type: workv1.WorkApplied, Status: metav1.ConditionTrue},
+		{Type: workv1.WorkAvailable, Status: metav1.ConditionTrue},
+	}
+
+	cases := []struct {
+		name           string
+		works          []*workv1.ManifestWork
+		expectedStatus metav1.ConditionStatus
+		expectedReason string
+	}{
+		{
+			name:           "no works",
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: constants.ConditionReasonHostedKlusterletWorkNotAvailable,
+		},
+		{
+			name: "klusterlet work is not applied",
+			works: []*workv1.ManifestWork{
+				{ObjectMeta: metav1.ObjectMeta{Name: "test-hosted-klusterlet", Namespace: "cluster1"}},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-hosted-kubeconfig", Namespace: "cluster1"},
+					Status:     workv1.ManifestWorkStatus{Conditions: availableConditions},
+				},
+			},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: constants.ConditionReasonHostedKlusterletWorkNotAvailable,
+		},
+		{
+			name: "kubeconfig work is not created",
+			works: []*workv1.ManifestWork{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-hosted-klusterlet", Namespace: "cluster1"},
+					Status:     workv1.ManifestWorkStatus{Conditions: availableConditions},
+				},
+			},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: constants.ConditionReasonHostedKubeconfigWorkNotAvailable,
+		},
+		{
+			name: "kubeconfig work is not available",
+			works: []*workv1.ManifestWork{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-hosted-klusterlet", Namespace: "cluster1"},
+					Status:     workv1.ManifestWorkStatus{Conditions: availableConditions},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-hosted-kubeconfig", Namespace: "cluster1"},
+					Status: workv1.ManifestWorkStatus{
+						Conditions: []metav1.Condition{{Type: workv1.WorkApplied, Status: metav1.ConditionTrue}},
+					},
+				},
+			},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: constants.ConditionReasonHostedKubeconfigWorkNotAvailable,
+		},
+		{
+			name: "all works are available",
+			works: []*workv1.ManifestWork{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-hosted-klusterlet", Namespace: "cluster1"},
+					Status:     workv1.ManifestWorkStatus{Conditions: availableConditions},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-hosted-kubeconfig", Namespace: "cluster1"},
+					Status:     workv1.ManifestWorkStatus{Conditions: availableConditions},
+				},
+			},
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: constants.ConditionReasonHostedKlusterletWorksAvailable,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			workInformerFactory := workinformers.NewSharedInformerFactory(workfake.NewSimpleClientset(), 10*time.Minute)
+			workInformer := workInformerFactory.Work().V1().ManifestWorks().Informer()
+			for _, work := range c.works {
+				workInformer.GetStore().Add(work)
+			}
+
+			r := &ReconcileHosted{
+				informerHolder: &source.InformerHolder{
+					HostedWorkLister: workInformerFactory.Work().V1().ManifestWorks().Lister(),
+				},
+			}
+
+			condition, err := r.newHostedKlusterletWorksAvailableCondition("test", "cluster1")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if condition.Status != c.expectedStatus {
+				t.Errorf("expected status %s, but got %s", c.expectedStatus, condition.Status)
+			}
+			if condition.Reason != c.expectedReason {
+				t.Errorf("expected reason %s, but got %s, message: %s", c.expectedReason, condition.Reason,
+					condition.Message)
+			}
+		})
+	}
+}
+
+func TestApplyManagedKubeconfigManifestWork(t *testing.T) {
+	newAutoImportSecret := func(kubeconfig string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: constants.AutoImportSecretName, Namespace: "test"},
+			Data:       map[string][]byte{"kubeconfig": []byte(kubeconfig)},
+		}
+	}
+
+	existing, err := createManagedKubeconfigManifestWork("test", newAutoImportSecret("kubeconfig-v1"), "cluster1", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	required, err := createManagedKubeconfigManifestWork("test", newAutoImportSecret("kubeconfig-v2"), "cluster1", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	workClient := workfake.NewSimpleClientset(existing)
+	r := &ReconcileHosted{
+		clientHolder: &helpers.ClientHolder{
+			WorkClient: workClient,
+		},
+		recorder: eventstesting.NewTestingEventRecorder(t),
+		scheme:   testscheme,
+	}
+
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+	if err := r.applyManagedKubeconfigManifestWork(context.TODO(), managedCluster, required); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	verbs := map[string]int{}
+	for _, action := range workClient.Actions() {
+		verbs[action.GetVerb()]++
+	}
+	if verbs["patch"] != 1 {
+		t.Errorf("expected the manifestwork is patched once, but got actions %v", verbs)
+	}
+	if verbs["delete"] != 0 || verbs["create"] != 0 || verbs["update"] != 0 {
+		t.Errorf("expected the manifestwork is not recreated or replaced, but got actions %v", verbs)
+	}
+
+	work, err := workClient.WorkV1().ManifestWorks("cluster1").Get(
+		context.TODO(), hostedManagedKubeconfigManifestWorkName("test"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !helpers.ManifestsEqual(work.Spec.Workload.Manifests, required.Spec.Workload.Manifests) {
+		t.Errorf("expected the manifestwork has the rotated kubeconfig")
+	}
+
+	// the manifestwork is not changed if the kubeconfig is not changed
+	workClient.ClearActions()
+	if err := r.applyManagedKubeconfigManifestWork(context.TODO(), managedCluster, required); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, action := range workClient.Actions() {
+		if action.GetVerb() != "get" {
+			t.Errorf("expected no changes on the manifestwork, but got %s", action.GetVerb())
+		}
+	}
+}
@@ -30,13 +30,17 @@ import (
 
 const controllerName = "hosted-manifestwork-controller"
 
+// maxConcurrentReconcilesEnvVarName is the env to specify the max concurrent reconciles of the hosted controller,
+// the global MAX_CONCURRENT_RECONCILES env is used if it is not set
+const maxConcurrentReconcilesEnvVarName = "HOSTED_MAX_CONCURRENT_RECONCILES"
+
 // Add creates a new manifestwork controller and adds it to the Manager.
 // The Manager will set fields on the Controller and Start it when the Manager is Started.
 func Add(mgr manager.Manager, clientHolder *helpers.ClientHolder, informerHolder *source.InformerHolder) (string, error) {
 
 	err := ctrl.NewControllerManagedBy(mgr).Named(controllerName).
 		WithOptions(controller.Options{
-			MaxConcurrentReconciles: helpers.GetMaxConcurrentReconciles(),
+			MaxConcurrentReconciles: helpers.GetControllerMaxConcurrentReconciles(maxConcurrentReconcilesEnvVarName),
 		}).
 		WatchesRawSource(
 			source.NewHostedWorkSource(informerHolder.HostedWorkInformer),
@@ -62,23 +66,7 @@ func Add(mgr manager.Manager, clientHolder *helpers.ClientHolder, informerHolder
 				GenericFunc: func(e event.GenericEvent) bool { return false },
 				CreateFunc:  func(e event.CreateEvent) bool { return true },
 				DeleteFunc:  func(e event.DeleteEvent) bool { return true },
-				UpdateFunc: func(e event.UpdateEvent) bool {
-					workName := e.ObjectNew.GetName()
-					// for update event, only watch hosted mode manifest works
-					if !strings.HasSuffix(workName, constants.HostedKlusterletManifestworkSuffix) &&
-						!strings.HasSuffix(workName, constants.HostedManagedKubeconfigManifestworkSuffix) {
-						return false
-					}
-
-					new, okNew := e.ObjectNew.(*workv1.ManifestWork)
-					old, okOld := e.ObjectOld.(*workv1.ManifestWork)
-					if okNew && okOld {
-						return !helpers.ManifestsEqual(new.Spec.Workload.Manifests, old.Spec.Workload.Manifests) ||
-							!equality.Semantic.DeepEqual(new.Status, old.Status)
-					}
-
-					return false
-				},
+				UpdateFunc:  hostedWorkUpdated,
 			}),
 		).
 		Watches(
@@ -138,6 +126,27 @@ func Add(mgr manager.Manager, clientHolder *helpers.ClientHolder, informerHolder
 	return controllerName, err
 }
 
+// hostedWorkUpdated returns true if the manifests or the status of a hosted mode manifest work are changed, a hosted
+// mode manifest work is either the hosted klusterlet manifest work or the hosted managed kubeconfig manifest work
+func hostedWorkUpdated(e event.UpdateEvent) bool {
+	workName := e.ObjectNew.GetName()
+	hasKlusterletSuffix := strings.HasSuffix(workName, constants.HostedKlusterletManifestworkSuffix)
+	hasKubeconfigSuffix := strings.HasSuffix(workName, constants.HostedManagedKubeconfigManifestworkSuffix)
+	// for update event, only watch hosted mode manifest works
+	if !(hasKlusterletSuffix || hasKubeconfigSuffix) {
+		return false
+	}
+
+	new, okNew := e.ObjectNew.(*workv1.ManifestWork)
+	old, okOld := e.ObjectOld.(*workv1.ManifestWork)
+	if okNew && okOld {
+		return !helpers.ManifestsEqual(new.Spec.Workload.Manifests, old.Spec.Workload.Manifests) ||
+			!equality.Semantic.DeepEqual(new.Status, old.Status)
+	}
+
+	return false
+}
+
 func isHostedModeObject(object client.Object) bool {
 	return strings.EqualFold(object.GetAnnotations()[constants.KlusterletDeployModeAnnotation], string(operatorv1.InstallModeHosted))
 }
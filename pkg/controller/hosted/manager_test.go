@@ -0,0 +1,65 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package hosted
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestHostedWorkUpdated(t *testing.T) {
+	newWork := func(name string, conditionStatus metav1.ConditionStatus) *workv1.ManifestWork {
+		return &workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "cluster1",
+			},
+			Status: workv1.ManifestWorkStatus{
+				Conditions: []metav1.Condition{
+					{
+						Type:   workv1.WorkAvailable,
+						Status: conditionStatus,
+					},
+				},
+			},
+		}
+	}
+
+	cases := []struct {
+		name     string
+		workName string
+		expected bool
+	}{
+		{
+			name:     "hosted klusterlet manifest work",
+			workName: "cluster1-hosted-klusterlet",
+			expected: true,
+		},
+		{
+			name:     "hosted managed kubeconfig manifest work",
+			workName: "cluster1-hosted-kubeconfig",
+			expected: true,
+		},
+		{
+			name:     "other manifest work",
+			workName: "cluster1-klusterlet",
+			expected: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			updated := hostedWorkUpdated(event.UpdateEvent{
+				ObjectOld: newWork(c.workName, metav1.ConditionFalse),
+				ObjectNew: newWork(c.workName, metav1.ConditionTrue),
+			})
+			if updated != c.expected {
+				t.Errorf("expected %v, but got %v", c.expected, updated)
+			}
+		})
+	}
+}
@@ -24,76 +24,109 @@ import (
 
 const defaultKlusterletNamespace = "open-cluster-management-agent"
 
+const (
+	// bootstrapTokenLifetime is the lifetime of the bootstrap token in the bootstrap kubeconfig
+	bootstrapTokenLifetime = 8640 * time.Hour
+
+	defaultBootstrapKubeConfigRenewalLeadTime = bootstrapTokenLifetime / 5
+	minBootstrapKubeConfigRenewalLeadTime     = time.Hour
+)
+
 // getBootstrapKubeConfigDataFromImportSecret aims to reuse the bootstrap kubeconfig data if possible.
-// The return values are: 1. kubeconfig data, 2. token expiration, 3. error
+// The return values are: 1. kubeconfig data, 2. token expiration, 3. whether the hub CA is rotated, 4. error
 // Note that the kubeconfig data could be `nil` if the import secret is not found or the kubeconfig data is invalid.
 func getBootstrapKubeConfigDataFromImportSecret(ctx context.Context, clientHolder *helpers.ClientHolder, clusterName string,
-	klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig) ([]byte, []byte, error) {
+	contextName string, klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig,
+	renewalLeadTime time.Duration) ([]byte, []byte, bool, error) {
 	importSecret, err := getImportSecret(ctx, clientHolder, clusterName)
 	if apierrors.IsNotFound(err) {
-		return nil, nil, nil
+		return nil, nil, false, nil
 	}
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, false, err
+	}
+
+	// the import secret that is generated by a different controller version may have an outdated schema, its
+	// klusterlet manifests are always regenerated, but its bootstrap kubeconfig is kept if it is still valid
+	version := importSecret.Annotations[constants.ImportSecretControllerVersionAnnotation]
+	outdated := version != bootstrap.KlusterletManifestsVersion()
+	if outdated {
+		klog.Infof("The import secret of the managed cluster %s is generated by the controller version %q, "+
+			"regenerate it to the version %q", clusterName, version, bootstrap.KlusterletManifestsVersion())
 	}
 
 	kubeConfigData := extractBootstrapKubeConfigDataFromImportSecret(importSecret)
 	if len(kubeConfigData) == 0 {
-		return nil, nil, nil
+		return nil, nil, false, nil
 	}
 
 	kubeAPIServer, proxyURL, caData, token, err := parseKubeConfigData(kubeConfigData)
+	if err != nil && outdated {
+		klog.Infof("The bootstrap kubeconfig of the outdated import secret of the managed cluster %s cannot be "+
+			"parsed, regenerate it: %v", clusterName, err)
+		return nil, nil, false, nil
+	}
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to parse kubeconfig data: %v", err)
+		return nil, nil, false, fmt.Errorf("failed to parse kubeconfig data: %v", err)
+	}
+
+	// check if the context name is changed
+	validContextName, err := validateContextName(kubeConfigData, contextName)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to validate context name: %v", err)
+	}
+	if !validContextName {
+		klog.Infof("Context name is invalid for the managed cluster %s, expected: %s", clusterName, contextName)
+		return nil, nil, false, nil
 	}
 
 	// check if the kube apiserver address is changed
 	validKubeAPIServer, err := validateKubeAPIServerAddress(ctx, kubeAPIServer, clientHolder)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to validate kube apiserver address: %v", err)
+		return nil, nil, false, fmt.Errorf("failed to validate kube apiserver address: %v", err)
 	}
 	if !validKubeAPIServer {
 		klog.Infof("KubeAPIServer invalid for the managed cluster %s, kubeAPIServer: %v", clusterName, kubeAPIServer)
-		return nil, nil, nil
+		return nil, nil, false, nil
 	}
 
 	// check if the CA data is changed
 	validCAData, err := validateCAData(ctx, caData, kubeAPIServer, clientHolder, clusterName)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to validate CA data: %v", err)
+		return nil, nil, false, fmt.Errorf("failed to validate CA data: %v", err)
 	}
 	if !validCAData {
 		klog.Infof("CAdata is invalid for the managed cluster %s", clusterName)
-		return nil, nil, nil
+		return nil, nil, len(caData) != 0, nil
 	}
 
 	// check if the proxy url changed
 	validProxyConfig, err := validateProxyConfig(proxyURL, caData, klusterletConfig)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to validate proxy config: %v", err)
+		return nil, nil, false, fmt.Errorf("failed to validate proxy config: %v", err)
 	}
 	if !validProxyConfig {
 		klog.Infof("Proxy config is invalid for the managed cluster %s", clusterName)
-		return nil, nil, nil
+		return nil, nil, false, nil
 	}
 
 	expiration := importSecret.Data[constants.ImportSecretTokenExpiration]
-	if !validateToken(token, expiration) {
+	if !validateToken(token, expiration, renewalLeadTime) {
 		klog.Infof("token is invalid for the managed cluster %s, expiration: %v", clusterName, string(expiration))
-		return nil, nil, nil
+		return nil, nil, false, nil
 	}
 
-	return kubeConfigData, expiration, nil
+	return kubeConfigData, expiration, false, nil
 }
 
 func getImportSecret(ctx context.Context, clientHolder *helpers.ClientHolder, clusterName string) (*corev1.Secret, error) {
-	importSecretName := fmt.Sprintf("%s-%s", clusterName, constants.ImportSecretNameSuffix)
+	importSecretName := helpers.GetImportSecretName(clusterName)
 	return clientHolder.KubeClient.CoreV1().Secrets(clusterName).Get(ctx, importSecretName, metav1.GetOptions{})
 }
 
 func extractBootstrapKubeConfigDataFromImportSecret(importSecret *corev1.Secret) []byte {
-	importYaml, ok := importSecret.Data[constants.ImportSecretImportYamlKey]
-	if !ok {
+	importYaml, err := helpers.GetImportYaml(importSecret)
+	if err != nil {
 		return nil
 	}
 
@@ -130,6 +163,20 @@ func parseKubeConfigData(kubeConfigData []byte) (kubeAPIServer, proxyURL string,
 	return
 }
 
+// validateContextName checks the expected context exists in the kubeconfig and is the current context
+func validateContextName(kubeConfigData []byte, contextName string) (bool, error) {
+	config, err := clientcmd.Load(kubeConfigData)
+	if err != nil {
+		return false, err
+	}
+
+	if _, ok := config.Contexts[contextName]; !ok {
+		return false, nil
+	}
+
+	return config.CurrentContext == contextName, nil
+}
+
 func validateKubeAPIServerAddress(ctx context.Context, kubeAPIServer string, clientHolder *helpers.ClientHolder) (bool, error) {
 	if len(kubeAPIServer) == 0 {
 		return false, nil
@@ -157,7 +204,7 @@ func validateCAData(ctx context.Context, caData []byte, kubeAPIServer string, cl
 	return reflect.DeepEqual(caData, currentCAData), nil
 }
 
-func validateToken(token string, expiration []byte) bool {
+func validateToken(token string, expiration []byte, renewalLeadTime time.Duration) bool {
 	if len(token) == 0 {
 		// no token in the kubeconfig
 		return false
@@ -174,9 +221,8 @@ func validateToken(token string, expiration []byte) bool {
 	}
 
 	now := metav1.Now()
-	refreshThreshold := 8640 * time.Hour / 5
 	lifetime := expirationTime.Sub(now.Time)
-	return lifetime > refreshThreshold
+	return lifetime > renewalLeadTime
 }
 
 func validateProxyConfig(kubeconfigProxyURL string, kubeconfigCAData []byte, klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig) (bool, error) {
@@ -228,3 +274,28 @@ func klusterletNamespace(managedClusterAnnotations map[string]string) string {
 
 	return defaultKlusterletNamespace
 }
+
+func klusterletOperatorNamespace(managedClusterAnnotations map[string]string) string {
+	return managedClusterAnnotations[constants.KlusterletOperatorNamespaceAnnotation]
+}
+
+// bootstrapKubeConfigRenewalLeadTime returns how long before the bootstrap token expires the bootstrap kubeconfig
+// should be renewed, the lead time must be less than the lifetime of the bootstrap token.
+func bootstrapKubeConfigRenewalLeadTime(managedClusterAnnotations map[string]string) (time.Duration, error) {
+	leadTime, ok := managedClusterAnnotations[constants.BootstrapKubeConfigRenewalLeadTimeAnnotation]
+	if !ok {
+		return defaultBootstrapKubeConfigRenewalLeadTime, nil
+	}
+
+	duration, err := time.ParseDuration(leadTime)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bootstrap kubeconfig renewal lead time annotation %v", err)
+	}
+
+	if duration < minBootstrapKubeConfigRenewalLeadTime || duration >= bootstrapTokenLifetime {
+		return 0, fmt.Errorf("the bootstrap kubeconfig renewal lead time %s should be between %s and %s",
+			duration, minBootstrapKubeConfigRenewalLeadTime, bootstrapTokenLifetime)
+	}
+
+	return duration, nil
+}
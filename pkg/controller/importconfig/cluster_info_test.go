@@ -92,7 +92,9 @@ func TestGetBootstrapKubeConfigDataFromImportSecret(t *testing.T) {
 		clientObjs       []client.Object
 		runtimeObjs      []runtime.Object
 		klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig
+		renewalLeadTime  time.Duration
 		want             *wantData
+		wantCARotated    bool
 		wantErr          bool
 	}{
 		{
@@ -115,6 +117,18 @@ func TestGetBootstrapKubeConfigDataFromImportSecret(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:       "token is in the customized renewal lead time",
+			clientObjs: []client.Object{testInfraConfigDNS, apiserverConfig},
+			runtimeObjs: []runtime.Object{secretCorrect,
+				mockImportSecret(t, time.Now().Add(2000*time.Hour),
+					"https://my-dns-name.com:6443",
+					[]byte("custom-cert-data"),
+					"mock-token"),
+			},
+			renewalLeadTime: 2160 * time.Hour,
+			wantErr:         false,
+		},
 		{
 			name:       "caData not validate",
 			clientObjs: []client.Object{testInfraConfigDNS, apiserverConfig},
@@ -124,7 +138,8 @@ func TestGetBootstrapKubeConfigDataFromImportSecret(t *testing.T) {
 					[]byte("wrong"),
 					"mock-token"),
 			},
-			wantErr: false,
+			wantCARotated: true,
+			wantErr:       false,
 		},
 		{
 			name:       "kubeAPIServer not validate",
@@ -137,6 +152,28 @@ func TestGetBootstrapKubeConfigDataFromImportSecret(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:       "import secret is generated by a different controller version",
+			clientObjs: []client.Object{testInfraConfigDNS, apiserverConfig},
+			runtimeObjs: []runtime.Object{secretCorrect,
+				func() *corev1.Secret {
+					secret := mockImportSecret(t, time.Now().Add(8640*time.Hour),
+						"https://my-dns-name.com:6443",
+						[]byte("custom-cert-data"),
+						"mock-token")
+					secret.Annotations[constants.ImportSecretControllerVersionAnnotation] = "outdated"
+					return secret
+				}(),
+			},
+			wantErr: false,
+			// the bootstrap kubeconfig is still valid, the token is kept
+			want: &wantData{
+				serverURL:   "https://my-dns-name.com:6443",
+				useInsecure: false,
+				certData:    []byte("custom-cert-data"),
+				token:       "mock-token",
+			},
+		},
 		{
 			name:       "all fileds are valid",
 			clientObjs: []client.Object{testInfraConfigDNS, apiserverConfig},
@@ -186,11 +223,20 @@ func TestGetBootstrapKubeConfigDataFromImportSecret(t *testing.T) {
 				KubeClient: fakeKubeClinet,
 			}
 
-			kubeconfigData, _, err := getBootstrapKubeConfigDataFromImportSecret(context.Background(), clientHolder, "testcluster", tt.klusterletConfig) // cluster.Name = testcluster
+			renewalLeadTime := tt.renewalLeadTime
+			if renewalLeadTime == 0 {
+				renewalLeadTime = defaultBootstrapKubeConfigRenewalLeadTime
+			}
+
+			kubeconfigData, _, caRotated, err := getBootstrapKubeConfigDataFromImportSecret(context.Background(), clientHolder, "testcluster",
+				bootstrap.DefaultBootstrapKubeConfigContextName, tt.klusterletConfig, renewalLeadTime) // cluster.Name = testcluster
 			if (err != nil) != tt.wantErr {
 				t.Errorf("getBootstrapKubeConfigDataFromImportSecret() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if caRotated != tt.wantCARotated {
+				t.Errorf("getBootstrapKubeConfigDataFromImportSecret() caRotated = %v, want %v", caRotated, tt.wantCARotated)
+			}
 			if err != nil {
 				// it's safe to return here, because the last step, if err is not nil, and we don't expect err, it will fail the test
 				return
@@ -565,6 +611,9 @@ func mockImportSecret(t *testing.T, expirationTime time.Time, server string, caD
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "testcluster-import",
 			Namespace: "testcluster",
+			Annotations: map[string]string{
+				constants.ImportSecretControllerVersionAnnotation: bootstrap.KlusterletManifestsVersion(),
+			},
 		},
 		Data: map[string][]byte{
 			"import.yaml": importYAML.Bytes(),
@@ -582,3 +631,61 @@ func mockImportSecretWithoutContent() *corev1.Secret {
 		Data: map[string][]byte{},
 	}
 }
+
+func TestBootstrapKubeConfigRenewalLeadTime(t *testing.T) {
+	cases := []struct {
+		name             string
+		annotations      map[string]string
+		expectedLeadTime time.Duration
+		expectedErr      bool
+	}{
+		{
+			name:             "default lead time",
+			annotations:      map[string]string{},
+			expectedLeadTime: defaultBootstrapKubeConfigRenewalLeadTime,
+		},
+		{
+			name: "customized lead time",
+			annotations: map[string]string{
+				constants.BootstrapKubeConfigRenewalLeadTimeAnnotation: "720h",
+			},
+			expectedLeadTime: 720 * time.Hour,
+		},
+		{
+			name: "invalid lead time",
+			annotations: map[string]string{
+				constants.BootstrapKubeConfigRenewalLeadTimeAnnotation: "30d",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "lead time is too short",
+			annotations: map[string]string{
+				constants.BootstrapKubeConfigRenewalLeadTimeAnnotation: "10m",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "lead time is not less than the token lifetime",
+			annotations: map[string]string{
+				constants.BootstrapKubeConfigRenewalLeadTimeAnnotation: "8640h",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			leadTime, err := bootstrapKubeConfigRenewalLeadTime(c.annotations)
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if leadTime != c.expectedLeadTime {
+				t.Errorf("expected lead time %s, but got %s", c.expectedLeadTime, leadTime)
+			}
+		})
+	}
+}
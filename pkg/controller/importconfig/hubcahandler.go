@@ -0,0 +1,56 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package importconfig
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+// HubRootCAConfigMapName is the name of the configmap which the hub root CA is published to in each namespace
+const HubRootCAConfigMapName = "kube-root-ca.crt"
+
+const hubRootCAKey = "ca.crt"
+
+var _ handler.EventHandler = &enqueueAllManagedClustersOnHubCAChange{}
+
+// enqueueAllManagedClustersOnHubCAChange enqueues all managed clusters when the hub root CA is rotated, the CA that
+// is embedded in the bootstrap kubeconfig of each import secret is invalid after the rotation, so the import secret
+// of each managed cluster will be regenerated with the new CA.
+type enqueueAllManagedClustersOnHubCAChange struct {
+	managedclusterIndexer cache.Indexer
+}
+
+func (e *enqueueAllManagedClustersOnHubCAChange) Create(ctx context.Context, evt event.CreateEvent, q workqueue.RateLimitingInterface) {
+}
+
+func (e *enqueueAllManagedClustersOnHubCAChange) Update(ctx context.Context, evt event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	oldCA, ok := evt.ObjectOld.(*corev1.ConfigMap)
+	if !ok {
+		return
+	}
+	newCA, ok := evt.ObjectNew.(*corev1.ConfigMap)
+	if !ok {
+		return
+	}
+	if oldCA.Data[hubRootCAKey] == newCA.Data[hubRootCAKey] {
+		return
+	}
+
+	klog.Infof("The hub root CA %s/%s is rotated, regenerate the import secrets of all managed clusters",
+		newCA.Namespace, newCA.Name)
+	enqueueAllManagedClusters(e.managedclusterIndexer, q)
+}
+
+func (e *enqueueAllManagedClustersOnHubCAChange) Delete(ctx context.Context, evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
+}
+
+func (e *enqueueAllManagedClustersOnHubCAChange) Generic(ctx context.Context, evt event.GenericEvent, q workqueue.RateLimitingInterface) {
+}
@@ -0,0 +1,70 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package importconfig
+
+import (
+	"context"
+
+	ocinfrav1 "github.com/openshift/api/config/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// infrastructureConfigName is the name of the ocp infrastructure config which the hub kube-apiserver URL is read from
+const infrastructureConfigName = "cluster"
+
+var _ handler.EventHandler = &enqueueAllManagedClustersOnHubServerURLChange{}
+
+// enqueueAllManagedClustersOnHubServerURLChange enqueues all managed clusters when the kube-apiserver URL of the hub
+// is changed, so the bootstrap kubeconfig in the import secret of each managed cluster will be refreshed with the
+// new server URL.
+type enqueueAllManagedClustersOnHubServerURLChange struct {
+	managedclusterIndexer cache.Indexer
+}
+
+func (e *enqueueAllManagedClustersOnHubServerURLChange) Create(ctx context.Context, evt event.CreateEvent, q workqueue.RateLimitingInterface) {
+}
+
+func (e *enqueueAllManagedClustersOnHubServerURLChange) Update(ctx context.Context, evt event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	oldInfra, ok := evt.ObjectOld.(*ocinfrav1.Infrastructure)
+	if !ok {
+		return
+	}
+	newInfra, ok := evt.ObjectNew.(*ocinfrav1.Infrastructure)
+	if !ok {
+		return
+	}
+	if oldInfra.Status.APIServerURL == newInfra.Status.APIServerURL {
+		return
+	}
+
+	klog.Infof("The hub kube-apiserver URL is changed from %q to %q, refresh the import secrets of all managed clusters",
+		oldInfra.Status.APIServerURL, newInfra.Status.APIServerURL)
+	enqueueAllManagedClusters(e.managedclusterIndexer, q)
+}
+
+func (e *enqueueAllManagedClustersOnHubServerURLChange) Delete(ctx context.Context, evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
+}
+
+func (e *enqueueAllManagedClustersOnHubServerURLChange) Generic(ctx context.Context, evt event.GenericEvent, q workqueue.RateLimitingInterface) {
+}
+
+// enqueueAllManagedClusters enqueues all of the managed clusters in the indexer
+func enqueueAllManagedClusters(managedclusterIndexer cache.Indexer, q workqueue.RateLimitingInterface) {
+	for _, obj := range managedclusterIndexer.List() {
+		mc, ok := obj.(*clusterv1.ManagedCluster)
+		if !ok {
+			continue
+		}
+		q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+			Name: mc.GetName(),
+		}})
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package importconfig
+
+import (
+	"context"
+	"testing"
+
+	ocinfrav1 "github.com/openshift/api/config/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestEnqueueAllManagedClustersOnHubServerURLChange(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, name := range []string{"test1", "test2", "test3"} {
+		if err := indexer.Add(&clusterv1.ManagedCluster{ObjectMeta: v1.ObjectMeta{Name: name}}); err != nil {
+			t.Fatalf("Failed to add managed cluster to indexer: %v", err)
+		}
+	}
+
+	newInfra := func(apiServerURL string) *ocinfrav1.Infrastructure {
+		return &ocinfrav1.Infrastructure{
+			ObjectMeta: v1.ObjectMeta{
+				Name: infrastructureConfigName,
+			},
+			Status: ocinfrav1.InfrastructureStatus{
+				APIServerURL: apiServerURL,
+			},
+		}
+	}
+
+	cases := []struct {
+		name             string
+		evt              event.UpdateEvent
+		expectedEnqueued []string
+	}{
+		{
+			name: "hub server url is not changed",
+			evt: event.UpdateEvent{
+				ObjectOld: newInfra("https://api.hub.example.com:6443"),
+				ObjectNew: func() *ocinfrav1.Infrastructure {
+					infra := newInfra("https://api.hub.example.com:6443")
+					infra.Status.InfrastructureName = "hub"
+					return infra
+				}(),
+			},
+			expectedEnqueued: []string{},
+		},
+		{
+			name: "hub server url is changed",
+			evt: event.UpdateEvent{
+				ObjectOld: newInfra("https://api.hub.example.com:6443"),
+				ObjectNew: newInfra("https://api.new-hub.example.com:6443"),
+			},
+			expectedEnqueued: []string{"test1", "test2", "test3"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+			defer queue.ShutDown()
+
+			h := &enqueueAllManagedClustersOnHubServerURLChange{managedclusterIndexer: indexer}
+			h.Update(context.Background(), c.evt, queue)
+
+			if queue.Len() != len(c.expectedEnqueued) {
+				t.Fatalf("Expected queue length to be %d, but got %d", len(c.expectedEnqueued), queue.Len())
+			}
+
+			enqueued := map[string]bool{}
+			for queue.Len() > 0 {
+				item, _ := queue.Get()
+				enqueued[item.(reconcile.Request).Name] = true
+				queue.Done(item)
+			}
+			for _, name := range c.expectedEnqueued {
+				if !enqueued[name] {
+					t.Errorf("Expected %s to be enqueued, but got %v", name, enqueued)
+				}
+			}
+		})
+	}
+}
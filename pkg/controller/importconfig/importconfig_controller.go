@@ -9,6 +9,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -62,11 +63,45 @@ func (r *ReconcileImportConfig) Reconcile(ctx context.Context, request reconcile
 	reqLogger.Info("Reconciling managed cluster")
 
 	mode := helpers.DetermineKlusterletMode(managedCluster)
+	if mode == helpers.InstallModeUnknown {
+		// do not requeue, the managed cluster will be reconciled again once its annotation is corrected
+		reqLogger.Info("The klusterlet deploy mode is invalid")
+		return reconcile.Result{}, helpers.UpdateManagedClusterStatus(
+			r.clientHolder.RuntimeClient,
+			managedCluster.Name,
+			helpers.NewManagedClusterImportSucceededCondition(
+				metav1.ConditionFalse,
+				constants.ConditionReasonInvalidDeployMode,
+				fmt.Sprintf("The klusterlet deploy mode %q is invalid, it should be one of %s, %s or %s",
+					managedCluster.GetAnnotations()[constants.KlusterletDeployModeAnnotation],
+					operatorv1.InstallModeDefault, operatorv1.InstallModeSingleton, operatorv1.InstallModeHosted),
+			),
+		)
+	}
 	if err := helpers.ValidateKlusterletMode(mode); err != nil {
 		reqLogger.Info(err.Error())
 		return reconcile.Result{}, nil
 	}
 
+	// do not requeue, the managed cluster will be reconciled again once its annotations are changed
+	if err := helpers.ValidateKlusterletNamespace(klusterletNamespace(managedCluster.GetAnnotations())); err != nil {
+		reqLogger.Info("The klusterlet namespace is invalid", "error", err)
+		return reconcile.Result{}, helpers.UpdateManagedClusterStatus(
+			r.clientHolder.RuntimeClient,
+			managedCluster.Name,
+			helpers.NewManagedClusterImportSucceededCondition(
+				metav1.ConditionFalse,
+				constants.ConditionReasonKlusterletNamespaceInvalid,
+				err.Error(),
+			),
+		)
+	}
+
+	if _, err := helpers.GetImageDigestPolicyFromManagedClusterAnnotations(managedCluster.GetAnnotations()); err != nil {
+		reqLogger.Info("The klusterlet image digest policy is invalid", "error", err)
+		return r.rejectKlusterletImages(ctx, managedCluster, constants.ConditionReasonImageDigestPolicyInvalid, err)
+	}
+
 	// make sure the managed cluster clusterrole, clusterrolebinding and bootstrap sa are updated
 	objects, err := bootstrap.GenerateHubBootstrapRBACObjects(managedCluster.Name)
 	if err != nil {
@@ -87,23 +122,101 @@ func (r *ReconcileImportConfig) Reconcile(ctx context.Context, request reconcile
 		}
 	}
 
+	contextName, err := bootstrap.GetBootstrapKubeConfigContextName(managedCluster.GetAnnotations())
+	if err != nil {
+		// do not requeue, the managed cluster will be reconciled again once its annotations are changed
+		reqLogger.Info("The kubeconfig context name is invalid", "error", err)
+		return reconcile.Result{}, helpers.UpdateManagedClusterStatus(
+			r.clientHolder.RuntimeClient,
+			managedCluster.Name,
+			helpers.NewManagedClusterImportSucceededCondition(
+				metav1.ConditionFalse,
+				constants.ConditionReasonKubeconfigContextNameInvalid,
+				err.Error(),
+			),
+		)
+	}
+
+	renewalLeadTime, err := bootstrapKubeConfigRenewalLeadTime(managedCluster.GetAnnotations())
+	if err != nil {
+		// do not requeue, the managed cluster will be reconciled again once its annotations are changed
+		reqLogger.Info("The bootstrap kubeconfig renewal lead time is invalid", "error", err)
+		return reconcile.Result{}, helpers.UpdateManagedClusterStatus(
+			r.clientHolder.RuntimeClient,
+			managedCluster.Name,
+			helpers.NewManagedClusterImportSucceededCondition(
+				metav1.ConditionFalse,
+				constants.ConditionReasonBootstrapKubeConfigRenewalLeadTimeInvalid,
+				err.Error(),
+			),
+		)
+	}
+
 	// get the previous bootstrap kubeconfig and expiration
-	bootstrapKubeconfigData, expiration, err := getBootstrapKubeConfigDataFromImportSecret(ctx, r.clientHolder, managedCluster.Name, kc)
+	bootstrapKubeconfigData, expiration, caRotated, err := getBootstrapKubeConfigDataFromImportSecret(
+		ctx, r.clientHolder, managedCluster.Name, contextName, kc, renewalLeadTime)
+	if result, pending, rbacErr := helpers.RequeueOnHubNamespaceRBACPending(
+		r.clientHolder.RuntimeClient, managedCluster, err); pending {
+		reqLogger.Info("The RBAC of the managed cluster namespace is not ready", "error", err)
+		return result, rbacErr
+	}
 	if err != nil {
 		return reconcile.Result{}, err
 	}
 
+	if caRotated {
+		reqLogger.Info("The hub CA is rotated, regenerate the import secret")
+		if err := helpers.UpdateManagedClusterStatus(
+			r.clientHolder.RuntimeClient,
+			managedCluster.Name,
+			metav1.Condition{
+				Type:   constants.ConditionBootstrapCARotated,
+				Status: metav1.ConditionTrue,
+				Reason: constants.ConditionReasonBootstrapCARotated,
+				Message: fmt.Sprintf("The hub CA is rotated, the import secret %s/%s is being regenerated with "+
+					"the new CA", managedCluster.Name, helpers.GetImportSecretName(managedCluster.Name)),
+			},
+		); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
 	// if bootstrapKubeconfig not exist or expired, create a new one
 	if bootstrapKubeconfigData == nil {
+		bootstrapSAName := bootstrap.GetBootstrapSAName(managedCluster.Name)
+		bootstrapSANamespace := bootstrap.GetBootstrapSANamespace(managedCluster.Name)
+		if err := helpers.ValidateBootstrapCapability(
+			ctx, r.clientHolder.KubeClient, bootstrapSANamespace, bootstrapSAName); err != nil {
+			// only report the misconfiguration on the clusters that are not imported, the transient errors and
+			// the errors of the imported clusters are retried without touching the import condition
+			if helpers.IsBootstrapMisconfigured(err) && !meta.IsStatusConditionTrue(
+				managedCluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded) {
+				if updateErr := helpers.UpdateManagedClusterStatus(
+					r.clientHolder.RuntimeClient,
+					managedCluster.Name,
+					helpers.NewManagedClusterImportSucceededCondition(
+						metav1.ConditionFalse,
+						constants.ConditionReasonHubBootstrapMisconfigured,
+						fmt.Sprintf("The hub is unable to mint the bootstrap token: %v", err),
+					),
+				); updateErr != nil {
+					return reconcile.Result{}, updateErr
+				}
+			}
+			return reconcile.Result{}, err
+		}
+
 		bootstrapKubeconfigData, expiration, err = bootstrap.CreateBootstrapKubeConfig(ctx, r.clientHolder,
-			bootstrap.GetBootstrapSAName(managedCluster.Name), managedCluster.Name, 8640*3600, kc)
+			bootstrapSAName, bootstrapSANamespace, int64(bootstrapTokenLifetime.Seconds()), contextName, kc)
 		if err != nil {
 			return reconcile.Result{}, err
 		}
 	}
 
 	var yamlcontent, crdsV1YAML, crdsV1beta1YAML []byte
-	var secretAnnotations map[string]string
+	secretAnnotations := map[string]string{
+		constants.ImportSecretControllerVersionAnnotation: bootstrap.KlusterletManifestsVersion(),
+	}
 	switch mode {
 	case operatorv1.InstallModeDefault, operatorv1.InstallModeSingleton:
 		yamlcontent, err = bootstrap.NewKlusterletManifestsConfig(
@@ -111,11 +224,12 @@ func (r *ReconcileImportConfig) Reconcile(ctx context.Context, request reconcile
 			managedCluster.Name,
 			klusterletNamespace(managedCluster.GetAnnotations()),
 			bootstrapKubeconfigData).
+			WithKlusterletOperatorNamespace(klusterletOperatorNamespace(managedCluster.GetAnnotations())).
 			WithManagedClusterAnnotations(managedCluster.GetAnnotations()).
 			WithKlusterletConfig(kc).
 			Generate(ctx, r.clientHolder)
 		if err != nil {
-			return reconcile.Result{}, err
+			return r.klusterletManifestsGenerateFailed(ctx, managedCluster, err)
 		}
 
 		crdsV1beta1YAML, err = bootstrap.GenerateKlusterletCRDsV1Beta1()
@@ -136,12 +250,10 @@ func (r *ReconcileImportConfig) Reconcile(ctx context.Context, request reconcile
 			WithManagedClusterAnnotations(managedCluster.GetAnnotations()).
 			WithImagePullSecretGenerate(false).Generate(ctx, r.clientHolder)
 		if err != nil {
-			return reconcile.Result{}, err
+			return r.klusterletManifestsGenerateFailed(ctx, managedCluster, err)
 		}
 
-		secretAnnotations = map[string]string{
-			constants.KlusterletDeployModeAnnotation: string(operatorv1.InstallModeHosted),
-		}
+		secretAnnotations[constants.KlusterletDeployModeAnnotation] = string(operatorv1.InstallModeHosted)
 	default:
 		return reconcile.Result{}, fmt.Errorf("klusterlet deploy mode %s not supportted", mode)
 	}
@@ -150,7 +262,7 @@ func (r *ReconcileImportConfig) Reconcile(ctx context.Context, request reconcile
 	importSecret := &corev1.Secret{
 		TypeMeta: metav1.TypeMeta{},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("%s-%s", managedCluster.Name, constants.ImportSecretNameSuffix),
+			Name:      helpers.GetImportSecretName(managedCluster.Name),
 			Namespace: managedCluster.Name,
 			Labels: map[string]string{
 				constants.ClusterImportSecretLabel: "",
@@ -168,10 +280,108 @@ func (r *ReconcileImportConfig) Reconcile(ctx context.Context, request reconcile
 		importSecret.Data[constants.ImportSecretTokenExpiration] = expiration
 	}
 
+	// the import secret is stored in etcd, it cannot exceed the object size limit
+	fits, err := compressOversizedImportSecret(importSecret, maxImportSecretSize)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if !fits {
+		// do not requeue, the managed cluster will be reconciled again once its annotations are changed
+		reqLogger.Info("The import secret is too large")
+		return reconcile.Result{}, helpers.UpdateManagedClusterStatus(
+			r.clientHolder.RuntimeClient,
+			managedCluster.Name,
+			helpers.NewManagedClusterImportSucceededCondition(
+				metav1.ConditionFalse,
+				constants.ConditionReasonImportSecretTooLarge,
+				fmt.Sprintf("The import secret %s/%s exceeds the size limit of %d bytes even if its %s is compressed",
+					importSecret.Namespace, importSecret.Name, maxImportSecretSize, constants.ImportSecretImportYamlKey),
+			),
+		)
+	}
+
 	if _, err := helpers.ApplyResources(
 		r.clientHolder, r.recorder, r.scheme, managedCluster, importSecret); err != nil {
 		return reconcile.Result{}, err
 	}
 
+	// the import secret that is regenerated after the hub CA rotation has been reused, the transition is done
+	if !caRotated && meta.IsStatusConditionTrue(managedCluster.Status.Conditions, constants.ConditionBootstrapCARotated) {
+		return reconcile.Result{}, helpers.UpdateManagedClusterStatus(
+			r.clientHolder.RuntimeClient,
+			managedCluster.Name,
+			metav1.Condition{
+				Type:    constants.ConditionBootstrapCARotated,
+				Status:  metav1.ConditionFalse,
+				Reason:  constants.ConditionReasonBootstrapCAUpToDate,
+				Message: "The import secret embeds the current hub CA",
+			},
+		)
+	}
+
 	return reconcile.Result{}, nil
 }
+
+// klusterletManifestsGenerateFailed rejects the klusterlet images that violate the image digest policy, the other
+// errors are returned to requeue the managed cluster
+func (r *ReconcileImportConfig) klusterletManifestsGenerateFailed(ctx context.Context,
+	managedCluster *clusterv1.ManagedCluster, err error) (reconcile.Result, error) {
+	if !helpers.IsImagesNotDigestPinned(err) {
+		return reconcile.Result{}, err
+	}
+
+	log.Info("The klusterlet images are not digest pinned", "Request.Name", managedCluster.Name, "error", err)
+	return r.rejectKlusterletImages(ctx, managedCluster, constants.ConditionReasonImagesNotDigestPinned, err)
+}
+
+// rejectKlusterletImages deletes the import secret of the managed cluster and reports the reason with the managed
+// cluster status. The previous import secret may reference the images that are rejected now, without it the
+// klusterlet works are not applied and the managed cluster is not auto imported anymore.
+func (r *ReconcileImportConfig) rejectKlusterletImages(ctx context.Context, managedCluster *clusterv1.ManagedCluster,
+	reason string, err error) (reconcile.Result, error) {
+	importSecretName := helpers.GetImportSecretName(managedCluster.Name)
+	if deleteErr := r.clientHolder.KubeClient.CoreV1().Secrets(managedCluster.Name).Delete(
+		ctx, importSecretName, metav1.DeleteOptions{}); deleteErr != nil && !errors.IsNotFound(deleteErr) {
+		return reconcile.Result{}, deleteErr
+	}
+
+	// do not requeue, the managed cluster will be reconciled again once its annotations are changed
+	return reconcile.Result{}, helpers.UpdateManagedClusterStatus(
+		r.clientHolder.RuntimeClient,
+		managedCluster.Name,
+		helpers.NewManagedClusterImportSucceededCondition(
+			metav1.ConditionFalse,
+			reason,
+			err.Error(),
+		),
+	)
+}
+
+// maxImportSecretSize is the max size of the import secret data, it is under the 1MiB object size limit of etcd
+// to leave room for the object metadata
+const maxImportSecretSize = 1000 * 1024
+
+// compressOversizedImportSecret compresses the import.yaml of the import secret if the import secret data exceeds
+// the size limit, it returns false if the import secret data still exceeds the size limit after the compression
+func compressOversizedImportSecret(importSecret *corev1.Secret, sizeLimit int) (bool, error) {
+	if importSecretSize(importSecret) <= sizeLimit {
+		return true, nil
+	}
+
+	compressed, err := helpers.CompressImportYaml(importSecret.Data[constants.ImportSecretImportYamlKey])
+	if err != nil {
+		return false, err
+	}
+	delete(importSecret.Data, constants.ImportSecretImportYamlKey)
+	importSecret.Data[constants.ImportSecretImportYamlGzipKey] = compressed
+
+	return importSecretSize(importSecret) <= sizeLimit, nil
+}
+
+func importSecretSize(importSecret *corev1.Secret) int {
+	size := 0
+	for key, value := range importSecret.Data {
+		size += len(key) + len(value)
+	}
+	return size
+}
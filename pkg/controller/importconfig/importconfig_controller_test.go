@@ -5,11 +5,13 @@ package importconfig
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/stolostron/managedcluster-import-controller/pkg/bootstrap"
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
@@ -27,14 +29,19 @@ import (
 	hivev1 "github.com/openshift/hive/apis/hive/v1"
 	operatorv1 "open-cluster-management.io/api/operator/v1"
 
+	authv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
 
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -75,6 +82,7 @@ func TestReconcile(t *testing.T) {
 		clientObjs       []runtimeclient.Object
 		runtimeObjs      []runtime.Object
 		klusterletconfig *klusterletconfigv1alpha1.KlusterletConfig
+		envs             map[string]string
 		request          reconcile.Request
 		validateFunc     func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface)
 	}{
@@ -735,10 +743,635 @@ func TestReconcile(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "invalid deploy mode",
+			clientObjs: []runtimeclient.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							constants.KlusterletDeployModeAnnotation: "hosted-mode",
+						},
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface) {
+				_, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "test-import", metav1.GetOptions{})
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected the import secret is not generated, but got %v", err)
+				}
+
+				cluster := &clusterv1.ManagedCluster{}
+				if err := client.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				condition := meta.FindStatusCondition(
+					cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+				if condition == nil || condition.Reason != constants.ConditionReasonInvalidDeployMode {
+					t.Errorf("expected import condition reason %s, but got %v",
+						constants.ConditionReasonInvalidDeployMode, condition)
+				}
+			},
+		},
+		{
+			name: "invalid klusterlet namespace",
+			clientObjs: []runtimeclient.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							constants.KlusterletNamespaceAnnotation: "tenant-a",
+						},
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface) {
+				_, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "test-import", metav1.GetOptions{})
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected the import secret is not generated, but got %v", err)
+				}
+
+				cluster := &clusterv1.ManagedCluster{}
+				if err := client.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				condition := meta.FindStatusCondition(
+					cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+				if condition == nil || condition.Reason != constants.ConditionReasonKlusterletNamespaceInvalid {
+					t.Errorf("expected import condition reason %s, but got %v",
+						constants.ConditionReasonKlusterletNamespaceInvalid, condition)
+				}
+			},
+		},
+		{
+			name: "invalid kubeconfig context name",
+			clientObjs: []runtimeclient.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							constants.KubeconfigContextNameAnnotation: "Invalid_Context",
+						},
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface) {
+				_, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "test-import", metav1.GetOptions{})
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected the import secret is not generated, but got %v", err)
+				}
+
+				cluster := &clusterv1.ManagedCluster{}
+				if err := client.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				condition := meta.FindStatusCondition(
+					cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+				if condition == nil || condition.Reason != constants.ConditionReasonKubeconfigContextNameInvalid {
+					t.Errorf("expected import condition reason %s, but got %v",
+						constants.ConditionReasonKubeconfigContextNameInvalid, condition)
+				}
+			},
+		},
+		{
+			name: "invalid bootstrap kubeconfig renewal lead time",
+			clientObjs: []runtimeclient.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							constants.BootstrapKubeConfigRenewalLeadTimeAnnotation: "1s",
+						},
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface) {
+				_, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "test-import", metav1.GetOptions{})
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected the import secret is not generated, but got %v", err)
+				}
+
+				cluster := &clusterv1.ManagedCluster{}
+				if err := client.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				condition := meta.FindStatusCondition(
+					cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+				if condition == nil ||
+					condition.Reason != constants.ConditionReasonBootstrapKubeConfigRenewalLeadTimeInvalid {
+					t.Errorf("expected import condition reason %s, but got %v",
+						constants.ConditionReasonBootstrapKubeConfigRenewalLeadTimeInvalid, condition)
+				}
+			},
+		},
+		{
+			name: "invalid image digest policy",
+			clientObjs: []runtimeclient.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							constants.KlusterletImageDigestPolicyAnnotation: "Always",
+						},
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				testinghelpers.GetImportSecret("test"),
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface) {
+				_, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "test-import", metav1.GetOptions{})
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected the import secret is deleted, but got %v", err)
+				}
+
+				cluster := &clusterv1.ManagedCluster{}
+				if err := client.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				condition := meta.FindStatusCondition(
+					cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+				if condition == nil || condition.Reason != constants.ConditionReasonImageDigestPolicyInvalid {
+					t.Errorf("expected import condition reason %s, but got %v",
+						constants.ConditionReasonImageDigestPolicyInvalid, condition)
+				}
+			},
+		},
+		{
+			name: "klusterlet images are not digest pinned",
+			clientObjs: []runtimeclient.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							constants.KlusterletImageDigestPolicyAnnotation: constants.ImageDigestPolicyRequired,
+						},
+					},
+				},
+				&configv1.Infrastructure{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "cluster",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				// the import secret that is generated before the policy is set
+				testinghelpers.GetImportSecret("test"),
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa",
+						Namespace: "test",
+					},
+					Secrets: []corev1.ObjectReference{
+						{
+							Name:      "test-bootstrap-sa-token-5pw5c",
+							Namespace: "test",
+						},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa-token-5pw5c",
+						Namespace: "test",
+					},
+					Data: map[string][]byte{
+						"token": []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeServiceAccountToken,
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "kube-root-ca.crt",
+						Namespace: "test",
+					},
+					Data: map[string]string{
+						"ca.crt": string(rootCACertData),
+					},
+				},
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface) {
+				// the default images of the tests are referenced by tag
+				_, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "test-import", metav1.GetOptions{})
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected the import secret is deleted, but got %v", err)
+				}
+
+				cluster := &clusterv1.ManagedCluster{}
+				if err := client.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				condition := meta.FindStatusCondition(
+					cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+				if condition == nil || condition.Reason != constants.ConditionReasonImagesNotDigestPinned {
+					t.Errorf("expected import condition reason %s, but got %v",
+						constants.ConditionReasonImagesNotDigestPinned, condition)
+				}
+			},
+		},
+		{
+			name: "customize kubeconfig context name",
+			clientObjs: []runtimeclient.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							constants.KubeconfigContextNameAnnotation: "test-context",
+						},
+					},
+				},
+				&configv1.Infrastructure{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "cluster",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa",
+						Namespace: "test",
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa-token-5pw5c",
+						Namespace: "test",
+					},
+					Data: map[string][]byte{
+						"token": []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeServiceAccountToken,
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "kube-root-ca.crt",
+						Namespace: "test",
+					},
+					Data: map[string]string{
+						"ca.crt": string(rootCACertData),
+					},
+				},
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface) {
+				importSecret, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "test-import", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				kubeConfigData := extractBootstrapKubeConfigDataFromImportSecret(importSecret)
+				config, err := clientcmd.Load(kubeConfigData)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if config.CurrentContext != "test-context" {
+					t.Errorf("expected current context test-context, but got %s", config.CurrentContext)
+				}
+				if _, ok := config.Contexts["test-context"]; !ok {
+					t.Errorf("expected context test-context in the bootstrap kubeconfig, but not found")
+				}
+			},
+		},
+		{
+			name: "custom bootstrap sa namespace",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+				&configv1.Infrastructure{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "cluster",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa",
+						Namespace: "bootstrap-sas",
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa-token-5pw5c",
+						Namespace: "bootstrap-sas",
+					},
+					Data: map[string][]byte{
+						"token": []byte("fake-token-in-bootstrap-sas"),
+					},
+					Type: corev1.SecretTypeServiceAccountToken,
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "kube-root-ca.crt",
+						Namespace: "bootstrap-sas",
+					},
+					Data: map[string]string{
+						"ca.crt": string(rootCACertData),
+					},
+				},
+			},
+			envs: map[string]string{
+				constants.BootstrapSANamespaceEnvVarName: "bootstrap-sas",
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface) {
+				importSecret, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "test-import", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				config, err := clientcmd.Load(extractBootstrapKubeConfigDataFromImportSecret(importSecret))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				for _, authInfo := range config.AuthInfos {
+					if authInfo.Token != "fake-token-in-bootstrap-sas" {
+						t.Errorf("expected the token of the bootstrap sa in bootstrap-sas, but got %s", authInfo.Token)
+					}
+				}
+			},
+		},
+		{
+			name: "custom import secret name suffix",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+				&configv1.Infrastructure{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "cluster",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa",
+						Namespace: "test",
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa-token-5pw5c",
+						Namespace: "test",
+					},
+					Data: map[string][]byte{
+						"token": []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeServiceAccountToken,
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "kube-root-ca.crt",
+						Namespace: "test",
+					},
+					Data: map[string]string{
+						"ca.crt": string(rootCACertData),
+					},
+				},
+			},
+			envs: map[string]string{
+				constants.ImportSecretNameSuffixEnvVarName: "tenant1-import",
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface) {
+				if _, err := kubeClient.CoreV1().Secrets("test").Get(
+					context.TODO(), "test-import", metav1.GetOptions{}); !errors.IsNotFound(err) {
+					t.Errorf("expected the import secret with the default suffix is not created, but got %v", err)
+				}
+
+				// the import secret with the custom suffix can be found by the name that is built elsewhere
+				importSecret, err := getImportSecret(context.TODO(), &helpers.ClientHolder{KubeClient: kubeClient}, "test")
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if importSecret.Name != "test-tenant1-import" {
+					t.Errorf("expected the import secret test-tenant1-import, but got %s", importSecret.Name)
+				}
+				if importSecret.Name != helpers.GetImportSecretName("test") {
+					t.Errorf("expected the import secret %s, but got %s", helpers.GetImportSecretName("test"),
+						importSecret.Name)
+				}
+			},
+		},
+		{
+			name: "regenerate the import secret of an old schema",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+				&configv1.Infrastructure{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "cluster",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa",
+						Namespace: "test",
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa-token-5pw5c",
+						Namespace: "test",
+					},
+					Data: map[string][]byte{
+						"token": []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeServiceAccountToken,
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "kube-root-ca.crt",
+						Namespace: "test",
+					},
+					Data: map[string]string{
+						"ca.crt": string(rootCACertData),
+					},
+				},
+				// the import secret is generated by an old controller, the bootstrap kubeconfig in it cannot be
+				// parsed with the current schema
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-import",
+						Namespace: "test",
+						Labels: map[string]string{
+							constants.ClusterImportSecretLabel: "",
+						},
+						Annotations: map[string]string{
+							constants.ImportSecretControllerVersionAnnotation: "outdated",
+						},
+					},
+					Data: map[string][]byte{
+						constants.ImportSecretImportYamlKey: []byte(constants.YamlSperator +
+							"apiVersion: v1\nkind: Secret\nmetadata:\n  name: bootstrap-hub-kubeconfig\n" +
+							"  namespace: open-cluster-management-agent\ndata:\n  kubeconfig: b2xkLXNjaGVtYQ==\n"),
+					},
+				},
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface) {
+				importSecret, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "test-import", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				version := importSecret.Annotations[constants.ImportSecretControllerVersionAnnotation]
+				if version != bootstrap.KlusterletManifestsVersion() {
+					t.Errorf("expected the import secret is regenerated to the version %s, but got %s",
+						bootstrap.KlusterletManifestsVersion(), version)
+				}
+
+				data := importSecret.Data[constants.ImportSecretImportYamlKey]
+				if len(strings.Split(strings.Replace(string(data), constants.YamlSperator, "", 1), constants.YamlSperator)) != 10 {
+					t.Errorf("expect 10 files, but failed")
+				}
+
+				config, err := clientcmd.Load(extractBootstrapKubeConfigDataFromImportSecret(importSecret))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				for _, authInfo := range config.AuthInfos {
+					if authInfo.Token != "fake-token" {
+						t.Errorf("expected the bootstrap kubeconfig is regenerated, but got token %s", authInfo.Token)
+					}
+				}
+			},
+		},
 	}
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
+			for name, value := range c.envs {
+				t.Setenv(name, value)
+			}
+
 			kubeClient := kubefake.NewSimpleClientset(c.runtimeObjs...)
 
 			// setup klusterletconfig informer
@@ -756,8 +1389,9 @@ func TestReconcile(t *testing.T) {
 			klusterletconfigLister := listerklusterletconfigv1alpha1.NewKlusterletConfigLister(klusterletconfigInformer.GetIndexer())
 
 			clientHolder := &helpers.ClientHolder{
-				KubeClient:          kubeClient,
-				RuntimeClient:       fake.NewClientBuilder().WithScheme(testscheme).WithObjects(c.clientObjs...).Build(),
+				KubeClient: kubeClient,
+				RuntimeClient: fake.NewClientBuilder().WithScheme(testscheme).WithObjects(c.clientObjs...).
+					WithStatusSubresource(&clusterv1.ManagedCluster{}).Build(),
 				ImageRegistryClient: imageregistry.NewClient(kubeClient),
 			}
 
@@ -777,3 +1411,149 @@ func TestReconcile(t *testing.T) {
 		})
 	}
 }
+
+func TestReconcileHubBootstrapMisconfigured(t *testing.T) {
+	cases := []struct {
+		name            string
+		conditions      []metav1.Condition
+		tokenRequestErr error
+		expectedReason  string
+	}{
+		{
+			name: "the token request is forbidden",
+			tokenRequestErr: errors.NewForbidden(
+				authv1.Resource("serviceaccounts/token"), "test-bootstrap-sa", fmt.Errorf("forbidden")),
+			expectedReason: constants.ConditionReasonHubBootstrapMisconfigured,
+		},
+		{
+			name: "the token request is forbidden for an imported cluster",
+			conditions: []metav1.Condition{
+				helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionTrue,
+					constants.ConditionReasonManagedClusterImported, "Import succeeded"),
+			},
+			tokenRequestErr: errors.NewForbidden(
+				authv1.Resource("serviceaccounts/token"), "test-bootstrap-sa", fmt.Errorf("forbidden")),
+			expectedReason: constants.ConditionReasonManagedClusterImported,
+		},
+		{
+			name:            "the token request is failed temporarily",
+			tokenRequestErr: errors.NewTimeoutError("timeout", 1),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			managedCluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+				},
+				Status: clusterv1.ManagedClusterStatus{
+					Conditions: c.conditions,
+				},
+			}
+
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeClient.PrependReactor(
+				"create",
+				"serviceaccounts/token",
+				func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+					return true, nil, c.tokenRequestErr
+				},
+			)
+
+			r := &ReconcileImportConfig{
+				clientHolder: &helpers.ClientHolder{
+					KubeClient: kubeClient,
+					RuntimeClient: fake.NewClientBuilder().WithScheme(testscheme).WithObjects(managedCluster).
+						WithStatusSubresource(managedCluster).Build(),
+					ImageRegistryClient: imageregistry.NewClient(kubeClient),
+				},
+				scheme:   testscheme,
+				recorder: eventstesting.NewTestingEventRecorder(t),
+			}
+
+			request := reconcile.Request{NamespacedName: types.NamespacedName{Name: "test"}}
+			if _, err := r.Reconcile(context.TODO(), request); err == nil {
+				t.Errorf("expected error, but failed")
+			}
+
+			cluster := &clusterv1.ManagedCluster{}
+			if err := r.clientHolder.RuntimeClient.Get(
+				context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			reason := ""
+			if condition := meta.FindStatusCondition(
+				cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded); condition != nil {
+				reason = condition.Reason
+			}
+			if reason != c.expectedReason {
+				t.Errorf("expected the import condition reason %q, but got %q", c.expectedReason, reason)
+			}
+		})
+	}
+}
+
+func TestCompressOversizedImportSecret(t *testing.T) {
+	importYaml := []byte(strings.Repeat("apiVersion: v1\nkind: ConfigMap\n---\n", 1000))
+	cases := []struct {
+		name               string
+		sizeLimit          int
+		expectedFits       bool
+		expectedCompressed bool
+	}{
+		{
+			name:         "import secret is under the size limit",
+			sizeLimit:    maxImportSecretSize,
+			expectedFits: true,
+		},
+		{
+			name:               "oversized import secret is compressed",
+			sizeLimit:          len(importYaml) / 2,
+			expectedFits:       true,
+			expectedCompressed: true,
+		},
+		{
+			name:               "import secret is too large",
+			sizeLimit:          10,
+			expectedFits:       false,
+			expectedCompressed: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			importSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-import",
+					Namespace: "test",
+				},
+				Data: map[string][]byte{
+					constants.ImportSecretImportYamlKey: importYaml,
+				},
+			}
+
+			fits, err := compressOversizedImportSecret(importSecret, c.sizeLimit)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if fits != c.expectedFits {
+				t.Errorf("expected fits %v, but got %v", c.expectedFits, fits)
+			}
+
+			_, compressed := importSecret.Data[constants.ImportSecretImportYamlGzipKey]
+			if compressed != c.expectedCompressed {
+				t.Errorf("expected compressed %v, but got %v", c.expectedCompressed, compressed)
+			}
+
+			data, err := helpers.GetImportYaml(importSecret)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(data) != string(importYaml) {
+				t.Errorf("expected the import.yaml is kept after the compression")
+			}
+		})
+	}
+}
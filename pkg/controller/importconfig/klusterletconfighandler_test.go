@@ -109,6 +109,53 @@ func TestEnqueueManagedClusterInKlusterletConfigAnnotation(t *testing.T) {
 				}
 			},
 		},
+		{
+			addEvent: func(h handler.EventHandler) {
+				// drain the queue, so only the requests of the update event are left
+				for queue.Len() > 0 {
+					item, _ := queue.Get()
+					queue.Done(item)
+				}
+
+				// Create a new update event for the klusterletconfig that is referenced by the managed clusters
+				evt := event.UpdateEvent{
+					ObjectOld: &klusterletconfigv1alpha1.KlusterletConfig{
+						ObjectMeta: v1.ObjectMeta{
+							Name: "test-klusterletconfig2",
+						},
+					},
+					ObjectNew: &klusterletconfigv1alpha1.KlusterletConfig{
+						ObjectMeta: v1.ObjectMeta{
+							Name: "test-klusterletconfig2",
+						},
+						Spec: klusterletconfigv1alpha1.KlusterletConfigSpec{
+							Registries: []klusterletconfigv1alpha1.Registries{
+								{
+									Source: "quay.io/open-cluster-management",
+									Mirror: "example.com/open-cluster-management",
+								},
+							},
+						},
+					},
+				}
+				h.Update(context.Background(), evt, queue)
+			},
+			verify: func(t *testing.T, queue workqueue.RateLimitingInterface) {
+				if queue.Len() != 2 {
+					t.Errorf("Expected queue length to be 2, but got %d", queue.Len())
+				}
+
+				enqueued := map[string]bool{}
+				for queue.Len() > 0 {
+					item, _ := queue.Get()
+					enqueued[item.(reconcile.Request).Name] = true
+					queue.Done(item)
+				}
+				if !enqueued["test2"] || !enqueued["test3"] {
+					t.Errorf("Expected test2 and test3 to be enqueued, but got %v", enqueued)
+				}
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -14,12 +14,14 @@ import (
 
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
+	ocinfrav1 "github.com/openshift/api/config/v1"
 	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
 )
 
@@ -41,10 +43,7 @@ func Add(mgr manager.Manager, clientHolder *helpers.ClientHolder, informerHolder
 				DeleteFunc:  func(e event.DeleteEvent) bool { return false },
 				CreateFunc:  func(e event.CreateEvent) bool { return true },
 				UpdateFunc: func(e event.UpdateEvent) bool {
-					// handle the labels changes for image registry
-					// handle the annotations changes for node placement and klusterletconfig
-					return !equality.Semantic.DeepEqual(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels()) ||
-						!equality.Semantic.DeepEqual(e.ObjectOld.GetAnnotations(), e.ObjectNew.GetAnnotations())
+					return importConfigChanged(e.ObjectOld, e.ObjectNew)
 				},
 			}),
 		).
@@ -105,6 +104,32 @@ func Add(mgr manager.Manager, clientHolder *helpers.ClientHolder, informerHolder
 				UpdateFunc:  func(e event.UpdateEvent) bool { return true },
 			}),
 		).
+		Watches(
+			&ocinfrav1.Infrastructure{},
+			&enqueueAllManagedClustersOnHubServerURLChange{
+				managedclusterIndexer: informerHolder.ManagedClusterInformer.GetIndexer(),
+			},
+			builder.WithPredicates(predicate.Funcs{
+				GenericFunc: func(e event.GenericEvent) bool { return false },
+				CreateFunc:  func(e event.CreateEvent) bool { return false },
+				DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					return e.ObjectNew.GetName() == infrastructureConfigName
+				},
+			}),
+		).
+		WatchesRawSource(
+			source.NewHubRootCASource(informerHolder.HubRootCAInformer),
+			&enqueueAllManagedClustersOnHubCAChange{
+				managedclusterIndexer: informerHolder.ManagedClusterInformer.GetIndexer(),
+			},
+			builder.WithPredicates(predicate.Funcs{
+				GenericFunc: func(e event.GenericEvent) bool { return false },
+				CreateFunc:  func(e event.CreateEvent) bool { return false },
+				DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+				UpdateFunc:  func(e event.UpdateEvent) bool { return true },
+			}),
+		).
 		WatchesRawSource(
 			source.NewImportSecretSource(informerHolder.ImportSecretInformer),
 			&source.ManagedClusterResourceEventHandler{},
@@ -123,3 +148,13 @@ func Add(mgr manager.Manager, clientHolder *helpers.ClientHolder, informerHolder
 		})
 	return controllerName, err
 }
+
+// importConfigChanged returns true if the changes of the managed cluster may affect the rendered klusterlet
+// manifests, e.g. the cluster is renamed via its display name label, so the import secret should be regenerated
+// and applied again.
+func importConfigChanged(oldCluster, newCluster client.Object) bool {
+	// handle the labels changes for image registry and cluster display name
+	// handle the annotations changes for node placement and klusterletconfig
+	return !equality.Semantic.DeepEqual(oldCluster.GetLabels(), newCluster.GetLabels()) ||
+		!equality.Semantic.DeepEqual(oldCluster.GetAnnotations(), newCluster.GetAnnotations())
+}
@@ -0,0 +1,102 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package importconfig
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+func TestImportConfigChanged(t *testing.T) {
+	cases := []struct {
+		name       string
+		oldCluster *clusterv1.ManagedCluster
+		newCluster *clusterv1.ManagedCluster
+		expected   bool
+	}{
+		{
+			name: "no changes",
+			oldCluster: &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "cluster1",
+					Labels: map[string]string{"name": "cluster1"},
+				},
+			},
+			newCluster: &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "cluster1",
+					Labels: map[string]string{"name": "cluster1"},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "status changed",
+			oldCluster: &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "cluster1",
+					Labels: map[string]string{"name": "cluster1"},
+				},
+			},
+			newCluster: &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "cluster1",
+					Labels: map[string]string{"name": "cluster1"},
+				},
+				Status: clusterv1.ManagedClusterStatus{
+					Conditions: []metav1.Condition{
+						{
+							Type:   clusterv1.ManagedClusterConditionAvailable,
+							Status: metav1.ConditionTrue,
+						},
+					},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "renamed via label",
+			oldCluster: &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "cluster1",
+					Labels: map[string]string{"name": "cluster1"},
+				},
+			},
+			newCluster: &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "cluster1",
+					Labels: map[string]string{"name": "production-east"},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "annotations changed",
+			oldCluster: &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "cluster1",
+				},
+			},
+			newCluster: &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "cluster1",
+					Annotations: map[string]string{
+						"open-cluster-management/nodeSelector": "{\"kubernetes.io/os\":\"linux\"}",
+					},
+				},
+			},
+			expected: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if changed := importConfigChanged(c.oldCluster, c.newCluster); changed != c.expected {
+				t.Errorf("expected %v, but got %v", c.expected, changed)
+			}
+		})
+	}
+}
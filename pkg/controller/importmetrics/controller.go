@@ -0,0 +1,139 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package importmetrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+var log = logf.Log.WithName(controllerName)
+
+// sweepPeriod is the period to recount all of the imported managed clusters
+const sweepPeriod = 10 * time.Minute
+
+// ReconcileImportMetrics reconciles the managed clusters to count the imported managed clusters in the hosted mode
+// and in the default mode
+type ReconcileImportMetrics struct {
+	client client.Client
+
+	lock sync.Mutex
+	// importedClusters records the klusterlet deploy modes of the imported managed clusters by cluster names
+	importedClusters map[string]operatorv1.InstallMode
+}
+
+// blank assignment to verify that ReconcileImportMetrics implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileImportMetrics{}
+
+func NewReconcileImportMetrics(client client.Client) *ReconcileImportMetrics {
+	return &ReconcileImportMetrics{
+		client:           client,
+		importedClusters: map[string]operatorv1.InstallMode{},
+	}
+}
+
+// Reconcile records whether the managed cluster is imported and in which mode it is imported, then updates the
+// imported managed clusters gauges
+func (r *ReconcileImportMetrics) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	managedCluster := &clusterv1.ManagedCluster{}
+	err := r.client.Get(ctx, types.NamespacedName{Name: request.Name}, managedCluster)
+	if errors.IsNotFound(err) {
+		// the managed cluster is deleted, forget it
+		r.record(request.Name, "", false)
+		return reconcile.Result{}, nil
+	}
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	mode, imported := importedMode(managedCluster)
+	r.record(request.Name, mode, imported)
+	return reconcile.Result{}, nil
+}
+
+// Sweep recounts all of the imported managed clusters periodically until the context is done
+func (r *ReconcileImportMetrics) Sweep(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.recount(ctx); err != nil {
+			log.Error(err, "failed to recount the imported managed clusters")
+		}
+	}, sweepPeriod)
+	return nil
+}
+
+func (r *ReconcileImportMetrics) recount(ctx context.Context) error {
+	managedClusters := &clusterv1.ManagedClusterList{}
+	if err := r.client.List(ctx, managedClusters); err != nil {
+		return err
+	}
+
+	importedClusters := map[string]operatorv1.InstallMode{}
+	for i := range managedClusters.Items {
+		if mode, imported := importedMode(&managedClusters.Items[i]); imported {
+			importedClusters[managedClusters.Items[i].Name] = mode
+		}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.importedClusters = importedClusters
+	r.publish()
+	return nil
+}
+
+func (r *ReconcileImportMetrics) record(clusterName string, mode operatorv1.InstallMode, imported bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if imported {
+		r.importedClusters[clusterName] = mode
+	} else {
+		delete(r.importedClusters, clusterName)
+	}
+	r.publish()
+}
+
+// publish sets the imported managed clusters gauges, the caller must hold the lock
+func (r *ReconcileImportMetrics) publish() {
+	hosted, dflt := 0, 0
+	for _, mode := range r.importedClusters {
+		if mode == operatorv1.InstallModeHosted {
+			hosted++
+			continue
+		}
+		dflt++
+	}
+	helpers.SetImportedClusters(hosted, dflt)
+}
+
+// importedMode returns the klusterlet deploy mode of the managed cluster if it is imported, the managed cluster
+// that is deleting is not considered as imported
+func importedMode(cluster *clusterv1.ManagedCluster) (operatorv1.InstallMode, bool) {
+	if !cluster.DeletionTimestamp.IsZero() {
+		return "", false
+	}
+
+	if !meta.IsStatusConditionTrue(cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded) {
+		return "", false
+	}
+
+	if helpers.DetermineKlusterletMode(cluster) == operatorv1.InstallModeHosted {
+		return operatorv1.InstallModeHosted, true
+	}
+	return operatorv1.InstallModeDefault, true
+}
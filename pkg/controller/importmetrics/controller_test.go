@@ -0,0 +1,119 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package importmetrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+var testscheme = scheme.Scheme
+
+func init() {
+	testscheme.AddKnownTypes(clusterv1.SchemeGroupVersion, &clusterv1.ManagedCluster{})
+	testscheme.AddKnownTypes(clusterv1.SchemeGroupVersion, &clusterv1.ManagedClusterList{})
+}
+
+func newManagedCluster(name string, mode operatorv1.InstallMode, imported bool) *clusterv1.ManagedCluster {
+	cluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+	if len(mode) != 0 {
+		cluster.Annotations = map[string]string{constants.KlusterletDeployModeAnnotation: string(mode)}
+	}
+	if imported {
+		cluster.Status.Conditions = []metav1.Condition{
+			{
+				Type:   constants.ConditionManagedClusterImportSucceeded,
+				Status: metav1.ConditionTrue,
+				Reason: constants.ConditionReasonManagedClusterImported,
+			},
+		}
+	}
+	return cluster
+}
+
+func expectedGauges(hosted, dflt string) string {
+	return `
+# HELP managedcluster_import_imported_clusters Number of the managed clusters that are imported in the hosted mode or in the default mode
+# TYPE managedcluster_import_imported_clusters gauge
+managedcluster_import_imported_clusters{mode="default"} ` + dflt + `
+managedcluster_import_imported_clusters{mode="hosted"} ` + hosted + `
+`
+}
+
+func TestReconcile(t *testing.T) {
+	fleet := []client.Object{
+		newManagedCluster("default1", "", true),
+		newManagedCluster("default2", operatorv1.InstallModeDefault, true),
+		newManagedCluster("singleton1", operatorv1.InstallModeSingleton, true),
+		newManagedCluster("hosted1", operatorv1.InstallModeHosted, true),
+		newManagedCluster("hosted2", operatorv1.InstallModeHosted, true),
+		newManagedCluster("importing1", "", false),
+		newManagedCluster("importing2", operatorv1.InstallModeHosted, false),
+	}
+
+	r := NewReconcileImportMetrics(fake.NewClientBuilder().WithScheme(testscheme).WithObjects(fleet...).Build())
+
+	for _, cluster := range fleet {
+		if _, err := r.Reconcile(context.TODO(),
+			reconcile.Request{NamespacedName: types.NamespacedName{Name: cluster.GetName()}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := testutil.GatherAndCompare(metrics.Registry, strings.NewReader(expectedGauges("2", "3")),
+		"managedcluster_import_imported_clusters"); err != nil {
+		t.Errorf("unexpected gauges after reconciling the fleet: %v", err)
+	}
+
+	// a hosted managed cluster is deleted
+	if err := r.client.Delete(context.TODO(), fleet[3]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.Reconcile(context.TODO(),
+		reconcile.Request{NamespacedName: types.NamespacedName{Name: "hosted1"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := testutil.GatherAndCompare(metrics.Registry, strings.NewReader(expectedGauges("1", "3")),
+		"managedcluster_import_imported_clusters"); err != nil {
+		t.Errorf("unexpected gauges after a hosted cluster is deleted: %v", err)
+	}
+}
+
+func TestRecount(t *testing.T) {
+	fleet := []client.Object{
+		newManagedCluster("default1", "", true),
+		newManagedCluster("hosted1", operatorv1.InstallModeHosted, true),
+		newManagedCluster("hosted2", operatorv1.InstallModeHosted, true),
+		newManagedCluster("importing1", "", false),
+	}
+
+	r := NewReconcileImportMetrics(fake.NewClientBuilder().WithScheme(testscheme).WithObjects(fleet...).Build())
+	// a stale record of a managed cluster that was deleted without a reconcile
+	r.importedClusters["stale"] = operatorv1.InstallModeDefault
+
+	if err := r.recount(context.TODO()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := testutil.GatherAndCompare(metrics.Registry, strings.NewReader(expectedGauges("2", "1")),
+		"managedcluster_import_imported_clusters"); err != nil {
+		t.Errorf("unexpected gauges after the sweep: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package importmetrics
+
+import (
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/source"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const controllerName = "importmetrics-controller"
+
+// Add creates a new importmetrics controller and adds it to the Manager.
+// The Manager will set fields on the Controller and Start it when the Manager is Started.
+func Add(mgr manager.Manager, clientHolder *helpers.ClientHolder, _ *source.InformerHolder) (string, error) {
+	r := NewReconcileImportMetrics(clientHolder.RuntimeClient)
+
+	// recount the imported managed clusters periodically, so the gauges are corrected even if some events are missed
+	if err := mgr.Add(manager.RunnableFunc(r.Sweep)); err != nil {
+		return controllerName, err
+	}
+
+	err := ctrl.NewControllerManagedBy(mgr).Named(controllerName).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: helpers.GetMaxConcurrentReconciles(),
+		}).
+		Watches(
+			&clusterv1.ManagedCluster{},
+			&handler.EnqueueRequestForObject{},
+			builder.WithPredicates(predicate.Funcs{
+				GenericFunc: func(e event.GenericEvent) bool { return false },
+				DeleteFunc:  func(e event.DeleteEvent) bool { return true },
+				CreateFunc:  func(e event.CreateEvent) bool { return true },
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					return importStateChanged(e.ObjectOld, e.ObjectNew)
+				},
+			}),
+		).
+		Complete(r)
+
+	return controllerName, err
+}
+
+// importStateChanged returns true if the import condition, the klusterlet deploy mode or the deletion timestamp
+// of the managed cluster is changed
+func importStateChanged(oldObj, newObj client.Object) bool {
+	oldCluster, okOld := oldObj.(*clusterv1.ManagedCluster)
+	newCluster, okNew := newObj.(*clusterv1.ManagedCluster)
+	if !okOld || !okNew {
+		return false
+	}
+
+	if helpers.DetermineKlusterletMode(oldCluster) != helpers.DetermineKlusterletMode(newCluster) {
+		return true
+	}
+
+	if oldCluster.DeletionTimestamp.IsZero() != newCluster.DeletionTimestamp.IsZero() {
+		return true
+	}
+
+	return meta.IsStatusConditionTrue(oldCluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded) !=
+		meta.IsStatusConditionTrue(newCluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+}
@@ -6,7 +6,10 @@ package importstatus
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
+	hivev1 "github.com/openshift/hive/apis/hive/v1"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -16,6 +19,7 @@ import (
 	workclient "open-cluster-management.io/api/client/work/clientset/versioned"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	operatorv1 "open-cluster-management.io/api/operator/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -26,6 +30,10 @@ import (
 
 var log = logf.Log.WithName(controllerName)
 
+// worksApplyStuckThreshold is the duration that the klusterlet manifestworks can stay in not applied, after
+// that, the manifestworks are considered stuck
+const worksApplyStuckThreshold = 5 * time.Minute
+
 // ReconcileImportStatus reconciles the klusterlet manifestworks to judge whether the cluster is imported successfully
 type ReconcileImportStatus struct {
 	client     client.Client
@@ -39,8 +47,6 @@ var _ reconcile.Reconciler = &ReconcileImportStatus{}
 
 // Reconcile sets the manged cluster import condition according to the klusterlet manifestwork status
 func (r *ReconcileImportStatus) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
-	reqLogger := log.WithValues("Request.Name", request.Name)
-
 	managedClusterName := request.Name
 	managedCluster := &clusterv1.ManagedCluster{}
 	err := r.client.Get(ctx, types.NamespacedName{Name: managedClusterName}, managedCluster)
@@ -60,15 +66,58 @@ func (r *ReconcileImportStatus) Reconcile(ctx context.Context, request reconcile
 		return reconcile.Result{}, nil
 	}
 
-	// This controller will only add/update the ImportSucceededCondition condition in following 2 cases:
+	result, err := r.reconcileImportCondition(ctx, managedCluster)
+	if err != nil {
+		return result, err
+	}
+
+	// summarize the import and registration conditions after the import condition is reconciled
+	if err := r.updateImportAndRegistrationReadyCondition(ctx, managedClusterName); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	return result, nil
+}
+
+// reconcileImportCondition sets the import condition of the managed cluster according to the klusterlet
+// manifestworks status
+func (r *ReconcileImportStatus) reconcileImportCondition(ctx context.Context,
+	managedCluster *clusterv1.ManagedCluster) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Name", managedCluster.Name)
+	managedClusterName := managedCluster.Name
+
+	workNames := []string{
+		fmt.Sprintf("%s-%s", managedClusterName, constants.KlusterletCRDsSuffix),
+		fmt.Sprintf("%s-%s", managedClusterName, constants.KlusterletSuffix),
+	}
+
+	works := []*workv1.ManifestWork{}
+	for _, name := range workNames {
+		work, err := r.workClient.WorkV1().ManifestWorks(managedClusterName).Get(ctx, name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if result, pending, rbacErr := helpers.RequeueOnHubNamespaceRBACPending(
+			r.client, managedCluster, err); pending {
+			reqLogger.Info("The RBAC of the managed cluster namespace is not ready", "error", err)
+			return result, rbacErr
+		}
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		works = append(works, work)
+	}
+
+	// This controller will only add/update the ImportSucceededCondition condition in following 3 cases:
 	// - Add the condition when it does not exist
+	// - Restore the condition from the klusterlet manifestworks when the works exist but the condition is lost
 	// - Set the condition status to True when manifestworks are available
 	//
 	// Will NOT change the condition in other situation, otherwise there will be a changing loop on the
 	// condition with other controllers
 	existedCondition := meta.FindStatusCondition(
 		managedCluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
-	if existedCondition == nil {
+	if existedCondition == nil && len(works) == 0 {
 		reqLogger.V(5).Info("Add ImportSucceededCondition with WaitForImporting reason")
 		return reconcile.Result{}, helpers.UpdateManagedClusterStatus(
 			r.client,
@@ -81,21 +130,60 @@ func (r *ReconcileImportStatus) Reconcile(ctx context.Context, request reconcile
 		)
 	}
 
-	available, err := helpers.IsManifestWorksAvailable(ctx, r.workClient, managedClusterName,
-		fmt.Sprintf("%s-%s", managedClusterName, constants.KlusterletCRDsSuffix),
-		fmt.Sprintf("%s-%s", managedClusterName, constants.KlusterletSuffix))
-	if err != nil {
-		reqLogger.V(5).Info("Check klusterlet manifestworks availability failed", "error", err)
+	// the klusterlet works exist but the condition is missing, the status of the managed cluster may be wiped,
+	// re-evaluate the condition from the works to restore it
+	if existedCondition == nil {
+		reqLogger.Info("The import condition is missing while the klusterlet manifestworks exist, restore it")
+	}
+
+	stuckCondition, recheckAfter := newKlusterletWorksApplyStuckCondition(works, time.Now())
+	if err := helpers.UpdateManagedClusterStatus(r.client, managedClusterName, stuckCondition); err != nil {
 		return reconcile.Result{}, err
 	}
-	if !available {
 
+	// the foreign klusterlet works can be only identified when the identity of this hub is specified
+	if len(helpers.GetHubIdentity()) != 0 {
+		klusterletWorks, err := r.workClient.WorkV1().ManifestWorks(managedClusterName).List(ctx, metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("%s=true", constants.KlusterletWorksLabel),
+		})
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if err := helpers.UpdateManagedClusterStatus(
+			r.client,
+			managedClusterName,
+			newForeignKlusterletWorksPresentCondition(klusterletWorks.Items),
+		); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	// the works are fetched above, check their availability directly instead of fetching them again
+	availableCondition := newKlusterletWorksAvailableCondition(
+		klusterletWorksAvailabilityPolicy(managedCluster), workNames, works)
+	if err := helpers.UpdateManagedClusterStatus(r.client, managedClusterName, availableCondition); err != nil {
+		return reconcile.Result{}, err
+	}
+	if availableCondition.Status != metav1.ConditionTrue {
 		reqLogger.V(5).Info("Klusterlet manifestworks are not available")
-		return reconcile.Result{}, nil
+		if existedCondition == nil {
+			if err := helpers.UpdateManagedClusterStatus(
+				r.client,
+				managedClusterName,
+				helpers.NewManagedClusterImportSucceededCondition(
+					metav1.ConditionFalse,
+					constants.ConditionReasonManagedClusterImporting,
+					"Wait for the klusterlet manifestworks to be available",
+				),
+			); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+		return reconcile.Result{RequeueAfter: recheckAfter}, nil
 	}
 
 	reqLogger.V(5).Info("Klusterlet manifestworks are available")
-	return reconcile.Result{}, helpers.UpdateManagedClusterStatus(
+	if err := helpers.UpdateManagedClusterStatus(
 		r.client,
 		managedClusterName,
 		helpers.NewManagedClusterImportSucceededCondition(
@@ -103,5 +191,301 @@ func (r *ReconcileImportStatus) Reconcile(ctx context.Context, request reconcile
 			constants.ConditionReasonManagedClusterImported,
 			"Import succeeded",
 		),
+	); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err := helpers.ResetImportFailedAttempts(ctx, r.client, managedCluster); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if existedCondition == nil || existedCondition.Status != metav1.ConditionTrue {
+		// the managed cluster becomes imported, record the time of this successful import. A restored condition
+		// is skipped, the cluster may have been imported long ago
+		if existedCondition != nil {
+			if err := helpers.UpdateManagedClusterStatus(
+				r.client, managedClusterName, newLastImportSucceededCondition(time.Now())); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+
+		// the auto import secret is not needed after the cluster is imported, delete it unless it is kept
+		if err := r.cleanupAutoImportSecret(ctx, managedClusterName); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	// only observe the duration when the cluster becomes imported, otherwise the same cluster will be
+	// observed repeatedly. A restored condition is skipped, the cluster may have been imported long ago
+	if existedCondition != nil && existedCondition.Status != metav1.ConditionTrue {
+		r.observeInstalledToImportedDuration(ctx, managedClusterName, time.Now())
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// newLastImportSucceededCondition returns the LastImportSucceeded condition that records the time of the successful
+// import in its message, the last transition time of the condition is kept once it is set, so it is the time of
+// the first successful import.
+func newLastImportSucceededCondition(importedTime time.Time) metav1.Condition {
+	return metav1.Condition{
+		Type:   constants.ConditionLastImportSucceeded,
+		Status: metav1.ConditionTrue,
+		Reason: constants.ConditionReasonLastImportSucceeded,
+		Message: fmt.Sprintf("The managed cluster was imported successfully at %s",
+			importedTime.UTC().Format(time.RFC3339)),
+	}
+}
+
+// observeInstalledToImportedDuration observes the duration between the clusterdeployment of the managed cluster
+// is installed and the managed cluster is imported, the cluster that is not provisioned by hive is ignored.
+func (r *ReconcileImportStatus) observeInstalledToImportedDuration(
+	ctx context.Context, managedClusterName string, importedTime time.Time) {
+	clusterDeployment := &hivev1.ClusterDeployment{}
+	err := r.client.Get(ctx,
+		types.NamespacedName{Name: managedClusterName, Namespace: managedClusterName}, clusterDeployment)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Info("Failed to get the clusterdeployment, skip observing the installed to imported duration",
+				"managedcluster", managedClusterName, "error", err.Error())
+		}
+		return
+	}
+
+	if !clusterDeployment.Spec.Installed || clusterDeployment.Status.InstalledTimestamp == nil {
+		return
+	}
+
+	helpers.ObserveClusterDeploymentInstalledToImported(clusterDeployment.Status.InstalledTimestamp.Time, importedTime)
+}
+
+// updateImportAndRegistrationReadyCondition updates the ImportAndRegistrationReady condition from the latest
+// managed cluster, the conditions of the managed cluster may be changed in the current reconcile
+func (r *ReconcileImportStatus) updateImportAndRegistrationReadyCondition(
+	ctx context.Context, managedClusterName string) error {
+	managedCluster := &clusterv1.ManagedCluster{}
+	if err := r.client.Get(ctx, types.NamespacedName{Name: managedClusterName}, managedCluster); err != nil {
+		return err
+	}
+
+	return helpers.UpdateManagedClusterStatus(
+		r.client,
+		managedClusterName,
+		newImportAndRegistrationReadyCondition(managedCluster),
 	)
 }
+
+// newImportAndRegistrationReadyCondition combines the import condition and the registration conditions
+// (HubAcceptedManagedCluster, ManagedClusterJoined and ManagedClusterConditionAvailable) of the managed
+// cluster into one condition
+func newImportAndRegistrationReadyCondition(managedCluster *clusterv1.ManagedCluster) metav1.Condition {
+	conditions := managedCluster.Status.Conditions
+
+	importCondition := meta.FindStatusCondition(conditions, constants.ConditionManagedClusterImportSucceeded)
+	if importCondition == nil || importCondition.Status != metav1.ConditionTrue {
+		message := "The managed cluster is not imported"
+		if importCondition != nil {
+			message = fmt.Sprintf("The managed cluster is not imported: %s", importCondition.Message)
+		}
+		return metav1.Condition{
+			Type:    constants.ConditionManagedClusterImportAndRegistrationReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  constants.ConditionReasonManagedClusterNotImported,
+			Message: message,
+		}
+	}
+
+	registrationConditions := []struct {
+		conditionType string
+		reason        string
+		message       string
+	}{
+		{
+			conditionType: clusterv1.ManagedClusterConditionHubAccepted,
+			reason:        constants.ConditionReasonManagedClusterNotAccepted,
+			message:       "The managed cluster is not accepted by the hub",
+		},
+		{
+			conditionType: clusterv1.ManagedClusterConditionJoined,
+			reason:        constants.ConditionReasonManagedClusterNotJoined,
+			message:       "The managed cluster has not joined the hub",
+		},
+		{
+			conditionType: clusterv1.ManagedClusterConditionAvailable,
+			reason:        constants.ConditionReasonManagedClusterNotAvailable,
+			message:       "The managed cluster is not available",
+		},
+	}
+	for _, rc := range registrationConditions {
+		if !meta.IsStatusConditionTrue(conditions, rc.conditionType) {
+			return metav1.Condition{
+				Type:    constants.ConditionManagedClusterImportAndRegistrationReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  rc.reason,
+				Message: rc.message,
+			}
+		}
+	}
+
+	return metav1.Condition{
+		Type:    constants.ConditionManagedClusterImportAndRegistrationReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  constants.ConditionReasonImportAndRegistrationReady,
+		Message: "The managed cluster is imported and registered",
+	}
+}
+
+// klusterletWorksAvailabilityPolicy returns the klusterlet works availability policy of the managed cluster, the
+// WaitForAll policy is used if the annotation is not set or its value is invalid.
+func klusterletWorksAvailabilityPolicy(managedCluster *clusterv1.ManagedCluster) string {
+	policy, ok := managedCluster.Annotations[constants.KlusterletWorksAvailabilityPolicyAnnotation]
+	if !ok {
+		return constants.KlusterletWorksAvailabilityPolicyWaitForAll
+	}
+
+	switch policy {
+	case constants.KlusterletWorksAvailabilityPolicyWaitForAll, constants.KlusterletWorksAvailabilityPolicyProceedOnFirst:
+		return policy
+	}
+
+	log.Info("Ignore the invalid klusterlet works availability policy", "managedcluster", managedCluster.Name,
+		"policy", policy)
+	return constants.KlusterletWorksAvailabilityPolicyWaitForAll
+}
+
+// newKlusterletWorksAvailableCondition checks the availability of the klusterlet manifestworks with the given
+// policy. With the WaitForAll policy, the condition is true only when all of the expected works are available,
+// with the ProceedOnFirst policy, the condition is true once one of the works is available.
+func newKlusterletWorksAvailableCondition(policy string, workNames []string,
+	works []*workv1.ManifestWork) metav1.Condition {
+	availableWorks := []string{}
+	for _, work := range works {
+		if meta.IsStatusConditionTrue(work.Status.Conditions, workv1.WorkAvailable) {
+			availableWorks = append(availableWorks, work.Name)
+		}
+	}
+
+	switch {
+	case len(availableWorks) == len(workNames):
+		return metav1.Condition{
+			Type:    constants.ConditionKlusterletWorksAvailable,
+			Status:  metav1.ConditionTrue,
+			Reason:  constants.ConditionReasonKlusterletWorksAllAvailable,
+			Message: "All of the klusterlet manifestworks are available",
+		}
+	case len(availableWorks) == 0:
+		return metav1.Condition{
+			Type:    constants.ConditionKlusterletWorksAvailable,
+			Status:  metav1.ConditionFalse,
+			Reason:  constants.ConditionReasonKlusterletWorksNotAvailable,
+			Message: "The klusterlet manifestworks are not available",
+		}
+	}
+
+	status := metav1.ConditionFalse
+	message := fmt.Sprintf("The klusterlet manifestworks %s are available, wait for all of the works to be available "+
+		"with the %s policy", strings.Join(availableWorks, ", "), policy)
+	if policy == constants.KlusterletWorksAvailabilityPolicyProceedOnFirst {
+		status = metav1.ConditionTrue
+		message = fmt.Sprintf("The klusterlet manifestworks %s are available, proceed with the %s policy",
+			strings.Join(availableWorks, ", "), policy)
+	}
+
+	return metav1.Condition{
+		Type:    constants.ConditionKlusterletWorksAvailable,
+		Status:  status,
+		Reason:  constants.ConditionReasonKlusterletWorksPartiallyAvailable,
+		Message: message,
+	}
+}
+
+// newKlusterletWorksApplyStuckCondition checks whether the klusterlet manifestworks stay in not applied over the
+// worksApplyStuckThreshold, if there are works that are not applied but not stuck yet, the duration to recheck
+// them is returned.
+func newKlusterletWorksApplyStuckCondition(works []*workv1.ManifestWork, now time.Time) (metav1.Condition, time.Duration) {
+	var recheckAfter time.Duration
+	for _, work := range works {
+		notAppliedSince := work.CreationTimestamp.Time
+		reason := constants.ConditionReasonKlusterletWorksNotApplied
+		message := fmt.Sprintf("The manifestwork %s is not applied", work.Name)
+
+		appliedCondition := meta.FindStatusCondition(work.Status.Conditions, workv1.WorkApplied)
+		if appliedCondition != nil {
+			if appliedCondition.Status == metav1.ConditionTrue {
+				continue
+			}
+
+			notAppliedSince = appliedCondition.LastTransitionTime.Time
+			if len(appliedCondition.Reason) != 0 {
+				reason = appliedCondition.Reason
+			}
+			if len(appliedCondition.Message) != 0 {
+				message = fmt.Sprintf("The manifestwork %s is not applied: %s", work.Name, appliedCondition.Message)
+			}
+		}
+
+		notAppliedDuration := now.Sub(notAppliedSince)
+		if notAppliedDuration >= worksApplyStuckThreshold {
+			return metav1.Condition{
+				Type:    constants.ConditionKlusterletWorksApplyStuck,
+				Status:  metav1.ConditionTrue,
+				Reason:  reason,
+				Message: message,
+			}, 0
+		}
+
+		if remaining := worksApplyStuckThreshold - notAppliedDuration; recheckAfter == 0 || remaining < recheckAfter {
+			recheckAfter = remaining
+		}
+	}
+
+	return metav1.Condition{
+		Type:    constants.ConditionKlusterletWorksApplyStuck,
+		Status:  metav1.ConditionFalse,
+		Reason:  constants.ConditionReasonKlusterletWorksNotStuck,
+		Message: "The klusterlet manifestworks are not stuck in applying",
+	}, recheckAfter
+}
+
+// newForeignKlusterletWorksPresentCondition checks whether there are klusterlet manifestworks that are created by
+// other hubs in the managed cluster namespace, these works are ignored when importing the cluster.
+func newForeignKlusterletWorksPresentCondition(works []workv1.ManifestWork) metav1.Condition {
+	foreignWorks := []string{}
+	for i := range works {
+		if helpers.IsForeignKlusterletWork(&works[i]) {
+			foreignWorks = append(foreignWorks, works[i].Name)
+		}
+	}
+
+	if len(foreignWorks) == 0 {
+		return metav1.Condition{
+			Type:    constants.ConditionForeignKlusterletWorksPresent,
+			Status:  metav1.ConditionFalse,
+			Reason:  constants.ConditionReasonForeignKlusterletWorksNotFound,
+			Message: "There are no klusterlet manifestworks from other hubs",
+		}
+	}
+
+	return metav1.Condition{
+		Type:   constants.ConditionForeignKlusterletWorksPresent,
+		Status: metav1.ConditionTrue,
+		Reason: constants.ConditionReasonForeignKlusterletWorksFound,
+		Message: fmt.Sprintf("The klusterlet manifestworks %s are created by other hubs, they are ignored",
+			strings.Join(foreignWorks, ", ")),
+	}
+}
+
+// cleanupAutoImportSecret deletes the auto import secret of the managed cluster if it still exists, the secret
+// that has the keeping auto import secret annotation is retained
+func (r *ReconcileImportStatus) cleanupAutoImportSecret(ctx context.Context, managedClusterName string) error {
+	autoImportSecret, err := r.kubeClient.CoreV1().Secrets(managedClusterName).Get(
+		ctx, constants.AutoImportSecretName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return helpers.DeleteAutoImportSecret(ctx, r.kubeClient, autoImportSecret, r.recorder)
+}
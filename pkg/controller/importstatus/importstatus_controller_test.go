@@ -5,6 +5,7 @@ package importstatus
 
 import (
 	"context"
+	"fmt"
 	operatorv1 "open-cluster-management.io/api/operator/v1"
 	"testing"
 	"time"
@@ -12,19 +13,24 @@ import (
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
 
+	hivev1 "github.com/openshift/hive/apis/hive/v1"
 	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
 	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
 	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	workv1 "open-cluster-management.io/api/work/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -32,6 +38,7 @@ var testscheme = scheme.Scheme
 
 func init() {
 	testscheme.AddKnownTypes(clusterv1.SchemeGroupVersion, &clusterv1.ManagedCluster{})
+	testscheme.AddKnownTypes(hivev1.SchemeGroupVersion, &hivev1.ClusterDeployment{})
 }
 
 func TestReconcile(t *testing.T) {
@@ -90,6 +97,96 @@ func TestReconcile(t *testing.T) {
 			works:       []runtime.Object{},
 			expectedErr: false,
 		},
+		{
+			name: "import condition missing with available manifestworks",
+			objs: []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: managedClusterName,
+					},
+				},
+			},
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet-crds",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+					Status: workv1.ManifestWorkStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   workv1.WorkApplied,
+								Status: metav1.ConditionTrue,
+							},
+							{
+								Type:   workv1.WorkAvailable,
+								Status: metav1.ConditionTrue,
+							},
+						},
+					},
+				},
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+					Status: workv1.ManifestWorkStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   workv1.WorkApplied,
+								Status: metav1.ConditionTrue,
+							},
+							{
+								Type:   workv1.WorkAvailable,
+								Status: metav1.ConditionTrue,
+							},
+						},
+					},
+				},
+			},
+			expectedErr:             false,
+			expectedConditionStatus: metav1.ConditionTrue,
+			expectedConditionReason: constants.ConditionReasonManagedClusterImported,
+		},
+		{
+			name: "import condition missing with unavailable manifestworks",
+			objs: []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: managedClusterName,
+					},
+				},
+			},
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet-crds",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+			},
+			expectedErr:             false,
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonManagedClusterImporting,
+		},
 		{
 			name: "managed cluster import condition not running",
 			objs: []client.Object{
@@ -263,3 +360,1038 @@ func TestReconcile(t *testing.T) {
 		})
 	}
 }
+
+func TestImportAndRegistrationReadyCondition(t *testing.T) {
+	managedClusterName := "test"
+	importedCondition := helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionTrue,
+		constants.ConditionReasonManagedClusterImported, "Import succeeded")
+	acceptedCondition := metav1.Condition{
+		Type:   clusterv1.ManagedClusterConditionHubAccepted,
+		Status: metav1.ConditionTrue,
+		Reason: "HubClusterAdminAccepted",
+	}
+	joinedCondition := metav1.Condition{
+		Type:   clusterv1.ManagedClusterConditionJoined,
+		Status: metav1.ConditionTrue,
+		Reason: "ManagedClusterJoined",
+	}
+	availableCondition := metav1.Condition{
+		Type:   clusterv1.ManagedClusterConditionAvailable,
+		Status: metav1.ConditionTrue,
+		Reason: "ManagedClusterAvailable",
+	}
+	unavailableCondition := metav1.Condition{
+		Type:   clusterv1.ManagedClusterConditionAvailable,
+		Status: metav1.ConditionUnknown,
+		Reason: "ManagedClusterLeaseUpdateStopped",
+	}
+
+	cases := []struct {
+		name                    string
+		conditions              []metav1.Condition
+		expectedConditionStatus metav1.ConditionStatus
+		expectedConditionReason string
+	}{
+		{
+			name:                    "not imported",
+			conditions:              []metav1.Condition{},
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonManagedClusterNotImported,
+		},
+		{
+			name: "importing",
+			conditions: []metav1.Condition{
+				helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+					constants.ConditionReasonManagedClusterImporting, "test"),
+				acceptedCondition,
+				joinedCondition,
+				availableCondition,
+			},
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonManagedClusterNotImported,
+		},
+		{
+			name:                    "imported but not accepted",
+			conditions:              []metav1.Condition{importedCondition},
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonManagedClusterNotAccepted,
+		},
+		{
+			name:                    "imported but not joined",
+			conditions:              []metav1.Condition{importedCondition, acceptedCondition},
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonManagedClusterNotJoined,
+		},
+		{
+			name: "imported but not available",
+			conditions: []metav1.Condition{
+				importedCondition,
+				acceptedCondition,
+				joinedCondition,
+				unavailableCondition,
+			},
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonManagedClusterNotAvailable,
+		},
+		{
+			name: "imported and registered",
+			conditions: []metav1.Condition{
+				importedCondition,
+				acceptedCondition,
+				joinedCondition,
+				availableCondition,
+			},
+			expectedConditionStatus: metav1.ConditionTrue,
+			expectedConditionReason: constants.ConditionReasonImportAndRegistrationReady,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			managedCluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: managedClusterName,
+				},
+				Status: clusterv1.ManagedClusterStatus{
+					Conditions: c.conditions,
+				},
+			}
+
+			r := ReconcileImportStatus{
+				client: fake.NewClientBuilder().WithScheme(testscheme).
+					WithObjects(managedCluster).WithStatusSubresource(managedCluster).Build(),
+				kubeClient: kubefake.NewSimpleClientset(),
+				workClient: workfake.NewSimpleClientset(),
+				recorder:   eventstesting.NewTestingEventRecorder(t),
+			}
+
+			ctx := context.TODO()
+			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: managedClusterName}}
+			if _, err := r.Reconcile(ctx, req); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			cluster := &clusterv1.ManagedCluster{}
+			if err := r.client.Get(ctx, types.NamespacedName{Name: managedClusterName}, cluster); err != nil {
+				t.Errorf("get managed cluster error: %v", err)
+			}
+			condition := meta.FindStatusCondition(cluster.Status.Conditions,
+				constants.ConditionManagedClusterImportAndRegistrationReady)
+			if condition == nil {
+				t.Fatalf("Expect condition %s, but not found",
+					constants.ConditionManagedClusterImportAndRegistrationReady)
+			}
+			if condition.Status != c.expectedConditionStatus {
+				t.Errorf("Expect condition status %s, got %s", c.expectedConditionStatus, condition.Status)
+			}
+			if condition.Reason != c.expectedConditionReason {
+				t.Errorf("Expect condition reason %s, got %s, message: %s",
+					c.expectedConditionReason, condition.Reason, condition.Message)
+			}
+		})
+	}
+}
+
+func TestImportAndRegistrationReadyConditionAfterImported(t *testing.T) {
+	managedClusterName := "test"
+	managedCluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: managedClusterName,
+		},
+		Status: clusterv1.ManagedClusterStatus{
+			Conditions: []metav1.Condition{
+				helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+					constants.ConditionReasonManagedClusterImporting, "test"),
+				{
+					Type:   clusterv1.ManagedClusterConditionHubAccepted,
+					Status: metav1.ConditionTrue,
+					Reason: "HubClusterAdminAccepted",
+				},
+				{
+					Type:   clusterv1.ManagedClusterConditionJoined,
+					Status: metav1.ConditionTrue,
+					Reason: "ManagedClusterJoined",
+				},
+				{
+					Type:   clusterv1.ManagedClusterConditionAvailable,
+					Status: metav1.ConditionTrue,
+					Reason: "ManagedClusterAvailable",
+				},
+			},
+		},
+	}
+
+	works := []runtime.Object{}
+	for _, name := range []string{"test-klusterlet-crds", "test-klusterlet"} {
+		works = append(works, &workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: managedClusterName,
+			},
+			Status: workv1.ManifestWorkStatus{
+				Conditions: []metav1.Condition{
+					{
+						Type:   workv1.WorkApplied,
+						Status: metav1.ConditionTrue,
+					},
+					{
+						Type:   workv1.WorkAvailable,
+						Status: metav1.ConditionTrue,
+					},
+				},
+			},
+		})
+	}
+
+	r := ReconcileImportStatus{
+		client: fake.NewClientBuilder().WithScheme(testscheme).
+			WithObjects(managedCluster).WithStatusSubresource(managedCluster).Build(),
+		kubeClient: kubefake.NewSimpleClientset(),
+		workClient: workfake.NewSimpleClientset(works...),
+		recorder:   eventstesting.NewTestingEventRecorder(t),
+	}
+
+	ctx := context.TODO()
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: managedClusterName}}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the cluster becomes imported in this reconcile, the summary condition should reflect it at once
+	cluster := &clusterv1.ManagedCluster{}
+	if err := r.client.Get(ctx, types.NamespacedName{Name: managedClusterName}, cluster); err != nil {
+		t.Fatalf("get managed cluster error: %v", err)
+	}
+	if !meta.IsStatusConditionTrue(cluster.Status.Conditions,
+		constants.ConditionManagedClusterImportAndRegistrationReady) {
+		t.Errorf("Expect condition %s is true, but got %v",
+			constants.ConditionManagedClusterImportAndRegistrationReady, cluster.Status.Conditions)
+	}
+}
+
+func TestKlusterletWorksApplyStuckCondition(t *testing.T) {
+	managedClusterName := "test"
+	cases := []struct {
+		name                    string
+		works                   []runtime.Object
+		expectedConditionStatus metav1.ConditionStatus
+		expectedConditionReason string
+	}{
+		{
+			name: "manifestworks are applied",
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "test-klusterlet",
+						Namespace:         managedClusterName,
+						CreationTimestamp: metav1.NewTime(time.Now().Add(-10 * time.Minute)),
+					},
+					Status: workv1.ManifestWorkStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   workv1.WorkApplied,
+								Status: metav1.ConditionTrue,
+							},
+						},
+					},
+				},
+			},
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonKlusterletWorksNotStuck,
+		},
+		{
+			name: "manifestworks are not applied in a short time",
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "test-klusterlet",
+						Namespace:         managedClusterName,
+						CreationTimestamp: metav1.NewTime(time.Now().Add(-1 * time.Minute)),
+					},
+				},
+			},
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonKlusterletWorksNotStuck,
+		},
+		{
+			name: "manifestworks are not handled by the work agent",
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "test-klusterlet",
+						Namespace:         managedClusterName,
+						CreationTimestamp: metav1.NewTime(time.Now().Add(-10 * time.Minute)),
+					},
+				},
+			},
+			expectedConditionStatus: metav1.ConditionTrue,
+			expectedConditionReason: constants.ConditionReasonKlusterletWorksNotApplied,
+		},
+		{
+			name: "manifestworks are stuck in applying",
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "test-klusterlet-crds",
+						Namespace:         managedClusterName,
+						CreationTimestamp: metav1.NewTime(time.Now().Add(-10 * time.Minute)),
+					},
+					Status: workv1.ManifestWorkStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:               workv1.WorkApplied,
+								Status:             metav1.ConditionFalse,
+								Reason:             "AppliedManifestWorkFailed",
+								Message:            "Failed to apply manifest",
+								LastTransitionTime: metav1.NewTime(time.Now().Add(-6 * time.Minute)),
+							},
+						},
+					},
+				},
+			},
+			expectedConditionStatus: metav1.ConditionTrue,
+			expectedConditionReason: "AppliedManifestWorkFailed",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			managedCluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: managedClusterName,
+				},
+				Status: clusterv1.ManagedClusterStatus{
+					Conditions: []metav1.Condition{
+						helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+							constants.ConditionReasonManagedClusterImporting, "test"),
+					},
+				},
+			}
+
+			r := ReconcileImportStatus{
+				client: fake.NewClientBuilder().WithScheme(testscheme).
+					WithObjects(managedCluster).WithStatusSubresource(managedCluster).Build(),
+				kubeClient: kubefake.NewSimpleClientset(),
+				workClient: workfake.NewSimpleClientset(c.works...),
+				recorder:   eventstesting.NewTestingEventRecorder(t),
+			}
+
+			_, err := r.Reconcile(context.TODO(), reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: managedClusterName,
+				},
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			cluster := &clusterv1.ManagedCluster{}
+			if err := r.client.Get(context.TODO(), types.NamespacedName{Name: managedClusterName}, cluster); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			condition := meta.FindStatusCondition(cluster.Status.Conditions, constants.ConditionKlusterletWorksApplyStuck)
+			if condition == nil {
+				t.Fatalf("expected the condition %s, but failed", constants.ConditionKlusterletWorksApplyStuck)
+			}
+			if condition.Status != c.expectedConditionStatus || condition.Reason != c.expectedConditionReason {
+				t.Errorf("expected condition %s/%s, but got %s/%s",
+					c.expectedConditionStatus, c.expectedConditionReason, condition.Status, condition.Reason)
+			}
+		})
+	}
+}
+
+func TestForeignKlusterletWorksPresentCondition(t *testing.T) {
+	managedClusterName := "test"
+	cases := []struct {
+		name                    string
+		hubIdentity             string
+		works                   []runtime.Object
+		expectedConditionStatus metav1.ConditionStatus
+	}{
+		{
+			name: "no hub identity",
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "hub2-test-klusterlet",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+						Annotations: map[string]string{
+							constants.KlusterletWorksHubIdentityAnnotation: "hub2",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:        "no foreign manifestworks",
+			hubIdentity: "hub1",
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+						Annotations: map[string]string{
+							constants.KlusterletWorksHubIdentityAnnotation: "hub1",
+						},
+					},
+				},
+			},
+			expectedConditionStatus: metav1.ConditionFalse,
+		},
+		{
+			name:        "foreign manifestworks are present",
+			hubIdentity: "hub1",
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+						Annotations: map[string]string{
+							constants.KlusterletWorksHubIdentityAnnotation: "hub1",
+						},
+					},
+				},
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "hub2-test-klusterlet",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+						Annotations: map[string]string{
+							constants.KlusterletWorksHubIdentityAnnotation: "hub2",
+						},
+					},
+				},
+			},
+			expectedConditionStatus: metav1.ConditionTrue,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv("HUB_IDENTITY", c.hubIdentity)
+
+			managedCluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: managedClusterName,
+				},
+				Status: clusterv1.ManagedClusterStatus{
+					Conditions: []metav1.Condition{
+						helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+							constants.ConditionReasonManagedClusterImporting, "test"),
+					},
+				},
+			}
+
+			r := ReconcileImportStatus{
+				client: fake.NewClientBuilder().WithScheme(testscheme).
+					WithObjects(managedCluster).WithStatusSubresource(managedCluster).Build(),
+				kubeClient: kubefake.NewSimpleClientset(),
+				workClient: workfake.NewSimpleClientset(c.works...),
+				recorder:   eventstesting.NewTestingEventRecorder(t),
+			}
+
+			_, err := r.Reconcile(context.TODO(), reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: managedClusterName,
+				},
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			cluster := &clusterv1.ManagedCluster{}
+			if err := r.client.Get(context.TODO(), types.NamespacedName{Name: managedClusterName}, cluster); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			condition := meta.FindStatusCondition(cluster.Status.Conditions, constants.ConditionForeignKlusterletWorksPresent)
+			if len(c.hubIdentity) == 0 {
+				if condition != nil {
+					t.Errorf("expected no condition %s, but got %v", constants.ConditionForeignKlusterletWorksPresent, condition)
+				}
+				return
+			}
+			if condition == nil {
+				t.Fatalf("expected condition %s, but not found", constants.ConditionForeignKlusterletWorksPresent)
+			}
+			if condition.Status != c.expectedConditionStatus {
+				t.Errorf("expected condition status %s, but got %s", c.expectedConditionStatus, condition.Status)
+			}
+		})
+	}
+}
+
+func TestKlusterletWorksAvailabilityPolicy(t *testing.T) {
+	managedClusterName := "test"
+	partiallyAvailableWorks := []runtime.Object{
+		&workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-klusterlet-crds",
+				Namespace: managedClusterName,
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "true",
+				},
+			},
+			Status: workv1.ManifestWorkStatus{
+				Conditions: []metav1.Condition{
+					{
+						Type:   workv1.WorkApplied,
+						Status: metav1.ConditionTrue,
+					},
+					{
+						Type:   workv1.WorkAvailable,
+						Status: metav1.ConditionTrue,
+					},
+				},
+			},
+		},
+		&workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-klusterlet",
+				Namespace: managedClusterName,
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "true",
+				},
+				CreationTimestamp: metav1.Now(),
+			},
+		},
+	}
+
+	cases := []struct {
+		name                          string
+		annotations                   map[string]string
+		expectedAvailableStatus       metav1.ConditionStatus
+		expectedImportConditionStatus metav1.ConditionStatus
+	}{
+		{
+			name:                          "wait for all by default",
+			expectedAvailableStatus:       metav1.ConditionFalse,
+			expectedImportConditionStatus: metav1.ConditionFalse,
+		},
+		{
+			name: "wait for all",
+			annotations: map[string]string{
+				constants.KlusterletWorksAvailabilityPolicyAnnotation: constants.KlusterletWorksAvailabilityPolicyWaitForAll,
+			},
+			expectedAvailableStatus:       metav1.ConditionFalse,
+			expectedImportConditionStatus: metav1.ConditionFalse,
+		},
+		{
+			name: "proceed on first",
+			annotations: map[string]string{
+				constants.KlusterletWorksAvailabilityPolicyAnnotation: constants.KlusterletWorksAvailabilityPolicyProceedOnFirst,
+			},
+			expectedAvailableStatus:       metav1.ConditionTrue,
+			expectedImportConditionStatus: metav1.ConditionTrue,
+		},
+		{
+			name: "invalid policy",
+			annotations: map[string]string{
+				constants.KlusterletWorksAvailabilityPolicyAnnotation: "ProceedOnAny",
+			},
+			expectedAvailableStatus:       metav1.ConditionFalse,
+			expectedImportConditionStatus: metav1.ConditionFalse,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			managedCluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        managedClusterName,
+					Annotations: c.annotations,
+				},
+				Status: clusterv1.ManagedClusterStatus{
+					Conditions: []metav1.Condition{
+						helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+							constants.ConditionReasonManagedClusterImporting, "test"),
+					},
+				},
+			}
+
+			r := ReconcileImportStatus{
+				client: fake.NewClientBuilder().WithScheme(testscheme).
+					WithObjects(managedCluster).WithStatusSubresource(managedCluster).Build(),
+				kubeClient: kubefake.NewSimpleClientset(),
+				workClient: workfake.NewSimpleClientset(partiallyAvailableWorks...),
+				recorder:   eventstesting.NewTestingEventRecorder(t),
+			}
+
+			_, err := r.Reconcile(context.TODO(), reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: managedClusterName,
+				},
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			cluster := &clusterv1.ManagedCluster{}
+			if err := r.client.Get(context.TODO(), types.NamespacedName{Name: managedClusterName}, cluster); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			availableCondition := meta.FindStatusCondition(cluster.Status.Conditions,
+				constants.ConditionKlusterletWorksAvailable)
+			if availableCondition == nil {
+				t.Fatalf("expected condition %s, but not found", constants.ConditionKlusterletWorksAvailable)
+			}
+			if availableCondition.Status != c.expectedAvailableStatus {
+				t.Errorf("expected condition status %s, but got %s", c.expectedAvailableStatus, availableCondition.Status)
+			}
+			if availableCondition.Reason != constants.ConditionReasonKlusterletWorksPartiallyAvailable {
+				t.Errorf("expected condition reason %s, but got %s",
+					constants.ConditionReasonKlusterletWorksPartiallyAvailable, availableCondition.Reason)
+			}
+
+			importCondition := meta.FindStatusCondition(cluster.Status.Conditions,
+				constants.ConditionManagedClusterImportSucceeded)
+			if importCondition == nil || importCondition.Status != c.expectedImportConditionStatus {
+				t.Errorf("expected import condition status %s, but got %v", c.expectedImportConditionStatus, importCondition)
+			}
+		})
+	}
+}
+
+func TestCleanupAutoImportSecret(t *testing.T) {
+	managedClusterName := "test"
+	availableCondition := []metav1.Condition{
+		{
+			Type:   workv1.WorkApplied,
+			Status: metav1.ConditionTrue,
+		},
+		{
+			Type:   workv1.WorkAvailable,
+			Status: metav1.ConditionTrue,
+		},
+	}
+	works := []runtime.Object{
+		&workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-klusterlet-crds",
+				Namespace: managedClusterName,
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "true",
+				},
+			},
+			Status: workv1.ManifestWorkStatus{Conditions: availableCondition},
+		},
+		&workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-klusterlet",
+				Namespace: managedClusterName,
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "true",
+				},
+			},
+			Status: workv1.ManifestWorkStatus{Conditions: availableCondition},
+		},
+	}
+
+	importingConditions := []metav1.Condition{
+		helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+			constants.ConditionReasonManagedClusterImporting, "test"),
+	}
+
+	cases := []struct {
+		name            string
+		conditions      []metav1.Condition
+		annotations     map[string]string
+		expectedDeleted bool
+	}{
+		{
+			name:            "delete the auto import secret after imported",
+			conditions:      importingConditions,
+			expectedDeleted: true,
+		},
+		{
+			name: "keep the auto import secret after imported",
+			annotations: map[string]string{
+				constants.AnnotationKeepingAutoImportSecret: "",
+			},
+			conditions:      importingConditions,
+			expectedDeleted: false,
+		},
+		{
+			name:            "delete the auto import secret after the restored cluster is imported",
+			expectedDeleted: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			managedCluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: managedClusterName,
+				},
+				Status: clusterv1.ManagedClusterStatus{
+					Conditions: c.conditions,
+				},
+			}
+			autoImportSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        constants.AutoImportSecretName,
+					Namespace:   managedClusterName,
+					Annotations: c.annotations,
+				},
+			}
+
+			r := ReconcileImportStatus{
+				client: fake.NewClientBuilder().WithScheme(testscheme).
+					WithObjects(managedCluster).WithStatusSubresource(managedCluster).Build(),
+				kubeClient: kubefake.NewSimpleClientset(autoImportSecret),
+				workClient: workfake.NewSimpleClientset(works...),
+				recorder:   eventstesting.NewTestingEventRecorder(t),
+			}
+
+			_, err := r.Reconcile(context.TODO(), reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: managedClusterName,
+				},
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			_, err = r.kubeClient.CoreV1().Secrets(managedClusterName).Get(
+				context.TODO(), constants.AutoImportSecretName, metav1.GetOptions{})
+			if c.expectedDeleted && !errors.IsNotFound(err) {
+				t.Errorf("expected the auto import secret is deleted, but got %v", err)
+			}
+			if !c.expectedDeleted && err != nil {
+				t.Errorf("expected the auto import secret is retained, but got %v", err)
+			}
+		})
+	}
+}
+
+func TestHubNamespaceRBACPending(t *testing.T) {
+	managedClusterName := "test"
+	managedCluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: managedClusterName,
+		},
+		Status: clusterv1.ManagedClusterStatus{
+			Conditions: []metav1.Condition{
+				helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+					constants.ConditionReasonManagedClusterImporting, "test"),
+			},
+		},
+	}
+
+	// simulate the RBAC of the managed cluster namespace lags on the first reconcile
+	workClient := workfake.NewSimpleClientset()
+	rbacReady := false
+	workClient.PrependReactor("get", "manifestworks",
+		func(action clienttesting.Action) (bool, runtime.Object, error) {
+			if rbacReady {
+				return false, nil, nil
+			}
+			return true, nil, errors.NewForbidden(workv1.Resource("manifestworks"),
+				action.(clienttesting.GetAction).GetName(), fmt.Errorf(
+					"User \"system:serviceaccount:open-cluster-management:managedcluster-import-controller\" "+
+						"cannot get resource \"manifestworks\" in API group \"work.open-cluster-management.io\" "+
+						"in the namespace %q", action.GetNamespace()))
+		})
+
+	r := ReconcileImportStatus{
+		client: fake.NewClientBuilder().WithScheme(testscheme).
+			WithObjects(managedCluster).WithStatusSubresource(managedCluster).Build(),
+		kubeClient: kubefake.NewSimpleClientset(),
+		workClient: workClient,
+		recorder:   eventstesting.NewTestingEventRecorder(t),
+	}
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: managedClusterName}}
+	result, err := r.Reconcile(context.TODO(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RequeueAfter == 0 {
+		t.Errorf("expected the managed cluster to be requeued, but not")
+	}
+
+	cluster := &clusterv1.ManagedCluster{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: managedClusterName}, cluster); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !meta.IsStatusConditionPresentAndEqual(cluster.Status.Conditions,
+		constants.ConditionManagedClusterImportSucceeded, metav1.ConditionFalse) {
+		t.Errorf("expected import condition is false, but got %v", cluster.Status.Conditions)
+	}
+	condition := meta.FindStatusCondition(cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+	if condition.Reason != constants.ConditionReasonHubNamespaceRBACPending {
+		t.Errorf("expected reason %s, but got %s", constants.ConditionReasonHubNamespaceRBACPending, condition.Reason)
+	}
+
+	// the RBAC becomes ready, the reconcile should proceed
+	rbacReady = true
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: managedClusterName}, cluster); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if meta.FindStatusCondition(cluster.Status.Conditions, constants.ConditionKlusterletWorksAvailable) == nil {
+		t.Errorf("expected condition %s, but not found", constants.ConditionKlusterletWorksAvailable)
+	}
+}
+
+func TestClusterDeploymentInstalledToImportedMetric(t *testing.T) {
+	managedClusterName := "test"
+	managedCluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: managedClusterName,
+		},
+		Status: clusterv1.ManagedClusterStatus{
+			Conditions: []metav1.Condition{
+				helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+					constants.ConditionReasonManagedClusterImporting, "test"),
+			},
+		},
+	}
+	clusterDeployment := &hivev1.ClusterDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      managedClusterName,
+			Namespace: managedClusterName,
+		},
+		Spec: hivev1.ClusterDeploymentSpec{
+			Installed: true,
+		},
+		Status: hivev1.ClusterDeploymentStatus{
+			InstalledTimestamp: &metav1.Time{Time: time.Now().Add(-10 * time.Minute)},
+		},
+	}
+
+	works := []runtime.Object{}
+	for _, name := range []string{"test-klusterlet-crds", "test-klusterlet"} {
+		works = append(works, &workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: managedClusterName,
+			},
+			Status: workv1.ManifestWorkStatus{
+				Conditions: []metav1.Condition{
+					{
+						Type:   workv1.WorkApplied,
+						Status: metav1.ConditionTrue,
+					},
+					{
+						Type:   workv1.WorkAvailable,
+						Status: metav1.ConditionTrue,
+					},
+				},
+			},
+		})
+	}
+
+	r := ReconcileImportStatus{
+		client: fake.NewClientBuilder().WithScheme(testscheme).
+			WithObjects(managedCluster, clusterDeployment).WithStatusSubresource(managedCluster).Build(),
+		kubeClient: kubefake.NewSimpleClientset(),
+		workClient: workfake.NewSimpleClientset(works...),
+		recorder:   eventstesting.NewTestingEventRecorder(t),
+	}
+
+	before := installedToImportedSampleCount(t)
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: managedClusterName}}
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count := installedToImportedSampleCount(t) - before; count != 1 {
+		t.Errorf("expected the duration is observed once, but got %d", count)
+	}
+
+	// the cluster is already imported, the duration should not be observed again
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count := installedToImportedSampleCount(t) - before; count != 1 {
+		t.Errorf("expected the duration is observed once, but got %d", count)
+	}
+}
+
+func TestLastImportSucceededCondition(t *testing.T) {
+	managedClusterName := "test"
+	restoredClusterName := "restored"
+	lastImportedCondition := newLastImportSucceededCondition(time.Now().Add(-1 * time.Hour))
+	managedCluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: managedClusterName,
+		},
+		Status: clusterv1.ManagedClusterStatus{
+			Conditions: []metav1.Condition{
+				helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionTrue,
+					constants.ConditionReasonManagedClusterImported, "Import succeeded"),
+				lastImportedCondition,
+			},
+		},
+	}
+	// the status of the restored cluster is wiped
+	restoredCluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: restoredClusterName,
+		},
+	}
+
+	works := []runtime.Object{}
+	for _, clusterName := range []string{managedClusterName, restoredClusterName} {
+		for _, name := range []string{clusterName + "-klusterlet-crds", clusterName + "-klusterlet"} {
+			works = append(works, &workv1.ManifestWork{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: clusterName,
+				},
+				Status: workv1.ManifestWorkStatus{
+					Conditions: []metav1.Condition{
+						{
+							Type:   workv1.WorkApplied,
+							Status: metav1.ConditionTrue,
+						},
+						{
+							Type:   workv1.WorkAvailable,
+							Status: metav1.ConditionTrue,
+						},
+					},
+				},
+			})
+		}
+	}
+
+	r := ReconcileImportStatus{
+		client: fake.NewClientBuilder().WithScheme(testscheme).
+			WithObjects(managedCluster, restoredCluster).
+			WithStatusSubresource(managedCluster, restoredCluster).Build(),
+		kubeClient: kubefake.NewSimpleClientset(),
+		workClient: workfake.NewSimpleClientset(works...),
+		recorder:   eventstesting.NewTestingEventRecorder(t),
+	}
+
+	getLastImportSucceeded := func(clusterName string) *metav1.Condition {
+		cluster := &clusterv1.ManagedCluster{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Name: clusterName}, cluster); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return meta.FindStatusCondition(cluster.Status.Conditions, constants.ConditionLastImportSucceeded)
+	}
+
+	// the cluster is already imported, the last import time should not be updated
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: managedClusterName}}
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if condition := getLastImportSucceeded(managedClusterName); condition == nil ||
+		condition.Message != lastImportedCondition.Message {
+		t.Errorf("expected the last import condition %v is kept, but got %v", lastImportedCondition, condition)
+	}
+
+	// the cluster is imported again, the last import time should be updated
+	if err := helpers.UpdateManagedClusterStatus(r.client, managedClusterName,
+		helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+			constants.ConditionReasonManagedClusterImporting, "test")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	condition := getLastImportSucceeded(managedClusterName)
+	if condition == nil || condition.Message == lastImportedCondition.Message {
+		t.Errorf("expected the last import time is updated, but got %v", condition)
+	}
+
+	// the cluster keeps imported, the last import time should not be updated
+	updatedCondition := condition.DeepCopy()
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if condition := getLastImportSucceeded(managedClusterName); condition == nil ||
+		condition.Message != updatedCondition.Message {
+		t.Errorf("expected the last import condition %v is kept, but got %v", updatedCondition, condition)
+	}
+
+	// the import condition of the restored cluster is restored, the last import time is unknown
+	if _, err := r.Reconcile(context.TODO(),
+		reconcile.Request{NamespacedName: types.NamespacedName{Name: restoredClusterName}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if condition := getLastImportSucceeded(restoredClusterName); condition != nil {
+		t.Errorf("expected no last import condition on the restored cluster, but got %v", condition)
+	}
+}
+
+func installedToImportedSampleCount(t *testing.T) uint64 {
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != "managedcluster_import_clusterdeployment_installed_to_imported_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			return metric.GetHistogram().GetSampleCount()
+		}
+	}
+
+	return 0
+}
+
+func TestHubNamespaceRBACPendingAfterImported(t *testing.T) {
+	managedClusterName := "test"
+	managedCluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: managedClusterName,
+		},
+		Status: clusterv1.ManagedClusterStatus{
+			Conditions: []metav1.Condition{
+				helpers.NewManagedClusterImportSucceededCondition(metav1.ConditionTrue,
+					constants.ConditionReasonManagedClusterImported, "test"),
+			},
+		},
+	}
+
+	// the RBAC of an imported managed cluster is not lagging, the forbidden error is returned as it is
+	workClient := workfake.NewSimpleClientset()
+	workClient.PrependReactor("get", "manifestworks",
+		func(action clienttesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.NewForbidden(workv1.Resource("manifestworks"),
+				action.(clienttesting.GetAction).GetName(), fmt.Errorf(
+					"User \"test\" cannot get resource \"manifestworks\" in API group "+
+						"\"work.open-cluster-management.io\" in the namespace %q", action.GetNamespace()))
+		})
+
+	r := ReconcileImportStatus{
+		client: fake.NewClientBuilder().WithScheme(testscheme).
+			WithObjects(managedCluster).WithStatusSubresource(managedCluster).Build(),
+		kubeClient: kubefake.NewSimpleClientset(),
+		workClient: workClient,
+		recorder:   eventstesting.NewTestingEventRecorder(t),
+	}
+
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: managedClusterName}})
+	if !errors.IsForbidden(err) {
+		t.Errorf("expected forbidden error, but got %v", err)
+	}
+
+	cluster := &clusterv1.ManagedCluster{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: managedClusterName}, cluster); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !meta.IsStatusConditionTrue(cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded) {
+		t.Errorf("expected import condition is kept, but got %v", cluster.Status.Conditions)
+	}
+}
@@ -17,6 +17,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -28,10 +29,6 @@ const clusterNameLabel = "name"
 
 const ClusterLabel = "cluster.open-cluster-management.io/managedCluster"
 
-const (
-	createdViaOther = "other"
-)
-
 var log = logf.Log.WithName(controllerName)
 
 // ReconcileManagedCluster reconciles a ManagedCluster object
@@ -56,7 +53,8 @@ func (r *ReconcileManagedCluster) Reconcile(ctx context.Context, request reconci
 	managedCluster := &clusterv1.ManagedCluster{}
 	err := r.client.Get(ctx, types.NamespacedName{Name: request.Name}, managedCluster)
 	if errors.IsNotFound(err) {
-		// the managed cluster could have been deleted, do nothing
+		// the managed cluster could have been deleted, forget its import attempts
+		helpers.ForgetImportFailedAttempts(request.Name)
 		return reconcile.Result{}, nil
 	}
 	if err != nil {
@@ -65,16 +63,28 @@ func (r *ReconcileManagedCluster) Reconcile(ctx context.Context, request reconci
 
 	reqLogger.Info("Reconciling the managed cluster meta object")
 
+	// the legacy import finalizers are not handled anymore, remove them to avoid blocking the cluster deletion
+	if err := helpers.RemoveLegacyImportFinalizers(ctx, r.client, r.recorder, managedCluster); err != nil {
+		return reconcile.Result{}, err
+	}
+
 	if managedCluster.DeletionTimestamp.IsZero() {
 		if err := r.ensureManagedClusterMetaObj(ctx, managedCluster); err != nil {
 			return reconcile.Result{}, err
 		}
 
+		// the reconcile trace may be disabled, clean up the recorded trace
+		if err := helpers.CleanupReconcileTrace(ctx, r.client, managedCluster); err != nil {
+			return reconcile.Result{}, err
+		}
+
 		// set cluster label on the managed cluster namespace
 		ns := &corev1.Namespace{}
 		err := r.client.Get(ctx, types.NamespacedName{Name: managedCluster.Name}, ns)
 		if errors.IsNotFound(err) {
-			return reconcile.Result{}, nil
+			// the managed cluster namespace is missing, the import secret and klusterlet works cannot be
+			// created without it, so recreate it
+			return reconcile.Result{}, r.recreateManagedClusterNamespace(ctx, managedCluster)
 		}
 		if err != nil {
 			return reconcile.Result{}, err
@@ -96,6 +106,9 @@ func (r *ReconcileManagedCluster) Reconcile(ctx context.Context, request reconci
 		return reconcile.Result{}, nil
 	}
 
+	// the managed cluster is deleting, it will not be imported anymore
+	helpers.ForgetImportFailedAttempts(managedCluster.Name)
+
 	if len(managedCluster.Finalizers) > 1 {
 		// managed cluster is deleting, but other components finalizers are remaining,
 		// wait for other components to remove their finalizers
@@ -154,6 +167,31 @@ func (r *ReconcileManagedCluster) ensureManagedClusterMetaObj(ctx context.Contex
 	return nil
 }
 
+func (r *ReconcileManagedCluster) recreateManagedClusterNamespace(
+	ctx context.Context, managedCluster *clusterv1.ManagedCluster) error {
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: managedCluster.Name,
+			Labels: map[string]string{
+				ClusterLabel: managedCluster.Name,
+			},
+		},
+	}
+
+	err := r.client.Create(ctx, ns)
+	if errors.IsAlreadyExists(err) {
+		// the namespace is created by others, the namespace create event will trigger the reconcile again
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	r.recorder.Warningf("ManagedClusterNamespaceRecreated",
+		"The managed cluster %s namespace is missing and recreated", managedCluster.Name)
+	return nil
+}
+
 func (r *ReconcileManagedCluster) deleteManagedClusterAddon(
 	ctx context.Context, managedCluster *clusterv1.ManagedCluster) error {
 	clusterName := managedCluster.Name
@@ -186,7 +224,7 @@ func (r *ReconcileManagedCluster) deleteManagedClusterAddon(
 }
 
 func ensureCreateViaAnnotation(modified *bool, cluster *clusterv1.ManagedCluster) {
-	createViaOtherAnnotation := map[string]string{constants.CreatedViaAnnotation: createdViaOther}
+	createViaOtherAnnotation := map[string]string{constants.CreatedViaAnnotation: constants.CreatedViaOther}
 	viaAnnotation, ok := cluster.Annotations[constants.CreatedViaAnnotation]
 	if !ok {
 		// no created-via annotation, set it with default annotation (other)
@@ -88,6 +88,30 @@ func TestReconcile(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "managed cluster namespace is missing",
+			startObjs: []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, runtimeClient client.Client) {
+				ns := &corev1.Namespace{}
+				if err := runtimeClient.Get(context.TODO(), types.NamespacedName{Name: "test"}, ns); err != nil {
+					t.Errorf("expected the namespace is recreated, but failed, %v", err)
+				}
+				if ns.Labels[ClusterLabel] != "test" {
+					t.Errorf("expected the namespace has the cluster label, but got %v", ns.Labels)
+				}
+			},
+		},
 		{
 			name: "managed clusters is deleting, but it has other finalizers",
 			startObjs: []client.Object{
@@ -205,6 +229,55 @@ func TestReconcile(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "managed cluster has legacy import finalizers",
+			startObjs: []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:       "test",
+						Finalizers: []string{"rcm-api.cluster"},
+					},
+				},
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, runtimeClient client.Client) {
+				cluster := &clusterv1.ManagedCluster{}
+				if err := runtimeClient.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+					t.Errorf("unexpected error, but failed, %v", err)
+				}
+				if len(cluster.Finalizers) != 1 || cluster.Finalizers[0] != constants.ImportFinalizer {
+					t.Errorf("expected the legacy finalizer is migrated, but failed, %v", cluster.Finalizers)
+				}
+			},
+		},
+		{
+			name: "managed cluster is deleting with legacy import finalizers",
+			startObjs: []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "test",
+						Finalizers:        []string{"rcm-api.cluster", constants.ImportFinalizer},
+						DeletionTimestamp: &now,
+					},
+				},
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, runtimeClient client.Client) {
+				cluster := &clusterv1.ManagedCluster{}
+				err := runtimeClient.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster)
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected the managed cluster is deleted, but failed, %v", err)
+				}
+			},
+		},
 		{
 			name: "managed clusters is deleting, but there are addons in its namespace",
 			startObjs: []client.Object{
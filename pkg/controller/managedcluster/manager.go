@@ -50,8 +50,9 @@ func Add(mgr manager.Manager, clientHolder *helpers.ClientHolder, _ *source.Info
 			&handler.EnqueueRequestForObject{},
 			builder.WithPredicates(predicate.Funcs{
 				GenericFunc: func(e event.GenericEvent) bool { return false },
-				DeleteFunc:  func(e event.DeleteEvent) bool { return false },
-				CreateFunc:  func(e event.CreateEvent) bool { return true },
+				// the namespace of a managed cluster may be deleted accidentally, recreate it
+				DeleteFunc: func(e event.DeleteEvent) bool { return true },
+				CreateFunc: func(e event.CreateEvent) bool { return true },
 				UpdateFunc: func(e event.UpdateEvent) bool {
 					// only handle the labels chanages
 					return !equality.Semantic.DeepEqual(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels())
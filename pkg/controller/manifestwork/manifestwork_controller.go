@@ -6,6 +6,7 @@ package manifestwork
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,7 +23,9 @@ import (
 	operatorhelpers "github.com/openshift/library-go/pkg/operator/v1helpers"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -32,6 +35,18 @@ import (
 
 var log = logf.Log.WithName(controllerName)
 
+// importSecretGenerationTimeout is the duration that the import secret of a managed cluster can be missing after
+// the managed cluster is created, after that, the import secret is considered not generated by the importconfig
+// controller
+const importSecretGenerationTimeout = 5 * time.Minute
+
+// partialApplyRetryPeriod is the period to retry the klusterlet manifest works that failed to apply when the
+// klusterlet manifest works are applied independently
+const partialApplyRetryPeriod = 10 * time.Second
+
+// klusterletCRDName is the name of the klusterlet crd in the klusterlet-crds manifest work
+const klusterletCRDName = "klusterlets.operator.open-cluster-management.io"
+
 // ReconcileManifestWork reconciles the ManagedClusters of the ManifestWorks object
 type ReconcileManifestWork struct {
 	clientHolder   *helpers.ClientHolder
@@ -94,6 +109,17 @@ func (r *ReconcileManifestWork) Reconcile(ctx context.Context, request reconcile
 		return reconcile.Result{RequeueAfter: 5 * time.Second}, r.deleteAddonsAndWorks(ctx, managedCluster, manifestWorks.Items)
 	}
 
+	// the klusterlet works are selected by the klusterlet works label with the value "true", repair the works
+	// whose label values are inconsistent, otherwise they are missed by the selector
+	repaired, err := r.repairKlusterletWorksLabel(ctx, managedClusterName)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if repaired {
+		// requeue to wait for the repaired works are synced to the informer
+		return reconcile.Result{Requeue: true}, nil
+	}
+
 	workSelector := labels.SelectorFromSet(map[string]string{constants.KlusterletWorksLabel: "true"})
 	manifestWorks, err := r.informerHolder.KlusterletWorkLister.ManifestWorks(managedClusterName).List(workSelector)
 	if err != nil {
@@ -110,10 +136,20 @@ func (r *ReconcileManifestWork) Reconcile(ctx context.Context, request reconcile
 	// Note: create the klusterlet manifest works before importing cluster to avoid the klusterlet applied manifest
 	// works are deleted from managed cluster if the restored hub has same host with the backup hub in the
 	// backup-restore case.
-	importSecretName := fmt.Sprintf("%s-%s", managedClusterName, constants.ImportSecretNameSuffix)
+	importSecretName := helpers.GetImportSecretName(managedClusterName)
 	importSecret, err := r.informerHolder.ImportSecretLister.Secrets(managedClusterName).Get(importSecretName)
 	if errors.IsNotFound(err) {
-		return reconcile.Result{}, nil
+		if helpers.DetermineKlusterletMode(managedCluster) == helpers.InstallModeUnknown {
+			// the import secret is not generated for an invalid deploy mode, the importconfig controller
+			// reports this on the managed cluster
+			return reconcile.Result{}, nil
+		}
+		if importSecretNotGeneratedReported(managedCluster) {
+			// the importconfig controller does not generate the import secret on purpose, e.g. the klusterlet
+			// images are rejected, and it reports the reason on the managed cluster, do not overwrite the reason
+			return reconcile.Result{}, nil
+		}
+		return r.checkImportSecretGeneration(managedCluster, time.Now())
 	}
 	if err != nil {
 		return reconcile.Result{}, err
@@ -123,17 +159,125 @@ func (r *ReconcileManifestWork) Reconcile(ctx context.Context, request reconcile
 		return reconcile.Result{}, err
 	}
 
+	importYaml, err := helpers.GetImportYaml(importSecret)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	crdsWorkDeleteOption, err := klusterletCRDsWorkDeleteOption(managedCluster)
+	if err != nil {
+		// the default delete option is used for an invalid delete option
+		r.recorder.Warningf("KlusterletWorksDeleteOptionInvalid", "The managed cluster %s: %v", managedClusterName, err)
+	}
+
+	crdsWork := createKlusterletCRDsManifestWork(managedCluster, importSecret, crdsWorkDeleteOption)
+	klusterletWork := createKlusterletManifestWork(managedCluster, importYaml)
+
+	serverSideApply, err := klusterletWorksServerSideApply(managedCluster)
+	if err != nil {
+		// the resources are updated by default for an invalid value
+		r.recorder.Warningf("KlusterletWorksServerSideApplyInvalid", "The managed cluster %s: %v",
+			managedClusterName, err)
+	}
+	if serverSideApply {
+		crdsWork.Spec.ManifestConfigs = serverSideApplyManifestConfigs(crdsWork.Spec.Workload.Manifests)
+		klusterletWork.Spec.ManifestConfigs = serverSideApplyManifestConfigs(klusterletWork.Spec.Workload.Manifests)
+	}
+
+	partialApply, err := klusterletWorksPartialApply(managedCluster)
+	if err != nil {
+		// the klusterlet works are applied together for an invalid value
+		r.recorder.Warningf("KlusterletWorksPartialApplyInvalid", "The managed cluster %s: %v",
+			managedClusterName, err)
+	}
+	if partialApply {
+		return r.applyKlusterletWorksPartially(managedCluster, crdsWork, klusterletWork)
+	}
+
+	// the klusterlet works are applied together, the PartialImport condition is not meaningful anymore
+	if err := r.clearPartialImportCondition(managedCluster, constants.ConditionReasonKlusterletWorksPartialApplyDisabled,
+		"The klusterlet manifestworks are applied together"); err != nil {
+		return reconcile.Result{}, err
+	}
+
 	_, err = helpers.ApplyResources(
 		r.clientHolder,
 		r.recorder,
 		r.scheme,
 		managedCluster,
-		createKlusterletCRDsManifestWork(managedCluster, importSecret),
-		createKlusterletManifestWork(managedCluster, importSecret),
+		crdsWork,
+		klusterletWork,
 	)
 	return reconcile.Result{}, err
 }
 
+// applyKlusterletWorksPartially applies the klusterlet manifest works independently. The klusterlet work cannot be
+// applied without the klusterlet CRDs, so a failed CRDs work fails the whole apply. If only the klusterlet work
+// fails to apply, the import proceeds with the applied CRDs work, the PartialImport condition of the managed cluster
+// reports the failed work and the managed cluster is requeued to retry it, the applied work is not applied again on
+// the retry unless it is changed.
+func (r *ReconcileManifestWork) applyKlusterletWorksPartially(managedCluster *clusterv1.ManagedCluster,
+	crdsWork, klusterletWork *workv1.ManifestWork) (reconcile.Result, error) {
+	if err := r.applyKlusterletWorkIfChanged(managedCluster, crdsWork); err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to apply the manifestwork %s: %v", crdsWork.Name, err)
+	}
+
+	if err := r.applyKlusterletWorkIfChanged(managedCluster, klusterletWork); err != nil {
+		log.Info("The klusterlet manifest work failed to apply, retry it",
+			"managedCluster", managedCluster.Name, "failedWork", klusterletWork.Name)
+		if err := helpers.UpdateManagedClusterStatus(
+			r.clientHolder.RuntimeClient,
+			managedCluster.Name,
+			metav1.Condition{
+				Type:   constants.ConditionPartialImport,
+				Status: metav1.ConditionTrue,
+				Reason: constants.ConditionReasonKlusterletWorksPartiallyApplied,
+				Message: fmt.Sprintf("The klusterlet manifestwork %s failed to apply, the klusterlet manifestwork "+
+					"%s is applied: %v. Will retry", klusterletWork.Name, crdsWork.Name, err),
+			},
+		); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{RequeueAfter: partialApplyRetryPeriod}, nil
+	}
+
+	// clear the PartialImport condition after the failed work is applied
+	return reconcile.Result{}, r.clearPartialImportCondition(managedCluster,
+		constants.ConditionReasonKlusterletWorksApplied, "All of the klusterlet manifestworks are applied")
+}
+
+// applyKlusterletWorkIfChanged applies the klusterlet manifest work unless the work in the informer cache is up to
+// date, so the applied works do not cost the hub requests on the retries
+func (r *ReconcileManifestWork) applyKlusterletWorkIfChanged(managedCluster *clusterv1.ManagedCluster,
+	work *workv1.ManifestWork) error {
+	existing, err := r.informerHolder.KlusterletWorkLister.ManifestWorks(work.Namespace).Get(work.Name)
+	if err == nil && helpers.ManifestWorkUpToDate(existing, work) {
+		return nil
+	}
+
+	_, err = helpers.ApplyResources(r.clientHolder, r.recorder, r.scheme, managedCluster, work)
+	return err
+}
+
+// clearPartialImportCondition sets the PartialImport condition of the managed cluster to false if it is true
+func (r *ReconcileManifestWork) clearPartialImportCondition(managedCluster *clusterv1.ManagedCluster,
+	reason, message string) error {
+	if !meta.IsStatusConditionTrue(managedCluster.Status.Conditions, constants.ConditionPartialImport) {
+		return nil
+	}
+
+	return helpers.UpdateManagedClusterStatus(
+		r.clientHolder.RuntimeClient,
+		managedCluster.Name,
+		metav1.Condition{
+			Type:    constants.ConditionPartialImport,
+			Status:  metav1.ConditionFalse,
+			Reason:  reason,
+			Message: message,
+		},
+	)
+}
+
 func (r *ReconcileManifestWork) deleteAddonsAndWorks(ctx context.Context,
 	cluster *clusterv1.ManagedCluster, works []workv1.ManifestWork) error {
 	errs := append(
@@ -145,23 +289,30 @@ func (r *ReconcileManifestWork) deleteAddonsAndWorks(ctx context.Context,
 }
 
 // deleteManifestWorks deletes manifest works when a managed cluster is deleting
-// If the managed cluster is unavailable, we will force delete all manifest works
+// If the managed cluster is unavailable, we will force delete all manifest works, unless the klusterlet cleanup
+// gate is enabled or the managed cluster is not imported by this controller, in that case, the manifest works
+// are kept until the managed cluster is back
 // If the managed cluster is available, we will
 //  1. delete the manifest work with the postpone-delete annotation until 10 min after the cluster is deleted.
 //  2. delete the manifest works that do not include klusterlet works and klusterlet addon works
 //  3. delete the klusterlet manifest work, the delete option of the klusterlet manifest work
-//     is orphan, so we can delete it safely
+//     is always orphan, so we can delete it safely
 //  4. after the klusterlet manifest work is deleted, we delete the klusterlet-crds manifest work,
 //     after the klusterlet-crds manifest work is deleted from the hub cluster, its klusterlet
 //     crds will be deleted from the managed cluster, then the kube system will delete the klusterlet
 //     cr from the managed cluster, once the klusterlet cr is deleted, the klusterlet operator will
-//     clean up the klusterlet on the managed cluster
+//     clean up the klusterlet on the managed cluster. If the klusterlet-crds manifest work has the
+//     orphan or selectively orphan delete option (see klusterletCRDsWorkDeleteOption), the klusterlet
+//     crd is kept, so the klusterlet is kept on the managed cluster
 func (r *ReconcileManifestWork) deleteManifestWorks(
 	ctx context.Context,
 	cluster *clusterv1.ManagedCluster,
 	works []workv1.ManifestWork) error {
 
-	if helpers.IsClusterUnavailable(cluster) {
+	// the manifest works of a cluster that is not imported by this controller may be handled by others, they are
+	// not force deleted even if the cluster is offline
+	if helpers.IsClusterUnavailable(cluster) && !klusterletCleanupGateEnabled(cluster) &&
+		helpers.IsControllerManaged(cluster) {
 		// the managed cluster is offline, force delete all manifest works
 		return helpers.ForceDeleteAllManifestWorks(ctx, r.clientHolder.WorkClient, r.recorder, works)
 	}
@@ -229,8 +380,12 @@ func (r *ReconcileManifestWork) deleteManifestWorks(
 	klusterletWork, err := r.clientHolder.WorkClient.WorkV1().ManifestWorks(cluster.Name).Get(ctx, klusterletName, metav1.GetOptions{})
 	if errors.IsNotFound(err) {
 		// the klusterlet work could be deleted, ensure the klusterlet crds work is deleted
-		return helpers.ForceDeleteManifestWork(ctx, r.clientHolder.WorkClient, r.recorder,
-			cluster.Name, fmt.Sprintf("%s-%s", cluster.Name, constants.KlusterletCRDsSuffix))
+		crdsWorkName := fmt.Sprintf("%s-%s", cluster.Name, constants.KlusterletCRDsSuffix)
+		if klusterletCleanupGateEnabled(cluster) {
+			// wait for the work agent to clean up the klusterlet crds from the managed cluster
+			return helpers.DeleteManifestWork(ctx, r.clientHolder.WorkClient, r.recorder, cluster.Name, crdsWorkName)
+		}
+		return helpers.ForceDeleteManifestWork(ctx, r.clientHolder.WorkClient, r.recorder, cluster.Name, crdsWorkName)
 	}
 	if err != nil {
 		return err
@@ -243,7 +398,8 @@ func (r *ReconcileManifestWork) deleteManifestWorks(
 	return helpers.DeleteManifestWork(ctx, r.clientHolder.WorkClient, r.recorder, klusterletWork.Namespace, klusterletWork.Name)
 }
 
-func createKlusterletCRDsManifestWork(managedCluster *clusterv1.ManagedCluster, importSecret *corev1.Secret) *workv1.ManifestWork {
+func createKlusterletCRDsManifestWork(managedCluster *clusterv1.ManagedCluster, importSecret *corev1.Secret,
+	deleteOption *workv1.DeleteOption) *workv1.ManifestWork {
 	crdsKey := constants.ImportSecretCRDSV1YamlKey
 	if managedCluster.Status.Version.Kubernetes != "" &&
 		!helpers.IsAPIExtensionV1Supported(managedCluster.Status.Version.Kubernetes) {
@@ -265,6 +421,7 @@ func createKlusterletCRDsManifestWork(managedCluster *clusterv1.ManagedCluster,
 			Labels: map[string]string{
 				constants.KlusterletWorksLabel: "true",
 			},
+			Annotations: klusterletWorksAnnotations(),
 		},
 		Spec: workv1.ManifestWorkSpec{
 			Workload: workv1.ManifestsTemplate{
@@ -272,13 +429,13 @@ func createKlusterletCRDsManifestWork(managedCluster *clusterv1.ManagedCluster,
 					{RawExtension: runtime.RawExtension{Raw: jsonData}},
 				},
 			},
+			DeleteOption: deleteOption,
 		},
 	}
 }
 
-func createKlusterletManifestWork(managedCluster *clusterv1.ManagedCluster, importSecret *corev1.Secret) *workv1.ManifestWork {
+func createKlusterletManifestWork(managedCluster *clusterv1.ManagedCluster, importYaml []byte) *workv1.ManifestWork {
 	manifests := []workv1.Manifest{}
-	importYaml := importSecret.Data[constants.ImportSecretImportYamlKey]
 	for _, yamlData := range helpers.SplitYamls(importYaml) {
 		jsonData, err := yaml.YAMLToJSON(yamlData)
 		if err != nil {
@@ -297,14 +454,217 @@ func createKlusterletManifestWork(managedCluster *clusterv1.ManagedCluster, impo
 			Labels: map[string]string{
 				constants.KlusterletWorksLabel: "true",
 			},
+			Annotations: klusterletWorksAnnotations(),
 		},
 		Spec: workv1.ManifestWorkSpec{
 			Workload: workv1.ManifestsTemplate{
 				Manifests: manifests,
 			},
+			// the klusterlet work must be orphaned, the klusterlet is cleaned up by deleting the
+			// klusterlet-crds work, see deleteManifestWorks
 			DeleteOption: &workv1.DeleteOption{
 				PropagationPolicy: workv1.DeletePropagationPolicyTypeOrphan,
 			},
 		},
 	}
 }
+
+// klusterletCRDsWorkDeleteOption returns the delete option of the klusterlet-crds manifestwork from the managed
+// cluster annotation. The klusterlet is cleaned up on the managed cluster by deleting the klusterlet-crds work, so
+// only the option of this work can be specified, the klusterlet work is always orphaned to keep the detach order.
+//   - Foreground, the default, the klusterlet crds are deleted, then the klusterlet is cleaned up
+//   - Orphan, the klusterlet crds are orphaned, so the klusterlet is kept on the managed cluster
+//   - SelectivelyOrphan, only the klusterlet crd is orphaned, so the klusterlet is kept on the managed cluster,
+//     the other resources of the work are deleted
+//
+// The default delete option is returned with an error for an invalid value.
+func klusterletCRDsWorkDeleteOption(managedCluster *clusterv1.ManagedCluster) (*workv1.DeleteOption, error) {
+	policy, ok := managedCluster.Annotations[constants.KlusterletWorksDeleteOptionAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	switch workv1.DeletePropagationPolicyType(policy) {
+	case workv1.DeletePropagationPolicyTypeForeground:
+		return nil, nil
+	case workv1.DeletePropagationPolicyTypeOrphan:
+		return &workv1.DeleteOption{PropagationPolicy: workv1.DeletePropagationPolicyTypeOrphan}, nil
+	case workv1.DeletePropagationPolicyTypeSelectivelyOrphan:
+		return &workv1.DeleteOption{
+			PropagationPolicy: workv1.DeletePropagationPolicyTypeSelectivelyOrphan,
+			SelectivelyOrphan: &workv1.SelectivelyOrphan{
+				OrphaningRules: []workv1.OrphaningRule{
+					{
+						Group:    "apiextensions.k8s.io",
+						Resource: "customresourcedefinitions",
+						Name:     klusterletCRDName,
+					},
+				},
+			},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("the klusterlet works delete option %q is invalid, it should be %s, %s or %s", policy,
+		workv1.DeletePropagationPolicyTypeForeground, workv1.DeletePropagationPolicyTypeOrphan,
+		workv1.DeletePropagationPolicyTypeSelectivelyOrphan)
+}
+
+// klusterletWorksServerSideApply returns whether the resources of the klusterlet manifestworks are applied with the
+// server side apply from the managed cluster annotation, false is returned with an error for an invalid value.
+func klusterletWorksServerSideApply(managedCluster *clusterv1.ManagedCluster) (bool, error) {
+	value, ok := managedCluster.Annotations[constants.KlusterletWorksServerSideApplyAnnotation]
+	if !ok {
+		return false, nil
+	}
+
+	serverSideApply, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("the klusterlet works server side apply %q is invalid, it should be a boolean", value)
+	}
+	return serverSideApply, nil
+}
+
+// klusterletWorksPartialApply returns whether the klusterlet manifestworks are applied independently from the
+// managed cluster annotation, false is returned with an error for an invalid value.
+func klusterletWorksPartialApply(managedCluster *clusterv1.ManagedCluster) (bool, error) {
+	value, ok := managedCluster.Annotations[constants.KlusterletWorksPartialApplyAnnotation]
+	if !ok {
+		return false, nil
+	}
+
+	partialApply, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("the klusterlet works partial apply %q is invalid, it should be a boolean", value)
+	}
+	return partialApply, nil
+}
+
+// serverSideApplyManifestConfigs returns the manifest configs that apply each of the manifests with the server side
+// apply, the manifest that cannot be decoded is ignored and is updated by the work agent as default
+func serverSideApplyManifestConfigs(manifests []workv1.Manifest) []workv1.ManifestConfigOption {
+	configs := []workv1.ManifestConfigOption{}
+	for _, manifest := range manifests {
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(manifest.Raw); err != nil {
+			continue
+		}
+
+		gvk := obj.GroupVersionKind()
+		gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+		configs = append(configs, workv1.ManifestConfigOption{
+			ResourceIdentifier: workv1.ResourceIdentifier{
+				Group:     gvk.Group,
+				Resource:  gvr.Resource,
+				Name:      obj.GetName(),
+				Namespace: obj.GetNamespace(),
+			},
+			UpdateStrategy: &workv1.UpdateStrategy{
+				Type: workv1.UpdateStrategyTypeServerSideApply,
+			},
+		})
+	}
+	return configs
+}
+
+// klusterletWorksAnnotations returns the annotations of the klusterlet manifestworks, the identity of this hub is
+// recorded if it is specified
+func klusterletWorksAnnotations() map[string]string {
+	hubIdentity := helpers.GetHubIdentity()
+	if len(hubIdentity) == 0 {
+		return nil
+	}
+
+	return map[string]string{constants.KlusterletWorksHubIdentityAnnotation: hubIdentity}
+}
+
+// checkImportSecretGeneration sets the ImportSucceeded condition with the ImportSecretNotGenerated reason if the
+// import secret of the managed cluster is missing for too long, otherwise, the managed cluster is requeued to
+// check the import secret again. A managed cluster that is already imported or available does not depend on the
+// import secret anymore, its condition is kept.
+func (r *ReconcileManifestWork) checkImportSecretGeneration(
+	managedCluster *clusterv1.ManagedCluster, now time.Time) (reconcile.Result, error) {
+	if meta.IsStatusConditionTrue(managedCluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded) ||
+		meta.IsStatusConditionTrue(managedCluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable) {
+		return reconcile.Result{}, nil
+	}
+
+	missingDuration := now.Sub(managedCluster.CreationTimestamp.Time)
+	if missingDuration < importSecretGenerationTimeout {
+		return reconcile.Result{RequeueAfter: importSecretGenerationTimeout - missingDuration}, nil
+	}
+
+	return reconcile.Result{}, helpers.UpdateManagedClusterStatus(
+		r.clientHolder.RuntimeClient,
+		managedCluster.Name,
+		helpers.NewManagedClusterImportSucceededCondition(
+			metav1.ConditionFalse,
+			constants.ConditionReasonImportSecretNotGenerated,
+			fmt.Sprintf("The import secret %s/%s is not generated in %s, check the importconfig controller",
+				managedCluster.Name, helpers.GetImportSecretName(managedCluster.Name), importSecretGenerationTimeout),
+		),
+	)
+}
+
+// importSecretNotGeneratedReported returns true if the ImportSucceeded condition of the managed cluster has a reason
+// that is reported by the importconfig controller when it does not generate the import secret
+func importSecretNotGeneratedReported(managedCluster *clusterv1.ManagedCluster) bool {
+	condition := meta.FindStatusCondition(
+		managedCluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+	if condition == nil || condition.Status != metav1.ConditionFalse {
+		return false
+	}
+	for _, reason := range constants.ImportSecretNotGeneratedReasons {
+		if condition.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// repairKlusterletWorksLabel normalizes the inconsistent klusterlet works label values of the klusterlet works, e.g.
+// "True" or "yes", to "true", it returns true if any work is repaired
+func (r *ReconcileManifestWork) repairKlusterletWorksLabel(ctx context.Context, clusterName string) (bool, error) {
+	works, err := r.informerHolder.KlusterletWorkLister.ManifestWorks(clusterName).List(labels.Everything())
+	if err != nil {
+		return false, err
+	}
+
+	repaired := false
+	patch := fmt.Sprintf("{\"metadata\":{\"labels\":{%q:\"true\"}}}", constants.KlusterletWorksLabel)
+	for _, work := range works {
+		value, ok := work.Labels[constants.KlusterletWorksLabel]
+		if !ok || value == "true" || !isTrueLabelValue(value) {
+			continue
+		}
+
+		if _, err := r.clientHolder.WorkClient.WorkV1().ManifestWorks(clusterName).Patch(
+			ctx, work.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+			return false, err
+		}
+
+		r.recorder.Eventf("KlusterletWorksLabelRepaired",
+			"The klusterlet works label value %q of the manifest work %s/%s is normalized to \"true\"",
+			value, clusterName, work.Name)
+		repaired = true
+	}
+
+	return repaired, nil
+}
+
+// isTrueLabelValue returns true if the label value means true regardless of the case, e.g. "True" or "yes"
+func isTrueLabelValue(value string) bool {
+	return strings.EqualFold(value, "true") || strings.EqualFold(value, "yes")
+}
+
+// klusterletCleanupGateEnabled returns true if the klusterlet cleanup gate is enabled on the managed cluster and
+// the klusterlet is expected to be cleaned up, the klusterlet is kept on the managed cluster if the klusterlet-crds
+// work is orphaned, so there is nothing to wait for
+func klusterletCleanupGateEnabled(cluster *clusterv1.ManagedCluster) bool {
+	if cluster.GetAnnotations()[constants.KlusterletCleanupGateAnnotation] != "true" {
+		return false
+	}
+
+	// the klusterlet is cleaned up by default for an invalid delete option
+	deleteOption, _ := klusterletCRDsWorkDeleteOption(cluster)
+	return deleteOption == nil || deleteOption.PropagationPolicy == workv1.DeletePropagationPolicyTypeForeground
+}
@@ -5,6 +5,7 @@ package manifestwork
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -24,12 +25,15 @@ import (
 
 	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
 
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/informers"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -462,6 +466,9 @@ func TestReconcile(t *testing.T) {
 						Name:              "test",
 						Finalizers:        []string{constants.ManifestWorkFinalizer},
 						DeletionTimestamp: &now,
+						Annotations: map[string]string{
+							constants.CreatedViaAnnotation: constants.CreatedViaOther,
+						},
 					},
 					Status: clusterv1.ManagedClusterStatus{
 						Conditions: []v1.Condition{
@@ -645,6 +652,227 @@ func TestReconcile(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "apply klusterlet manifest works with delete option",
+			startObjs: []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: v1.ObjectMeta{
+						Name:       "test",
+						Finalizers: []string{constants.ManifestWorkFinalizer},
+						Annotations: map[string]string{
+							constants.KlusterletWorksDeleteOptionAnnotation: "Orphan",
+						},
+					},
+					Status: clusterv1.ManagedClusterStatus{
+						Conditions: []v1.Condition{
+							{
+								Type:   clusterv1.ManagedClusterConditionJoined,
+								Status: v1.ConditionTrue,
+							},
+						},
+						Version: clusterv1.ManagedClusterVersion{Kubernetes: "v1.18.0"},
+					},
+				},
+			},
+			works: []runtime.Object{},
+			secrets: []runtime.Object{
+				testinghelpers.GetImportSecret("test"),
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, runtimeClient client.Client, workClient workclient.Interface) {
+				manifestWorks, err := workClient.WorkV1().ManifestWorks("test").List(context.TODO(), v1.ListOptions{})
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if len(manifestWorks.Items) != 2 {
+					t.Errorf("expected two works, but failed %d", len(manifestWorks.Items))
+				}
+				for _, work := range manifestWorks.Items {
+					if work.Spec.DeleteOption == nil ||
+						work.Spec.DeleteOption.PropagationPolicy != workv1.DeletePropagationPolicyTypeOrphan {
+						t.Errorf("expected the delete option of work %s is Orphan, but got %v",
+							work.Name, work.Spec.DeleteOption)
+					}
+				}
+			},
+		},
+		{
+			name: "apply klusterlet manifest works with server side apply",
+			startObjs: []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: v1.ObjectMeta{
+						Name:       "test",
+						Finalizers: []string{constants.ManifestWorkFinalizer},
+						Annotations: map[string]string{
+							constants.KlusterletWorksServerSideApplyAnnotation: "true",
+						},
+					},
+					Status: clusterv1.ManagedClusterStatus{
+						Version: clusterv1.ManagedClusterVersion{Kubernetes: "v1.18.0"},
+					},
+				},
+			},
+			works: []runtime.Object{},
+			secrets: []runtime.Object{
+				testinghelpers.GetImportSecret("test"),
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, runtimeClient client.Client, workClient workclient.Interface) {
+				manifestWorks, err := workClient.WorkV1().ManifestWorks("test").List(context.TODO(), v1.ListOptions{})
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if len(manifestWorks.Items) != 2 {
+					t.Errorf("expected two works, but failed %d", len(manifestWorks.Items))
+				}
+				for _, work := range manifestWorks.Items {
+					if len(work.Spec.ManifestConfigs) != len(work.Spec.Workload.Manifests) {
+						t.Errorf("expected each manifest of work %s has a manifest config, but got %d/%d", work.Name,
+							len(work.Spec.ManifestConfigs), len(work.Spec.Workload.Manifests))
+					}
+					for _, config := range work.Spec.ManifestConfigs {
+						if config.UpdateStrategy == nil ||
+							config.UpdateStrategy.Type != workv1.UpdateStrategyTypeServerSideApply {
+							t.Errorf("expected the update strategy of %v is ServerSideApply, but got %v",
+								config.ResourceIdentifier, config.UpdateStrategy)
+						}
+					}
+					if work.Name == "test-klusterlet-crds" &&
+						work.Spec.ManifestConfigs[0].ResourceIdentifier != (workv1.ResourceIdentifier{
+							Group:    "apiextensions.k8s.io",
+							Resource: "customresourcedefinitions",
+							Name:     "klusterlets.operator.open-cluster-management.io",
+						}) {
+						t.Errorf("unexpected resource identifier %v", work.Spec.ManifestConfigs[0].ResourceIdentifier)
+					}
+				}
+			},
+		},
+		{
+			name: "apply klusterlet manifest works with the selectively orphan delete option",
+			startObjs: []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: v1.ObjectMeta{
+						Name:       "test",
+						Finalizers: []string{constants.ManifestWorkFinalizer},
+						Annotations: map[string]string{
+							constants.KlusterletWorksDeleteOptionAnnotation: "SelectivelyOrphan",
+						},
+					},
+					Status: clusterv1.ManagedClusterStatus{
+						Conditions: []v1.Condition{
+							{
+								Type:   clusterv1.ManagedClusterConditionJoined,
+								Status: v1.ConditionTrue,
+							},
+						},
+						Version: clusterv1.ManagedClusterVersion{Kubernetes: "v1.18.0"},
+					},
+				},
+			},
+			works: []runtime.Object{},
+			secrets: []runtime.Object{
+				testinghelpers.GetImportSecret("test"),
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, runtimeClient client.Client, workClient workclient.Interface) {
+				manifestWorks, err := workClient.WorkV1().ManifestWorks("test").List(context.TODO(), v1.ListOptions{})
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if len(manifestWorks.Items) != 2 {
+					t.Errorf("expected two works, but failed %d", len(manifestWorks.Items))
+				}
+				for _, work := range manifestWorks.Items {
+					switch work.Name {
+					case "test-klusterlet-crds":
+						if work.Spec.DeleteOption == nil || work.Spec.DeleteOption.PropagationPolicy !=
+							workv1.DeletePropagationPolicyTypeSelectivelyOrphan {
+							t.Fatalf("expected the delete option of work %s is SelectivelyOrphan, but got %v",
+								work.Name, work.Spec.DeleteOption)
+						}
+						rules := work.Spec.DeleteOption.SelectivelyOrphan.OrphaningRules
+						if len(rules) != 1 || rules[0].Name != "klusterlets.operator.open-cluster-management.io" {
+							t.Errorf("expected the klusterlet crd is orphaned, but got %v", rules)
+						}
+					case "test-klusterlet":
+						if work.Spec.DeleteOption == nil ||
+							work.Spec.DeleteOption.PropagationPolicy != workv1.DeletePropagationPolicyTypeOrphan {
+							t.Errorf("expected the delete option of work %s is Orphan, but got %v",
+								work.Name, work.Spec.DeleteOption)
+						}
+					}
+				}
+			},
+		},
+		{
+			name: "apply klusterlet manifest works with an invalid delete option",
+			startObjs: []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: v1.ObjectMeta{
+						Name:       "test",
+						Finalizers: []string{constants.ManifestWorkFinalizer},
+						Annotations: map[string]string{
+							constants.KlusterletWorksDeleteOptionAnnotation: "Background",
+						},
+					},
+					Status: clusterv1.ManagedClusterStatus{
+						Conditions: []v1.Condition{
+							{
+								Type:   clusterv1.ManagedClusterConditionJoined,
+								Status: v1.ConditionTrue,
+							},
+						},
+						Version: clusterv1.ManagedClusterVersion{Kubernetes: "v1.18.0"},
+					},
+				},
+			},
+			works: []runtime.Object{},
+			secrets: []runtime.Object{
+				testinghelpers.GetImportSecret("test"),
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, runtimeClient client.Client, workClient workclient.Interface) {
+				manifestWorks, err := workClient.WorkV1().ManifestWorks("test").List(context.TODO(), v1.ListOptions{})
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if len(manifestWorks.Items) != 2 {
+					t.Errorf("expected two works, but failed %d", len(manifestWorks.Items))
+				}
+				for _, work := range manifestWorks.Items {
+					switch work.Name {
+					case "test-klusterlet-crds":
+						if work.Spec.DeleteOption != nil {
+							t.Errorf("expected the default delete option of work %s, but got %v",
+								work.Name, work.Spec.DeleteOption)
+						}
+					case "test-klusterlet":
+						if work.Spec.DeleteOption == nil ||
+							work.Spec.DeleteOption.PropagationPolicy != workv1.DeletePropagationPolicyTypeOrphan {
+							t.Errorf("expected the delete option of work %s is Orphan, but got %v",
+								work.Name, work.Spec.DeleteOption)
+						}
+					}
+				}
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -687,3 +915,565 @@ func TestReconcile(t *testing.T) {
 		})
 	}
 }
+
+func TestImportSecretNotGenerated(t *testing.T) {
+	cases := []struct {
+		name              string
+		creationTimestamp time.Time
+		conditions        []v1.Condition
+		expectedRequeue   bool
+		expectedReason    string
+	}{
+		{
+			name:              "import secret is missing in a short time",
+			creationTimestamp: time.Now().Add(-1 * time.Minute),
+			expectedRequeue:   true,
+		},
+		{
+			name:              "import secret is missing for too long",
+			creationTimestamp: time.Now().Add(-10 * time.Minute),
+			expectedReason:    constants.ConditionReasonImportSecretNotGenerated,
+		},
+		{
+			name:              "import secret is missing after the cluster is imported",
+			creationTimestamp: time.Now().Add(-10 * time.Minute),
+			conditions: []v1.Condition{
+				helpers.NewManagedClusterImportSucceededCondition(v1.ConditionTrue,
+					constants.ConditionReasonManagedClusterImported, "imported"),
+			},
+			expectedReason: constants.ConditionReasonManagedClusterImported,
+		},
+		{
+			name:              "import secret is deleted for the rejected klusterlet images",
+			creationTimestamp: time.Now().Add(-10 * time.Minute),
+			conditions: []v1.Condition{
+				helpers.NewManagedClusterImportSucceededCondition(v1.ConditionFalse,
+					constants.ConditionReasonImagesNotDigestPinned, "not digest pinned"),
+			},
+			expectedReason: constants.ConditionReasonImagesNotDigestPinned,
+		},
+		{
+			name:              "import secret is not generated for an invalid klusterlet namespace",
+			creationTimestamp: time.Now().Add(-10 * time.Minute),
+			conditions: []v1.Condition{
+				helpers.NewManagedClusterImportSucceededCondition(v1.ConditionFalse,
+					constants.ConditionReasonKlusterletNamespaceInvalid, "invalid namespace"),
+			},
+			expectedReason: constants.ConditionReasonKlusterletNamespaceInvalid,
+		},
+		{
+			name:              "import secret is too large",
+			creationTimestamp: time.Now().Add(-10 * time.Minute),
+			conditions: []v1.Condition{
+				helpers.NewManagedClusterImportSucceededCondition(v1.ConditionFalse,
+					constants.ConditionReasonImportSecretTooLarge, "too large"),
+			},
+			expectedReason: constants.ConditionReasonImportSecretTooLarge,
+		},
+		{
+			name:              "import secret is not generated for the misconfigured hub bootstrap",
+			creationTimestamp: time.Now().Add(-10 * time.Minute),
+			conditions: []v1.Condition{
+				helpers.NewManagedClusterImportSucceededCondition(v1.ConditionFalse,
+					constants.ConditionReasonHubBootstrapMisconfigured, "misconfigured"),
+			},
+			expectedReason: constants.ConditionReasonHubBootstrapMisconfigured,
+		},
+		{
+			name:              "import secret is missing after the cluster is available",
+			creationTimestamp: time.Now().Add(-10 * time.Minute),
+			conditions: []v1.Condition{
+				{
+					Type:   clusterv1.ManagedClusterConditionAvailable,
+					Status: v1.ConditionTrue,
+					Reason: "ManagedClusterAvailable",
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			managedCluster := &clusterv1.ManagedCluster{
+				ObjectMeta: v1.ObjectMeta{
+					Name:              "test",
+					Finalizers:        []string{constants.ManifestWorkFinalizer},
+					CreationTimestamp: v1.NewTime(c.creationTimestamp),
+				},
+				Status: clusterv1.ManagedClusterStatus{
+					Conditions: c.conditions,
+				},
+			}
+
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+			workClient := workfake.NewSimpleClientset()
+			workInformerFactory := workinformers.NewSharedInformerFactory(workClient, 10*time.Minute)
+
+			r := &ReconcileManifestWork{
+				clientHolder: &helpers.ClientHolder{
+					RuntimeClient: fake.NewClientBuilder().WithScheme(testscheme).
+						WithObjects(managedCluster).WithStatusSubresource(managedCluster).Build(),
+					KubeClient: kubeClient,
+					WorkClient: workClient,
+				},
+				informerHolder: &source.InformerHolder{
+					ImportSecretLister:   kubeInformerFactory.Core().V1().Secrets().Lister(),
+					KlusterletWorkLister: workInformerFactory.Work().V1().ManifestWorks().Lister(),
+				},
+				scheme:   testscheme,
+				recorder: eventstesting.NewTestingEventRecorder(t),
+			}
+
+			// the import secret is never generated, reconcile the managed cluster repeatedly
+			for i := 0; i < 2; i++ {
+				result, err := r.Reconcile(context.TODO(), reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: "test"},
+				})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if c.expectedRequeue != (result.RequeueAfter > 0) {
+					t.Errorf("expected requeue %v, but got %v", c.expectedRequeue, result)
+				}
+			}
+
+			cluster := &clusterv1.ManagedCluster{}
+			if err := r.clientHolder.RuntimeClient.Get(
+				context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			condition := meta.FindStatusCondition(
+				cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+			switch {
+			case len(c.expectedReason) == 0 && condition != nil:
+				t.Errorf("expected no import condition, but got %v", condition)
+			case len(c.expectedReason) != 0 && (condition == nil || condition.Reason != c.expectedReason):
+				t.Errorf("expected import condition reason %s, but got %v", c.expectedReason, condition)
+			}
+		})
+	}
+}
+
+func TestPartialImport(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{
+		ObjectMeta: v1.ObjectMeta{
+			Name:       "test",
+			Finalizers: []string{constants.ManifestWorkFinalizer},
+			Annotations: map[string]string{
+				constants.KlusterletWorksPartialApplyAnnotation: "true",
+			},
+		},
+	}
+	importSecret := testinghelpers.GetImportSecret("test")
+
+	kubeClient := kubefake.NewSimpleClientset(importSecret)
+	kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+	kubeInformerFactory.Core().V1().Secrets().Informer().GetStore().Add(importSecret)
+
+	// the klusterlet work fails to apply until the failure is fixed
+	failKlusterletWork := true
+	workClient := workfake.NewSimpleClientset()
+	workClient.PrependReactor("create", "manifestworks",
+		func(action clienttesting.Action) (bool, runtime.Object, error) {
+			work := action.(clienttesting.CreateAction).GetObject().(*workv1.ManifestWork)
+			if failKlusterletWork && work.Name == "test-klusterlet" {
+				return true, nil, errors.NewBadRequest("the manifestwork is rejected")
+			}
+			return false, nil, nil
+		})
+	workInformerFactory := workinformers.NewSharedInformerFactory(workClient, 10*time.Minute)
+
+	r := &ReconcileManifestWork{
+		clientHolder: &helpers.ClientHolder{
+			RuntimeClient: fake.NewClientBuilder().WithScheme(testscheme).
+				WithObjects(managedCluster).WithStatusSubresource(managedCluster).Build(),
+			KubeClient: kubeClient,
+			WorkClient: workClient,
+		},
+		informerHolder: &source.InformerHolder{
+			ImportSecretLister:   kubeInformerFactory.Core().V1().Secrets().Lister(),
+			KlusterletWorkLister: workInformerFactory.Work().V1().ManifestWorks().Lister(),
+		},
+		scheme:   testscheme,
+		recorder: eventstesting.NewTestingEventRecorder(t),
+	}
+
+	getCondition := func() *v1.Condition {
+		cluster := &clusterv1.ManagedCluster{}
+		if err := r.clientHolder.RuntimeClient.Get(
+			context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return meta.FindStatusCondition(cluster.Status.Conditions, constants.ConditionPartialImport)
+	}
+
+	result, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "test"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RequeueAfter == 0 {
+		t.Errorf("expected the failed work is retried, but got %v", result)
+	}
+	crdsWork, err := workClient.WorkV1().ManifestWorks("test").Get(
+		context.TODO(), "test-klusterlet-crds", v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the crds work is applied, but got %v", err)
+	}
+	// sync the applied work to the informer
+	if err := workInformerFactory.Work().V1().ManifestWorks().Informer().GetStore().Add(crdsWork); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	condition := getCondition()
+	if condition == nil || condition.Status != v1.ConditionTrue ||
+		condition.Reason != constants.ConditionReasonKlusterletWorksPartiallyApplied {
+		t.Fatalf("expected the partial import condition, but got %v", condition)
+	}
+	if !strings.Contains(condition.Message, "test-klusterlet failed to apply") {
+		t.Errorf("expected the condition message contains the failed work, but got %q", condition.Message)
+	}
+
+	// only the failed work is applied on the retry
+	failKlusterletWork = false
+	workClient.ClearActions()
+	if _, err := r.Reconcile(context.TODO(), reconcile.Request{
+		NamespacedName: types.NamespacedName{Name: "test"},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, action := range workClient.Actions() {
+		var name string
+		switch a := action.(type) {
+		case clienttesting.GetAction:
+			name = a.GetName()
+		case clienttesting.CreateAction:
+			name = a.GetObject().(*workv1.ManifestWork).Name
+		case clienttesting.UpdateAction:
+			name = a.GetObject().(*workv1.ManifestWork).Name
+		default:
+			continue
+		}
+		if name != "test-klusterlet" {
+			t.Errorf("expected only the failed work is applied, but got %s %s", action.GetVerb(), name)
+		}
+	}
+	if _, err := workClient.WorkV1().ManifestWorks("test").Get(
+		context.TODO(), "test-klusterlet", v1.GetOptions{}); err != nil {
+		t.Errorf("expected the klusterlet work is applied, but got %v", err)
+	}
+	condition = getCondition()
+	if condition == nil || condition.Status != v1.ConditionFalse {
+		t.Errorf("expected the partial import condition is cleared, but got %v", condition)
+	}
+
+	// the partial apply is disabled, the partial import condition is cleared
+	if err := helpers.UpdateManagedClusterStatus(r.clientHolder.RuntimeClient, "test", v1.Condition{
+		Type:   constants.ConditionPartialImport,
+		Status: v1.ConditionTrue,
+		Reason: constants.ConditionReasonKlusterletWorksPartiallyApplied,
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cluster := &clusterv1.ManagedCluster{}
+	if err := r.clientHolder.RuntimeClient.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cluster.Annotations[constants.KlusterletWorksPartialApplyAnnotation] = "false"
+	if err := r.clientHolder.RuntimeClient.Update(context.TODO(), cluster); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.Reconcile(context.TODO(), reconcile.Request{
+		NamespacedName: types.NamespacedName{Name: "test"},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	condition = getCondition()
+	if condition == nil || condition.Status != v1.ConditionFalse ||
+		condition.Reason != constants.ConditionReasonKlusterletWorksPartialApplyDisabled {
+		t.Errorf("expected the partial import condition is cleared, but got %v", condition)
+	}
+}
+
+func TestPartialImportCRDsWorkFailed(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{
+		ObjectMeta: v1.ObjectMeta{
+			Name:       "test",
+			Finalizers: []string{constants.ManifestWorkFinalizer},
+			Annotations: map[string]string{
+				constants.KlusterletWorksPartialApplyAnnotation: "true",
+			},
+		},
+	}
+	importSecret := testinghelpers.GetImportSecret("test")
+
+	kubeClient := kubefake.NewSimpleClientset(importSecret)
+	kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+	kubeInformerFactory.Core().V1().Secrets().Informer().GetStore().Add(importSecret)
+
+	workClient := workfake.NewSimpleClientset()
+	workClient.PrependReactor("create", "manifestworks",
+		func(action clienttesting.Action) (bool, runtime.Object, error) {
+			work := action.(clienttesting.CreateAction).GetObject().(*workv1.ManifestWork)
+			if work.Name == "test-klusterlet-crds" {
+				return true, nil, errors.NewBadRequest("the manifestwork is rejected")
+			}
+			return false, nil, nil
+		})
+	workInformerFactory := workinformers.NewSharedInformerFactory(workClient, 10*time.Minute)
+
+	r := &ReconcileManifestWork{
+		clientHolder: &helpers.ClientHolder{
+			RuntimeClient: fake.NewClientBuilder().WithScheme(testscheme).
+				WithObjects(managedCluster).WithStatusSubresource(managedCluster).Build(),
+			KubeClient: kubeClient,
+			WorkClient: workClient,
+		},
+		informerHolder: &source.InformerHolder{
+			ImportSecretLister:   kubeInformerFactory.Core().V1().Secrets().Lister(),
+			KlusterletWorkLister: workInformerFactory.Work().V1().ManifestWorks().Lister(),
+		},
+		scheme:   testscheme,
+		recorder: eventstesting.NewTestingEventRecorder(t),
+	}
+
+	if _, err := r.Reconcile(context.TODO(), reconcile.Request{
+		NamespacedName: types.NamespacedName{Name: "test"},
+	}); err == nil {
+		t.Errorf("expected the failed crds work fails the apply, but succeeded")
+	}
+	if _, err := workClient.WorkV1().ManifestWorks("test").Get(
+		context.TODO(), "test-klusterlet", v1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the klusterlet work is not applied without the crds, but got %v", err)
+	}
+
+	cluster := &clusterv1.ManagedCluster{}
+	if err := r.clientHolder.RuntimeClient.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if condition := meta.FindStatusCondition(
+		cluster.Status.Conditions, constants.ConditionPartialImport); condition != nil {
+		t.Errorf("expected no partial import condition, but got %v", condition)
+	}
+}
+
+func TestKlusterletCleanupGate(t *testing.T) {
+	cases := []struct {
+		name                string
+		deleteOption        string
+		expectedForceDelete bool
+	}{
+		{
+			name: "the klusterlet is cleaned up",
+		},
+		{
+			name:                "the klusterlet is orphaned",
+			deleteOption:        string(workv1.DeletePropagationPolicyTypeOrphan),
+			expectedForceDelete: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			annotations := map[string]string{
+				constants.CreatedViaAnnotation:            constants.CreatedViaOther,
+				constants.KlusterletCleanupGateAnnotation: "true",
+			}
+			if len(c.deleteOption) != 0 {
+				annotations[constants.KlusterletWorksDeleteOptionAnnotation] = c.deleteOption
+			}
+			managedCluster := &clusterv1.ManagedCluster{
+				ObjectMeta: v1.ObjectMeta{
+					Name:              "test",
+					Finalizers:        []string{constants.ManifestWorkFinalizer},
+					Annotations:       annotations,
+					DeletionTimestamp: &now,
+				},
+				Status: clusterv1.ManagedClusterStatus{
+					Conditions: []v1.Condition{
+						{
+							Type:   clusterv1.ManagedClusterConditionAvailable,
+							Status: v1.ConditionUnknown,
+						},
+					},
+				},
+			}
+			works := []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: v1.ObjectMeta{
+						Name:       "test-klusterlet",
+						Namespace:  "test",
+						Finalizers: []string{"test"},
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+				&workv1.ManifestWork{
+					ObjectMeta: v1.ObjectMeta{
+						Name:       "test-klusterlet-crds",
+						Namespace:  "test",
+						Finalizers: []string{"test"},
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+			}
+
+			// the work agent is not able to clean up the klusterlet, the deleted works are kept with their
+			// finalizers
+			workClient := workfake.NewSimpleClientset(works...)
+			cleanedUp := false
+			workClient.PrependReactor("delete", "manifestworks",
+				func(action clienttesting.Action) (bool, runtime.Object, error) {
+					return !cleanedUp, nil, nil
+				})
+
+			r := &ReconcileManifestWork{
+				clientHolder: &helpers.ClientHolder{
+					RuntimeClient: fake.NewClientBuilder().WithScheme(testscheme).WithObjects(managedCluster).Build(),
+					KubeClient:    kubefake.NewSimpleClientset(),
+					WorkClient:    workClient,
+				},
+				scheme:   testscheme,
+				recorder: eventstesting.NewTestingEventRecorder(t),
+			}
+			request := reconcile.Request{NamespacedName: types.NamespacedName{Name: "test"}}
+
+			if _, err := r.Reconcile(context.TODO(), request); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			forceDeleted := false
+			for _, action := range workClient.Actions() {
+				if action.GetVerb() == "patch" {
+					forceDeleted = true
+				}
+			}
+			if forceDeleted != c.expectedForceDelete {
+				t.Errorf("expected the works are force deleted %v, but got %v", c.expectedForceDelete, forceDeleted)
+			}
+			if c.expectedForceDelete {
+				return
+			}
+
+			cluster := &clusterv1.ManagedCluster{}
+			if err := r.clientHolder.RuntimeClient.Get(
+				context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !hasFinalizer(cluster, constants.ManifestWorkFinalizer) {
+				t.Errorf("expected the deletion is blocked by the manifestwork finalizer, but got %v",
+					cluster.Finalizers)
+			}
+
+			// the work agent cleans up the klusterlet, the works are removed
+			cleanedUp = true
+			for i := 0; i < 3; i++ {
+				if _, err := r.Reconcile(context.TODO(), request); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			err := r.clientHolder.RuntimeClient.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster)
+			if err == nil && hasFinalizer(cluster, constants.ManifestWorkFinalizer) {
+				t.Errorf("expected the manifestwork finalizer is removed, but got %v", cluster.Finalizers)
+			}
+			if err != nil && !errors.IsNotFound(err) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRepairKlusterletWorksLabel(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{
+		ObjectMeta: v1.ObjectMeta{
+			Name:       "test",
+			Finalizers: []string{constants.ManifestWorkFinalizer},
+		},
+	}
+	works := []runtime.Object{
+		&workv1.ManifestWork{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "test-klusterlet",
+				Namespace: "test",
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "True",
+				},
+			},
+		},
+		&workv1.ManifestWork{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "test-klusterlet-crds",
+				Namespace: "test",
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "yes",
+				},
+			},
+		},
+		&workv1.ManifestWork{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "test-disabled",
+				Namespace: "test",
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "false",
+				},
+			},
+		},
+	}
+
+	kubeClient := kubefake.NewSimpleClientset()
+	kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+	workClient := workfake.NewSimpleClientset(works...)
+	workInformerFactory := workinformers.NewSharedInformerFactory(workClient, 10*time.Minute)
+	workInformer := workInformerFactory.Work().V1().ManifestWorks().Informer()
+	for _, work := range works {
+		workInformer.GetStore().Add(work)
+	}
+
+	r := &ReconcileManifestWork{
+		clientHolder: &helpers.ClientHolder{
+			RuntimeClient: fake.NewClientBuilder().WithScheme(testscheme).WithObjects(managedCluster).Build(),
+			KubeClient:    kubeClient,
+			WorkClient:    workClient,
+		},
+		informerHolder: &source.InformerHolder{
+			ImportSecretLister:   kubeInformerFactory.Core().V1().Secrets().Lister(),
+			KlusterletWorkLister: workInformerFactory.Work().V1().ManifestWorks().Lister(),
+		},
+		scheme:   testscheme,
+		recorder: eventstesting.NewTestingEventRecorder(t),
+	}
+
+	result, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "test"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Requeue {
+		t.Errorf("expected requeue after the works are repaired, but got %v", result)
+	}
+
+	expectedLabels := map[string]string{
+		"test-klusterlet":      "true",
+		"test-klusterlet-crds": "true",
+		"test-disabled":        "false",
+	}
+	for name, expected := range expectedLabels {
+		work, err := workClient.WorkV1().ManifestWorks("test").Get(context.TODO(), name, v1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if work.Labels[constants.KlusterletWorksLabel] != expected {
+			t.Errorf("expected the klusterlet works label of work %s is %q, but got %q",
+				name, expected, work.Labels[constants.KlusterletWorksLabel])
+		}
+	}
+}
+
+func hasFinalizer(cluster *clusterv1.ManagedCluster, finalizer string) bool {
+	for _, f := range cluster.Finalizers {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
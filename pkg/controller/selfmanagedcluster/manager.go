@@ -63,9 +63,11 @@ func Add(mgr manager.Manager, clientHolder *helpers.ClientHolder, informerHolder
 					DeleteFunc:  func(e event.DeleteEvent) bool { return false },
 					CreateFunc:  func(e event.CreateEvent) bool { return false },
 					UpdateFunc: func(e event.UpdateEvent) bool {
-						// only handle the label changed and new self managed label is true
+						// only handle the label or the force import annotation changed and new self managed label
+						// is true
 						newLabels := e.ObjectNew.GetLabels()
-						return !equality.Semantic.DeepEqual(e.ObjectOld.GetLabels(), newLabels) &&
+						return (!equality.Semantic.DeepEqual(e.ObjectOld.GetLabels(), newLabels) ||
+							helpers.ForceImportAnnotationAdded(e.ObjectOld, e.ObjectNew)) &&
 							strings.EqualFold(newLabels[constants.SelfManagedLabel], "true")
 					},
 				},
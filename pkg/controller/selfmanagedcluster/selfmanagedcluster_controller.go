@@ -6,6 +6,7 @@ package selfmanagedcluster
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
@@ -48,7 +49,7 @@ func NewReconcileLocalCluster(
 			func(secret *v1.Secret) (*helpers.ClientHolder, meta.RESTMapper, error) {
 				return clientHolder, restMapper, nil
 			},
-		),
+		).WithRuntimeClient(clientHolder.RuntimeClient),
 	}
 }
 
@@ -61,12 +62,14 @@ var _ reconcile.Reconciler = &ReconcileLocalCluster{}
 // Note: The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
 func (r *ReconcileLocalCluster) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	start := time.Now()
 	reqLogger := log.WithValues("Request.Name", request.Name)
 
 	managedCluster := &clusterv1.ManagedCluster{}
 	err := r.clientHolder.RuntimeClient.Get(ctx, types.NamespacedName{Name: request.Name}, managedCluster)
 	if errors.IsNotFound(err) {
-		// the managed cluster could have been deleted, do nothing
+		// the managed cluster could have been deleted, forget its failed imports
+		r.importHelper.ResetBackoff(request.Name)
 		return reconcile.Result{}, nil
 	}
 	if err != nil {
@@ -74,6 +77,7 @@ func (r *ReconcileLocalCluster) Reconcile(ctx context.Context, request reconcile
 	}
 
 	if !managedCluster.DeletionTimestamp.IsZero() {
+		r.importHelper.ResetBackoff(request.Name)
 		return reconcile.Result{}, nil
 	}
 
@@ -88,13 +92,21 @@ func (r *ReconcileLocalCluster) Reconcile(ctx context.Context, request reconcile
 	// the cluster
 	_, err = r.informerHolder.AutoImportSecretLister.Secrets(request.Name).Get(constants.AutoImportSecretName)
 	if err == nil {
+		helpers.RecordReconcileTrace(ctx, r.clientHolder.KubeClient, managedCluster, controllerName,
+			helpers.ReconcileDecisionSkippedAutoImport, "")
 		return reconcile.Result{}, nil
 	}
 	if !errors.IsNotFound(err) {
 		return reconcile.Result{}, err
 	}
 
-	result, condition, modified, _, iErr := r.importHelper.Import(false, request.Name, nil, 0, 1)
+	result, condition, modified, _, iErr := r.importHelper.Import(ctx, false, managedCluster, nil, 0, 1)
+	helpers.RecordReconcileTrace(ctx, r.clientHolder.KubeClient, managedCluster, controllerName,
+		helpers.ImportReconcileDecision(&condition, iErr), condition.Message)
+	if err := helpers.IncreaseImportFailedAttempts(
+		ctx, r.clientHolder.RuntimeClient, managedCluster, &condition, iErr); err != nil {
+		return reconcile.Result{}, err
+	}
 	// if resources are applied but NOT modified, will not update the condition, keep the original condition.
 	// This check is to prevent the current controller and import status controller from modifying the
 	// ManagedClusterImportSucceeded condition of the managed cluster in a loop
@@ -106,6 +118,8 @@ func (r *ReconcileLocalCluster) Reconcile(ctx context.Context, request reconcile
 		); err != nil {
 			return reconcile.Result{}, err
 		}
+		helpers.ObserveImportResult(
+			helpers.DetermineKlusterletMode(managedCluster), managedCluster, &condition, start)
 	}
 
 	return result, iErr
@@ -29,6 +29,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
 	"k8s.io/client-go/informers"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -54,6 +56,20 @@ var apiGroupResources = []*restmapper.APIGroupResources{
 			},
 		},
 	},
+	{
+		Group: metav1.APIGroup{
+			Name: "operator.open-cluster-management.io",
+			Versions: []metav1.GroupVersionForDiscovery{
+				{Version: "v1"},
+			},
+			PreferredVersion: metav1.GroupVersionForDiscovery{Version: "v1"},
+		},
+		VersionedResources: map[string][]metav1.APIResource{
+			"v1": {
+				{Name: "klusterlets", Namespaced: false, Kind: "Klusterlet"},
+			},
+		},
+	},
 }
 
 var testscheme = scheme.Scheme
@@ -236,6 +252,10 @@ func TestReconcile(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			kubeClient := kubefake.NewSimpleClientset(c.secrets...)
+			// the rest mapper only has the v1beta1 crds, use a kube version lower than v1.16
+			kubeClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{
+				GitVersion: "v1.15.0",
+			}
 			kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
 			secretInformer := kubeInformerFactory.Core().V1().Secrets().Informer()
 			for _, secret := range c.secrets {
@@ -279,3 +299,37 @@ func TestReconcile(t *testing.T) {
 		})
 	}
 }
+
+func TestReconcileResetBackoff(t *testing.T) {
+	backoff := helpers.NewImportBackoff(time.Second, time.Minute, 0)
+	backoff.Next("local-cluster")
+
+	kubeInformerFactory := informers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 10*time.Minute)
+	workInformerFactory := workinformers.NewSharedInformerFactory(workfake.NewSimpleClientset(), 10*time.Minute)
+	r := NewReconcileLocalCluster(
+		&helpers.ClientHolder{
+			RuntimeClient: fake.NewClientBuilder().WithScheme(testscheme).Build(),
+		},
+		&source.InformerHolder{
+			AutoImportSecretLister: kubeInformerFactory.Core().V1().Secrets().Lister(),
+			ImportSecretLister:     kubeInformerFactory.Core().V1().Secrets().Lister(),
+			KlusterletWorkLister:   workInformerFactory.Work().V1().ManifestWorks().Lister(),
+		},
+		restmapper.NewDiscoveryRESTMapper(apiGroupResources),
+		eventstesting.NewTestingEventRecorder(t),
+	)
+	r.importHelper = r.importHelper.WithImportBackoff(backoff)
+
+	// the managed cluster is deleted, its failed imports are forgotten
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Name: "local-cluster",
+		},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if failures := backoff.Failures("local-cluster"); failures != 0 {
+		t.Errorf("expected the backoff is reset, but got %d failures", failures)
+	}
+}
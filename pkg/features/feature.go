@@ -21,6 +21,15 @@ const (
 
 	// AgentRegistration enables a server to provide an endpoint for clients to get manifests
 	AgentRegistration featuregate.Feature = "AgentRegistration"
+
+	// ResyncLowPriorityQueue enqueues the reconcile requests that are triggered by the periodic informer resyncs
+	// into a lower-priority queue, the requests are processed only when the controller queue is idle, so the
+	// resyncs do not starve the requests that are triggered by the real changes
+	ResyncLowPriorityQueue featuregate.Feature = "ResyncLowPriorityQueue"
+
+	// AutoImportSecretWebhook enables a validating webhook to reject the auto import secrets that do not contain
+	// a kubeconfig or a server with a token, the webhook serving certificate is required
+	AutoImportSecretWebhook featuregate.Feature = "AutoImportSecretWebhook"
 )
 
 var (
@@ -38,6 +47,8 @@ func init() {
 // feature keys.  To add a new feature, define a key for it above and
 // add it here.
 var defaultRegistrationFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
-	KlusterletHostedMode: {Default: true, PreRelease: featuregate.Alpha},
-	AgentRegistration:    {Default: true, PreRelease: featuregate.Alpha},
+	KlusterletHostedMode:    {Default: true, PreRelease: featuregate.Alpha},
+	AgentRegistration:       {Default: true, PreRelease: featuregate.Alpha},
+	ResyncLowPriorityQueue:  {Default: false, PreRelease: featuregate.Alpha},
+	AutoImportSecretWebhook: {Default: false, PreRelease: featuregate.Alpha},
 }
@@ -5,7 +5,15 @@ package helpers
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -15,7 +23,13 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/openshift/library-go/pkg/operator/events"
@@ -23,6 +37,27 @@ import (
 	"github.com/stolostron/managedcluster-import-controller/pkg/source"
 )
 
+// hubNamespaceRBACPendingBackoffBase is the initial period to requeue a managed cluster when the controller is
+// forbidden to read the resources in the managed cluster namespace
+const hubNamespaceRBACPendingBackoffBase = 5 * time.Second
+
+// hubNamespaceRBACPendingBackoff tracks the forbidden reads in the managed cluster namespaces, it is shared by the
+// controllers because the RBAC of a managed cluster namespace is ready for all of them at the same time
+var hubNamespaceRBACPendingBackoff = NewImportBackoff(
+	hubNamespaceRBACPendingBackoffBase, defaultImportBackoffMax, defaultImportBackoffJitter)
+
+// maxImportErrorsMessageLength is the max length of the errors in the import condition message
+const maxImportErrorsMessageLength = 2048
+
+// defaultKlusterletWorksWaitingTimeout is how long the klusterlet manifest works can be missing before the import
+// reports the WaitingForKlusterletWorks reason
+const defaultKlusterletWorksWaitingTimeout = 5 * time.Minute
+
+const (
+	minSpokeKubeVersionEnvVarName = "MIN_SPOKE_KUBE_VERSION"
+	defaultMinSpokeKubeVersion    = "v1.11.0"
+)
+
 // DeleteAutoImportSecret delete the auto-import-secret if the secret does not have the keeping annotation
 func DeleteAutoImportSecret(ctx context.Context, kubeClient kubernetes.Interface,
 	secret *corev1.Secret, recorder events.Recorder) error {
@@ -51,6 +86,52 @@ func NewManagedClusterImportSucceededCondition(s metav1.ConditionStatus, reason,
 	}
 }
 
+// RequeueOnHubNamespaceRBACPending checks whether the err is a permission-denied error returned by reading the
+// resources in the managed cluster namespace. The RBAC of a newly created managed cluster namespace may lag, so
+// instead of failing the reconcile, the ImportSucceeded condition is set with the HubNamespaceRBACPending reason
+// and the managed cluster is requeued with the import backoff. The returned bool is false if the err is not a
+// permission-denied error in the managed cluster namespace or the managed cluster is already imported, the
+// RBAC of an imported managed cluster is not lagging, so the err should be handled as it is.
+func RequeueOnHubNamespaceRBACPending(runtimeClient client.Client, cluster *clusterv1.ManagedCluster, err error) (
+	reconcile.Result, bool, error) {
+	if err == nil {
+		hubNamespaceRBACPendingBackoff.Reset(cluster.Name)
+		return reconcile.Result{}, false, nil
+	}
+
+	if !isHubNamespaceForbidden(err, cluster.Name) {
+		return reconcile.Result{}, false, nil
+	}
+
+	if meta.IsStatusConditionTrue(cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded) {
+		return reconcile.Result{}, false, nil
+	}
+
+	if updateErr := UpdateManagedClusterStatus(
+		runtimeClient,
+		cluster.Name,
+		NewManagedClusterImportSucceededCondition(
+			metav1.ConditionFalse,
+			constants.ConditionReasonHubNamespaceRBACPending,
+			fmt.Sprintf("Wait for the RBAC of the namespace %s to be ready: %v", cluster.Name, err),
+		),
+	); updateErr != nil {
+		return reconcile.Result{}, true, updateErr
+	}
+
+	return reconcile.Result{RequeueAfter: hubNamespaceRBACPendingBackoff.Next(cluster.Name)}, true, nil
+}
+
+// isHubNamespaceForbidden returns true if the err is a permission-denied error of the request in the given
+// namespace, the apiserver reports the namespace of the denied request in the error message
+func isHubNamespaceForbidden(err error, namespace string) bool {
+	if !errors.IsForbidden(err) {
+		return false
+	}
+
+	return strings.Contains(err.Error(), fmt.Sprintf("in the namespace %q", namespace))
+}
+
 func ContainAuthError(err error) bool {
 	if errors.IsUnauthorized(err) || errors.IsForbidden(err) {
 		return true
@@ -91,6 +172,10 @@ type ApplyResourcesFunc func(backupRestore bool, client *ClientHolder, restMappe
 // used to import cluster(apply resources to the managed cluster)
 type GenerateClientHolderFunc func(secret *corev1.Secret) (*ClientHolder, meta.RESTMapper, error)
 
+// DryRunImportFunc is a function to validate the import of the managed cluster without applying anything
+type DryRunImportFunc func(ctx context.Context, client *ClientHolder, restMapper meta.RESTMapper,
+	importSecret *corev1.Secret) error
+
 // ImportHelper is used to helper controller to import managed cluster
 type ImportHelper struct {
 	informerHolder *source.InformerHolder
@@ -99,6 +184,26 @@ type ImportHelper struct {
 
 	generateClientHolderFunc GenerateClientHolderFunc
 	applyResourcesFunc       ApplyResourcesFunc
+	dryRunImportFunc         DryRunImportFunc
+
+	// minSpokeKubeVersion is the minimum kube version of the managed cluster that the klusterlet requires
+	minSpokeKubeVersion *version.Version
+
+	// backoff computes the durations to wait before retrying the failed imports of the managed clusters
+	backoff *ImportBackoff
+
+	// runtimeClient is used to record the last applied hash of the import secret on the managed cluster, the
+	// import is not skipped if it is not set
+	runtimeClient client.Client
+
+	// klusterletWorksWaitingTimeout is how long the klusterlet manifest works can be missing before the import
+	// reports the WaitingForKlusterletWorks reason
+	klusterletWorksWaitingTimeout time.Duration
+
+	// klusterletWorksWaitingSince records when the imports of the managed clusters start to wait for the
+	// klusterlet manifest works
+	klusterletWorksWaitingLock  sync.Mutex
+	klusterletWorksWaitingSince map[string]time.Time
 }
 
 func (i *ImportHelper) WithApplyResourcesFunc(f ApplyResourcesFunc) *ImportHelper {
@@ -106,6 +211,33 @@ func (i *ImportHelper) WithApplyResourcesFunc(f ApplyResourcesFunc) *ImportHelpe
 	return i
 }
 
+func (i *ImportHelper) WithImportBackoff(b *ImportBackoff) *ImportHelper {
+	i.backoff = b
+	return i
+}
+
+func (i *ImportHelper) WithKlusterletWorksWaitingTimeout(timeout time.Duration) *ImportHelper {
+	i.klusterletWorksWaitingTimeout = timeout
+	return i
+}
+
+// ResetBackoff forgets the failed imports and the klusterlet manifest works waiting of the managed cluster, it
+// should be called after the managed cluster is deleted
+func (i *ImportHelper) ResetBackoff(clusterName string) {
+	i.backoff.Reset(clusterName)
+	i.stopWaitingForKlusterletWorks(clusterName)
+}
+
+func (i *ImportHelper) WithRuntimeClient(c client.Client) *ImportHelper {
+	i.runtimeClient = c
+	return i
+}
+
+func (i *ImportHelper) WithDryRunImportFunc(f DryRunImportFunc) *ImportHelper {
+	i.dryRunImportFunc = f
+	return i
+}
+
 func (i *ImportHelper) WithGenerateClientHolderFunc(f GenerateClientHolderFunc) *ImportHelper {
 	i.generateClientHolderFunc = f
 	return i
@@ -120,25 +252,90 @@ func NewImportHelper(informerHolder *source.InformerHolder,
 
 		generateClientHolderFunc: GenerateClientFromSecret,
 		applyResourcesFunc:       defaultApplyResourcesFunc,
+		dryRunImportFunc:         DryRunImportManagedClusterFromSecret,
+		minSpokeKubeVersion:      getMinSpokeKubeVersion(),
+		backoff: NewImportBackoff(
+			defaultImportBackoffBase, defaultImportBackoffMax, defaultImportBackoffJitter),
+		klusterletWorksWaitingTimeout: defaultKlusterletWorksWaitingTimeout,
+		klusterletWorksWaitingSince:   map[string]time.Time{},
+	}
+}
+
+// waitForKlusterletWorks records the import of the managed cluster is waiting for the klusterlet manifest works
+// and returns how long it has been waiting
+func (i *ImportHelper) waitForKlusterletWorks(clusterName string) time.Duration {
+	i.klusterletWorksWaitingLock.Lock()
+	defer i.klusterletWorksWaitingLock.Unlock()
+
+	since, ok := i.klusterletWorksWaitingSince[clusterName]
+	if !ok {
+		since = time.Now()
+		i.klusterletWorksWaitingSince[clusterName] = since
+	}
+	return time.Since(since)
+}
+
+// stopWaitingForKlusterletWorks forgets the waiting for the klusterlet manifest works of the managed cluster
+func (i *ImportHelper) stopWaitingForKlusterletWorks(clusterName string) {
+	i.klusterletWorksWaitingLock.Lock()
+	defer i.klusterletWorksWaitingLock.Unlock()
+
+	delete(i.klusterletWorksWaitingSince, clusterName)
+}
+
+// getMinSpokeKubeVersion gets the minimum kube version of the managed cluster from MIN_SPOKE_KUBE_VERSION env,
+// if the env is not set or is invalid, the default version v1.11.0 is used.
+func getMinSpokeKubeVersion() *version.Version {
+	minVersion := os.Getenv(minSpokeKubeVersionEnvVarName)
+	if minVersion == "" {
+		return version.MustParseGeneric(defaultMinSpokeKubeVersion)
 	}
+
+	v, err := version.ParseGeneric(minVersion)
+	if err != nil {
+		klog.Warningf("The value of %s env is wrong, using default version (%s)",
+			minSpokeKubeVersionEnvVarName, defaultMinSpokeKubeVersion)
+		return version.MustParseGeneric(defaultMinSpokeKubeVersion)
+	}
+	return v
+}
+
+// checkSpokeKubeVersion checks whether the kube version of the managed cluster is supported, the version
+// of the managed cluster is also returned.
+// If the version cannot be determined, the check is skipped, the subsequent import will report the error.
+func (i *ImportHelper) checkSpokeKubeVersion(kubeClient kubernetes.Interface) (bool, string) {
+	serverVersion, err := kubeClient.Discovery().ServerVersion()
+	if err != nil {
+		i.log.Info("Failed to get the kube version of the managed cluster, skip the check", "error", err)
+		return true, ""
+	}
+
+	spokeVersion, err := version.ParseGeneric(serverVersion.GitVersion)
+	if err != nil {
+		i.log.Info("Failed to parse the kube version of the managed cluster, skip the check",
+			"version", serverVersion.GitVersion, "error", err)
+		return true, serverVersion.GitVersion
+	}
+
+	return spokeVersion.AtLeast(i.minSpokeKubeVersion), serverVersion.GitVersion
 }
 
 func defaultApplyResourcesFunc(backupRestore bool, client *ClientHolder,
 	restMapper meta.RESTMapper, recorder events.Recorder, importSecret *corev1.Secret) (bool, error) {
-	if backupRestore {
-		// only update the bootstrap secret on the managed cluster with the auto-import-secret
-		return UpdateManagedClusterBootstrapSecret(client, importSecret, recorder)
-	}
-	return ImportManagedClusterFromSecret(client, restMapper, recorder, importSecret)
+	return applyKlusterletResources(backupRestore, client, restMapper, recorder, importSecret)
 }
 
 // Import uses the managedClusterKubeClientSecret to generate a managed cluster client,
 // then use this client to import the managed cluster, return condition when finished
 // apply
-func (i *ImportHelper) Import(backupRestore bool, clusterName string,
+//
+// The agent is imported by the importer that is specified by the importer annotation of the managed cluster,
+// the klusterlet is imported by default.
+func (i *ImportHelper) Import(ctx context.Context, backupRestore bool, cluster *clusterv1.ManagedCluster,
 	managedClusterKubeClientSecret *corev1.Secret, lastRetry, totalRetry int) (
 	reconcile.Result, metav1.Condition, bool, int, error) {
 
+	clusterName := cluster.Name
 	reqLogger := i.log.WithValues("Request.Name", clusterName)
 	currentRetry := lastRetry
 
@@ -153,29 +350,123 @@ func (i *ImportHelper) Import(backupRestore bool, clusterName string,
 				fmt.Sprintf("Get klusterlet manifestwork failed: %v. Will retry", err),
 			), false, currentRetry, err
 	}
-	if errors.IsNotFound(err) || len(manifestWorks) != 2 {
-		reqLogger.Info(fmt.Sprintf("Waiting for klusterlet manifest works for managed cluster %s", clusterName))
+	// ignore the klusterlet manifest works that are created by other hubs
+	ownManifestWorks := []*workv1.ManifestWork{}
+	for _, work := range manifestWorks {
+		if IsForeignKlusterletWork(work) {
+			reqLogger.Info("Ignore the foreign klusterlet manifest work", "work", work.Name)
+			continue
+		}
+		ownManifestWorks = append(ownManifestWorks, work)
+	}
+	if unexpected := unexpectedKlusterletWorks(clusterName, ownManifestWorks); len(unexpected) > 0 {
+		reqLogger.Info("Unexpected klusterlet manifest works are found", "works", unexpected)
 		return reconcile.Result{RequeueAfter: 3 * time.Second},
 			NewManagedClusterImportSucceededCondition(
 				metav1.ConditionFalse,
-				constants.ConditionReasonManagedClusterImporting,
-				fmt.Sprintf("Expect 2 manifestworks, but got %v. Will retry", len(manifestWorks)),
+				constants.ConditionReasonUnexpectedKlusterletWorks,
+				fmt.Sprintf("The manifestworks %s have the klusterlet works label but are not the klusterlet "+
+					"manifestworks, please remove the label from them. Will retry", strings.Join(unexpected, ",")),
 			), false, currentRetry, nil
 	}
+	if errors.IsNotFound(err) || len(ownManifestWorks) != 2 {
+		reqLogger.Info(fmt.Sprintf("Waiting for klusterlet manifest works for managed cluster %s", clusterName))
+		condition := NewManagedClusterImportSucceededCondition(
+			metav1.ConditionFalse,
+			constants.ConditionReasonManagedClusterImporting,
+			fmt.Sprintf("Expect 2 manifestworks, but got %v. Will retry", len(ownManifestWorks)),
+		)
+		// the klusterlet manifest works may never be created if the manifestwork controller fails, report it
+		// instead of waiting silently
+		if waiting := i.waitForKlusterletWorks(clusterName); waiting >= i.klusterletWorksWaitingTimeout {
+			condition.Reason = constants.ConditionReasonWaitingForKlusterletWorks
+			condition.Message = fmt.Sprintf("The klusterlet manifestworks have been waited on for %s, "+
+				"expect 2 manifestworks, but got %v. Will retry", waiting.Round(time.Second), len(ownManifestWorks))
+		}
+		return reconcile.Result{RequeueAfter: 3 * time.Second}, condition, false, currentRetry, nil
+	}
+	i.stopWaitingForKlusterletWorks(clusterName)
 
-	clientHolder, restMapper, err := i.generateClientHolderFunc(managedClusterKubeClientSecret)
+	applyResourcesFunc := i.applyResourcesFunc
+	importer, err := GetImporter(cluster.GetAnnotations())
 	if err != nil {
 		return reconcile.Result{},
 			NewManagedClusterImportSucceededCondition(
 				metav1.ConditionFalse,
 				constants.ConditionReasonManagedClusterImportFailed,
-				fmt.Sprintf("AutoImportSecretInvalid %s/%s; generate kube client by secret error: %v",
+				err.Error(),
+			), false, currentRetry, nil
+	}
+	strategy, err := GetAutoImportStrategy(managedClusterKubeClientSecret)
+	if err != nil {
+		return reconcile.Result{},
+			NewManagedClusterImportSucceededCondition(
+				metav1.ConditionFalse,
+				constants.ConditionReasonManagedClusterImportFailed,
+				fmt.Sprintf("AutoImportSecretInvalid %s/%s; %v",
 					managedClusterKubeClientSecret.Namespace, managedClusterKubeClientSecret.Name, err),
 			), false, currentRetry, nil
 	}
+	if importer != nil && strategy == constants.AutoImportStrategyToken {
+		// the importer imports the agent with its own manifests, it cannot honor the token strategy
+		return reconcile.Result{},
+			NewManagedClusterImportSucceededCondition(
+				metav1.ConditionFalse,
+				constants.ConditionReasonManagedClusterImportFailed,
+				fmt.Sprintf("AutoImportSecretInvalid %s/%s; the %s auto import strategy cannot be used with the "+
+					"importer %s that is specified by the annotation %s",
+					managedClusterKubeClientSecret.Namespace, managedClusterKubeClientSecret.Name,
+					constants.AutoImportStrategyToken, cluster.GetAnnotations()[constants.ImporterAnnotation],
+					constants.ImporterAnnotation),
+			), false, currentRetry, nil
+	}
+	if importer != nil {
+		applyResourcesFunc = importerApplyResourcesFunc(ctx, importer)
+	} else if strategy == constants.AutoImportStrategyToken {
+		// the klusterlet is deployed by the managed cluster itself, only apply the bootstrap manifests with the
+		// limited permissions of the bootstrap token
+		applyResourcesFunc = bootstrapTokenApplyResourcesFunc
+	}
+
+	// record the durations of the import stages, so the slow stage can be identified
+	stageStart := time.Now()
+	clientHolder, restMapper, err := i.generateClientHolderFunc(managedClusterKubeClientSecret)
+	clientBuildDuration := time.Since(stageStart)
+	if err != nil {
+		condition := NewManagedClusterImportSucceededCondition(
+			metav1.ConditionFalse,
+			importFailedReason(totalRetry),
+			fmt.Sprintf("AutoImportSecretInvalid %s/%s; generate kube client by secret error: %v",
+				managedClusterKubeClientSecret.Namespace, managedClusterKubeClientSecret.Name, err),
+		)
+
+		if currentRetry+1 < totalRetry {
+			// the managed cluster may be unreachable temporarily, retry with the backoff
+			condition.Reason = constants.ConditionReasonManagedClusterImporting
+			condition.Message = fmt.Sprintf("Try to import managed cluster, retry times: %d/%d, "+
+				"generate kube client by secret error: %v", currentRetry+1, totalRetry, err)
+			return reconcile.Result{RequeueAfter: i.backoff.Next(clusterName)},
+				condition, false, currentRetry + 1, nil
+		}
+
+		return reconcile.Result{}, condition, false, currentRetry, nil
+	}
 
-	importSecretName := fmt.Sprintf("%s-%s", clusterName, constants.ImportSecretNameSuffix)
+	// preflight, the klusterlet cannot run on the managed cluster that has an old kube version
+	if supported, spokeVersion := i.checkSpokeKubeVersion(clientHolder.KubeClient); !supported {
+		return reconcile.Result{},
+			NewManagedClusterImportSucceededCondition(
+				metav1.ConditionFalse,
+				constants.ConditionReasonSpokeVersionUnsupported,
+				fmt.Sprintf("The kube version %s of the managed cluster is unsupported, the minimum version is %s",
+					spokeVersion, i.minSpokeKubeVersion),
+			), false, currentRetry, nil
+	}
+
+	importSecretName := GetImportSecretName(clusterName)
+	stageStart = time.Now()
 	importSecret, err := i.informerHolder.ImportSecretLister.Secrets(clusterName).Get(importSecretName)
+	secretFetchDuration := time.Since(stageStart)
 	if errors.IsNotFound(err) {
 		return reconcile.Result{},
 			NewManagedClusterImportSucceededCondition(
@@ -193,37 +484,78 @@ func (i *ImportHelper) Import(backupRestore bool, clusterName string,
 			), false, currentRetry, err
 	}
 
+	// neither the import secret nor the credential of the managed cluster is changed since the last successful
+	// import and the managed cluster is available, skip the apply to avoid re-applying the same manifests, the other
+	// importers and the backup restore do not record the hash. An auto import secret is always applied, it is
+	// created to (re)import the managed cluster, e.g. after the klusterlet is removed from the managed cluster
+	importSecretHash := ImportSecretHash(importSecret, managedClusterKubeClientSecret)
+	recordHash := i.runtimeClient != nil && importer == nil && !backupRestore
+	if recordHash && !IsForceImport(cluster) && !isAutoImportSecret(managedClusterKubeClientSecret) &&
+		cluster.GetAnnotations()[constants.LastAppliedHashAnnotation] == importSecretHash &&
+		lastImportSucceeded(cluster) &&
+		meta.IsStatusConditionTrue(cluster.Status.Conditions, clusterv1.ManagedClusterConditionAvailable) {
+		reqLogger.V(5).Info("The import secret is not changed since the last import, skip the apply",
+			"hash", importSecretHash)
+		return reconcile.Result{},
+			NewManagedClusterImportSucceededCondition(
+				metav1.ConditionFalse,
+				constants.ConditionReasonManagedClusterImporting,
+				conditionMessageImportingResourcesApplied,
+			), false, currentRetry, nil
+	}
+
 	currentRetry++
-	modified, err := i.applyResourcesFunc(backupRestore, clientHolder, restMapper, i.recorder, importSecret)
+	stageStart = time.Now()
+	modified, err := applyResourcesFunc(backupRestore, clientHolder, restMapper, i.recorder, importSecret)
+	i.recorder.Eventf("ManagedClusterImportTimings",
+		"The managed cluster %s import stage durations: clientBuild=%s, secretFetch=%s, apply=%s",
+		clusterName, clientBuildDuration, secretFetchDuration, time.Since(stageStart))
 	if err != nil {
 		condition := NewManagedClusterImportSucceededCondition(
 			metav1.ConditionFalse,
-			constants.ConditionReasonManagedClusterImportFailed,
-			fmt.Sprintf("Try to import managed cluster, retry times: %d/%d, error: %v",
-				currentRetry, totalRetry, err),
+			importFailedReason(totalRetry),
+			fmt.Sprintf("Try to import managed cluster, retry times: %d/%d, error: %s",
+				currentRetry, totalRetry, FormatImportErrors(err)),
 		)
 
 		if ContainAuthError(err) {
 			// return message reflects the auto import secret is invalid, so the user knows that
 			// a correct secret needs to be re-provided
 			condition.Message = fmt.Sprintf(
-				"AutoImportSecretInvalid %s/%s; please check its permission, apply resources error: %v",
-				managedClusterKubeClientSecret.Namespace, managedClusterKubeClientSecret.Name, err)
+				"AutoImportSecretInvalid %s/%s; please check its permission, apply resources error: %s",
+				managedClusterKubeClientSecret.Namespace, managedClusterKubeClientSecret.Name, FormatImportErrors(err))
 			return reconcile.Result{}, condition, modified, currentRetry, nil
 		}
 
+		if IsSpokeMissingKlusterletCRDs(err) {
+			// the klusterlet CRDs may be rejected or not be served by the managed cluster, retry with the backoff
+			// and give the user a hint to check the CRDs on the managed cluster
+			reqLogger.Info("The klusterlet CRDs are missing on the managed cluster, will retry", "error", err.Error())
+			condition.Reason = constants.ConditionReasonSpokeMissingKlusterletCRDs
+			condition.Message = fmt.Sprintf(
+				"The klusterlet CRDs cannot be resolved on the managed cluster; please check the CRD "+
+					"klusterlets.operator.open-cluster-management.io is established on the managed cluster "+
+					"and the auto import secret has the permission to create CRDs, error: %s. Will Retry",
+				FormatImportErrors(err))
+			return reconcile.Result{RequeueAfter: i.backoff.Next(clusterName)},
+				condition, modified, lastRetry, nil
+		}
+
 		if ContainInternalServerError(err) {
-			// might be some internal server error, does not take up retry times
+			// might be some internal server error, does not take up retry times, retry with the backoff
+			// instead of requeuing immediately, so the managed cluster is not hammered
+			reqLogger.Info("Failed to apply the klusterlet resources, will retry", "error", err.Error())
 			condition.Reason = constants.ConditionReasonManagedClusterImporting
 			condition.Message = fmt.Sprintf(
-				"Try to import managed cluster, apply resources error: %v. Will Retry", err)
-			return reconcile.Result{}, condition, modified, lastRetry, err
+				"Try to import managed cluster, apply resources error: %s. Will Retry", FormatImportErrors(err))
+			return reconcile.Result{RequeueAfter: i.backoff.Next(clusterName)},
+				condition, modified, lastRetry, nil
 		}
 
 		if currentRetry < totalRetry {
-			// maybe some network issue, retry after 10 second
+			// maybe some network issue, retry with the backoff
 			condition.Reason = constants.ConditionReasonManagedClusterImporting
-			return reconcile.Result{RequeueAfter: 10 * time.Second},
+			return reconcile.Result{RequeueAfter: i.backoff.Next(clusterName)},
 				condition, modified, currentRetry, nil
 		}
 
@@ -231,6 +563,13 @@ func (i *ImportHelper) Import(backupRestore bool, clusterName string,
 			condition, modified, currentRetry, nil
 	}
 
+	i.backoff.Reset(clusterName)
+	if recordHash {
+		// the import is re-applied next time if the hash is not recorded, so the error is not returned
+		if err := recordLastAppliedHash(ctx, i.runtimeClient, cluster, importSecretHash); err != nil {
+			reqLogger.Error(err, "Failed to record the last applied hash of the import secret")
+		}
+	}
 	return reconcile.Result{},
 		NewManagedClusterImportSucceededCondition(
 			metav1.ConditionFalse,
@@ -239,10 +578,94 @@ func (i *ImportHelper) Import(backupRestore bool, clusterName string,
 		), modified, currentRetry, nil
 }
 
+// ImportSecretHash returns the hash of the import secret data and the data of the credential secret (the auto
+// import secret or the admin kubeconfig secret) that is used to apply the import secret, so the import is not
+// skipped once the credential is rotated or the import strategy is changed
+func ImportSecretHash(importSecret, credentialSecret *corev1.Secret) string {
+	h := sha256.New()
+	writeSecretData(h, importSecret.Data)
+	// separate the import secret data from the credential data
+	h.Write([]byte{0})
+	if credentialSecret != nil {
+		writeSecretData(h, credentialSecret.Data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeSecretData(h hash.Hash, data map[string][]byte) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write(data[key])
+		h.Write([]byte{0})
+	}
+}
+
+// IsForceImport returns true if the managed cluster has the force import annotation
+func IsForceImport(cluster *clusterv1.ManagedCluster) bool {
+	_, ok := cluster.GetAnnotations()[constants.ForceImportAnnotation]
+	return ok
+}
+
+// ForceImportAnnotationAdded returns true if the force import annotation is added to the managed cluster or its value
+// is changed, the removal of the annotation after the import is ignored
+func ForceImportAnnotationAdded(oldCluster, newCluster client.Object) bool {
+	newValue, ok := newCluster.GetAnnotations()[constants.ForceImportAnnotation]
+	if !ok {
+		return false
+	}
+	oldValue, ok := oldCluster.GetAnnotations()[constants.ForceImportAnnotation]
+	return !ok || oldValue != newValue
+}
+
+// lastImportSucceeded returns true if the managed cluster is imported or the importing resources are applied
+func lastImportSucceeded(cluster *clusterv1.ManagedCluster) bool {
+	condition := meta.FindStatusCondition(cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+	if condition == nil {
+		return false
+	}
+	return condition.Status == metav1.ConditionTrue || ImportingResourcesApplied(condition)
+}
+
+// isAutoImportSecret returns true if the secret is the auto import secret of the managed cluster
+func isAutoImportSecret(secret *corev1.Secret) bool {
+	return secret != nil && secret.Name == constants.AutoImportSecretName
+}
+
+// recordLastAppliedHash records the last applied hash of the import secret on the managed cluster and removes the
+// force import annotation, so the next import is skipped until the import secret is changed
+func recordLastAppliedHash(ctx context.Context, runtimeClient client.Client, cluster *clusterv1.ManagedCluster,
+	hash string) error {
+	if cluster.GetAnnotations()[constants.LastAppliedHashAnnotation] == hash && !IsForceImport(cluster) {
+		return nil
+	}
+
+	patch := client.MergeFrom(cluster.DeepCopy())
+	if cluster.Annotations == nil {
+		cluster.Annotations = map[string]string{}
+	}
+	cluster.Annotations[constants.LastAppliedHashAnnotation] = hash
+	delete(cluster.Annotations, constants.ForceImportAnnotation)
+	return runtimeClient.Patch(ctx, cluster, patch)
+}
+
 const (
 	conditionMessageImportingResourcesApplied = "Importing resources are applied, wait for resources be available"
 )
 
+// IsWaitingForKlusterletWorks returns true if the ImportSucceeded condition of the managed cluster reports the
+// klusterlet manifest works have been waited on for too long
+func IsWaitingForKlusterletWorks(cluster *clusterv1.ManagedCluster) bool {
+	condition := meta.FindStatusCondition(cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+	return condition != nil && condition.Reason == constants.ConditionReasonWaitingForKlusterletWorks
+}
+
 func ImportingResourcesApplied(condition *metav1.Condition) bool {
 	if condition != nil && condition.Type == constants.ConditionManagedClusterImportSucceeded &&
 		condition.Reason == constants.ConditionReasonManagedClusterImporting &&
@@ -251,3 +674,198 @@ func ImportingResourcesApplied(condition *metav1.Condition) bool {
 	}
 	return false
 }
+
+// ImportReconcileDecision returns the reconcile decision of an import from the import condition and error
+func ImportReconcileDecision(condition *metav1.Condition, err error) string {
+	switch {
+	case err != nil:
+		return ReconcileDecisionFailed
+	case condition.Reason == constants.ConditionReasonWaitingForKlusterletWorks:
+		return ReconcileDecisionWaitingWorks
+	case condition.Reason != constants.ConditionReasonManagedClusterImporting:
+		return ReconcileDecisionFailed
+	case ImportingResourcesApplied(condition):
+		return ReconcileDecisionApplied
+	default:
+		return ReconcileDecisionWaitingWorks
+	}
+}
+
+// GetImportFailedAttempts returns the failed import attempts of the managed cluster from its failed import attempts
+// annotation, 0 is returned if the annotation is not set or is invalid
+func GetImportFailedAttempts(cluster *clusterv1.ManagedCluster) int {
+	attempts, err := strconv.Atoi(cluster.GetAnnotations()[constants.ImportFailedAttemptsAnnotation])
+	if err != nil || attempts < 0 {
+		return 0
+	}
+	return attempts
+}
+
+// IncreaseImportFailedAttempts increases the failed import attempts of the managed cluster by one if the reconcile
+// ran a failed import, the reconcile that applied the importing resources or is waiting for the klusterlet works is
+// not counted, so the managed cluster is not patched on every reconcile
+func IncreaseImportFailedAttempts(ctx context.Context, runtimeClient client.Client,
+	cluster *clusterv1.ManagedCluster, condition *metav1.Condition, err error) error {
+	if ImportReconcileDecision(condition, err) != ReconcileDecisionFailed {
+		return nil
+	}
+
+	attempts := GetImportFailedAttempts(cluster) + 1
+	patch := client.MergeFrom(cluster.DeepCopy())
+	if cluster.Annotations == nil {
+		cluster.Annotations = map[string]string{}
+	}
+	cluster.Annotations[constants.ImportFailedAttemptsAnnotation] = strconv.Itoa(attempts)
+	if err := runtimeClient.Patch(ctx, cluster, patch); err != nil {
+		return err
+	}
+
+	importFailedAttempts.WithLabelValues(cluster.Name).Set(float64(attempts))
+	return nil
+}
+
+// ResetImportFailedAttempts removes the failed import attempts of the managed cluster after the managed cluster is
+// imported
+func ResetImportFailedAttempts(ctx context.Context, runtimeClient client.Client,
+	cluster *clusterv1.ManagedCluster) error {
+	importFailedAttempts.DeleteLabelValues(cluster.Name)
+
+	if _, ok := cluster.GetAnnotations()[constants.ImportFailedAttemptsAnnotation]; !ok {
+		return nil
+	}
+
+	patch := client.MergeFrom(cluster.DeepCopy())
+	delete(cluster.Annotations, constants.ImportFailedAttemptsAnnotation)
+	return runtimeClient.Patch(ctx, cluster, patch)
+}
+
+// ForgetImportFailedAttempts removes the failed import attempts metric of the managed cluster after the managed
+// cluster is deleted
+func ForgetImportFailedAttempts(clusterName string) {
+	importFailedAttempts.DeleteLabelValues(clusterName)
+}
+
+// importFailedReason returns the reason of the failed import condition, if the import was allowed to be retried,
+// the reason indicates that all the retries are used up
+func importFailedReason(totalRetry int) string {
+	if totalRetry > 1 {
+		return constants.ConditionReasonImportRetryExhausted
+	}
+	return constants.ConditionReasonManagedClusterImportFailed
+}
+
+// IsImportFailed returns true if the import is failed and should not be retried anymore
+func IsImportFailed(condition *metav1.Condition) bool {
+	return condition.Reason == constants.ConditionReasonManagedClusterImportFailed ||
+		condition.Reason == constants.ConditionReasonImportRetryExhausted
+}
+
+// DryRunImport uses the managedClusterKubeClientSecret to generate a managed cluster client, then validates the
+// klusterlet manifests of the import secret on the managed cluster with the server-side dry-run, the returned
+// condition reports the result of the dry-run
+func (i *ImportHelper) DryRunImport(ctx context.Context, clusterName string,
+	managedClusterKubeClientSecret *corev1.Secret) (metav1.Condition, error) {
+	clientHolder, restMapper, err := i.generateClientHolderFunc(managedClusterKubeClientSecret)
+	if err != nil {
+		return NewManagedClusterImportSucceededCondition(
+			metav1.ConditionFalse,
+			constants.ConditionReasonImportDryRunFailed,
+			fmt.Sprintf("Generate kube client by secret %s/%s error: %v",
+				managedClusterKubeClientSecret.Namespace, managedClusterKubeClientSecret.Name, err),
+		), nil
+	}
+
+	importSecretName := GetImportSecretName(clusterName)
+	importSecret, err := i.informerHolder.ImportSecretLister.Secrets(clusterName).Get(importSecretName)
+	if errors.IsNotFound(err) {
+		return NewManagedClusterImportSucceededCondition(
+			metav1.ConditionFalse,
+			constants.ConditionReasonManagedClusterImporting,
+			"Wait for import secret",
+		), nil
+	}
+	if err != nil {
+		return NewManagedClusterImportSucceededCondition(
+			metav1.ConditionFalse,
+			constants.ConditionReasonManagedClusterImporting,
+			fmt.Sprintf("Get import secret failed: %v. Will retry", err),
+		), err
+	}
+
+	if err := i.dryRunImportFunc(ctx, clientHolder, restMapper, importSecret); err != nil {
+		return NewManagedClusterImportSucceededCondition(
+			metav1.ConditionFalse,
+			constants.ConditionReasonImportDryRunFailed,
+			fmt.Sprintf("Dry-run import managed cluster failed: %v", err),
+		), nil
+	}
+
+	return NewManagedClusterImportSucceededCondition(
+		metav1.ConditionFalse,
+		constants.ConditionReasonImportDryRunSucceeded,
+		"Dry-run import managed cluster succeeded, remove the dry-run annotation to import it",
+	), nil
+}
+
+// unexpectedKlusterletWorks returns the names of the manifestworks that have the klusterlet works label but are
+// not the klusterlet manifestworks of the managed cluster
+func unexpectedKlusterletWorks(clusterName string, works []*workv1.ManifestWork) []string {
+	expected := sets.New[string](
+		fmt.Sprintf("%s-%s", clusterName, constants.KlusterletCRDsSuffix),
+		fmt.Sprintf("%s-%s", clusterName, constants.KlusterletSuffix),
+	)
+
+	unexpected := []string{}
+	for _, work := range works {
+		if !expected.Has(work.Name) {
+			unexpected = append(unexpected, work.Name)
+		}
+	}
+	return unexpected
+}
+
+// FormatImportErrors formats the errors of applying the klusterlet manifests on the managed cluster, each error
+// is on its own line, the most actionable errors (the permission errors, then the invalid manifests) are put
+// first, and the message is truncated to maxImportErrorsMessageLength
+func FormatImportErrors(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	errs := []error{err}
+	if agg, ok := err.(utilerrors.Aggregate); ok {
+		// copy the errors, so the order of the aggregate is not changed by the sorting
+		errs = append([]error{}, utilerrors.Flatten(agg).Errors()...)
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return importErrorPriority(errs[i]) < importErrorPriority(errs[j])
+	})
+
+	var message strings.Builder
+	for i, e := range errs {
+		line := e.Error()
+		if i > 0 {
+			line = "\n" + line
+		}
+		if i == 0 && len(line) > maxImportErrorsMessageLength {
+			line = line[:maxImportErrorsMessageLength] + "..."
+		}
+		if i > 0 && message.Len()+len(line) > maxImportErrorsMessageLength {
+			fmt.Fprintf(&message, "\n... and %d more errors", len(errs)-i)
+			break
+		}
+		message.WriteString(line)
+	}
+	return message.String()
+}
+
+func importErrorPriority(err error) int {
+	switch {
+	case errors.IsUnauthorized(err) || errors.IsForbidden(err):
+		return 0
+	case errors.IsInvalid(err) || errors.IsBadRequest(err):
+		return 1
+	default:
+		return 2
+	}
+}
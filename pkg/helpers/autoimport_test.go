@@ -4,21 +4,32 @@ package helpers
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	kubeversion "k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
 	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	workv1 "open-cluster-management.io/api/work/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
@@ -170,6 +181,46 @@ func TestImportHelper(t *testing.T) {
 			expectedConditionStatus: metav1.ConditionFalse,
 			expectedConditionReason: constants.ConditionReasonManagedClusterImporting,
 		},
+		{
+			name:       "unexpected klusterlet manifest works",
+			lastRetry:  0,
+			totalRetry: 1,
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-foo",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bar",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+			},
+			autoImportSecret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "auto-import-secret",
+					Namespace: managedClusterName,
+				},
+				Data: map[string][]byte{
+					"kubeconfig": testinghelpers.BuildKubeconfig(config),
+				},
+			},
+			importSecret:            testinghelpers.GetImportSecret(managedClusterName),
+			expectedErr:             false,
+			expectedCurrentRetry:    0,
+			expectedRequeueAfter:    3 * time.Second,
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonUnexpectedKlusterletWorks,
+		},
 		{
 			name:       "no import-secret",
 			lastRetry:  0,
@@ -289,6 +340,88 @@ func TestImportHelper(t *testing.T) {
 			expectedConditionStatus: metav1.ConditionFalse,
 			expectedConditionReason: constants.ConditionReasonManagedClusterImportFailed,
 		},
+		{
+			name:       "retry to generate kube client with auto-import secret",
+			lastRetry:  0,
+			totalRetry: 2,
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet-crds",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+			},
+			importSecret: testinghelpers.GetImportSecret(managedClusterName),
+			autoImportSecret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "auto-import-secret",
+					Namespace: managedClusterName,
+				},
+				Data: map[string][]byte{
+					"server": []byte(config.Host),
+					// no auth info
+				},
+			},
+			expectedErr:             false,
+			expectedCurrentRetry:    1,
+			expectedRequeueAfter:    10 * time.Second,
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonManagedClusterImporting,
+		},
+		{
+			name:       "import cluster with auto-import secret retry exhausted",
+			lastRetry:  1,
+			totalRetry: 2,
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet-crds",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+			},
+			importSecret: testinghelpers.GetImportSecret(managedClusterName),
+			autoImportSecret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "auto-import-secret",
+					Namespace: managedClusterName,
+				},
+				Data: map[string][]byte{
+					"server": []byte(config.Host),
+					// no auth info
+				},
+			},
+			expectedErr:             false,
+			expectedCurrentRetry:    1,
+			expectedRequeueAfter:    0 * time.Second,
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonImportRetryExhausted,
+		},
 		{
 			name:       "only update the bootstrap secret",
 			lastRetry:  0,
@@ -402,7 +535,8 @@ func TestImportHelper(t *testing.T) {
 			importHelper := NewImportHelper(&source.InformerHolder{
 				ImportSecretLister:   kubeInformerFactory.Core().V1().Secrets().Lister(),
 				KlusterletWorkLister: workInformerFactory.Work().V1().ManifestWorks().Lister(),
-			}, eventstesting.NewTestingEventRecorder(t), logf.Log.WithName("import-helper-tester"))
+			}, eventstesting.NewTestingEventRecorder(t), logf.Log.WithName("import-helper-tester")).
+				WithImportBackoff(NewImportBackoff(10*time.Second, 5*time.Minute, 0))
 
 			backupRestore := false
 			if c.autoImportSecret != nil {
@@ -412,8 +546,9 @@ func TestImportHelper(t *testing.T) {
 				}
 			}
 
-			result, condition, _, currentRetry, err := importHelper.Import(
-				backupRestore, managedClusterName, c.autoImportSecret, c.lastRetry, c.totalRetry)
+			managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: managedClusterName}}
+			result, condition, _, currentRetry, err := importHelper.Import(context.TODO(),
+				backupRestore, managedCluster, c.autoImportSecret, c.lastRetry, c.totalRetry)
 			if c.expectedErr && err == nil {
 				t.Errorf("name %v : expected error, but failed", c.name)
 			}
@@ -443,3 +578,632 @@ func TestImportHelper(t *testing.T) {
 		})
 	}
 }
+
+func TestImportSpokeKubeVersionPreflight(t *testing.T) {
+	managedClusterName := "test"
+	works := []runtime.Object{
+		&workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-klusterlet-crds",
+				Namespace: managedClusterName,
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "true",
+				},
+			},
+		},
+		&workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-klusterlet",
+				Namespace: managedClusterName,
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "true",
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		name                    string
+		minSpokeKubeVersion     string
+		spokeKubeVersion        string
+		expectedConditionReason string
+	}{
+		{
+			name:                    "spoke kube version is unsupported",
+			spokeKubeVersion:        "v1.10.0",
+			expectedConditionReason: constants.ConditionReasonSpokeVersionUnsupported,
+		},
+		{
+			name:                    "spoke kube version is supported",
+			spokeKubeVersion:        "v1.27.3",
+			expectedConditionReason: constants.ConditionReasonManagedClusterImporting,
+		},
+		{
+			name:                    "spoke kube version is lower than the customized minimum version",
+			minSpokeKubeVersion:     "v1.28.0",
+			spokeKubeVersion:        "v1.27.3+k3s1",
+			expectedConditionReason: constants.ConditionReasonSpokeVersionUnsupported,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv(minSpokeKubeVersionEnvVarName, c.minSpokeKubeVersion)
+
+			spokeKubeClient := kubefake.NewSimpleClientset()
+			spokeKubeClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &kubeversion.Info{
+				GitVersion: c.spokeKubeVersion,
+			}
+
+			kubeInformerFactory := informers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 10*time.Minute)
+			workInformerFactory := workinformers.NewSharedInformerFactory(workfake.NewSimpleClientset(works...), 10*time.Minute)
+			workInformer := workInformerFactory.Work().V1().ManifestWorks().Informer()
+			for _, work := range works {
+				workInformer.GetStore().Add(work)
+			}
+
+			importHelper := NewImportHelper(&source.InformerHolder{
+				ImportSecretLister:   kubeInformerFactory.Core().V1().Secrets().Lister(),
+				KlusterletWorkLister: workInformerFactory.Work().V1().ManifestWorks().Lister(),
+			}, eventstesting.NewTestingEventRecorder(t), logf.Log.WithName("import-helper-tester")).
+				WithGenerateClientHolderFunc(func(secret *corev1.Secret) (*ClientHolder, meta.RESTMapper, error) {
+					return &ClientHolder{KubeClient: spokeKubeClient}, nil, nil
+				})
+
+			managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: managedClusterName}}
+			_, condition, _, _, err := importHelper.Import(context.TODO(), false, managedCluster, &corev1.Secret{}, 0, 1)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if condition.Reason != c.expectedConditionReason {
+				t.Errorf("expect condition reason %s, got %s, message: %s",
+					c.expectedConditionReason, condition.Reason, condition.Message)
+			}
+		})
+	}
+}
+
+func TestImportWithForeignKlusterletWorks(t *testing.T) {
+	t.Setenv(hubIdentityEnvVarName, "hub1")
+
+	managedClusterName := "test"
+	works := []runtime.Object{
+		&workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-klusterlet-crds",
+				Namespace: managedClusterName,
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "true",
+				},
+				Annotations: map[string]string{
+					constants.KlusterletWorksHubIdentityAnnotation: "hub1",
+				},
+			},
+		},
+		&workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-klusterlet",
+				Namespace: managedClusterName,
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "true",
+				},
+				Annotations: map[string]string{
+					constants.KlusterletWorksHubIdentityAnnotation: "hub1",
+				},
+			},
+		},
+		&workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "hub2-test-klusterlet",
+				Namespace: managedClusterName,
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "true",
+				},
+				Annotations: map[string]string{
+					constants.KlusterletWorksHubIdentityAnnotation: "hub2",
+				},
+			},
+		},
+	}
+
+	spokeKubeClient := kubefake.NewSimpleClientset()
+	spokeKubeClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &kubeversion.Info{
+		GitVersion: "v1.27.3",
+	}
+
+	kubeInformerFactory := informers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 10*time.Minute)
+	workInformerFactory := workinformers.NewSharedInformerFactory(workfake.NewSimpleClientset(works...), 10*time.Minute)
+	workInformer := workInformerFactory.Work().V1().ManifestWorks().Informer()
+	for _, work := range works {
+		workInformer.GetStore().Add(work)
+	}
+
+	clientBuilt := false
+	importHelper := NewImportHelper(&source.InformerHolder{
+		ImportSecretLister:   kubeInformerFactory.Core().V1().Secrets().Lister(),
+		KlusterletWorkLister: workInformerFactory.Work().V1().ManifestWorks().Lister(),
+	}, eventstesting.NewTestingEventRecorder(t), logf.Log.WithName("import-helper-tester")).
+		WithGenerateClientHolderFunc(func(secret *corev1.Secret) (*ClientHolder, meta.RESTMapper, error) {
+			clientBuilt = true
+			return &ClientHolder{KubeClient: spokeKubeClient}, nil, nil
+		})
+
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: managedClusterName}}
+	_, _, _, _, err := importHelper.Import(context.TODO(), false, managedCluster, &corev1.Secret{}, 0, 1)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !clientBuilt {
+		t.Errorf("expected the foreign klusterlet manifestwork is ignored, but the import is not started")
+	}
+}
+
+func TestImportTimings(t *testing.T) {
+	managedClusterName := "test"
+	works := []runtime.Object{
+		&workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-klusterlet-crds",
+				Namespace: managedClusterName,
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "true",
+				},
+			},
+		},
+		&workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-klusterlet",
+				Namespace: managedClusterName,
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "true",
+				},
+			},
+		},
+	}
+	importSecret := testinghelpers.GetImportSecret(managedClusterName)
+
+	kubeInformerFactory := informers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 10*time.Minute)
+	kubeInformerFactory.Core().V1().Secrets().Informer().GetStore().Add(importSecret)
+	workInformerFactory := workinformers.NewSharedInformerFactory(workfake.NewSimpleClientset(works...), 10*time.Minute)
+	workInformer := workInformerFactory.Work().V1().ManifestWorks().Informer()
+	for _, work := range works {
+		workInformer.GetStore().Add(work)
+	}
+
+	spokeKubeClient := kubefake.NewSimpleClientset()
+	spokeKubeClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &kubeversion.Info{
+		GitVersion: "v1.27.3",
+	}
+
+	recorder := events.NewInMemoryRecorder("import-helper-tester")
+	importHelper := NewImportHelper(&source.InformerHolder{
+		ImportSecretLister:   kubeInformerFactory.Core().V1().Secrets().Lister(),
+		KlusterletWorkLister: workInformerFactory.Work().V1().ManifestWorks().Lister(),
+	}, recorder, logf.Log.WithName("import-helper-tester")).
+		WithGenerateClientHolderFunc(func(secret *corev1.Secret) (*ClientHolder, meta.RESTMapper, error) {
+			return &ClientHolder{KubeClient: spokeKubeClient}, nil, nil
+		}).
+		WithApplyResourcesFunc(func(backupRestore bool, client *ClientHolder, restMapper meta.RESTMapper,
+			recorder events.Recorder, importSecret *corev1.Secret) (bool, error) {
+			return true, nil
+		})
+
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: managedClusterName}}
+	if _, _, _, _, err := importHelper.Import(context.TODO(), false, managedCluster, &corev1.Secret{}, 0, 1); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	found := false
+	for _, event := range recorder.Events() {
+		if event.Reason != "ManagedClusterImportTimings" {
+			continue
+		}
+		found = true
+		for _, stage := range []string{"clientBuild=", "secretFetch=", "apply="} {
+			if !strings.Contains(event.Message, stage) {
+				t.Errorf("expected stage %s in the timings event, but got %s", stage, event.Message)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected the import timings event, but failed")
+	}
+}
+
+func TestImportWithLastAppliedHash(t *testing.T) {
+	managedClusterName := "test"
+	works := []runtime.Object{
+		&workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-klusterlet-crds",
+				Namespace: managedClusterName,
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "true",
+				},
+			},
+		},
+		&workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-klusterlet",
+				Namespace: managedClusterName,
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "true",
+				},
+			},
+		},
+	}
+	importSecret := testinghelpers.GetImportSecret(managedClusterName)
+
+	kubeInformerFactory := informers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 10*time.Minute)
+	kubeInformerFactory.Core().V1().Secrets().Informer().GetStore().Add(importSecret)
+	workInformerFactory := workinformers.NewSharedInformerFactory(workfake.NewSimpleClientset(works...), 10*time.Minute)
+	workInformer := workInformerFactory.Work().V1().ManifestWorks().Informer()
+	for _, work := range works {
+		workInformer.GetStore().Add(work)
+	}
+
+	spokeKubeClient := kubefake.NewSimpleClientset()
+	spokeKubeClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &kubeversion.Info{
+		GitVersion: "v1.27.3",
+	}
+
+	runtimeClient := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(&clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: managedClusterName,
+		},
+	}).Build()
+
+	applied := 0
+	importHelper := NewImportHelper(&source.InformerHolder{
+		ImportSecretLister:   kubeInformerFactory.Core().V1().Secrets().Lister(),
+		KlusterletWorkLister: workInformerFactory.Work().V1().ManifestWorks().Lister(),
+	}, eventstesting.NewTestingEventRecorder(t), logf.Log.WithName("import-helper-tester")).
+		WithGenerateClientHolderFunc(func(secret *corev1.Secret) (*ClientHolder, meta.RESTMapper, error) {
+			return &ClientHolder{KubeClient: spokeKubeClient}, nil, nil
+		}).
+		WithApplyResourcesFunc(func(backupRestore bool, client *ClientHolder, restMapper meta.RESTMapper,
+			recorder events.Recorder, importSecret *corev1.Secret) (bool, error) {
+			applied++
+			return true, nil
+		}).
+		WithRuntimeClient(runtimeClient)
+
+	imported := NewManagedClusterImportSucceededCondition(metav1.ConditionTrue,
+		constants.ConditionReasonManagedClusterImported, "imported")
+
+	adminKubeconfigSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-admin-kubeconfig",
+			Namespace: managedClusterName,
+		},
+		Data: map[string][]byte{
+			"token": []byte("token"),
+		},
+	}
+	rotatedAdminKubeconfigSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-admin-kubeconfig",
+			Namespace: managedClusterName,
+		},
+		Data: map[string][]byte{
+			"token": []byte("rotated-token"),
+		},
+	}
+	autoImportSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      constants.AutoImportSecretName,
+			Namespace: managedClusterName,
+		},
+		Data: map[string][]byte{
+			"token": []byte("rotated-token"),
+		},
+	}
+
+	steps := []struct {
+		name            string
+		condition       *metav1.Condition
+		available       bool
+		forceImport     bool
+		credential      *corev1.Secret
+		expectedApplied int
+	}{
+		{
+			name:            "first import",
+			expectedApplied: 1,
+		},
+		{
+			name:            "the last import is not succeeded",
+			expectedApplied: 2,
+		},
+		{
+			name:            "the import secret is not changed since the last successful import",
+			condition:       &imported,
+			available:       true,
+			expectedApplied: 2,
+		},
+		{
+			name:            "the managed cluster is not available",
+			condition:       &imported,
+			expectedApplied: 3,
+		},
+		{
+			name:            "force import",
+			condition:       &imported,
+			available:       true,
+			forceImport:     true,
+			expectedApplied: 4,
+		},
+		{
+			name:            "the force import annotation is removed",
+			condition:       &imported,
+			available:       true,
+			expectedApplied: 4,
+		},
+		{
+			name:            "the admin kubeconfig is rotated",
+			condition:       &imported,
+			available:       true,
+			credential:      rotatedAdminKubeconfigSecret,
+			expectedApplied: 5,
+		},
+		{
+			name:            "the rotated admin kubeconfig is not changed",
+			condition:       &imported,
+			available:       true,
+			credential:      rotatedAdminKubeconfigSecret,
+			expectedApplied: 5,
+		},
+		{
+			name:            "the auto import secret is always applied",
+			condition:       &imported,
+			available:       true,
+			credential:      autoImportSecret,
+			expectedApplied: 6,
+		},
+		{
+			name:            "the same auto import secret is applied again",
+			condition:       &imported,
+			available:       true,
+			credential:      autoImportSecret,
+			expectedApplied: 7,
+		},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			cluster := &clusterv1.ManagedCluster{}
+			if err := runtimeClient.Get(context.TODO(), types.NamespacedName{Name: managedClusterName}, cluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			cluster.Status.Conditions = nil
+			if step.condition != nil {
+				meta.SetStatusCondition(&cluster.Status.Conditions, *step.condition)
+			}
+			if step.available {
+				meta.SetStatusCondition(&cluster.Status.Conditions, metav1.Condition{
+					Type:   clusterv1.ManagedClusterConditionAvailable,
+					Status: metav1.ConditionTrue,
+					Reason: "ManagedClusterAvailable",
+				})
+			}
+			if step.forceImport {
+				cluster.Annotations[constants.ForceImportAnnotation] = ""
+			}
+			credential := adminKubeconfigSecret
+			if step.credential != nil {
+				credential = step.credential
+			}
+
+			_, condition, _, _, err := importHelper.Import(context.TODO(), false, cluster, credential, 0, 1)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !ImportingResourcesApplied(&condition) {
+				t.Errorf("expected the importing resources are applied, but got %v", condition)
+			}
+			if applied != step.expectedApplied {
+				t.Errorf("expected the import secret is applied %d times, but got %d", step.expectedApplied, applied)
+			}
+
+			if err := runtimeClient.Get(context.TODO(), types.NamespacedName{Name: managedClusterName}, cluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expectedHash := ImportSecretHash(importSecret, credential)
+			if cluster.Annotations[constants.LastAppliedHashAnnotation] != expectedHash {
+				t.Errorf("expected the last applied hash %s, but got %s",
+					expectedHash, cluster.Annotations[constants.LastAppliedHashAnnotation])
+			}
+			if IsForceImport(cluster) {
+				t.Errorf("expected the force import annotation is removed")
+			}
+		})
+	}
+}
+
+func TestForceImportAnnotationAdded(t *testing.T) {
+	cases := []struct {
+		name           string
+		oldAnnotations map[string]string
+		newAnnotations map[string]string
+		expected       bool
+	}{
+		{
+			name:     "no force import annotation",
+			expected: false,
+		},
+		{
+			name:           "the force import annotation is added",
+			newAnnotations: map[string]string{constants.ForceImportAnnotation: ""},
+			expected:       true,
+		},
+		{
+			name:           "the force import annotation is changed",
+			oldAnnotations: map[string]string{constants.ForceImportAnnotation: "1"},
+			newAnnotations: map[string]string{constants.ForceImportAnnotation: "2"},
+			expected:       true,
+		},
+		{
+			name:           "the force import annotation is not changed",
+			oldAnnotations: map[string]string{constants.ForceImportAnnotation: "1"},
+			newAnnotations: map[string]string{constants.ForceImportAnnotation: "1", "other": "true"},
+			expected:       false,
+		},
+		{
+			name:           "the force import annotation is removed",
+			oldAnnotations: map[string]string{constants.ForceImportAnnotation: ""},
+			expected:       false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			oldCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Annotations: c.oldAnnotations}}
+			newCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Annotations: c.newAnnotations}}
+			if added := ForceImportAnnotationAdded(oldCluster, newCluster); added != c.expected {
+				t.Errorf("expected %v, but got %v", c.expected, added)
+			}
+		})
+	}
+}
+
+func TestImportFailedAttempts(t *testing.T) {
+	cluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "attempts",
+		},
+	}
+	runtimeClient := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(cluster).Build()
+
+	failed := NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+		constants.ConditionReasonManagedClusterImportFailed, "failed")
+	waiting := NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+		constants.ConditionReasonManagedClusterImporting, "Wait for import secret")
+	applied := NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+		constants.ConditionReasonManagedClusterImporting, conditionMessageImportingResourcesApplied)
+
+	steps := []struct {
+		name             string
+		condition        *metav1.Condition
+		imported         bool
+		expectedAttempts int
+	}{
+		{
+			name:             "import failed",
+			condition:        &failed,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "import failed again",
+			condition:        &failed,
+			expectedAttempts: 2,
+		},
+		{
+			name:             "wait for import secret",
+			condition:        &waiting,
+			expectedAttempts: 2,
+		},
+		{
+			name:             "importing resources applied",
+			condition:        &applied,
+			expectedAttempts: 2,
+		},
+		{
+			name:             "imported",
+			imported:         true,
+			expectedAttempts: 0,
+		},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			current := &clusterv1.ManagedCluster{}
+			if err := runtimeClient.Get(context.TODO(), types.NamespacedName{Name: cluster.Name}, current); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var err error
+			if step.imported {
+				err = ResetImportFailedAttempts(context.TODO(), runtimeClient, current)
+			} else {
+				err = IncreaseImportFailedAttempts(context.TODO(), runtimeClient, current, step.condition, nil)
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if err := runtimeClient.Get(context.TODO(), types.NamespacedName{Name: cluster.Name}, current); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if attempts := GetImportFailedAttempts(current); attempts != step.expectedAttempts {
+				t.Errorf("expected import attempts %d, but got %d", step.expectedAttempts, attempts)
+			}
+			if step.imported {
+				if _, ok := current.Annotations[constants.ImportFailedAttemptsAnnotation]; ok {
+					t.Errorf("expected the import attempts annotation is removed, but got %v", current.Annotations)
+				}
+				if count := testutil.CollectAndCount(importFailedAttempts); count != 0 {
+					t.Errorf("expected the import attempts metric is removed, but got %d", count)
+				}
+				return
+			}
+			metric := testutil.ToFloat64(importFailedAttempts.WithLabelValues(cluster.Name))
+			if int(metric) != step.expectedAttempts {
+				t.Errorf("expected import attempts metric %d, but got %v", step.expectedAttempts, metric)
+			}
+		})
+	}
+
+	t.Run("cluster deleted", func(t *testing.T) {
+		importFailedAttempts.WithLabelValues(cluster.Name).Set(1)
+		ForgetImportFailedAttempts(cluster.Name)
+		if count := testutil.CollectAndCount(importFailedAttempts); count != 0 {
+			t.Errorf("expected the import attempts metric is removed, but got %d", count)
+		}
+	})
+}
+
+func TestFormatImportErrors(t *testing.T) {
+	notFoundErr := fmt.Errorf("apps/v1, Kind=Deployment open-cluster-management/klusterlet: %w",
+		errors.NewNotFound(schema.GroupResource{Group: "apps", Resource: "deployments"}, "klusterlet"))
+	forbiddenErr := fmt.Errorf("/v1, Kind=Namespace open-cluster-management-agent: %w",
+		errors.NewForbidden(schema.GroupResource{Resource: "namespaces"}, "open-cluster-management-agent",
+			fmt.Errorf("no permission")))
+
+	cases := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "no error",
+			expected: "",
+		},
+		{
+			name:     "single error",
+			err:      notFoundErr,
+			expected: notFoundErr.Error(),
+		},
+		{
+			name:     "the permission error is first",
+			err:      utilerrors.NewAggregate([]error{notFoundErr, forbiddenErr}),
+			expected: forbiddenErr.Error() + "\n" + notFoundErr.Error(),
+		},
+		{
+			name: "truncate the errors",
+			err: utilerrors.NewAggregate([]error{
+				fmt.Errorf("%s", strings.Repeat("a", maxImportErrorsMessageLength-10)),
+				fmt.Errorf("%s", strings.Repeat("b", 20)),
+				fmt.Errorf("%s", strings.Repeat("c", 20)),
+			}),
+			expected: strings.Repeat("a", maxImportErrorsMessageLength-10) + "\n... and 2 more errors",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := FormatImportErrors(c.err); actual != c.expected {
+				t.Errorf("expected %q, but got %q", c.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	defaultImportBackoffBase   = 10 * time.Second
+	defaultImportBackoffMax    = 5 * time.Minute
+	defaultImportBackoffJitter = 0.2
+)
+
+// ImportBackoff tracks the failed imports of the managed clusters by cluster names and computes the durations to
+// wait before the next imports, the duration is doubled for each failure until the max duration, then a random
+// jitter is added so the retries of the managed clusters that fail at the same time are spread out.
+type ImportBackoff struct {
+	lock     sync.Mutex
+	failures map[string]int
+
+	base   time.Duration
+	max    time.Duration
+	jitter float64
+}
+
+// NewImportBackoff returns an ImportBackoff, the jitter is the max factor of the duration that is added randomly
+func NewImportBackoff(base, max time.Duration, jitter float64) *ImportBackoff {
+	return &ImportBackoff{
+		failures: map[string]int{},
+		base:     base,
+		max:      max,
+		jitter:   jitter,
+	}
+}
+
+// Next records a failed import of the managed cluster and returns the duration to wait before the next import
+func (b *ImportBackoff) Next(clusterName string) time.Duration {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.failures[clusterName]++
+
+	duration := b.base
+	for i := 1; i < b.failures[clusterName] && duration < b.max; i++ {
+		duration *= 2
+	}
+	if duration > b.max {
+		duration = b.max
+	}
+
+	if b.jitter > 0 {
+		duration = wait.Jitter(duration, b.jitter)
+	}
+	return duration
+}
+
+// Failures returns the number of the failed imports of the managed cluster since its last successful import
+func (b *ImportBackoff) Failures(clusterName string) int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.failures[clusterName]
+}
+
+// Reset forgets the failed imports of the managed cluster, it is called after the managed cluster is imported
+// successfully or the managed cluster is deleted
+func (b *ImportBackoff) Reset(clusterName string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	delete(b.failures, clusterName)
+}
@@ -0,0 +1,53 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestImportBackoff(t *testing.T) {
+	b := NewImportBackoff(10*time.Second, time.Minute, 0)
+
+	expected := []time.Duration{
+		10 * time.Second,
+		20 * time.Second,
+		40 * time.Second,
+		time.Minute,
+		time.Minute,
+	}
+	for i, duration := range expected {
+		if next := b.Next("cluster1"); next != duration {
+			t.Errorf("expected the backoff %v after %d failures, but got %v", duration, i+1, next)
+		}
+	}
+	if failures := b.Failures("cluster1"); failures != len(expected) {
+		t.Errorf("expected %d failures, but got %d", len(expected), failures)
+	}
+
+	// the failures are tracked by cluster
+	if next := b.Next("cluster2"); next != 10*time.Second {
+		t.Errorf("expected the backoff of another cluster starts from the base, but got %v", next)
+	}
+
+	b.Reset("cluster1")
+	if failures := b.Failures("cluster1"); failures != 0 {
+		t.Errorf("expected the failures are reset, but got %d", failures)
+	}
+	if next := b.Next("cluster1"); next != 10*time.Second {
+		t.Errorf("expected the backoff starts from the base after reset, but got %v", next)
+	}
+}
+
+func TestImportBackoffJitter(t *testing.T) {
+	b := NewImportBackoff(10*time.Second, time.Minute, 0.5)
+
+	for i := 0; i < 10; i++ {
+		b.Reset("cluster1")
+		if next := b.Next("cluster1"); next < 10*time.Second || next > 15*time.Second {
+			t.Errorf("expected the backoff is between 10s and 15s, but got %v", next)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	authv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/pointer"
+)
+
+// bootstrapMisconfiguredError indicates the hub is not able to mint the bootstrap token, retrying will not help
+// until the bootstrap service account or the permissions of this controller are fixed
+type bootstrapMisconfiguredError struct {
+	err error
+}
+
+func (e *bootstrapMisconfiguredError) Error() string {
+	return e.err.Error()
+}
+
+func (e *bootstrapMisconfiguredError) Unwrap() error {
+	return e.err
+}
+
+// IsBootstrapMisconfigured returns true if the error indicates the hub is not able to mint the bootstrap token
+func IsBootstrapMisconfigured(err error) bool {
+	var target *bootstrapMisconfiguredError
+	return errors.As(err, &target)
+}
+
+// ValidateBootstrapCapability checks whether the hub is able to mint the bootstrap token with the given bootstrap
+// service account, the service account must exist, and it must have a token secret or a token can be requested
+// for it. The returned error is a misconfiguration only if the service account is missing or the token request
+// is rejected, other errors are transient.
+func ValidateBootstrapCapability(ctx context.Context, kubeClient kubernetes.Interface, saNamespace, saName string) error {
+	sa, err := kubeClient.CoreV1().ServiceAccounts(saNamespace).Get(ctx, saName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return &bootstrapMisconfiguredError{
+			err: fmt.Errorf("the bootstrap service account %s/%s is not found", saNamespace, saName),
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get the bootstrap service account %s/%s: %v", saNamespace, saName, err)
+	}
+
+	for _, ref := range sa.Secrets {
+		secret, err := kubeClient.CoreV1().Secrets(saNamespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if secret.Type == corev1.SecretTypeServiceAccountToken && len(secret.Data["token"]) != 0 {
+			return nil
+		}
+	}
+
+	_, err = kubeClient.CoreV1().ServiceAccounts(saNamespace).CreateToken(ctx, saName,
+		&authv1.TokenRequest{
+			Spec: authv1.TokenRequestSpec{
+				// the minimum expiration of a token request
+				ExpirationSeconds: pointer.Int64(600),
+			},
+		},
+		metav1.CreateOptions{},
+	)
+	switch {
+	case err == nil:
+		return nil
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err), apierrors.IsNotFound(err),
+		apierrors.IsMethodNotSupported(err), apierrors.IsBadRequest(err):
+		return &bootstrapMisconfiguredError{
+			err: fmt.Errorf("failed to request a token for the bootstrap service account %s/%s: %v",
+				saNamespace, saName, err),
+		}
+	default:
+		return fmt.Errorf("failed to request a token for the bootstrap service account %s/%s: %v",
+			saNamespace, saName, err)
+	}
+}
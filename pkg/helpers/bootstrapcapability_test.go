@@ -0,0 +1,136 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	authv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestValidateBootstrapCapability(t *testing.T) {
+	bootstrapSA := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-bootstrap-sa",
+			Namespace: "test",
+		},
+	}
+
+	cases := []struct {
+		name                 string
+		objs                 []runtime.Object
+		getSAErr             error
+		tokenRequestErr      error
+		expectedErr          bool
+		expectedMisconfigure bool
+	}{
+		{
+			name:                 "the bootstrap service account is missing",
+			objs:                 []runtime.Object{},
+			expectedErr:          true,
+			expectedMisconfigure: true,
+		},
+		{
+			name:        "failed to get the bootstrap service account",
+			objs:        []runtime.Object{bootstrapSA},
+			getSAErr:    errors.NewServiceUnavailable("unavailable"),
+			expectedErr: true,
+		},
+		{
+			name: "the bootstrap service account has a token secret",
+			objs: []runtime.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa",
+						Namespace: "test",
+					},
+					Secrets: []corev1.ObjectReference{
+						{
+							Name:      "test-bootstrap-sa-token-5pw5c",
+							Namespace: "test",
+						},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa-token-5pw5c",
+						Namespace: "test",
+					},
+					Data: map[string][]byte{
+						"token": []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeServiceAccountToken,
+				},
+			},
+			tokenRequestErr: fmt.Errorf("the token should not be requested"),
+		},
+		{
+			name: "a token can be requested for the bootstrap service account",
+			objs: []runtime.Object{bootstrapSA},
+		},
+		{
+			name: "the token request is forbidden",
+			objs: []runtime.Object{bootstrapSA},
+			tokenRequestErr: errors.NewForbidden(
+				authv1.Resource("serviceaccounts/token"), "test-bootstrap-sa", fmt.Errorf("forbidden")),
+			expectedErr:          true,
+			expectedMisconfigure: true,
+		},
+		{
+			name:            "the token request is failed temporarily",
+			objs:            []runtime.Object{bootstrapSA},
+			tokenRequestErr: errors.NewTimeoutError("timeout", 1),
+			expectedErr:     true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset(c.objs...)
+			if c.getSAErr != nil {
+				kubeClient.PrependReactor(
+					"get",
+					"serviceaccounts",
+					func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+						return true, nil, c.getSAErr
+					},
+				)
+			}
+			kubeClient.PrependReactor(
+				"create",
+				"serviceaccounts",
+				func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+					if action.GetSubresource() != "token" {
+						return false, nil, nil
+					}
+					if c.tokenRequestErr != nil {
+						return true, nil, c.tokenRequestErr
+					}
+					return true, &authv1.TokenRequest{
+						Status: authv1.TokenRequestStatus{Token: "fake-token", ExpirationTimestamp: metav1.Now()},
+					}, nil
+				},
+			)
+
+			err := ValidateBootstrapCapability(context.TODO(), kubeClient, "test", "test-bootstrap-sa")
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if IsBootstrapMisconfigured(err) != c.expectedMisconfigure {
+				t.Errorf("expected misconfigured %v, but got %v", c.expectedMisconfigure, IsBootstrapMisconfigured(err))
+			}
+		})
+	}
+}
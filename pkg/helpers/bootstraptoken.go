@@ -0,0 +1,105 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+// bootstrapTokenRequiredVerbs are the verbs on the bootstrap hub kubeconfig secret in the klusterlet namespace that
+// the bootstrap token of the token auto import strategy requires
+var bootstrapTokenRequiredVerbs = []string{"get", "create", "update"}
+
+// GetAutoImportStrategy returns the auto import strategy of the auto import secret, an empty string is returned if
+// the strategy is not set. The token strategy requires the server and the token of the auto import secret.
+func GetAutoImportStrategy(secret *corev1.Secret) (string, error) {
+	strategy := strings.TrimSpace(string(secret.Data[constants.AutoImportStrategyKey]))
+	switch strategy {
+	case "":
+		return "", nil
+	case constants.AutoImportStrategyToken:
+		if len(secret.Data[autoImportServerKey]) == 0 || len(secret.Data[autoImportTokenKey]) == 0 {
+			return "", fmt.Errorf("the %s auto import strategy requires the keys %q and %q",
+				strategy, autoImportServerKey, autoImportTokenKey)
+		}
+		return strategy, nil
+	}
+
+	return "", fmt.Errorf("the auto import strategy %q is unsupported, it should be %q or not set",
+		strategy, constants.AutoImportStrategyToken)
+}
+
+// ApplyBootstrapManifestsWithToken applies the bootstrap hub kubeconfig secret of the import secret on the managed
+// cluster with the bootstrap token of the token auto import strategy, the deployed klusterlet registers the
+// managed cluster with the bootstrap hub kubeconfig. A forbidden error that lists the missing permissions is
+// returned if the token is not allowed to apply the secret.
+func ApplyBootstrapManifestsWithToken(client *ClientHolder, importSecret *corev1.Secret,
+	recorder events.Recorder) (bool, error) {
+	bootstrapSecret, err := getBootstrapHubKubeconfigSecret(importSecret)
+	if err != nil {
+		return false, err
+	}
+
+	if err := checkBootstrapTokenPermissions(context.TODO(), client.KubeClient,
+		bootstrapSecret.Namespace, bootstrapSecret.Name); err != nil {
+		return false, err
+	}
+
+	return ApplyResources(client, recorder, nil, nil, bootstrapSecret)
+}
+
+// checkBootstrapTokenPermissions reviews whether the bootstrap token is allowed to apply the secret in the namespace
+func checkBootstrapTokenPermissions(ctx context.Context, kubeClient kubernetes.Interface,
+	namespace, name string) error {
+	deniedVerbs := []string{}
+	for _, verb := range bootstrapTokenRequiredVerbs {
+		attributes := &authorizationv1.ResourceAttributes{
+			Namespace: namespace,
+			Verb:      verb,
+			Resource:  "secrets",
+		}
+		if verb != "create" {
+			attributes.Name = name
+		}
+
+		review, err := kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx,
+			&authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attributes},
+			}, metav1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+		if !review.Status.Allowed {
+			deniedVerbs = append(deniedVerbs, verb)
+		}
+	}
+
+	if len(deniedVerbs) == 0 {
+		return nil
+	}
+	return errors.NewForbidden(schema.GroupResource{Resource: "secrets"}, name,
+		fmt.Errorf("the bootstrap token of the %s auto import strategy is not allowed to %s the secrets in the "+
+			"namespace %s, it requires the verbs %s", constants.AutoImportStrategyToken,
+			strings.Join(deniedVerbs, ","), namespace, strings.Join(bootstrapTokenRequiredVerbs, ",")))
+}
+
+// bootstrapTokenApplyResourcesFunc adapts the ApplyBootstrapManifestsWithToken to the ApplyResourcesFunc, the
+// bootstrap hub kubeconfig secret is the only resource that is applied, so the backup restore case is the same.
+func bootstrapTokenApplyResourcesFunc(_ bool, client *ClientHolder, _ meta.RESTMapper,
+	recorder events.Recorder, importSecret *corev1.Secret) (bool, error) {
+	return ApplyBootstrapManifestsWithToken(client, importSecret, recorder)
+}
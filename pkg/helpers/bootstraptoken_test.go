@@ -0,0 +1,220 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeversion "k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	testinghelpers "github.com/stolostron/managedcluster-import-controller/pkg/helpers/testing"
+	"github.com/stolostron/managedcluster-import-controller/pkg/source"
+)
+
+func TestGetAutoImportStrategy(t *testing.T) {
+	cases := []struct {
+		name             string
+		data             map[string][]byte
+		expectedStrategy string
+		expectedErr      bool
+	}{
+		{
+			name: "no strategy",
+			data: map[string][]byte{
+				"kubeconfig": []byte("test"),
+			},
+		},
+		{
+			name: "token strategy",
+			data: map[string][]byte{
+				constants.AutoImportStrategyKey: []byte("token"),
+				"server":                        []byte("https://api.test.com:6443"),
+				"token":                         []byte("test"),
+			},
+			expectedStrategy: constants.AutoImportStrategyToken,
+		},
+		{
+			name: "token strategy without token",
+			data: map[string][]byte{
+				constants.AutoImportStrategyKey: []byte("token"),
+				"kubeconfig":                    []byte("test"),
+			},
+			expectedErr: true,
+		},
+		{
+			name: "unsupported strategy",
+			data: map[string][]byte{
+				constants.AutoImportStrategyKey: []byte("unknown"),
+				"kubeconfig":                    []byte("test"),
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			strategy, err := GetAutoImportStrategy(&corev1.Secret{Data: c.data})
+			if c.expectedErr != (err != nil) {
+				t.Errorf("expected error %v, but got %v", c.expectedErr, err)
+			}
+			if strategy != c.expectedStrategy {
+				t.Errorf("expected strategy %q, but got %q", c.expectedStrategy, strategy)
+			}
+		})
+	}
+}
+
+func TestApplyBootstrapManifestsWithToken(t *testing.T) {
+	cases := []struct {
+		name        string
+		deniedVerbs []string
+		expectedErr bool
+	}{
+		{
+			name: "the token has the required permissions",
+		},
+		{
+			name:        "the token is not allowed to update the secret",
+			deniedVerbs: []string{"update"},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeClient.PrependReactor("create", "selfsubjectaccessreviews",
+				func(action clienttesting.Action) (bool, runtime.Object, error) {
+					review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+					review.Status.Allowed = true
+					for _, verb := range c.deniedVerbs {
+						if review.Spec.ResourceAttributes.Verb == verb {
+							review.Status.Allowed = false
+						}
+					}
+					return true, review, nil
+				})
+
+			clientHolder := &ClientHolder{KubeClient: kubeClient}
+			_, err := ApplyBootstrapManifestsWithToken(clientHolder, testinghelpers.GetImportSecret("test"),
+				eventstesting.NewTestingEventRecorder(t))
+			if !c.expectedErr {
+				if err != nil {
+					t.Fatalf("unexpected err %v", err)
+				}
+				_, err = kubeClient.CoreV1().Secrets("open-cluster-management-agent").Get(
+					context.TODO(), "bootstrap-hub-kubeconfig", metav1.GetOptions{})
+				if err != nil {
+					t.Errorf("unexpected err %v", err)
+				}
+				return
+			}
+
+			if !errors.IsForbidden(err) || !strings.Contains(err.Error(), "update") {
+				t.Errorf("expected a forbidden error that reports the denied verbs, but got %v", err)
+			}
+			if !ContainAuthError(err) {
+				t.Errorf("expected an auth error, but got %v", err)
+			}
+			_, err = kubeClient.CoreV1().Secrets("open-cluster-management-agent").Get(
+				context.TODO(), "bootstrap-hub-kubeconfig", metav1.GetOptions{})
+			if !errors.IsNotFound(err) {
+				t.Errorf("expected the bootstrap secret is not applied, but got %v", err)
+			}
+		})
+	}
+}
+
+func TestImportWithTokenStrategy(t *testing.T) {
+	managedClusterName := "test"
+	works := []runtime.Object{}
+	for _, name := range []string{"test-klusterlet-crds", "test-klusterlet"} {
+		works = append(works, &workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: managedClusterName,
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "true",
+				},
+			},
+		})
+	}
+
+	kubeInformerFactory := informers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 10*time.Minute)
+	kubeInformerFactory.Core().V1().Secrets().Informer().GetStore().Add(
+		testinghelpers.GetImportSecret(managedClusterName))
+	workInformerFactory := workinformers.NewSharedInformerFactory(workfake.NewSimpleClientset(works...), 10*time.Minute)
+	workInformer := workInformerFactory.Work().V1().ManifestWorks().Informer()
+	for _, work := range works {
+		workInformer.GetStore().Add(work)
+	}
+
+	spokeKubeClient := kubefake.NewSimpleClientset()
+	spokeKubeClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &kubeversion.Info{
+		GitVersion: "v1.27.3",
+	}
+	spokeKubeClient.PrependReactor("create", "selfsubjectaccessreviews",
+		func(action clienttesting.Action) (bool, runtime.Object, error) {
+			review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+			review.Status.Allowed = true
+			return true, review, nil
+		})
+
+	defaultApplied := false
+	importHelper := NewImportHelper(&source.InformerHolder{
+		ImportSecretLister:   kubeInformerFactory.Core().V1().Secrets().Lister(),
+		KlusterletWorkLister: workInformerFactory.Work().V1().ManifestWorks().Lister(),
+	}, eventstesting.NewTestingEventRecorder(t), logf.Log.WithName("import-helper-tester")).
+		WithGenerateClientHolderFunc(func(secret *corev1.Secret) (*ClientHolder, meta.RESTMapper, error) {
+			return &ClientHolder{KubeClient: spokeKubeClient}, nil, nil
+		}).
+		WithApplyResourcesFunc(func(backupRestore bool, client *ClientHolder, restMapper meta.RESTMapper,
+			recorder events.Recorder, importSecret *corev1.Secret) (bool, error) {
+			defaultApplied = true
+			return true, nil
+		})
+
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: managedClusterName}}
+	_, condition, modified, _, err := importHelper.Import(context.TODO(), false, managedCluster, &corev1.Secret{
+		Data: map[string][]byte{
+			constants.AutoImportStrategyKey: []byte(constants.AutoImportStrategyToken),
+			"server":                        []byte("https://api.test.com:6443"),
+			"token":                         []byte("test-token"),
+		},
+	}, 0, 1)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if defaultApplied {
+		t.Errorf("expected only the bootstrap manifests are applied with the token strategy")
+	}
+	if !modified || !ImportingResourcesApplied(&condition) {
+		t.Errorf("expected the bootstrap manifests are applied, but got %v", condition)
+	}
+	if _, err := spokeKubeClient.CoreV1().Secrets("open-cluster-management-agent").Get(
+		context.TODO(), "bootstrap-hub-kubeconfig", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the bootstrap secret is applied, but got %v", err)
+	}
+}
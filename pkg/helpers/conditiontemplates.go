@@ -0,0 +1,124 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+// ConditionMessageTemplatesConfigMapName is the name of the configmap in the namespace of the import controller
+// to customize the messages of the ManagedClusterImportSucceeded condition. The keys of the configmap are the
+// condition reasons and the values are the text templates of the messages, the templates can use the
+// placeholders {{.ClusterName}}, {{.Reason}} and {{.Message}}, the {{.Message}} is the default message of the
+// condition which includes the error if the import is failed.
+const ConditionMessageTemplatesConfigMapName = "import-condition-message-templates"
+
+// conditionMessageTemplateData is the data to render the condition message templates
+type conditionMessageTemplateData struct {
+	ClusterName string
+	Reason      string
+	Message     string
+}
+
+var conditionMessageTemplates = struct {
+	sync.RWMutex
+	templates map[string]*template.Template
+}{
+	templates: map[string]*template.Template{},
+}
+
+// SetConditionMessageTemplates replaces the condition message templates with the templates of the configmap, the
+// invalid templates are ignored and returned as an aggregated error. If the configmap is nil, all of the templates
+// are removed and the default messages are used.
+func SetConditionMessageTemplates(configMap *corev1.ConfigMap) error {
+	templates := map[string]*template.Template{}
+	errs := []error{}
+	if configMap != nil {
+		reasons := make([]string, 0, len(configMap.Data))
+		for reason := range configMap.Data {
+			reasons = append(reasons, reason)
+		}
+		sort.Strings(reasons)
+
+		for _, reason := range reasons {
+			tmpl, err := template.New(reason).Option("missingkey=error").Parse(configMap.Data[reason])
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid message template of the reason %s: %v", reason, err))
+				continue
+			}
+			templates[reason] = tmpl
+		}
+	}
+
+	conditionMessageTemplates.Lock()
+	defer conditionMessageTemplates.Unlock()
+	conditionMessageTemplates.templates = templates
+	return utilerrors.NewAggregate(errs)
+}
+
+// RenderConditionMessage returns the message of the ManagedClusterImportSucceeded condition that is rendered by
+// the template of the condition reason. The default message is returned if there is no template for the reason
+// or the template cannot be rendered.
+func RenderConditionMessage(clusterName string, cond metav1.Condition) string {
+	if cond.Type != constants.ConditionManagedClusterImportSucceeded {
+		return cond.Message
+	}
+
+	conditionMessageTemplates.RLock()
+	tmpl, ok := conditionMessageTemplates.templates[cond.Reason]
+	conditionMessageTemplates.RUnlock()
+	if !ok {
+		return cond.Message
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, conditionMessageTemplateData{
+		ClusterName: clusterName,
+		Reason:      cond.Reason,
+		Message:     cond.Message,
+	}); err != nil {
+		klog.Warningf("failed to render the message template of the reason %s: %v", cond.Reason, err)
+		return cond.Message
+	}
+	return buf.String()
+}
+
+// ConditionMessageTemplatesEventHandler returns the event handler of the configmap informer to load the condition
+// message templates from the ConditionMessageTemplatesConfigMapName configmap
+func ConditionMessageTemplatesEventHandler() cache.ResourceEventHandler {
+	load := func(obj interface{}) {
+		configMap, ok := obj.(*corev1.ConfigMap)
+		if !ok || configMap.Name != ConditionMessageTemplatesConfigMapName {
+			return
+		}
+		if err := SetConditionMessageTemplates(configMap); err != nil {
+			klog.Errorf("failed to load the condition message templates: %v", err)
+		}
+	}
+
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    load,
+		UpdateFunc: func(_, newObj interface{}) { load(newObj) },
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if configMap, ok := obj.(*corev1.ConfigMap); ok && configMap.Name == ConditionMessageTemplatesConfigMapName {
+				_ = SetConditionMessageTemplates(nil)
+			}
+		},
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+func TestRenderConditionMessage(t *testing.T) {
+	defer func() { _ = SetConditionMessageTemplates(nil) }()
+
+	err := SetConditionMessageTemplates(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: ConditionMessageTemplatesConfigMapName,
+		},
+		Data: map[string]string{
+			constants.ConditionReasonManagedClusterImportFailed: "Cluster {{.ClusterName}} failed: {{.Message}}",
+			constants.ConditionReasonManagedClusterImported:     "{{.ClusterName}} is ready",
+			constants.ConditionReasonManagedClusterImporting:    "{{.Unknown}}",
+			constants.ConditionReasonSpokeVersionUnsupported:    "{{.ClusterName",
+		},
+	})
+	if err == nil {
+		t.Errorf("expected error for the invalid template, but failed")
+	}
+
+	cases := []struct {
+		name            string
+		condition       metav1.Condition
+		expectedMessage string
+	}{
+		{
+			name: "custom template with the error",
+			condition: NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+				constants.ConditionReasonManagedClusterImportFailed, "connection refused"),
+			expectedMessage: "Cluster test failed: connection refused",
+		},
+		{
+			name: "custom template",
+			condition: NewManagedClusterImportSucceededCondition(metav1.ConditionTrue,
+				constants.ConditionReasonManagedClusterImported, "Import succeeded"),
+			expectedMessage: "test is ready",
+		},
+		{
+			name: "template cannot be rendered",
+			condition: NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+				constants.ConditionReasonManagedClusterImporting, "Wait for import secret"),
+			expectedMessage: "Wait for import secret",
+		},
+		{
+			name: "invalid template",
+			condition: NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+				constants.ConditionReasonSpokeVersionUnsupported, "unsupported"),
+			expectedMessage: "unsupported",
+		},
+		{
+			name: "no template",
+			condition: NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+				constants.ConditionReasonManagedClusterWaitForImporting, "Waiting"),
+			expectedMessage: "Waiting",
+		},
+		{
+			name: "other conditions",
+			condition: metav1.Condition{
+				Type:    constants.ConditionManagedClusterPermanentlyUnreachable,
+				Reason:  constants.ConditionReasonManagedClusterImported,
+				Message: "reachable",
+			},
+			expectedMessage: "reachable",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if message := RenderConditionMessage("test", c.condition); message != c.expectedMessage {
+				t.Errorf("expected message %q, but got %q", c.expectedMessage, message)
+			}
+		})
+	}
+}
+
+func TestUpdateManagedClusterStatusWithMessageTemplates(t *testing.T) {
+	defer func() { _ = SetConditionMessageTemplates(nil) }()
+
+	cluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+	runtimeClient := fake.NewClientBuilder().WithScheme(testscheme).
+		WithObjects(cluster).WithStatusSubresource(cluster).Build()
+	condition := NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+		constants.ConditionReasonManagedClusterImportFailed, "connection refused")
+
+	// the default message is used when the templates are unset
+	if err := UpdateManagedClusterStatus(runtimeClient, "test", condition); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertImportConditionMessage(t, runtimeClient, "connection refused")
+
+	if err := SetConditionMessageTemplates(&corev1.ConfigMap{
+		Data: map[string]string{
+			constants.ConditionReasonManagedClusterImportFailed: "{{.ClusterName}}: {{.Message}}",
+		},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := UpdateManagedClusterStatus(runtimeClient, "test", condition); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertImportConditionMessage(t, runtimeClient, "test: connection refused")
+}
+
+func assertImportConditionMessage(t *testing.T, runtimeClient client.Client, expected string) {
+	cluster := &clusterv1.ManagedCluster{}
+	if err := runtimeClient.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	condition := meta.FindStatusCondition(cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+	if condition == nil || condition.Message != expected {
+		t.Errorf("expected condition message %q, but got %v", expected, condition)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+// DetachManagedCluster cleans up the import artifacts of a managed cluster on the hub, it deletes the import
+// secret, the auto import secret and the klusterlet manifest works of the managed cluster, and removes the import
+// finalizer from the managed cluster. The artifacts that are already cleaned up are skipped, so it can be re-run
+// on a partially detached managed cluster. Each step is attempted even if a previous step fails, the errors are
+// returned as an aggregated error.
+//
+// Note: the import controller adds the import finalizer back to a managed cluster that is not being deleted, so
+// the managed cluster is expected to be deleted after it is detached.
+func DetachManagedCluster(ctx context.Context, clientHolder *ClientHolder, clusterName string) error {
+	errs := []error{}
+
+	secretNames := []string{
+		GetImportSecretName(clusterName),
+		constants.AutoImportSecretName,
+	}
+	for _, name := range secretNames {
+		err := clientHolder.KubeClient.CoreV1().Secrets(clusterName).Delete(ctx, name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete the secret %s/%s: %w", clusterName, name, err))
+		}
+	}
+
+	workSelector := labels.SelectorFromSet(map[string]string{constants.KlusterletWorksLabel: "true"})
+	works, err := clientHolder.WorkClient.WorkV1().ManifestWorks(clusterName).List(ctx, metav1.ListOptions{
+		LabelSelector: workSelector.String(),
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to list the klusterlet works of %s: %w", clusterName, err))
+	} else {
+		for _, work := range works.Items {
+			err := clientHolder.WorkClient.WorkV1().ManifestWorks(clusterName).Delete(
+				ctx, work.Name, metav1.DeleteOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("failed to delete the manifestwork %s/%s: %w",
+					clusterName, work.Name, err))
+			}
+		}
+	}
+
+	if err := removeImportFinalizer(ctx, clientHolder, clusterName); err != nil {
+		errs = append(errs, fmt.Errorf("failed to remove the import finalizer from %s: %w", clusterName, err))
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+func removeImportFinalizer(ctx context.Context, clientHolder *ClientHolder, clusterName string) error {
+	managedCluster := &clusterv1.ManagedCluster{}
+	err := clientHolder.RuntimeClient.Get(ctx, types.NamespacedName{Name: clusterName}, managedCluster)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	copiedFinalizers := []string{}
+	for _, finalizer := range managedCluster.Finalizers {
+		if finalizer == constants.ImportFinalizer {
+			continue
+		}
+		copiedFinalizers = append(copiedFinalizers, finalizer)
+	}
+	if len(copiedFinalizers) == len(managedCluster.Finalizers) {
+		return nil
+	}
+
+	patch := client.MergeFrom(managedCluster.DeepCopy())
+	managedCluster.Finalizers = copiedFinalizers
+	if err := clientHolder.RuntimeClient.Patch(ctx, managedCluster, patch); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+func TestDetachManagedCluster(t *testing.T) {
+	clusterName := "cluster1"
+
+	cases := []struct {
+		name               string
+		objs               []client.Object
+		secrets            []runtime.Object
+		works              []runtime.Object
+		expectedFinalizers []string
+	}{
+		{
+			name: "detach a managed cluster",
+			objs: []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:       clusterName,
+						Finalizers: []string{constants.ImportFinalizer, "test"},
+					},
+				},
+			},
+			secrets: []runtime.Object{
+				&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "cluster1-import", Namespace: clusterName}},
+				&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: constants.AutoImportSecretName, Namespace: clusterName}},
+				&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: clusterName}},
+			},
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "cluster1-klusterlet",
+						Namespace: clusterName,
+						Labels:    map[string]string{constants.KlusterletWorksLabel: "true"},
+					},
+				},
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "cluster1-klusterlet-crds",
+						Namespace: clusterName,
+						Labels:    map[string]string{constants.KlusterletWorksLabel: "true"},
+					},
+				},
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: clusterName},
+				},
+			},
+			expectedFinalizers: []string{"test"},
+		},
+		{
+			name: "partially detached managed cluster",
+			objs: []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:       clusterName,
+						Finalizers: []string{"test"},
+					},
+				},
+			},
+			secrets: []runtime.Object{
+				&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: constants.AutoImportSecretName, Namespace: clusterName}},
+				&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: clusterName}},
+			},
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: clusterName},
+				},
+			},
+			expectedFinalizers: []string{"test"},
+		},
+		{
+			name: "the managed cluster is deleted",
+			secrets: []runtime.Object{
+				&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: clusterName}},
+			},
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: clusterName},
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clientHolder := &ClientHolder{
+				KubeClient:    kubefake.NewSimpleClientset(c.secrets...),
+				WorkClient:    workfake.NewSimpleClientset(c.works...),
+				RuntimeClient: fake.NewClientBuilder().WithScheme(testscheme).WithObjects(c.objs...).Build(),
+			}
+
+			// detaching is idempotent, the second run completes cleanly
+			for i := 0; i < 2; i++ {
+				if err := DetachManagedCluster(context.TODO(), clientHolder, clusterName); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+
+			secrets, err := clientHolder.KubeClient.CoreV1().Secrets(clusterName).List(
+				context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(secrets.Items) != 1 || secrets.Items[0].Name != "other" {
+				t.Errorf("expected only the other secret is kept, but got %v", secrets.Items)
+			}
+
+			works, err := clientHolder.WorkClient.WorkV1().ManifestWorks(clusterName).List(
+				context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(works.Items) != 1 || works.Items[0].Name != "other" {
+				t.Errorf("expected only the other manifestwork is kept, but got %v", works.Items)
+			}
+
+			if len(c.objs) == 0 {
+				return
+			}
+			managedCluster := &clusterv1.ManagedCluster{}
+			if err := clientHolder.RuntimeClient.Get(
+				context.TODO(), types.NamespacedName{Name: clusterName}, managedCluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(managedCluster.Finalizers, c.expectedFinalizers) {
+				t.Errorf("expected finalizers %v, but got %v", c.expectedFinalizers, managedCluster.Finalizers)
+			}
+		})
+	}
+}
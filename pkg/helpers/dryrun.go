@@ -0,0 +1,95 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+const dryRunFieldManager = "managedcluster-import-controller"
+
+// IsImportDryRun returns true if the managed cluster has the import dry-run annotation, the import of the managed
+// cluster only validates the klusterlet manifests on the managed cluster in this case
+func IsImportDryRun(annotations map[string]string) bool {
+	return strings.EqualFold(annotations[constants.ImportDryRunAnnotation], "true")
+}
+
+// DryRunImportManagedClusterFromSecret uses the managed cluster client to apply the klusterlet manifests of the
+// import secret with the server-side dry-run, nothing is persisted on the managed cluster.
+//
+// The crds and the namespaces of the klusterlet manifests are not created in the dry-run, so the objects whose
+// kinds are defined by these crds or which are in these namespaces cannot be validated by the managed cluster,
+// they are skipped after they are parsed.
+func DryRunImportManagedClusterFromSecret(ctx context.Context, clientHolder *ClientHolder,
+	restMapper meta.RESTMapper, importSecret *corev1.Secret) error {
+	if err := ValidateImportSecret(importSecret); err != nil {
+		return err
+	}
+
+	crdsKey := constants.ImportSecretCRDSV1YamlKey
+	if _, err := restMapper.RESTMapping(crdGroupKind, "v1"); err != nil {
+		crdsKey = constants.ImportSecretCRDSV1beta1YamlKey
+	}
+
+	importYaml, err := GetImportYaml(importSecret)
+	if err != nil {
+		return err
+	}
+
+	objs := []*unstructured.Unstructured{}
+	for _, raw := range append(SplitYamls(importSecret.Data[crdsKey]), SplitYamls(importYaml)...) {
+		if len(strings.TrimSpace(string(raw))) == 0 {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(raw, &obj.Object); err != nil {
+			return fmt.Errorf("failed to parse the klusterlet manifest: %v", err)
+		}
+		objs = append(objs, obj)
+	}
+
+	pendingGroupKinds := sets.New[schema.GroupKind]()
+	pendingNamespaces := sets.New[string]()
+	for _, obj := range objs {
+		switch obj.GetKind() {
+		case "CustomResourceDefinition":
+			group, _, _ := unstructured.NestedString(obj.Object, "spec", "group")
+			kind, _, _ := unstructured.NestedString(obj.Object, "spec", "names", "kind")
+			pendingGroupKinds.Insert(schema.GroupKind{Group: group, Kind: kind})
+		case "Namespace":
+			pendingNamespaces.Insert(obj.GetName())
+		}
+	}
+
+	errs := []error{}
+	for _, obj := range objs {
+		err := clientHolder.RuntimeClient.Patch(ctx, obj, client.Apply,
+			client.DryRunAll, client.ForceOwnership, client.FieldOwner(dryRunFieldManager))
+		switch {
+		case err == nil:
+		case meta.IsNoMatchError(err) && pendingGroupKinds.Has(obj.GroupVersionKind().GroupKind()):
+		case errors.IsNotFound(err) && pendingNamespaces.Has(obj.GetNamespace()):
+		default:
+			errs = append(errs, fmt.Errorf("dry-run apply %s %s failed: %v",
+				obj.GetKind(), client.ObjectKeyFromObject(obj), err))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,153 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+const dryRunCRDsYaml = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: klusterlets.operator.open-cluster-management.io
+spec:
+  group: operator.open-cluster-management.io
+  names:
+    kind: Klusterlet
+`
+
+const dryRunImportYaml = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: open-cluster-management-agent
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: klusterlet
+  namespace: open-cluster-management-agent
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: klusterlet
+---
+apiVersion: operator.open-cluster-management.io/v1
+kind: Klusterlet
+metadata:
+  name: klusterlet
+`
+
+func TestDryRunImportManagedClusterFromSecret(t *testing.T) {
+	importSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-import",
+			Namespace: "test",
+		},
+		Data: map[string][]byte{
+			"crds.yaml":        []byte(dryRunCRDsYaml),
+			"crdsv1.yaml":      []byte(dryRunCRDsYaml),
+			"crdsv1beta1.yaml": []byte(dryRunCRDsYaml),
+			"import.yaml":      []byte(dryRunImportYaml),
+		},
+	}
+
+	restMapper := meta.NewDefaultRESTMapper(nil)
+	restMapper.Add(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1",
+		Kind: "CustomResourceDefinition"}, meta.RESTScopeRoot)
+
+	cases := []struct {
+		name            string
+		patchErrs       map[string]error
+		expectedErr     string
+		expectedPatches []string
+	}{
+		{
+			name: "the objects of the pending crd kinds and namespaces are skipped",
+			patchErrs: map[string]error{
+				"Klusterlet": &meta.NoKindMatchError{
+					GroupKind: schema.GroupKind{Group: "operator.open-cluster-management.io", Kind: "Klusterlet"},
+				},
+				"ServiceAccount": errors.NewNotFound(
+					schema.GroupResource{Resource: "namespaces"}, "open-cluster-management-agent"),
+			},
+			expectedPatches: []string{"CustomResourceDefinition", "Namespace", "ServiceAccount", "ClusterRole",
+				"Klusterlet"},
+		},
+		{
+			name: "the object that is rejected by the managed cluster is reported",
+			patchErrs: map[string]error{
+				"ClusterRole": errors.NewForbidden(
+					schema.GroupResource{Group: "rbac.authorization.k8s.io", Resource: "clusterroles"},
+					"klusterlet", fmt.Errorf("no permission")),
+			},
+			expectedErr: "dry-run apply ClusterRole",
+			expectedPatches: []string{"CustomResourceDefinition", "Namespace", "ServiceAccount", "ClusterRole",
+				"Klusterlet"},
+		},
+		{
+			name: "the object whose kind is not defined by the klusterlet crds is reported",
+			patchErrs: map[string]error{
+				"ClusterRole": &meta.NoKindMatchError{
+					GroupKind: schema.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"},
+				},
+			},
+			expectedErr: "dry-run apply ClusterRole",
+			expectedPatches: []string{"CustomResourceDefinition", "Namespace", "ServiceAccount", "ClusterRole",
+				"Klusterlet"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			patches := []string{}
+			runtimeClient := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+				Patch: func(_ context.Context, _ client.WithWatch, obj client.Object, patch client.Patch,
+					opts ...client.PatchOption) error {
+					if patch.Type() != types.ApplyPatchType {
+						t.Errorf("expected the server-side apply patch, but got %s", patch.Type())
+					}
+					options := &client.PatchOptions{}
+					options.ApplyOptions(opts)
+					if !sets.New(options.DryRun...).Has(metav1.DryRunAll) {
+						t.Errorf("expected the dry-run patch, but got %v", options.DryRun)
+					}
+					if options.FieldManager != dryRunFieldManager {
+						t.Errorf("expected the field manager %s, but got %s", dryRunFieldManager, options.FieldManager)
+					}
+
+					kind := obj.GetObjectKind().GroupVersionKind().Kind
+					patches = append(patches, kind)
+					return c.patchErrs[kind]
+				},
+			}).Build()
+
+			err := DryRunImportManagedClusterFromSecret(context.TODO(),
+				&ClientHolder{RuntimeClient: runtimeClient}, restMapper, importSecret)
+			switch {
+			case len(c.expectedErr) == 0 && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case len(c.expectedErr) != 0 && (err == nil || !strings.Contains(err.Error(), c.expectedErr)):
+				t.Errorf("expected error %q, but got %v", c.expectedErr, err)
+			}
+			if strings.Join(patches, ",") != strings.Join(c.expectedPatches, ",") {
+				t.Errorf("expected patches %v, but got %v", c.expectedPatches, patches)
+			}
+		})
+	}
+}
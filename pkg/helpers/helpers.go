@@ -6,10 +6,16 @@ package helpers
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"math"
+	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"text/template"
@@ -17,10 +23,12 @@ import (
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
 	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
+	"golang.org/x/net/http/httpproxy"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	crdv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	crdv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
@@ -29,19 +37,23 @@ import (
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/types"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cliflag "k8s.io/component-base/cli/flag"
 	"k8s.io/klog/v2"
 	addonv1alpha1 "open-cluster-management.io/api/addon/v1alpha1"
 	operatorclient "open-cluster-management.io/api/client/operator/clientset/versioned"
@@ -60,11 +72,51 @@ import (
 
 const maxConcurrentReconcilesEnvVarName = "MAX_CONCURRENT_RECONCILES"
 
+// EventsNamespace is a dedicated namespace for the events of the controllers, it is set by the events-namespace
+// flag, by default, the events are created in the namespace of this component
+var EventsNamespace string
+
+const (
+	spokeTLSMinVersionEnvVarName   = "SPOKE_TLS_MIN_VERSION"
+	spokeTLSCipherSuitesEnvVarName = "SPOKE_TLS_CIPHER_SUITES"
+)
+
+// the data keys of the secret that is used to generate the client of the managed cluster
+const (
+	autoImportKubeconfigKey            = "kubeconfig"
+	autoImportServerKey                = "server"
+	autoImportTokenKey                 = "token"
+	autoImportCACertKey                = "caCert"
+	autoImportInsecureSkipTLSVerifyKey = "insecureSkipTLSVerify"
+	autoImportHTTPProxyKey             = "httpProxy"
+	autoImportHTTPSProxyKey            = "httpsProxy"
+	autoImportNoProxyKey               = "noProxy"
+	autoImportProxyCABundleKey         = "proxyCABundle"
+)
+
 const (
 	nodeSelectorAnnotation = "open-cluster-management/nodeSelector"
 	tolerationsAnnotation  = "open-cluster-management/tolerations"
 )
 
+const (
+	// the tmpdir volume is mounted into the klusterlet deployment by default
+	reservedKlusterletVolumeName      = "tmpdir"
+	reservedKlusterletVolumeMountPath = "/tmp"
+)
+
+const (
+	defaultKlusterletMetricsPort int64 = 8443
+	minKlusterletMetricsPort     int64 = 1024
+	maxKlusterletMetricsPort     int64 = 65535
+)
+
+const (
+	// the minimum expiration seconds of a certificate signing request is 600 seconds
+	minClientCertExpirationSeconds int64 = 600
+	maxClientCertExpirationSeconds int64 = math.MaxInt32
+)
+
 var v1APIExtensionMinVersion = version.MustParseGeneric("v1.16.0")
 
 var crdGroupKind = schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
@@ -78,6 +130,7 @@ var (
 func init() {
 	utilruntime.Must(appsv1.AddToScheme(genericScheme))
 	utilruntime.Must(corev1.AddToScheme(genericScheme))
+	utilruntime.Must(policyv1.AddToScheme(genericScheme))
 	utilruntime.Must(rbacv1.AddToScheme(genericScheme))
 	utilruntime.Must(crdv1beta1.AddToScheme(genericScheme))
 	utilruntime.Must(crdv1.AddToScheme(genericScheme))
@@ -95,40 +148,114 @@ type ClientHolder struct {
 }
 
 // GetMaxConcurrentReconciles get the max concurrent reconciles from MAX_CONCURRENT_RECONCILES env,
-// if the reconciles cannot be found, return 1
+// if the reconciles cannot be found or is not a positive integer, return 1
 func GetMaxConcurrentReconciles() int {
-	maxConcurrentReconciles := 1
-	if os.Getenv(maxConcurrentReconcilesEnvVarName) != "" {
-		var err error
-		maxConcurrentReconciles, err = strconv.Atoi(os.Getenv(maxConcurrentReconcilesEnvVarName))
-		if err != nil {
-			klog.Warningf("The value of %s env is wrong, using default reconciles (1)", maxConcurrentReconcilesEnvVarName)
-			maxConcurrentReconciles = 1
+	if reconciles, ok := maxConcurrentReconcilesFromEnv(maxConcurrentReconcilesEnvVarName); ok {
+		return reconciles
+	}
+	return 1
+}
+
+// GetControllerMaxConcurrentReconciles get the max concurrent reconciles of a controller, the precedence is:
+//  1. the controller specific env, e.g. HOSTED_MAX_CONCURRENT_RECONCILES
+//  2. the global MAX_CONCURRENT_RECONCILES env
+//  3. the default reconciles (1)
+//
+// an unset, zero or invalid value falls back to the next one, so a controller cannot be disabled by the envs
+func GetControllerMaxConcurrentReconciles(controllerEnvVarName string) int {
+	if reconciles, ok := maxConcurrentReconcilesFromEnv(controllerEnvVarName); ok {
+		return reconciles
+	}
+	return GetMaxConcurrentReconciles()
+}
+
+func maxConcurrentReconcilesFromEnv(envVarName string) (int, bool) {
+	value := os.Getenv(envVarName)
+	if value == "" {
+		return 0, false
+	}
+
+	reconciles, err := strconv.Atoi(value)
+	if err != nil || reconciles <= 0 {
+		klog.Warningf("The value of %s env is wrong, it should be a positive integer, ignore it", envVarName)
+		return 0, false
+	}
+	return reconciles, true
+}
+
+// GetImportSecretName returns the name of the import secret of the managed cluster, the name suffix is specified
+// by the IMPORT_SECRET_NAME_SUFFIX env, by default, it is import.
+func GetImportSecretName(clusterName string) string {
+	suffix := constants.ImportSecretNameSuffix
+	if customized := os.Getenv(constants.ImportSecretNameSuffixEnvVarName); len(customized) != 0 {
+		suffix = customized
+	}
+	return fmt.Sprintf("%s-%s", clusterName, suffix)
+}
+
+// ValidateAutoImportSecret checks the auto import secret has a recognized shape, it contains either a kubeconfig
+// or a server with a bearer token, the returned error lists the accepted key combinations, so the user can find
+// a mistyped key before the import fails
+func ValidateAutoImportSecret(secret *corev1.Secret) error {
+	hasKey := func(key string) bool {
+		if len(secret.Data[key]) != 0 {
+			return true
 		}
+		return len(secret.StringData[key]) != 0
+	}
+
+	kubeconfig := hasKey(autoImportKubeconfigKey)
+	token := hasKey(autoImportTokenKey)
+	server := hasKey(autoImportServerKey)
+	switch {
+	case kubeconfig && !token && !server:
+		return nil
+	case !kubeconfig && token && server:
+		return nil
+	}
+
+	keys := sets.New[string]()
+	for key := range secret.Data {
+		keys.Insert(key)
 	}
-	return maxConcurrentReconciles
+	for key := range secret.StringData {
+		keys.Insert(key)
+	}
+	return fmt.Errorf("the auto import secret %s/%s has the keys %v; it must contain either the key %q, "+
+		"or both of the keys %q and %q, but not both forms", secret.Namespace, secret.Name, sets.List(keys),
+		autoImportKubeconfigKey, autoImportServerKey, autoImportTokenKey)
 }
 
-// GenerateClientFromSecret generate a client from a given secret
+// GenerateClientFromSecret generate a client from a given secret, the secret contains either a kubeconfig or
+// a server with a bearer token, for the token form, the optional caCert and insecureSkipTLSVerify are used to
+// verify the server, if the caCert is not provided, the server will not be verified by default
 func GenerateClientFromSecret(secret *corev1.Secret) (*ClientHolder, meta.RESTMapper, error) {
 	var err error
 	var config *clientcmdapi.Config
 
-	if kubeconfig, ok := secret.Data["kubeconfig"]; ok {
+	kubeconfig, kok := secret.Data[autoImportKubeconfigKey]
+	token, tok := secret.Data[autoImportTokenKey]
+	server, sok := secret.Data[autoImportServerKey]
+	if kok && (tok || sok) {
+		return nil, nil, fmt.Errorf("ambiguous client config, the %s and the %s/%s cannot be both specified",
+			autoImportKubeconfigKey, autoImportTokenKey, autoImportServerKey)
+	}
+
+	if kok {
 		config, err = clientcmd.Load(kubeconfig)
 		if err != nil {
 			return nil, nil, err
 		}
 	}
 
-	token, tok := secret.Data["token"]
-	server, sok := secret.Data["server"]
 	if tok && sok {
-		config = clientcmdapi.NewConfig()
-		config.Clusters["default"] = &clientcmdapi.Cluster{
-			Server:                string(server),
-			InsecureSkipTLSVerify: true,
+		cluster, err := clusterFromTokenSecret(secret, string(server))
+		if err != nil {
+			return nil, nil, err
 		}
+
+		config = clientcmdapi.NewConfig()
+		config.Clusters["default"] = cluster
 		config.AuthInfos["default"] = &clientcmdapi.AuthInfo{
 			Token: string(token),
 		}
@@ -148,6 +275,14 @@ func GenerateClientFromSecret(secret *corev1.Secret) (*ClientHolder, meta.RESTMa
 		return nil, nil, err
 	}
 
+	if err := setSpokeTLSPolicy(clientConfig); err != nil {
+		return nil, nil, err
+	}
+
+	if err := setSpokeProxy(clientConfig, secret); err != nil {
+		return nil, nil, err
+	}
+
 	kubeClient, err := kubernetes.NewForConfig(clientConfig)
 	if err != nil {
 		return nil, nil, err
@@ -185,6 +320,124 @@ func GenerateClientFromSecret(secret *corev1.Secret) (*ClientHolder, meta.RESTMa
 	}, mapper, nil
 }
 
+// clusterFromTokenSecret builds the cluster of the client config for the secret that contains a server and a
+// bearer token
+func clusterFromTokenSecret(secret *corev1.Secret, server string) (*clientcmdapi.Cluster, error) {
+	caCert := secret.Data[autoImportCACertKey]
+
+	// keep the server unverified if there is no ca cert for backward compatibility
+	insecure := len(caCert) == 0
+	if v, ok := secret.Data[autoImportInsecureSkipTLSVerifyKey]; ok {
+		var err error
+		insecure, err = strconv.ParseBool(string(v))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %v", autoImportInsecureSkipTLSVerifyKey, string(v), err)
+		}
+	}
+
+	if insecure {
+		return &clientcmdapi.Cluster{
+			Server:                server,
+			InsecureSkipTLSVerify: true,
+		}, nil
+	}
+
+	if len(caCert) == 0 {
+		return nil, fmt.Errorf("the %s is required if the %s is false",
+			autoImportCACertKey, autoImportInsecureSkipTLSVerifyKey)
+	}
+
+	return &clientcmdapi.Cluster{
+		Server:                   server,
+		CertificateAuthorityData: caCert,
+	}, nil
+}
+
+// setSpokeProxy sets the forward proxy of the managed cluster client config from the httpProxy, httpsProxy and
+// noProxy of the secret, the hosts in the noProxy are accessed directly. The proxyCABundle of the secret is added
+// to the certificate authorities of the managed cluster, so the TLS connection to an HTTPS proxy can be verified.
+func setSpokeProxy(clientConfig *rest.Config, secret *corev1.Secret) error {
+	proxyConfig := &httpproxy.Config{
+		HTTPProxy:  string(secret.Data[autoImportHTTPProxyKey]),
+		HTTPSProxy: string(secret.Data[autoImportHTTPSProxyKey]),
+		NoProxy:    string(secret.Data[autoImportNoProxyKey]),
+	}
+	if len(proxyConfig.HTTPProxy) == 0 && len(proxyConfig.HTTPSProxy) == 0 {
+		return nil
+	}
+
+	for key, proxyURL := range map[string]string{
+		autoImportHTTPProxyKey:  proxyConfig.HTTPProxy,
+		autoImportHTTPSProxyKey: proxyConfig.HTTPSProxy,
+	} {
+		if len(proxyURL) == 0 {
+			continue
+		}
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %v", key, proxyURL, err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			return fmt.Errorf("invalid %s %q: the proxy must be an http or https URL", key, proxyURL)
+		}
+	}
+
+	if caBundle := secret.Data[autoImportProxyCABundleKey]; len(caBundle) != 0 && !clientConfig.Insecure {
+		if len(clientConfig.CAData) == 0 {
+			return fmt.Errorf("the %s requires the certificate authority of the managed cluster",
+				autoImportProxyCABundleKey)
+		}
+		clientConfig.CAData = append(append(append([]byte{}, clientConfig.CAData...), '\n'), caBundle...)
+	}
+
+	proxyFunc := proxyConfig.ProxyFunc()
+	clientConfig.Proxy = func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+	return nil
+}
+
+// setSpokeTLSPolicy sets the minimum TLS version and the cipher suites on the transport of the managed
+// cluster client config, they are read from the SPOKE_TLS_MIN_VERSION and SPOKE_TLS_CIPHER_SUITES envs.
+// If the envs are not set, the minimum TLS version is VersionTLS12 and the default cipher suites of golang
+// are used. The handshake with a lower TLS version will be rejected.
+//
+// The policy is applied by wrapping the transport that client-go builds, so the TLS options of the client
+// config (CA, client certificates, exec credential plugins) and the proxy settings are kept as they are.
+func setSpokeTLSPolicy(clientConfig *rest.Config) error {
+	minVersion, err := cliflag.TLSVersion(os.Getenv(spokeTLSMinVersionEnvVarName))
+	if err != nil {
+		return fmt.Errorf("invalid %s env: %v", spokeTLSMinVersionEnvVarName, err)
+	}
+
+	var cipherSuites []uint16
+	if cipherSuiteNames := os.Getenv(spokeTLSCipherSuitesEnvVarName); len(cipherSuiteNames) != 0 {
+		cipherSuites, err = cliflag.TLSCipherSuites(strings.Split(cipherSuiteNames, ","))
+		if err != nil {
+			return fmt.Errorf("invalid %s env: %v", spokeTLSCipherSuitesEnvVarName, err)
+		}
+	}
+
+	clientConfig.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		transport, ok := rt.(*http.Transport)
+		if !ok {
+			klog.Warningf("The transport %T of the managed cluster client is unknown, the TLS policy is not applied", rt)
+			return rt
+		}
+
+		// the transport may be shared with other clients by the client-go transport cache, so a copy of
+		// the transport is changed
+		transport = transport.Clone()
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.MinVersion = minVersion
+		transport.TLSClientConfig.CipherSuites = cipherSuites
+		return transport
+	})
+	return nil
+}
+
 // AddManagedClusterFinalizer add a finalizer to a managed cluster
 func AddManagedClusterFinalizer(modified *bool, managedCluster *clusterv1.ManagedCluster, finalizer string) {
 	for i := range managedCluster.Finalizers {
@@ -223,6 +476,43 @@ func RemoveManagedClusterFinalizer(ctx context.Context, runtimeClient client.Cli
 	return nil
 }
 
+// RemoveLegacyImportFinalizers removes the legacy import finalizers from a managed cluster or a clusterdeployment
+func RemoveLegacyImportFinalizers(ctx context.Context, runtimeClient client.Client, recorder events.Recorder,
+	obj client.Object) error {
+	copiedFinalizers := []string{}
+	removedFinalizers := []string{}
+	for _, finalizer := range obj.GetFinalizers() {
+		if isLegacyImportFinalizer(finalizer) {
+			removedFinalizers = append(removedFinalizers, finalizer)
+			continue
+		}
+		copiedFinalizers = append(copiedFinalizers, finalizer)
+	}
+
+	if len(removedFinalizers) == 0 {
+		return nil
+	}
+
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+	obj.SetFinalizers(copiedFinalizers)
+	if err := runtimeClient.Patch(ctx, obj, patch); err != nil {
+		return err
+	}
+
+	recorder.Eventf("LegacyImportFinalizersRemoved",
+		"The legacy import finalizers %s are removed from %s", strings.Join(removedFinalizers, ","), obj.GetName())
+	return nil
+}
+
+func isLegacyImportFinalizer(finalizer string) bool {
+	for _, legacyFinalizer := range constants.LegacyImportFinalizers {
+		if finalizer == legacyFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
 // UpdateManagedClusterStatus update managed cluster status
 func UpdateManagedClusterStatus(client client.Client, managedClusterName string, cond metav1.Condition) error {
 	managedCluster := &clusterv1.ManagedCluster{}
@@ -234,6 +524,7 @@ func UpdateManagedClusterStatus(client client.Client, managedClusterName string,
 	oldStatus := &managedCluster.Status
 	newStatus := oldStatus.DeepCopy()
 
+	cond.Message = RenderConditionMessage(managedClusterName, cond)
 	meta.SetStatusCondition(&newStatus.Conditions, cond)
 	if equality.Semantic.DeepEqual(managedCluster.Status.Conditions, newStatus.Conditions) {
 		return nil
@@ -264,16 +555,16 @@ func ValidateImportSecret(importSecret *corev1.Secret) error {
 		return fmt.Errorf("the %s is required", constants.ImportSecretCRDSV1YamlKey)
 	}
 
-	if data, ok := importSecret.Data[constants.ImportSecretImportYamlKey]; !ok || len(data) == 0 {
-		return fmt.Errorf("the %s is required", constants.ImportSecretImportYamlKey)
+	if _, err := GetImportYaml(importSecret); err != nil {
+		return err
 	}
 	return nil
 }
 
 // ValidateHostedImportSecret validate hosted mode managed cluster import secret
 func ValidateHostedImportSecret(importSecret *corev1.Secret) error {
-	if data, ok := importSecret.Data[constants.ImportSecretImportYamlKey]; !ok || len(data) == 0 {
-		return fmt.Errorf("the %s is required", constants.ImportSecretImportYamlKey)
+	if _, err := GetImportYaml(importSecret); err != nil {
+		return err
 	}
 	return nil
 }
@@ -291,36 +582,99 @@ func ImportManagedClusterFromSecret(client *ClientHolder, restMapper meta.RESTMa
 		crdsKey = constants.ImportSecretCRDSV1beta1YamlKey
 	}
 
+	importYaml, err := GetImportYaml(importSecret)
+	if err != nil {
+		return false, err
+	}
+
 	objs := []runtime.Object{}
 	objs = append(objs, MustCreateObject(importSecret.Data[crdsKey]))
-	for _, yaml := range SplitYamls(importSecret.Data[constants.ImportSecretImportYamlKey]) {
+	for _, yaml := range SplitYamls(importYaml) {
 		objs = append(objs, MustCreateObject(yaml))
 	}
 	// using managed cluster client to apply resources in managed cluster, so the owner is not need
-	return ApplyResources(client, recorder, nil, nil, objs...)
+	// apply the resources one by one, so each error can be reported with the object that failed to be applied
+	changed := false
+	errs := []error{}
+	for i, obj := range objs {
+		modified, err := ApplyResources(client, recorder, nil, nil, obj)
+		changed = changed || modified
+		if err != nil {
+			errs = append(errs, newApplyObjectErrors(obj, err)...)
+		}
+
+		// the first object is the klusterlet CRD, once it is applied, make sure the klusterlet can be
+		// resolved on the managed cluster before applying the klusterlet
+		if i == 0 {
+			if err := checkKlusterletCRDs(client, restMapper); err != nil {
+				return changed, utilerrors.NewAggregate(append(errs, err))
+			}
+		}
+	}
+	return changed, utilerrors.NewAggregate(errs)
+}
+
+// newApplyObjectErrors wraps the errors of applying an object with the kind and the namespace/name of the object,
+// the aggregate error is flattened and each of its errors is wrapped, so the API status of the errors can be still
+// found by errors.As
+func newApplyObjectErrors(obj runtime.Object, err error) []error {
+	kind := fmt.Sprintf("%T", obj)
+	if gvk, gvkErr := apiutil.GVKForObject(obj, genericScheme); gvkErr == nil {
+		kind = gvk.String()
+	}
+
+	name := ""
+	if metaObj, ok := obj.(metav1.Object); ok {
+		name = metaObj.GetName()
+		if len(metaObj.GetNamespace()) != 0 {
+			name = fmt.Sprintf("%s/%s", metaObj.GetNamespace(), metaObj.GetName())
+		}
+	}
+
+	errs := []error{err}
+	if agg, ok := err.(utilerrors.Aggregate); ok {
+		errs = utilerrors.Flatten(agg).Errors()
+	}
+
+	wrapped := []error{}
+	for _, e := range errs {
+		wrapped = append(wrapped, fmt.Errorf("%s %s: %w", kind, name, e))
+	}
+	return wrapped
 }
 
-// UpdateManagedClusterBootstrapSecret update the bootstrap secret on the managed cluster
+// UpdateManagedClusterBootstrapSecret update the bootstrap secret on the managed cluster, an error is returned and
+// nothing is applied if the import secret has no bootstrap secret
 func UpdateManagedClusterBootstrapSecret(client *ClientHolder, importSecret *corev1.Secret,
 	recorder events.Recorder) (bool, error) {
+	bootstrapSecret, err := getBootstrapHubKubeconfigSecret(importSecret)
+	if err != nil {
+		return false, err
+	}
+	return ApplyResources(client, recorder, nil, nil, bootstrapSecret)
+}
+
+// getBootstrapHubKubeconfigSecret returns the bootstrap-hub-kubeconfig secret in the import.yaml of the import
+// secret, an error is returned if it is not found
+func getBootstrapHubKubeconfigSecret(importSecret *corev1.Secret) (*corev1.Secret, error) {
+	importYaml, err := GetImportYaml(importSecret)
+	if err != nil {
+		return nil, err
+	}
 
-	var obj runtime.Object
-	for _, yaml := range SplitYamls(importSecret.Data[constants.ImportSecretImportYamlKey]) {
-		obj = MustCreateObject(yaml)
+	objs := []runtime.Object{}
+	for _, yaml := range SplitYamls(importYaml) {
+		objs = append(objs, MustCreateObject(yaml))
+	}
+
+	for _, obj := range objs {
 		// bootstrap-hub-kubeconfig
-		secret, ok := obj.(*corev1.Secret)
-		if !ok {
-			continue
-		}
-		if secret.Name == "bootstrap-hub-kubeconfig" {
-			break
+		if secret, ok := obj.(*corev1.Secret); ok && secret.Name == "bootstrap-hub-kubeconfig" {
+			return secret, nil
 		}
 	}
-	if obj == nil {
-		return false, fmt.Errorf("failed to find bootstrap-hub-kubeconfig in import secret %s/%s",
-			importSecret.Namespace, importSecret.Name)
-	}
-	return ApplyResources(client, recorder, nil, nil, obj)
+	return nil, fmt.Errorf("failed to find bootstrap-hub-kubeconfig in import secret %s/%s",
+		importSecret.Namespace, importSecret.Name)
 }
 
 // SplitYamls split yamls with sperator `---`
@@ -379,8 +733,8 @@ func ManifestsEqual(newManifests, oldManifests []workv1.Manifest) bool {
 	return true
 }
 
-// ApplyResources apply resources, includes: serviceaccount, secret, deployment, clusterrole, clusterrolebinding,
-// crdv1beta1, crdv1, manifestwork and klusterlet
+// ApplyResources apply resources, includes: serviceaccount, secret, deployment, poddisruptionbudget, clusterrole,
+// clusterrolebinding, crdv1beta1, crdv1, manifestwork and klusterlet
 func ApplyResources(clientHolder *ClientHolder, recorder events.Recorder,
 	scheme *runtime.Scheme, owner metav1.Object, objs ...runtime.Object) (bool, error) {
 	changed := false
@@ -419,6 +773,11 @@ func ApplyResources(clientHolder *ClientHolder, recorder events.Recorder,
 			modified, err := applyDeployment(clientHolder, recorder, required)
 			errs = append(errs, err)
 			changed = changed || modified
+		case *policyv1.PodDisruptionBudget:
+			_, modified, err := resourceapply.ApplyPodDisruptionBudget(context.TODO(),
+				clientHolder.KubeClient.PolicyV1(), recorder, required)
+			errs = append(errs, err)
+			changed = changed || modified
 		case *rbacv1.ClusterRole:
 			_, modified, err := resourceapply.ApplyClusterRole(context.TODO(),
 				clientHolder.KubeClient.RbacV1(), recorder, required)
@@ -518,6 +877,9 @@ func applyManifestWork(workClient workclient.Interface, recorder events.Recorder
 	if errors.IsNotFound(err) {
 		_, err := workClient.WorkV1().ManifestWorks(required.Namespace).Create(
 			context.TODO(), required, metav1.CreateOptions{})
+		if errors.IsAlreadyExists(err) {
+			manifestWorkConflicts.WithLabelValues(required.Namespace).Inc()
+		}
 		if err != nil {
 			return false, err
 		}
@@ -529,24 +891,37 @@ func applyManifestWork(workClient workclient.Interface, recorder events.Recorder
 		return false, err
 	}
 
-	modified := resourcemerge.BoolPtr(false)
-	resourcemerge.EnsureObjectMeta(modified, &existing.ObjectMeta, required.ObjectMeta)
-	if !ManifestsEqual(existing.Spec.Workload.Manifests, required.Spec.Workload.Manifests) {
-		*modified = true
-	}
-
-	if !*modified {
+	if ManifestWorkUpToDate(existing, required) {
 		return false, nil
 	}
 
+	modified := resourcemerge.BoolPtr(false)
+	resourcemerge.EnsureObjectMeta(modified, &existing.ObjectMeta, required.ObjectMeta)
 	existing.Spec = required.Spec
 	if _, err := workClient.WorkV1().ManifestWorks(required.Namespace).Update(context.TODO(), existing, metav1.UpdateOptions{}); err != nil {
+		if errors.IsConflict(err) {
+			manifestWorkConflicts.WithLabelValues(required.Namespace).Inc()
+		}
 		return false, err
 	}
 	reportEvent(recorder, required, "ManifestWork", "updated")
 	return true, nil
 }
 
+// ManifestWorkUpToDate returns true if the existing manifest work has the required metadata and spec, the existing
+// manifest work is not changed
+func ManifestWorkUpToDate(existing, required *workv1.ManifestWork) bool {
+	modified := resourcemerge.BoolPtr(false)
+	resourcemerge.EnsureObjectMeta(modified, existing.ObjectMeta.DeepCopy(), required.ObjectMeta)
+	if *modified {
+		return false
+	}
+
+	return ManifestsEqual(existing.Spec.Workload.Manifests, required.Spec.Workload.Manifests) &&
+		equality.Semantic.DeepEqual(existing.Spec.DeleteOption, required.Spec.DeleteOption) &&
+		equality.Semantic.DeepEqual(existing.Spec.ManifestConfigs, required.Spec.ManifestConfigs)
+}
+
 // MustCreateObject translate object from raw bytes to runtime object
 func MustCreateObject(raw []byte) runtime.Object {
 	obj, _, err := genericCodec.Decode(raw, nil, nil)
@@ -567,6 +942,21 @@ func reportEvent(recorder events.Recorder, metaObj metav1.Object, objKind, actio
 }
 
 func NewEventRecorder(kubeClient kubernetes.Interface, controllerName string) events.Recorder {
+	options := events.RecommendedClusterSingletonCorrelatorOptions()
+
+	if eventsNamespace := EventsNamespace; len(eventsNamespace) > 0 {
+		// the involved object of an event must be in the same namespace with the event, so the events namespace
+		// is used as the involved object
+		controllerRef := &corev1.ObjectReference{
+			Kind:       "Namespace",
+			Namespace:  eventsNamespace,
+			Name:       eventsNamespace,
+			APIVersion: "v1",
+		}
+		return events.NewKubeRecorderWithOptions(
+			kubeClient.CoreV1().Events(eventsNamespace), options, controllerName, controllerRef)
+	}
+
 	namespace, err := GetComponentNamespace()
 	if err != nil {
 		klog.Warningf("unable to identify the current namespace for events: %v", err)
@@ -577,7 +967,6 @@ func NewEventRecorder(kubeClient kubernetes.Interface, controllerName string) ev
 		klog.Warningf("unable to get owner reference (falling back to namespace): %v", err)
 	}
 
-	options := events.RecommendedClusterSingletonCorrelatorOptions()
 	return events.NewKubeRecorderWithOptions(kubeClient.CoreV1().Events(namespace), options, controllerName, controllerRef)
 }
 
@@ -608,6 +997,321 @@ func GetNodeSelectorFromManagedClusterAnnotations(clusterAnnotations map[string]
 	return nodeSelector, nil
 }
 
+// GetPodLabelsFromManagedClusterAnnotations returns the extra klusterlet pod labels from the managed cluster
+// annotations
+func GetPodLabelsFromManagedClusterAnnotations(clusterAnnotations map[string]string) (map[string]string, error) {
+	podLabels := map[string]string{}
+
+	podLabelsString, ok := clusterAnnotations[constants.KlusterletPodLabelsAnnotation]
+	if !ok {
+		return podLabels, nil
+	}
+
+	if err := json.Unmarshal([]byte(podLabelsString), &podLabels); err != nil {
+		return nil, fmt.Errorf("invalid klusterlet pod labels annotation %v", err)
+	}
+
+	return podLabels, nil
+}
+
+// GetKlusterletNamespaceLabelsFromManagedClusterAnnotations returns the extra klusterlet namespace labels from
+// the managed cluster annotations
+func GetKlusterletNamespaceLabelsFromManagedClusterAnnotations(
+	clusterAnnotations map[string]string) (map[string]string, error) {
+	namespaceLabels := map[string]string{}
+
+	namespaceLabelsString, ok := clusterAnnotations[constants.KlusterletNamespaceLabelsAnnotation]
+	if !ok {
+		return namespaceLabels, nil
+	}
+
+	if err := json.Unmarshal([]byte(namespaceLabelsString), &namespaceLabels); err != nil {
+		return nil, fmt.Errorf("invalid klusterlet namespace labels annotation %v", err)
+	}
+
+	return namespaceLabels, nil
+}
+
+// KlusterletVolume is an extra volume of the klusterlet deployment, it is mounted from an existing configmap or
+// secret on the managed cluster
+type KlusterletVolume struct {
+	Name      string `json:"name"`
+	ConfigMap string `json:"configMap,omitempty"`
+	Secret    string `json:"secret,omitempty"`
+	MountPath string `json:"mountPath"`
+}
+
+// GetKlusterletExtraVolumesFromManagedClusterAnnotations returns the extra volumes of the klusterlet deployment
+// from the managed cluster annotations
+func GetKlusterletExtraVolumesFromManagedClusterAnnotations(
+	clusterAnnotations map[string]string) ([]KlusterletVolume, error) {
+	volumes := []KlusterletVolume{}
+
+	volumesString, ok := clusterAnnotations[constants.KlusterletExtraVolumesAnnotation]
+	if !ok {
+		return volumes, nil
+	}
+
+	if err := json.Unmarshal([]byte(volumesString), &volumes); err != nil {
+		return nil, fmt.Errorf("invalid klusterlet extra volumes annotation %v", err)
+	}
+
+	return volumes, nil
+}
+
+// GetClusterClaimsFromManagedClusterAnnotations returns the initial infrastructure claims from the managed
+// cluster annotations
+func GetClusterClaimsFromManagedClusterAnnotations(clusterAnnotations map[string]string) (map[string]string, error) {
+	claims := map[string]string{}
+
+	claimsString, ok := clusterAnnotations[constants.ClusterClaimsAnnotation]
+	if !ok {
+		return claims, nil
+	}
+
+	if err := json.Unmarshal([]byte(claimsString), &claims); err != nil {
+		return nil, fmt.Errorf("invalid cluster claims annotation %v", err)
+	}
+
+	return claims, nil
+}
+
+// GetKlusterletImagesFromManagedClusterAnnotations returns the image overrides of the klusterlet components from
+// the managed cluster annotations
+func GetKlusterletImagesFromManagedClusterAnnotations(clusterAnnotations map[string]string) (map[string]string, error) {
+	images := map[string]string{}
+
+	imagesString, ok := clusterAnnotations[constants.KlusterletImagesAnnotation]
+	if !ok {
+		return images, nil
+	}
+
+	if err := json.Unmarshal([]byte(imagesString), &images); err != nil {
+		return nil, fmt.Errorf("invalid klusterlet images annotation %v", err)
+	}
+
+	return images, nil
+}
+
+// GetKlusterletMetricsPortFromManagedClusterAnnotations returns the metrics port of the klusterlet operator from
+// the managed cluster annotations, the default port 8443 is returned if the annotation is not set
+func GetKlusterletMetricsPortFromManagedClusterAnnotations(clusterAnnotations map[string]string) (int32, error) {
+	metricsPort, ok := clusterAnnotations[constants.KlusterletMetricsPortAnnotation]
+	if !ok {
+		return int32(defaultKlusterletMetricsPort), nil
+	}
+
+	port, err := strconv.ParseInt(metricsPort, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid klusterlet metrics port annotation %v", err)
+	}
+
+	if port < minKlusterletMetricsPort || port > maxKlusterletMetricsPort {
+		return 0, fmt.Errorf("the klusterlet metrics port %d should be between %d and %d",
+			port, minKlusterletMetricsPort, maxKlusterletMetricsPort)
+	}
+
+	return int32(port), nil
+}
+
+// GetKlusterletPDBMinAvailableFromManagedClusterAnnotations returns the minAvailable of the klusterlet agent
+// PodDisruptionBudget from the managed cluster annotations, an empty string is returned if the annotation is not set
+func GetKlusterletPDBMinAvailableFromManagedClusterAnnotations(clusterAnnotations map[string]string) (string, error) {
+	minAvailable, ok := clusterAnnotations[constants.KlusterletPDBMinAvailableAnnotation]
+	if !ok {
+		return "", nil
+	}
+
+	value := intstr.Parse(minAvailable)
+	if value.Type == intstr.Int {
+		if value.IntVal < 1 {
+			return "", fmt.Errorf("the klusterlet pdb minAvailable %d should be a positive integer", value.IntVal)
+		}
+		return value.String(), nil
+	}
+
+	if !strings.HasSuffix(minAvailable, "%") {
+		return "", fmt.Errorf("invalid klusterlet pdb minAvailable %q, it should be an integer or a percentage",
+			minAvailable)
+	}
+	percent, err := strconv.Atoi(strings.TrimSuffix(minAvailable, "%"))
+	if err != nil {
+		return "", fmt.Errorf("invalid klusterlet pdb minAvailable annotation %v", err)
+	}
+	if percent < 1 || percent > 100 {
+		return "", fmt.Errorf("the klusterlet pdb minAvailable %s should be between 1%% and 100%%", minAvailable)
+	}
+
+	return minAvailable, nil
+}
+
+// GetClientCertExpirationFromManagedClusterAnnotations returns the expiration seconds of the registration agent
+// client certificate from the managed cluster annotations, 0 is returned if the annotation is not set
+func GetClientCertExpirationFromManagedClusterAnnotations(clusterAnnotations map[string]string) (int32, error) {
+	expiration, ok := clusterAnnotations[constants.KlusterletClientCertExpirationAnnotation]
+	if !ok {
+		return 0, nil
+	}
+
+	seconds, err := strconv.ParseInt(expiration, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid client cert expiration annotation %v", err)
+	}
+
+	if seconds < minClientCertExpirationSeconds || seconds > maxClientCertExpirationSeconds {
+		return 0, fmt.Errorf("the client cert expiration %d should be between %d and %d seconds",
+			seconds, minClientCertExpirationSeconds, maxClientCertExpirationSeconds)
+	}
+
+	return int32(seconds), nil
+}
+
+// GetCSRApprovalFromManagedClusterAnnotations returns the CSR approval expectation of the registration agent from
+// the managed cluster annotations, an empty string is returned if the annotation is not set
+func GetCSRApprovalFromManagedClusterAnnotations(clusterAnnotations map[string]string) (string, error) {
+	approval, ok := clusterAnnotations[constants.KlusterletCSRApprovalAnnotation]
+	if !ok {
+		return "", nil
+	}
+
+	switch approval {
+	case constants.CSRApprovalAuto, constants.CSRApprovalManual:
+		return approval, nil
+	default:
+		return "", fmt.Errorf("the CSR approval %q should be %s or %s",
+			approval, constants.CSRApprovalAuto, constants.CSRApprovalManual)
+	}
+}
+
+// GetClusterProxyHintFromManagedClusterAnnotations returns the cluster-proxy hint from the managed cluster
+// annotations, an empty string is returned if the annotation is not set
+func GetClusterProxyHintFromManagedClusterAnnotations(clusterAnnotations map[string]string) (string, error) {
+	hint, ok := clusterAnnotations[constants.ClusterProxyHintAnnotation]
+	if !ok {
+		return "", nil
+	}
+
+	switch hint {
+	case constants.ClusterProxyHintTunnel, constants.ClusterProxyHintDirect:
+		return hint, nil
+	default:
+		return "", fmt.Errorf("the cluster-proxy hint %q should be %s or %s",
+			hint, constants.ClusterProxyHintTunnel, constants.ClusterProxyHintDirect)
+	}
+}
+
+// GetKubeDistributionFromManagedClusterAnnotations returns the kubernetes distribution of the managed cluster from
+// the managed cluster annotations, the value is matched case-insensitively and the canonical name of the distribution
+// is returned, an empty string is returned if the annotation is not set
+func GetKubeDistributionFromManagedClusterAnnotations(clusterAnnotations map[string]string) (string, error) {
+	distribution, ok := clusterAnnotations[constants.KubeDistributionAnnotation]
+	if !ok {
+		return "", nil
+	}
+
+	for _, supported := range constants.KubeDistributions {
+		if strings.EqualFold(distribution, supported) {
+			return supported, nil
+		}
+	}
+
+	return "", fmt.Errorf("the kube distribution %q should be one of %s",
+		distribution, strings.Join(constants.KubeDistributions, ", "))
+}
+
+// GetOpenShiftVersionFromManagedClusterAnnotations returns the OpenShift version of the managed cluster from the
+// managed cluster annotations, the version must be a semantic version without the "v" prefix, e.g. 4.14.3
+func GetOpenShiftVersionFromManagedClusterAnnotations(clusterAnnotations map[string]string) (string, error) {
+	openshiftVersion, ok := clusterAnnotations[constants.OpenShiftVersionAnnotation]
+	if !ok {
+		return "", nil
+	}
+
+	if strings.HasPrefix(openshiftVersion, "v") {
+		return "", fmt.Errorf("the openshift version %q should not have the v prefix", openshiftVersion)
+	}
+	if _, err := version.ParseSemantic(openshiftVersion); err != nil {
+		return "", fmt.Errorf("the openshift version %q is not a semantic version: %v", openshiftVersion, err)
+	}
+
+	return openshiftVersion, nil
+}
+
+// GetCapacityClaimsFromManagedClusterAnnotations returns the custom capacities of the managed cluster from the
+// managed cluster annotations
+func GetCapacityClaimsFromManagedClusterAnnotations(
+	clusterAnnotations map[string]string) (map[string]resource.Quantity, error) {
+	capacities := map[string]resource.Quantity{}
+
+	capacitiesString, ok := clusterAnnotations[constants.CapacityClaimsAnnotation]
+	if !ok {
+		return capacities, nil
+	}
+
+	if err := json.Unmarshal([]byte(capacitiesString), &capacities); err != nil {
+		return nil, fmt.Errorf("invalid capacity claims annotation %v", err)
+	}
+
+	return capacities, nil
+}
+
+// GetHostedKonnectivityEndpointFromManagedClusterAnnotations returns the konnectivity proxy endpoint of a Hosted
+// mode managed cluster from the managed cluster annotations, an empty string is returned if the annotation is not set
+func GetHostedKonnectivityEndpointFromManagedClusterAnnotations(clusterAnnotations map[string]string) (string, error) {
+	endpoint, ok := clusterAnnotations[constants.HostedKonnectivityEndpointAnnotation]
+	if !ok {
+		return "", nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid konnectivity endpoint annotation %v", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return "", fmt.Errorf("the scheme of the konnectivity endpoint %q should be http, https or socks5", endpoint)
+	}
+	if len(u.Host) == 0 {
+		return "", fmt.Errorf("the konnectivity endpoint %q has no host", endpoint)
+	}
+
+	return endpoint, nil
+}
+
+// GetClusterRoleAggregationLabelFromManagedClusterAnnotations returns the aggregation label of the klusterlet
+// clusterroles from the managed cluster annotations
+func GetClusterRoleAggregationLabelFromManagedClusterAnnotations(clusterAnnotations map[string]string) (
+	map[string]string, error) {
+	aggregationLabel, ok := clusterAnnotations[constants.KlusterletClusterRoleAggregationLabelAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	key, value, found := strings.Cut(aggregationLabel, "=")
+	if !found {
+		return nil, fmt.Errorf("the aggregation label %q should be in the format <key>=<value>", aggregationLabel)
+	}
+
+	errs := []error{}
+	if errMsgs := validation.IsQualifiedName(key); len(errMsgs) != 0 {
+		errs = append(errs, fmt.Errorf(strings.Join(errMsgs, ";")))
+	}
+	// the clusterrole would be aggregated into the built-in clusterroles (e.g. admin or edit) with the kubernetes
+	// rbac labels, this is not allowed
+	if strings.HasPrefix(key, rbacv1.GroupName+"/") {
+		errs = append(errs, fmt.Errorf("the aggregation label key %q cannot have the %s/ prefix", key, rbacv1.GroupName))
+	}
+	if errMsgs := validation.IsValidLabelValue(value); len(errMsgs) != 0 {
+		errs = append(errs, fmt.Errorf(strings.Join(errMsgs, ";")))
+	}
+	if len(errs) != 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+
+	return map[string]string{key: value}, nil
+}
+
 func GetTolerationsFromManagedClusterAnnotations(clusterAnnotations map[string]string) ([]corev1.Toleration, error) {
 	tolerations := []corev1.Toleration{}
 
@@ -630,10 +1334,31 @@ func GetTolerationsFromManagedClusterAnnotations(clusterAnnotations map[string]s
 	return tolerations, nil
 }
 
+// GetKlusterletNodePlacementFromManagedClusterAnnotations returns the node placement of the hosted mode klusterlet
+// from the managed cluster annotations, nil is returned if the annotation is not set
+func GetKlusterletNodePlacementFromManagedClusterAnnotations(
+	clusterAnnotations map[string]string) (*operatorv1.NodePlacement, error) {
+	nodePlacementString, ok := clusterAnnotations[constants.KlusterletNodePlacementAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	nodePlacement := &operatorv1.NodePlacement{}
+	if err := json.Unmarshal([]byte(nodePlacementString), nodePlacement); err != nil {
+		return nil, fmt.Errorf("invalid klusterlet node placement annotation %v", err)
+	}
+
+	return nodePlacement, nil
+}
+
+// InstallModeUnknown is the klusterlet mode of a managed cluster whose klusterlet deploy mode annotation is
+// not one of Default, Singleton or Hosted
+const InstallModeUnknown operatorv1.InstallMode = "Unknown"
+
 // DetermineKlusterletMode gets the klusterlet deploy mode for the managed cluster.
 func DetermineKlusterletMode(cluster *clusterv1.ManagedCluster) operatorv1.InstallMode {
-	mode, ok := cluster.Annotations[constants.KlusterletDeployModeAnnotation]
-	if !ok {
+	mode := cluster.Annotations[constants.KlusterletDeployModeAnnotation]
+	if len(mode) == 0 {
 		return operatorv1.InstallModeSingleton
 	}
 
@@ -649,7 +1374,7 @@ func DetermineKlusterletMode(cluster *clusterv1.ManagedCluster) operatorv1.Insta
 		return operatorv1.InstallModeHosted
 	}
 
-	return "Unknown"
+	return InstallModeUnknown
 }
 
 func ValidateKlusterletMode(mode operatorv1.InstallMode) error {
@@ -755,6 +1480,159 @@ func ValidateNodeSelector(nodeSelector map[string]string) error {
 	return utilerrors.NewAggregate(errs)
 }
 
+// ValidatePodLabels validates the extra klusterlet pod labels, the label "app" is reserved for the
+// deployment selector and cannot be overridden
+func ValidatePodLabels(podLabels map[string]string) error {
+	errs := []error{}
+	for key, val := range podLabels {
+		if key == "app" {
+			errs = append(errs, fmt.Errorf("the label %q is reserved", key))
+			continue
+		}
+		if errMsgs := validation.IsQualifiedName(key); len(errMsgs) != 0 {
+			errs = append(errs, fmt.Errorf(strings.Join(errMsgs, ";")))
+		}
+		if errMsgs := validation.IsValidLabelValue(val); len(errMsgs) != 0 {
+			errs = append(errs, fmt.Errorf(strings.Join(errMsgs, ";")))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// ValidateNamespaceLabels validates the extra klusterlet namespace labels, the label
+// "kubernetes.io/metadata.name" is set by the kube apiserver and cannot be overridden
+func ValidateNamespaceLabels(namespaceLabels map[string]string) error {
+	errs := []error{}
+	for key, val := range namespaceLabels {
+		if key == corev1.LabelMetadataName {
+			errs = append(errs, fmt.Errorf("the label %q is reserved", key))
+			continue
+		}
+		if errMsgs := validation.IsQualifiedName(key); len(errMsgs) != 0 {
+			errs = append(errs, fmt.Errorf(strings.Join(errMsgs, ";")))
+		}
+		if errMsgs := validation.IsValidLabelValue(val); len(errMsgs) != 0 {
+			errs = append(errs, fmt.Errorf(strings.Join(errMsgs, ";")))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// ValidateKlusterletVolumes validates the extra volumes of the klusterlet deployment, the volume name must be
+// unique and cannot be the reserved tmpdir, each volume must reference either a configmap or a secret with a
+// valid name, and the mount path must be an absolute path other than /tmp
+func ValidateKlusterletVolumes(volumes []KlusterletVolume) error {
+	errs := []error{}
+	names := sets.New[string](reservedKlusterletVolumeName)
+	mountPaths := sets.New[string](reservedKlusterletVolumeMountPath)
+	for _, volume := range volumes {
+		if errMsgs := validation.IsDNS1123Label(volume.Name); len(errMsgs) != 0 {
+			errs = append(errs, fmt.Errorf("invalid volume name %q: %s", volume.Name, strings.Join(errMsgs, ";")))
+			continue
+		}
+		if names.Has(volume.Name) {
+			errs = append(errs, fmt.Errorf("the volume name %q is reserved or duplicated", volume.Name))
+			continue
+		}
+		names.Insert(volume.Name)
+
+		switch {
+		case len(volume.ConfigMap) != 0 && len(volume.Secret) != 0:
+			errs = append(errs, fmt.Errorf("the volume %q references both a configmap and a secret", volume.Name))
+		case len(volume.ConfigMap) != 0:
+			if errMsgs := validation.IsDNS1123Subdomain(volume.ConfigMap); len(errMsgs) != 0 {
+				errs = append(errs, fmt.Errorf("invalid configmap %q of the volume %q: %s",
+					volume.ConfigMap, volume.Name, strings.Join(errMsgs, ";")))
+			}
+		case len(volume.Secret) != 0:
+			if errMsgs := validation.IsDNS1123Subdomain(volume.Secret); len(errMsgs) != 0 {
+				errs = append(errs, fmt.Errorf("invalid secret %q of the volume %q: %s",
+					volume.Secret, volume.Name, strings.Join(errMsgs, ";")))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("the volume %q does not reference a configmap or a secret", volume.Name))
+		}
+
+		if !path.IsAbs(volume.MountPath) || strings.ContainsAny(volume.MountPath, "\"\\:") {
+			errs = append(errs, fmt.Errorf("invalid mount path %q of the volume %q", volume.MountPath, volume.Name))
+			continue
+		}
+		if mountPaths.Has(path.Clean(volume.MountPath)) {
+			errs = append(errs, fmt.Errorf("the mount path %q of the volume %q is reserved or duplicated",
+				volume.MountPath, volume.Name))
+		}
+		mountPaths.Insert(path.Clean(volume.MountPath))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// ValidateClusterClaims validates the initial infrastructure claims, the claim name must be a valid cluster
+// claim name and a valid annotation key with the claim prefix, the claim value cannot be empty
+func ValidateClusterClaims(claims map[string]string) error {
+	errs := []error{}
+	for name, value := range claims {
+		if errMsgs := validation.IsDNS1123Subdomain(name); len(errMsgs) != 0 {
+			errs = append(errs, fmt.Errorf("invalid claim name %q: %s", name, strings.Join(errMsgs, ";")))
+			continue
+		}
+		if errMsgs := validation.IsQualifiedName(constants.ClusterClaimAnnotationPrefix + name); len(errMsgs) != 0 {
+			errs = append(errs, fmt.Errorf("invalid claim name %q: %s", name, strings.Join(errMsgs, ";")))
+		}
+		if len(value) == 0 {
+			errs = append(errs, fmt.Errorf("the value of claim %q is empty", name))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// ValidateCapacityClaims validates the custom capacities, the capacity name must be a valid dns label and its
+// cluster claim must be a valid cluster claim, the capacity quantity cannot be negative
+func ValidateCapacityClaims(capacities map[string]resource.Quantity) error {
+	errs := []error{}
+	for name, quantity := range capacities {
+		if errMsgs := validation.IsDNS1123Label(name); len(errMsgs) != 0 {
+			errs = append(errs, fmt.Errorf("invalid capacity name %q: %s", name, strings.Join(errMsgs, ";")))
+			continue
+		}
+		if err := ValidateClusterClaims(map[string]string{
+			name + constants.CapacityClusterClaimSuffix: quantity.String(),
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("invalid capacity name %q: %v", name, err))
+		}
+		if quantity.Sign() < 0 {
+			errs = append(errs, fmt.Errorf("the quantity %s of capacity %q is negative", quantity.String(), name))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// imageReferenceRegexp matches an image reference in the form of [registry[:port]/]repository[:tag][@digest],
+// refer to https://github.com/distribution/reference/blob/main/regexp.go
+var imageReferenceRegexp = regexp.MustCompile(`^` +
+	`(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*(?::[0-9]+)?/)?` +
+	`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+	`(?::[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127})?` +
+	`(?:@[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-fA-F0-9]{32,})?$`)
+
+// ValidateKlusterletImages validates the image overrides of the klusterlet components, the component must be one
+// of the supported klusterlet components and the image must be a valid image reference
+func ValidateKlusterletImages(images map[string]string) error {
+	errs := []error{}
+	supported := sets.New[string](constants.KlusterletImageComponents...)
+	for component, image := range images {
+		if !supported.Has(component) {
+			errs = append(errs, fmt.Errorf("the klusterlet component %q should be one of %s",
+				component, strings.Join(constants.KlusterletImageComponents, ", ")))
+			continue
+		}
+		if !imageReferenceRegexp.MatchString(image) {
+			errs = append(errs, fmt.Errorf("the image %q of klusterlet component %q is not a valid image reference",
+				image, component))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
 // refer to https://github.com/kubernetes/kubernetes/blob/master/pkg/apis/core/validation/validation.go#L3330
 func ValidateTolerations(tolerations []corev1.Toleration) error {
 	errs := []error{}
@@ -5,11 +5,17 @@ package helpers
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	hivev1 "github.com/openshift/hive/apis/hive/v1"
 	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
 	"github.com/openshift/library-go/pkg/operator/resource/resourceapply"
 	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
@@ -25,10 +31,12 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/restmapper"
+	clienttesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/utils/diff"
@@ -39,6 +47,7 @@ import (
 	operatorv1 "open-cluster-management.io/api/operator/v1"
 	workv1 "open-cluster-management.io/api/work/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -50,6 +59,7 @@ var testscheme = scheme.Scheme
 
 func init() {
 	testscheme.AddKnownTypes(clusterv1.GroupVersion, &clusterv1.ManagedCluster{})
+	testscheme.AddKnownTypes(hivev1.SchemeGroupVersion, &hivev1.ClusterDeployment{})
 	testscheme.AddKnownTypes(operatorv1.GroupVersion, &operatorv1.Klusterlet{})
 	testscheme.AddKnownTypes(addonv1alpha1.GroupVersion, &addonv1alpha1.ManagedClusterAddOn{})
 	testscheme.AddKnownTypes(addonv1alpha1.GroupVersion, &addonv1alpha1.ManagedClusterAddOnList{})
@@ -57,6 +67,17 @@ func init() {
 	testscheme.AddKnownTypes(crdv1.SchemeGroupVersion, &crdv1.CustomResourceDefinition{})
 }
 
+func TestGetImportSecretName(t *testing.T) {
+	if name := GetImportSecretName("cluster1"); name != "cluster1-import" {
+		t.Errorf("expected the default import secret name cluster1-import, but got %s", name)
+	}
+
+	t.Setenv(constants.ImportSecretNameSuffixEnvVarName, "tenant1-import")
+	if name := GetImportSecretName("cluster1"); name != "cluster1-tenant1-import" {
+		t.Errorf("expected the import secret name cluster1-tenant1-import, but got %s", name)
+	}
+}
+
 func TestGetMaxConcurrentReconciles(t *testing.T) {
 	os.Setenv(maxConcurrentReconcilesEnvVarName, "invalid")
 	defer os.Unsetenv(maxConcurrentReconcilesEnvVarName)
@@ -67,6 +88,83 @@ func TestGetMaxConcurrentReconciles(t *testing.T) {
 	}
 }
 
+func TestGetControllerMaxConcurrentReconciles(t *testing.T) {
+	cases := []struct {
+		name                 string
+		globalReconciles     string
+		controllerReconciles string
+		expected             int
+	}{
+		{
+			name:     "no envs",
+			expected: 1,
+		},
+		{
+			name:             "global env",
+			globalReconciles: "10",
+			expected:         10,
+		},
+		{
+			name:                 "controller env overrides global env",
+			globalReconciles:     "10",
+			controllerReconciles: "3",
+			expected:             3,
+		},
+		{
+			name:                 "zero controller env falls back to global env",
+			globalReconciles:     "10",
+			controllerReconciles: "0",
+			expected:             10,
+		},
+		{
+			name:                 "invalid envs fall back to default",
+			globalReconciles:     "-1",
+			controllerReconciles: "invalid",
+			expected:             1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv(maxConcurrentReconcilesEnvVarName, c.globalReconciles)
+			t.Setenv("TEST_MAX_CONCURRENT_RECONCILES", c.controllerReconciles)
+
+			reconciles := GetControllerMaxConcurrentReconciles("TEST_MAX_CONCURRENT_RECONCILES")
+			if reconciles != c.expected {
+				t.Errorf("expected %d, but got %d", c.expected, reconciles)
+			}
+		})
+	}
+}
+
+func TestNewEventRecorderWithEventsNamespace(t *testing.T) {
+	EventsNamespace = "events"
+	defer func() {
+		EventsNamespace = ""
+	}()
+
+	kubeClient := kubefake.NewSimpleClientset()
+	recorder := NewEventRecorder(kubeClient, "test-controller")
+	recorder.Eventf("TestReason", "test message")
+
+	// the events are recorded asynchronously
+	err := wait.PollImmediate(100*time.Millisecond, 5*time.Second, func() (bool, error) {
+		events, err := kubeClient.CoreV1().Events("events").List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, event := range events.Items {
+			if event.Reason == "TestReason" && event.InvolvedObject.Namespace == "events" {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		t.Errorf("the event is not created in the events namespace: %v", err)
+	}
+}
+
 func TestGenerateClientFromSecret(t *testing.T) {
 
 	// if err := os.Setenv("KUBEBUILDER_ASSETS", "./../../_output/kubebuilder/bin"); err != nil { // uncomment these lines to run the test locally
@@ -128,6 +226,62 @@ func TestGenerateClientFromSecret(t *testing.T) {
 			},
 			expectedErr: "unknown",
 		},
+		{
+			name: "using token with ca cert",
+			generateSecret: func(server string, config *rest.Config) *corev1.Secret {
+				return &corev1.Secret{
+					Data: map[string][]byte{
+						"token":  []byte(config.BearerToken),
+						"server": []byte(server),
+						"caCert": config.CAData,
+					},
+				}
+			},
+			expectedErr: "unknown",
+		},
+		{
+			name: "using token without ca cert and tls verify",
+			generateSecret: func(server string, config *rest.Config) *corev1.Secret {
+				return &corev1.Secret{
+					Data: map[string][]byte{
+						"token":                 []byte(config.BearerToken),
+						"server":                []byte(server),
+						"insecureSkipTLSVerify": []byte("false"),
+					},
+				}
+			},
+			expectedErr: "the caCert is required if the insecureSkipTLSVerify is false",
+		},
+		{
+			name: "using token with invalid insecureSkipTLSVerify",
+			generateSecret: func(server string, config *rest.Config) *corev1.Secret {
+				return &corev1.Secret{
+					Data: map[string][]byte{
+						"token":                 []byte(config.BearerToken),
+						"server":                []byte(server),
+						"insecureSkipTLSVerify": []byte("maybe"),
+					},
+				}
+			},
+			expectedErr: "invalid insecureSkipTLSVerify",
+		},
+		{
+			name: "ambiguous kubeconfig and token",
+			generateSecret: func(server string, config *rest.Config) *corev1.Secret {
+				apiConfig := createBasic(server, "test", config.CAData, config.KeyData, config.CertData)
+				bconfig, err := clientcmd.Write(*apiConfig)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return &corev1.Secret{
+					Data: map[string][]byte{
+						"kubeconfig": bconfig,
+						"token":      []byte(config.BearerToken),
+					},
+				}
+			},
+			expectedErr: "ambiguous client config",
+		},
 	}
 
 	for _, c := range cases {
@@ -144,6 +298,202 @@ func TestGenerateClientFromSecret(t *testing.T) {
 	}
 }
 
+func TestGenerateClientFromSecretTLSPolicy(t *testing.T) {
+	cases := []struct {
+		name             string
+		minVersion       string
+		serverMaxVersion uint16
+		expectedErr      bool
+	}{
+		{
+			name:             "reject the handshake lower than the default minimum version",
+			serverMaxVersion: tls.VersionTLS11,
+			expectedErr:      true,
+		},
+		{
+			name:             "reject the handshake lower than the customized minimum version",
+			minVersion:       "VersionTLS13",
+			serverMaxVersion: tls.VersionTLS12,
+			expectedErr:      true,
+		},
+		{
+			name:             "accept the handshake with the minimum version",
+			minVersion:       "VersionTLS12",
+			serverMaxVersion: tls.VersionTLS12,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv(spokeTLSMinVersionEnvVarName, c.minVersion)
+
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"major":"1","minor":"27","gitVersion":"v1.27.3"}`))
+			}))
+			server.TLS = &tls.Config{MaxVersion: c.serverMaxVersion}
+			server.StartTLS()
+			defer server.Close()
+
+			clientHolder, _, err := GenerateClientFromSecret(&corev1.Secret{
+				Data: map[string][]byte{
+					"token":  []byte("test"),
+					"server": []byte(server.URL),
+				},
+			})
+			if err == nil {
+				_, err = clientHolder.KubeClient.Discovery().ServerVersion()
+			}
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerateClientFromSecretProxy(t *testing.T) {
+	cases := []struct {
+		name            string
+		data            map[string][]byte
+		expectedErr     string
+		expectedProxied bool
+	}{
+		{
+			name: "invalid proxy",
+			data: map[string][]byte{
+				"httpProxy": []byte("proxy.test:8080"),
+			},
+			expectedErr: "invalid httpProxy \"proxy.test:8080\": the proxy must be an http or https URL",
+		},
+		{
+			name: "requests are sent through the proxy",
+			data: map[string][]byte{
+				"httpProxy": []byte("PROXY"),
+			},
+			expectedProxied: true,
+		},
+		{
+			name: "the managed cluster is in the noProxy",
+			data: map[string][]byte{
+				"httpProxy": []byte("PROXY"),
+				"noProxy":   []byte("example.com,.cluster.test"),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			proxied := false
+			proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				proxied = true
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"major":"1","minor":"27","gitVersion":"v1.27.3"}`))
+			}))
+			defer proxy.Close()
+
+			data := map[string][]byte{
+				"token":  []byte("test"),
+				"server": []byte("http://managed.cluster.test:6443"),
+			}
+			for key, value := range c.data {
+				data[key] = []byte(strings.ReplaceAll(string(value), "PROXY", proxy.URL))
+			}
+
+			clientHolder, _, err := GenerateClientFromSecret(&corev1.Secret{Data: data})
+			if len(c.expectedErr) != 0 {
+				if err == nil || err.Error() != c.expectedErr {
+					t.Errorf("expected error %q, but got %v", c.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, err = clientHolder.KubeClient.Discovery().ServerVersion()
+			if c.expectedProxied && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if proxied != c.expectedProxied {
+				t.Errorf("expected the request is proxied %v, but got %v", c.expectedProxied, proxied)
+			}
+		})
+	}
+}
+
+func TestSetSpokeProxyCABundle(t *testing.T) {
+	secret := &corev1.Secret{
+		Data: map[string][]byte{
+			"httpsProxy":    []byte("https://proxy.test:8443"),
+			"proxyCABundle": []byte("proxy-ca"),
+		},
+	}
+
+	clientConfig := &rest.Config{}
+	if err := setSpokeProxy(clientConfig, secret); err == nil {
+		t.Errorf("expected error, but failed")
+	}
+
+	clientConfig = &rest.Config{TLSClientConfig: rest.TLSClientConfig{CAData: []byte("cluster-ca")}}
+	if err := setSpokeProxy(clientConfig, secret); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(clientConfig.CAData) != "cluster-ca\nproxy-ca" {
+		t.Errorf("unexpected CA data: %s", string(clientConfig.CAData))
+	}
+	if clientConfig.Proxy == nil {
+		t.Errorf("expected the proxy is set")
+	}
+}
+
+func TestSetSpokeTLSPolicyWithExecPlugin(t *testing.T) {
+	t.Setenv(spokeTLSMinVersionEnvVarName, "VersionTLS13")
+
+	clientConfig := &rest.Config{
+		Host: "https://127.0.0.1:6443",
+		TLSClientConfig: rest.TLSClientConfig{
+			ServerName: "test-server",
+		},
+		ExecProvider: &clientcmdapi.ExecConfig{
+			APIVersion:      "client.authentication.k8s.io/v1",
+			Command:         "test-credential-plugin",
+			InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+		},
+	}
+
+	if err := setSpokeTLSPolicy(clientConfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if clientConfig.Transport != nil {
+		t.Errorf("expected the transport is not replaced, but got %T", clientConfig.Transport)
+	}
+	if clientConfig.TLSClientConfig.ServerName != "test-server" {
+		t.Errorf("expected the TLS client config is kept, but got %v", clientConfig.TLSClientConfig)
+	}
+	if _, err := rest.HTTPClientFor(clientConfig); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	base := &http.Transport{TLSClientConfig: &tls.Config{ServerName: "test"}}
+	transport, ok := clientConfig.WrapTransport(base).(*http.Transport)
+	if !ok {
+		t.Fatalf("expected the wrapped transport is a http transport")
+	}
+	if transport.TLSClientConfig.MinVersion != tls.VersionTLS13 {
+		t.Errorf("expected the min version %d, but got %d", tls.VersionTLS13, transport.TLSClientConfig.MinVersion)
+	}
+	if transport.TLSClientConfig.ServerName != "test" {
+		t.Errorf("expected the server name is kept, but got %s", transport.TLSClientConfig.ServerName)
+	}
+	if base.TLSClientConfig.MinVersion != 0 {
+		t.Errorf("expected the base transport is not changed, but got min version %d", base.TLSClientConfig.MinVersion)
+	}
+}
+
 func TestUpdateManagedClusterStatus(t *testing.T) {
 	cases := []struct {
 		name           string
@@ -260,6 +610,20 @@ func TestDetermineKlusterletMode(t *testing.T) {
 			},
 			expectedMode: operatorv1.InstallModeHosted,
 		},
+		{
+			name: "empty",
+			annotations: map[string]string{
+				constants.KlusterletDeployModeAnnotation: "",
+			},
+			expectedMode: operatorv1.InstallModeSingleton,
+		},
+		{
+			name: "unknown",
+			annotations: map[string]string{
+				constants.KlusterletDeployModeAnnotation: "hosted-mode",
+			},
+			expectedMode: InstallModeUnknown,
+		},
 	}
 
 	for _, c := range cases {
@@ -380,6 +744,70 @@ func TestRemoveManagedClusterFinalizer(t *testing.T) {
 	}
 }
 
+func TestRemoveLegacyImportFinalizers(t *testing.T) {
+	cases := []struct {
+		name               string
+		obj                client.Object
+		expectedFinalizers []string
+	}{
+		{
+			name: "managed cluster has legacy finalizers",
+			obj: &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+					Finalizers: []string{
+						"rcm-api.cluster",
+						constants.ImportFinalizer,
+						"managedcluster-import-controller/cleanup",
+					},
+				},
+			},
+			expectedFinalizers: []string{constants.ImportFinalizer},
+		},
+		{
+			name: "managed cluster does not have legacy finalizers",
+			obj: &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Finalizers: []string{constants.ImportFinalizer},
+				},
+			},
+			expectedFinalizers: []string{constants.ImportFinalizer},
+		},
+		{
+			name: "clusterdeployment has legacy finalizers",
+			obj: &hivev1.ClusterDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Namespace:  "test",
+					Finalizers: []string{"hive.openshift.io/deprovision", "rcm-api.cluster"},
+				},
+			},
+			expectedFinalizers: []string{"hive.openshift.io/deprovision"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fakeClient := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(c.obj).Build()
+
+			err := RemoveLegacyImportFinalizers(context.TODO(), fakeClient, eventstesting.NewTestingEventRecorder(t), c.obj)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			updated := c.obj.DeepCopyObject().(client.Object)
+			if err := fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(c.obj), updated); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(updated.GetFinalizers(), c.expectedFinalizers) {
+				t.Errorf("expected finalizers %v, but got %v", c.expectedFinalizers, updated.GetFinalizers())
+			}
+		})
+	}
+}
+
 func TestApplyResources(t *testing.T) {
 	var replicas int32 = 2
 
@@ -882,6 +1310,7 @@ func TestImportManagedClusterFromSecret(t *testing.T) {
 	cases := []struct {
 		name              string
 		apiGroupResources []*restmapper.APIGroupResources
+		expectedErr       bool
 	}{
 		{
 			name: "only have crdv1beta1",
@@ -900,6 +1329,20 @@ func TestImportManagedClusterFromSecret(t *testing.T) {
 						},
 					},
 				},
+				{
+					Group: metav1.APIGroup{
+						Name: "operator.open-cluster-management.io",
+						Versions: []metav1.GroupVersionForDiscovery{
+							{Version: "v1"},
+						},
+						PreferredVersion: metav1.GroupVersionForDiscovery{Version: "v1"},
+					},
+					VersionedResources: map[string][]metav1.APIResource{
+						"v1": {
+							{Name: "klusterlets", Namespaced: false, Kind: "Klusterlet"},
+						},
+					},
+				},
 			},
 		},
 		{
@@ -933,9 +1376,46 @@ func TestImportManagedClusterFromSecret(t *testing.T) {
 						},
 					},
 				},
+				{
+					Group: metav1.APIGroup{
+						Name: "operator.open-cluster-management.io",
+						Versions: []metav1.GroupVersionForDiscovery{
+							{Version: "v1"},
+						},
+						PreferredVersion: metav1.GroupVersionForDiscovery{Version: "v1"},
+					},
+					VersionedResources: map[string][]metav1.APIResource{
+						"v1": {
+							{Name: "klusterlets", Namespaced: false, Kind: "Klusterlet"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "the managed cluster lacks the klusterlet crds",
+			apiGroupResources: []*restmapper.APIGroupResources{
+				{
+					Group: metav1.APIGroup{
+						Name: "apiextensions.k8s.io",
+						Versions: []metav1.GroupVersionForDiscovery{
+							{Version: "v1"},
+						},
+						PreferredVersion: metav1.GroupVersionForDiscovery{Version: "v1"},
+					},
+					VersionedResources: map[string][]metav1.APIResource{
+						"v1": {
+							{Name: "customresourcedefinitions", Namespaced: false, Kind: "CustomResourceDefinition"},
+						},
+					},
+				},
 			},
+			expectedErr: true,
 		},
 	}
+	klusterletCRDsPollInterval = 10 * time.Millisecond
+	klusterletCRDsPollTimeout = 50 * time.Millisecond
+
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			mapper := restmapper.NewDiscoveryRESTMapper(c.apiGroupResources)
@@ -948,13 +1428,88 @@ func TestImportManagedClusterFromSecret(t *testing.T) {
 				RuntimeClient:       fake.NewClientBuilder().WithScheme(testscheme).Build(),
 			}
 			_, err := ImportManagedClusterFromSecret(clientHolder, mapper, fakeRecorder, importSecret)
-			if err != nil {
+			if c.expectedErr {
+				if !IsSpokeMissingKlusterletCRDs(err) {
+					t.Errorf("expected the klusterlet crds missing error, but got %v", err)
+				}
+				klusterlets, err := clientHolder.OperatorClient.OperatorV1().Klusterlets().List(
+					context.TODO(), metav1.ListOptions{})
+				if err != nil {
+					t.Errorf("unexpect err %v", err)
+				}
+				if len(klusterlets.Items) != 0 {
+					t.Errorf("expected the klusterlet is not applied, but got %d", len(klusterlets.Items))
+				}
+				return
+			}
+			if err != nil {
 				t.Errorf("unexpect err %v", err)
 			}
 		})
 	}
 }
 
+func TestImportManagedClusterFromSecretForbidden(t *testing.T) {
+	mapper := restmapper.NewDiscoveryRESTMapper([]*restmapper.APIGroupResources{
+		{
+			Group: metav1.APIGroup{
+				Name: "apiextensions.k8s.io",
+				Versions: []metav1.GroupVersionForDiscovery{
+					{Version: "v1"},
+				},
+				PreferredVersion: metav1.GroupVersionForDiscovery{Version: "v1"},
+			},
+			VersionedResources: map[string][]metav1.APIResource{
+				"v1": {
+					{Name: "customresourcedefinitions", Namespaced: false, Kind: "CustomResourceDefinition"},
+				},
+			},
+		},
+		{
+			Group: metav1.APIGroup{
+				Name: "operator.open-cluster-management.io",
+				Versions: []metav1.GroupVersionForDiscovery{
+					{Version: "v1"},
+				},
+				PreferredVersion: metav1.GroupVersionForDiscovery{Version: "v1"},
+			},
+			VersionedResources: map[string][]metav1.APIResource{
+				"v1": {
+					{Name: "klusterlets", Namespaced: false, Kind: "Klusterlet"},
+				},
+			},
+		},
+	})
+
+	kubeClient := kubefake.NewSimpleClientset()
+	kubeClient.PrependReactor(
+		"create",
+		"serviceaccounts",
+		func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+			return true, nil, errors.NewForbidden(
+				corev1.Resource("serviceaccounts"), "klusterlet", fmt.Errorf("no permission"))
+		},
+	)
+	clientHolder := &ClientHolder{
+		KubeClient:          kubeClient,
+		APIExtensionsClient: apiextensionsfake.NewSimpleClientset(),
+		OperatorClient:      operatorfake.NewSimpleClientset(),
+		RuntimeClient:       fake.NewClientBuilder().WithScheme(testscheme).Build(),
+	}
+
+	_, err := ImportManagedClusterFromSecret(clientHolder, mapper, eventstesting.NewTestingEventRecorder(t),
+		testinghelpers.GetImportSecret("test_cluster"))
+	if err == nil {
+		t.Fatalf("expected error, but failed")
+	}
+	if !ContainAuthError(err) {
+		t.Errorf("expected the forbidden error can be found, but got %v", err)
+	}
+	if !strings.Contains(err.Error(), "ServiceAccount") {
+		t.Errorf("expected the error is wrapped with the failed object, but got %v", err)
+	}
+}
+
 var badImportYaml = `
 apiVersion: v1
 kind: ServiceAccount
@@ -988,6 +1543,28 @@ func TestUpdateManagedClusterBootstrapSecret(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "no bootstrap secret in the import secret",
+			importSecret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-import",
+					Namespace: "test",
+				},
+				Data: map[string][]byte{
+					"import.yaml": []byte(constants.YamlSperator + "apiVersion: v1\nkind: Namespace\n" +
+						"metadata:\n  name: open-cluster-management-agent\n"),
+				},
+			},
+			expectedErr: true,
+			verifyFunc: func(t *testing.T, clientHolder *ClientHolder) {
+				// the other objects of the import secret are not applied instead of the bootstrap secret
+				_, err := clientHolder.KubeClient.CoreV1().Namespaces().Get(
+					context.TODO(), "open-cluster-management-agent", metav1.GetOptions{})
+				if !errors.IsNotFound(err) {
+					t.Errorf("unexpect err %v", err)
+				}
+			},
+		},
 		{
 			name:         "update import secret",
 			importSecret: testinghelpers.GetImportSecret("test"),
@@ -1011,6 +1588,9 @@ func TestUpdateManagedClusterBootstrapSecret(t *testing.T) {
 			if !c.expectedErr && err != nil {
 				t.Errorf("unexpect err %v", err)
 			}
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
 
 			c.verifyFunc(t, clientHolder)
 		})
@@ -1118,6 +1698,790 @@ func TestGetNodeSelectorAndValidate(t *testing.T) {
 	}
 }
 
+func TestGetKlusterletMetricsPortFromManagedClusterAnnotations(t *testing.T) {
+	cases := []struct {
+		name         string
+		annotations  map[string]string
+		expectedPort int32
+		expectedErr  bool
+	}{
+		{
+			name:         "default port",
+			annotations:  map[string]string{},
+			expectedPort: 8443,
+		},
+		{
+			name: "customized port",
+			annotations: map[string]string{
+				constants.KlusterletMetricsPortAnnotation: "9443",
+			},
+			expectedPort: 9443,
+		},
+		{
+			name: "invalid port",
+			annotations: map[string]string{
+				constants.KlusterletMetricsPortAnnotation: "https",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "privileged port",
+			annotations: map[string]string{
+				constants.KlusterletMetricsPortAnnotation: "443",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "port out of range",
+			annotations: map[string]string{
+				constants.KlusterletMetricsPortAnnotation: "65536",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			port, err := GetKlusterletMetricsPortFromManagedClusterAnnotations(c.annotations)
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if port != c.expectedPort {
+				t.Errorf("expected port %d, but got %d", c.expectedPort, port)
+			}
+		})
+	}
+}
+
+func TestGetKlusterletPDBMinAvailableFromManagedClusterAnnotations(t *testing.T) {
+	cases := []struct {
+		name                 string
+		annotations          map[string]string
+		expectedMinAvailable string
+		expectedErr          bool
+	}{
+		{
+			name:        "no annotation",
+			annotations: map[string]string{},
+		},
+		{
+			name: "integer",
+			annotations: map[string]string{
+				constants.KlusterletPDBMinAvailableAnnotation: "2",
+			},
+			expectedMinAvailable: "2",
+		},
+		{
+			name: "percentage",
+			annotations: map[string]string{
+				constants.KlusterletPDBMinAvailableAnnotation: "50%",
+			},
+			expectedMinAvailable: "50%",
+		},
+		{
+			name: "zero",
+			annotations: map[string]string{
+				constants.KlusterletPDBMinAvailableAnnotation: "0",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "percentage out of range",
+			annotations: map[string]string{
+				constants.KlusterletPDBMinAvailableAnnotation: "120%",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid value",
+			annotations: map[string]string{
+				constants.KlusterletPDBMinAvailableAnnotation: "all",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			minAvailable, err := GetKlusterletPDBMinAvailableFromManagedClusterAnnotations(c.annotations)
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if minAvailable != c.expectedMinAvailable {
+				t.Errorf("expected minAvailable %q, but got %q", c.expectedMinAvailable, minAvailable)
+			}
+		})
+	}
+}
+
+func TestGetClientCertExpirationFromManagedClusterAnnotations(t *testing.T) {
+	cases := []struct {
+		name               string
+		annotations        map[string]string
+		expectedExpiration int32
+		expectedErr        bool
+	}{
+		{
+			name:        "no annotation",
+			annotations: map[string]string{},
+		},
+		{
+			name: "valid expiration",
+			annotations: map[string]string{
+				constants.KlusterletClientCertExpirationAnnotation: "86400",
+			},
+			expectedExpiration: 86400,
+		},
+		{
+			name: "invalid expiration",
+			annotations: map[string]string{
+				constants.KlusterletClientCertExpirationAnnotation: "1d",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "expiration is too short",
+			annotations: map[string]string{
+				constants.KlusterletClientCertExpirationAnnotation: "300",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "expiration is too long",
+			annotations: map[string]string{
+				constants.KlusterletClientCertExpirationAnnotation: "4294967296",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			expiration, err := GetClientCertExpirationFromManagedClusterAnnotations(c.annotations)
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if expiration != c.expectedExpiration {
+				t.Errorf("expected expiration %d, but got %d", c.expectedExpiration, expiration)
+			}
+		})
+	}
+}
+
+func TestGetCSRApprovalFromManagedClusterAnnotations(t *testing.T) {
+	cases := []struct {
+		name             string
+		annotations      map[string]string
+		expectedApproval string
+		expectedErr      bool
+	}{
+		{
+			name:        "no annotation",
+			annotations: map[string]string{},
+		},
+		{
+			name: "auto approval",
+			annotations: map[string]string{
+				constants.KlusterletCSRApprovalAnnotation: constants.CSRApprovalAuto,
+			},
+			expectedApproval: constants.CSRApprovalAuto,
+		},
+		{
+			name: "manual approval",
+			annotations: map[string]string{
+				constants.KlusterletCSRApprovalAnnotation: constants.CSRApprovalManual,
+			},
+			expectedApproval: constants.CSRApprovalManual,
+		},
+		{
+			name: "invalid approval",
+			annotations: map[string]string{
+				constants.KlusterletCSRApprovalAnnotation: "manual",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			approval, err := GetCSRApprovalFromManagedClusterAnnotations(c.annotations)
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if approval != c.expectedApproval {
+				t.Errorf("expected approval %q, but got %q", c.expectedApproval, approval)
+			}
+		})
+	}
+}
+
+func TestGetClusterProxyHintFromManagedClusterAnnotations(t *testing.T) {
+	cases := []struct {
+		name         string
+		annotations  map[string]string
+		expectedHint string
+		expectedErr  bool
+	}{
+		{
+			name:        "no annotation",
+			annotations: map[string]string{},
+		},
+		{
+			name: "tunnel",
+			annotations: map[string]string{
+				constants.ClusterProxyHintAnnotation: constants.ClusterProxyHintTunnel,
+			},
+			expectedHint: constants.ClusterProxyHintTunnel,
+		},
+		{
+			name: "direct",
+			annotations: map[string]string{
+				constants.ClusterProxyHintAnnotation: constants.ClusterProxyHintDirect,
+			},
+			expectedHint: constants.ClusterProxyHintDirect,
+		},
+		{
+			name: "invalid hint",
+			annotations: map[string]string{
+				constants.ClusterProxyHintAnnotation: "proxy",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hint, err := GetClusterProxyHintFromManagedClusterAnnotations(c.annotations)
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if hint != c.expectedHint {
+				t.Errorf("expected hint %q, but got %q", c.expectedHint, hint)
+			}
+		})
+	}
+}
+
+func TestValidateKlusterletVolumes(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		expectedErr bool
+	}{
+		{
+			name:        "no annotation",
+			annotations: map[string]string{},
+		},
+		{
+			name: "valid volumes",
+			annotations: map[string]string{
+				constants.KlusterletExtraVolumesAnnotation: `[` +
+					`{"name":"custom-ca","configMap":"custom-ca","mountPath":"/etc/custom-ca"},` +
+					`{"name":"custom-creds","secret":"custom-creds","mountPath":"/etc/custom-creds"}]`,
+			},
+		},
+		{
+			name: "invalid json",
+			annotations: map[string]string{
+				constants.KlusterletExtraVolumesAnnotation: `{"name":"custom-ca"}`,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "reserved volume name",
+			annotations: map[string]string{
+				constants.KlusterletExtraVolumesAnnotation: `[{"name":"tmpdir","configMap":"ca","mountPath":"/etc/ca"}]`,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "no reference",
+			annotations: map[string]string{
+				constants.KlusterletExtraVolumesAnnotation: `[{"name":"ca","mountPath":"/etc/ca"}]`,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "both configmap and secret",
+			annotations: map[string]string{
+				constants.KlusterletExtraVolumesAnnotation: `[{"name":"ca","configMap":"ca","secret":"ca","mountPath":"/etc/ca"}]`,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid reference",
+			annotations: map[string]string{
+				constants.KlusterletExtraVolumesAnnotation: `[{"name":"ca","secret":"Invalid_Secret","mountPath":"/etc/ca"}]`,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "relative mount path",
+			annotations: map[string]string{
+				constants.KlusterletExtraVolumesAnnotation: `[{"name":"ca","configMap":"ca","mountPath":"etc/ca"}]`,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "duplicated mount path",
+			annotations: map[string]string{
+				constants.KlusterletExtraVolumesAnnotation: `[` +
+					`{"name":"ca","configMap":"ca","mountPath":"/etc/ca"},` +
+					`{"name":"creds","secret":"creds","mountPath":"/etc/ca/"}]`,
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			volumes, err := GetKlusterletExtraVolumesFromManagedClusterAnnotations(c.annotations)
+			if err == nil {
+				err = ValidateKlusterletVolumes(volumes)
+			}
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateCapacityClaims(t *testing.T) {
+	cases := []struct {
+		name               string
+		annotations        map[string]string
+		expectedCapacities map[string]string
+		expectedErr        bool
+	}{
+		{
+			name:               "no annotation",
+			annotations:        map[string]string{},
+			expectedCapacities: map[string]string{},
+		},
+		{
+			name: "valid capacities",
+			annotations: map[string]string{
+				constants.CapacityClaimsAnnotation: `{"gpu":"4","storage":"0.5Ti","memory":"1024Mi"}`,
+			},
+			expectedCapacities: map[string]string{"gpu": "4", "storage": "512Gi", "memory": "1Gi"},
+		},
+		{
+			name: "invalid json",
+			annotations: map[string]string{
+				constants.CapacityClaimsAnnotation: `["gpu"]`,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid quantity",
+			annotations: map[string]string{
+				constants.CapacityClaimsAnnotation: `{"gpu":"four"}`,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "negative quantity",
+			annotations: map[string]string{
+				constants.CapacityClaimsAnnotation: `{"gpu":"-1"}`,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid capacity name",
+			annotations: map[string]string{
+				constants.CapacityClaimsAnnotation: `{"nvidia.com/gpu":"4"}`,
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			capacities, err := GetCapacityClaimsFromManagedClusterAnnotations(c.annotations)
+			if err == nil {
+				err = ValidateCapacityClaims(capacities)
+			}
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if c.expectedErr {
+				return
+			}
+
+			actual := map[string]string{}
+			for name, quantity := range capacities {
+				actual[name] = quantity.String()
+			}
+			if !reflect.DeepEqual(actual, c.expectedCapacities) {
+				t.Errorf("expected capacities %v, but got %v", c.expectedCapacities, actual)
+			}
+		})
+	}
+}
+
+func TestValidateKlusterletImages(t *testing.T) {
+	cases := []struct {
+		name           string
+		annotations    map[string]string
+		expectedImages map[string]string
+		expectedErr    bool
+	}{
+		{
+			name:           "no annotation",
+			annotations:    map[string]string{},
+			expectedImages: map[string]string{},
+		},
+		{
+			name: "valid images",
+			annotations: map[string]string{
+				constants.KlusterletImagesAnnotation: `{"registration-operator":"localhost:5000/ocm/operator:v1",` +
+					`"registration":"mirror.example.com/ocm/registration@sha256:` +
+					`0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef","work":"work"}`,
+			},
+			expectedImages: map[string]string{
+				"registration-operator": "localhost:5000/ocm/operator:v1",
+				"registration": "mirror.example.com/ocm/registration@sha256:" +
+					"0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+				"work": "work",
+			},
+		},
+		{
+			name: "invalid json",
+			annotations: map[string]string{
+				constants.KlusterletImagesAnnotation: `["work"]`,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "unsupported component",
+			annotations: map[string]string{
+				constants.KlusterletImagesAnnotation: `{"placement":"quay.io/ocm/placement:v1"}`,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid image",
+			annotations: map[string]string{
+				constants.KlusterletImagesAnnotation: `{"work":"quay.io/OCM/work:"}`,
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			images, err := GetKlusterletImagesFromManagedClusterAnnotations(c.annotations)
+			if err == nil {
+				err = ValidateKlusterletImages(images)
+			}
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if c.expectedErr {
+				return
+			}
+			if !reflect.DeepEqual(images, c.expectedImages) {
+				t.Errorf("expected images %v, but got %v", c.expectedImages, images)
+			}
+		})
+	}
+}
+
+func TestValidateNamespaceLabels(t *testing.T) {
+	cases := []struct {
+		name           string
+		annotations    map[string]string
+		expectedLabels map[string]string
+		expectedErr    bool
+	}{
+		{
+			name:           "no annotation",
+			annotations:    map[string]string{},
+			expectedLabels: map[string]string{},
+		},
+		{
+			name: "valid labels",
+			annotations: map[string]string{
+				constants.KlusterletNamespaceLabelsAnnotation: `{"team":"platform","example.com/tenant":"a"}`,
+			},
+			expectedLabels: map[string]string{"team": "platform", "example.com/tenant": "a"},
+		},
+		{
+			name: "invalid json",
+			annotations: map[string]string{
+				constants.KlusterletNamespaceLabelsAnnotation: `["team"]`,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "reserved label",
+			annotations: map[string]string{
+				constants.KlusterletNamespaceLabelsAnnotation: `{"kubernetes.io/metadata.name":"test"}`,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid label value",
+			annotations: map[string]string{
+				constants.KlusterletNamespaceLabelsAnnotation: `{"team":"platform team"}`,
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			namespaceLabels, err := GetKlusterletNamespaceLabelsFromManagedClusterAnnotations(c.annotations)
+			if err == nil {
+				err = ValidateNamespaceLabels(namespaceLabels)
+			}
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if c.expectedErr {
+				return
+			}
+			if !reflect.DeepEqual(namespaceLabels, c.expectedLabels) {
+				t.Errorf("expected labels %v, but got %v", c.expectedLabels, namespaceLabels)
+			}
+		})
+	}
+}
+
+func TestGetKubeDistributionFromManagedClusterAnnotations(t *testing.T) {
+	cases := []struct {
+		name                 string
+		annotations          map[string]string
+		expectedDistribution string
+		expectedErr          bool
+	}{
+		{
+			name:        "no annotation",
+			annotations: map[string]string{},
+		},
+		{
+			name: "supported distribution",
+			annotations: map[string]string{
+				constants.KubeDistributionAnnotation: "MicroK8s",
+			},
+			expectedDistribution: "MicroK8s",
+		},
+		{
+			name: "case-insensitive distribution",
+			annotations: map[string]string{
+				constants.KubeDistributionAnnotation: "k3s",
+			},
+			expectedDistribution: "K3s",
+		},
+		{
+			name: "unsupported distribution",
+			annotations: map[string]string{
+				constants.KubeDistributionAnnotation: "unknown",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			distribution, err := GetKubeDistributionFromManagedClusterAnnotations(c.annotations)
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if distribution != c.expectedDistribution {
+				t.Errorf("expected distribution %q, but got %q", c.expectedDistribution, distribution)
+			}
+		})
+	}
+}
+
+func TestGetOpenShiftVersionFromManagedClusterAnnotations(t *testing.T) {
+	cases := []struct {
+		name            string
+		annotations     map[string]string
+		expectedVersion string
+		expectedErr     bool
+	}{
+		{
+			name:        "no annotation",
+			annotations: map[string]string{},
+		},
+		{
+			name: "release version",
+			annotations: map[string]string{
+				constants.OpenShiftVersionAnnotation: "4.14.3",
+			},
+			expectedVersion: "4.14.3",
+		},
+		{
+			name: "pre-release version",
+			annotations: map[string]string{
+				constants.OpenShiftVersionAnnotation: "4.15.0-rc.1",
+			},
+			expectedVersion: "4.15.0-rc.1",
+		},
+		{
+			name: "version with v prefix",
+			annotations: map[string]string{
+				constants.OpenShiftVersionAnnotation: "v4.14.3",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid version",
+			annotations: map[string]string{
+				constants.OpenShiftVersionAnnotation: "4.14",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			openshiftVersion, err := GetOpenShiftVersionFromManagedClusterAnnotations(c.annotations)
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if openshiftVersion != c.expectedVersion {
+				t.Errorf("expected version %q, but got %q", c.expectedVersion, openshiftVersion)
+			}
+		})
+	}
+}
+
+func TestGetHostedKonnectivityEndpointFromManagedClusterAnnotations(t *testing.T) {
+	cases := []struct {
+		name             string
+		annotations      map[string]string
+		expectedEndpoint string
+		expectedErr      bool
+	}{
+		{
+			name:        "no annotation",
+			annotations: map[string]string{},
+		},
+		{
+			name: "valid endpoint",
+			annotations: map[string]string{
+				constants.HostedKonnectivityEndpointAnnotation: "http://konnectivity.test.svc:8090",
+			},
+			expectedEndpoint: "http://konnectivity.test.svc:8090",
+		},
+		{
+			name: "unsupported scheme",
+			annotations: map[string]string{
+				constants.HostedKonnectivityEndpointAnnotation: "grpc://konnectivity.test.svc:8090",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "no host",
+			annotations: map[string]string{
+				constants.HostedKonnectivityEndpointAnnotation: "konnectivity.test.svc:8090",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			endpoint, err := GetHostedKonnectivityEndpointFromManagedClusterAnnotations(c.annotations)
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if endpoint != c.expectedEndpoint {
+				t.Errorf("expected endpoint %q, but got %q", c.expectedEndpoint, endpoint)
+			}
+		})
+	}
+}
+
+func TestGetClusterRoleAggregationLabelFromManagedClusterAnnotations(t *testing.T) {
+	cases := []struct {
+		name           string
+		annotations    map[string]string
+		expectedLabels map[string]string
+		expectedErr    bool
+	}{
+		{
+			name:        "no annotation",
+			annotations: map[string]string{},
+		},
+		{
+			name: "valid aggregation label",
+			annotations: map[string]string{
+				constants.KlusterletClusterRoleAggregationLabelAnnotation: "rbac.example.com/aggregate-to-agent=true",
+			},
+			expectedLabels: map[string]string{"rbac.example.com/aggregate-to-agent": "true"},
+		},
+		{
+			name: "invalid format",
+			annotations: map[string]string{
+				constants.KlusterletClusterRoleAggregationLabelAnnotation: "rbac.example.com/aggregate-to-agent",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "kubernetes rbac aggregation label",
+			annotations: map[string]string{
+				constants.KlusterletClusterRoleAggregationLabelAnnotation: "rbac.authorization.k8s.io/aggregate-to-edit=true",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			labels, err := GetClusterRoleAggregationLabelFromManagedClusterAnnotations(c.annotations)
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(labels, c.expectedLabels) {
+				t.Errorf("expected labels %v, but got %v", c.expectedLabels, labels)
+			}
+		})
+	}
+}
+
 func TestGetTolerationsAndValidate(t *testing.T) {
 	cases := []struct {
 		name           string
@@ -0,0 +1,133 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// the weight of the latest hub request in the error rate of the hub requests
+	hubErrorRateWeight = 0.05
+	// the hub requests are throttled once the error rate exceeds the threshold
+	hubErrorRateThreshold = 0.2
+	// the maximum delay of a hub request when all of the hub requests fail
+	hubThrottleMaxDelay = 5 * time.Second
+)
+
+// DefaultHubThrottle is the throttle of the requests that are sent to the hub by all of the controllers
+var DefaultHubThrottle = NewHubThrottle(hubErrorRateWeight, hubErrorRateThreshold, hubThrottleMaxDelay)
+
+// HubThrottle slows down the requests to the hub when the hub is overloaded. It tracks the error rate of the hub
+// requests with an exponentially weighted moving average, once the error rate exceeds the threshold, each request
+// is delayed in proportion to the error rate until the error rate goes back to normal.
+type HubThrottle struct {
+	lock      sync.Mutex
+	errorRate float64
+	weight    float64
+	threshold float64
+	maxDelay  time.Duration
+	throttled bool
+}
+
+// NewHubThrottle returns a HubThrottle, the weight is the weight of the latest request in the error rate.
+func NewHubThrottle(weight, threshold float64, maxDelay time.Duration) *HubThrottle {
+	return &HubThrottle{
+		weight:    weight,
+		threshold: threshold,
+		maxDelay:  maxDelay,
+	}
+}
+
+// Observe records the result of a hub request
+func (t *HubThrottle) Observe(failed bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	sample := 0.0
+	if failed {
+		sample = 1.0
+	}
+	t.errorRate = t.errorRate*(1-t.weight) + sample*t.weight
+
+	throttled := t.errorRate > t.threshold
+	if throttled != t.throttled {
+		if throttled {
+			klog.Warningf("The error rate of the hub requests is %.2f, throttle the hub requests", t.errorRate)
+		} else {
+			klog.Infof("The error rate of the hub requests is %.2f, stop throttling the hub requests", t.errorRate)
+		}
+		t.throttled = throttled
+	}
+}
+
+// Delay returns the duration that a hub request should wait before it is sent
+func (t *HubThrottle) Delay() time.Duration {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.errorRate <= t.threshold {
+		return 0
+	}
+	return time.Duration(float64(t.maxDelay) * (t.errorRate - t.threshold) / (1 - t.threshold))
+}
+
+// Wait waits for the delay of the throttle, it returns early when the context is done
+func (t *HubThrottle) Wait(ctx context.Context) {
+	delay := t.Delay()
+	if delay == 0 {
+		return
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// WrapTransport wraps the transport of the hub clients, so the hub requests are throttled by the error rate
+func (t *HubThrottle) WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return &hubThrottleRoundTripper{throttle: t, delegate: rt}
+}
+
+// ThrottledConfig returns a copy of the hub config whose requests are throttled by the error rate, the given config
+// is not changed, so it can be used by the clients that should not be slowed down, e.g. the leader election client
+func (t *HubThrottle) ThrottledConfig(cfg *rest.Config) *rest.Config {
+	throttledCfg := rest.CopyConfig(cfg)
+	throttledCfg.Wrap(t.WrapTransport)
+	return throttledCfg
+}
+
+type hubThrottleRoundTripper struct {
+	throttle *HubThrottle
+	delegate http.RoundTripper
+}
+
+func (rt *hubThrottleRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.throttle.Wait(req.Context())
+
+	resp, err := rt.delegate.RoundTrip(req)
+	if err != nil {
+		// the request that is canceled by the caller does not mean the hub is overloaded
+		rt.throttle.Observe(!errors.Is(err, context.Canceled))
+		return resp, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		rt.throttle.Observe(true)
+	default:
+		rt.throttle.Observe(false)
+	}
+	return resp, err
+}
@@ -0,0 +1,114 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestHubThrottle(t *testing.T) {
+	var overloaded atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if overloaded.Load() {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	throttle := NewHubThrottle(0.5, 0.2, 20*time.Millisecond)
+	client := &http.Client{Transport: throttle.WrapTransport(http.DefaultTransport)}
+
+	// sendRequests returns the number of the requests that are sent in the duration
+	sendRequests := func(duration time.Duration) int {
+		count := 0
+		for start := time.Now(); time.Since(start) < duration; count++ {
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp.Body.Close()
+		}
+		return count
+	}
+
+	healthy := sendRequests(200 * time.Millisecond)
+	if delay := throttle.Delay(); delay != 0 {
+		t.Errorf("expected no delay when the hub is healthy, but got %v", delay)
+	}
+
+	overloaded.Store(true)
+	throttled := sendRequests(200 * time.Millisecond)
+	if throttled*2 > healthy {
+		t.Errorf("expected the throughput is reduced when the hub is overloaded, healthy: %d, throttled: %d",
+			healthy, throttled)
+	}
+	if delay := throttle.Delay(); delay == 0 {
+		t.Errorf("expected the requests are delayed when the hub is overloaded")
+	}
+
+	overloaded.Store(false)
+	sendRequests(200 * time.Millisecond)
+	if delay := throttle.Delay(); delay != 0 {
+		t.Errorf("expected the throttle recovers when the hub is healthy again, but got %v", delay)
+	}
+}
+
+func TestHubThrottleThrottledConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	throttle := NewHubThrottle(0.5, 0.2, 20*time.Millisecond)
+	// simulate an overloaded hub
+	for i := 0; i < 10; i++ {
+		throttle.Observe(true)
+	}
+
+	cfg := &rest.Config{Host: server.URL}
+	throttledCfg := throttle.ThrottledConfig(cfg)
+	if cfg.WrapTransport != nil {
+		t.Errorf("expected the original config is not throttled")
+	}
+
+	// sendRequests returns the number of the requests that are sent with the config in the duration
+	sendRequests := func(config *rest.Config, duration time.Duration) int {
+		client, err := rest.HTTPClientFor(config)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		count := 0
+		for start := time.Now(); time.Since(start) < duration; count++ {
+			req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp.Body.Close()
+			// keep the hub overloaded
+			throttle.Observe(true)
+		}
+		return count
+	}
+
+	unthrottled := sendRequests(cfg, 200*time.Millisecond)
+	throttled := sendRequests(throttledCfg, 200*time.Millisecond)
+	if throttled*2 > unthrottled {
+		t.Errorf("expected only the throughput of the throttled config is reduced, unthrottled: %d, throttled: %d",
+			unthrottled, throttled)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+// imageDigestRegexp matches the digest of an image reference, e.g. @sha256:<hex>
+var imageDigestRegexp = regexp.MustCompile(`@[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-fA-F0-9]{32,}$`)
+
+// imagesNotDigestPinnedError indicates some klusterlet images are not referenced by digest
+type imagesNotDigestPinnedError struct {
+	images []string
+}
+
+func (e *imagesNotDigestPinnedError) Error() string {
+	return fmt.Sprintf("the image digest policy is %s, but the klusterlet images %s are not referenced by digest",
+		constants.ImageDigestPolicyRequired, strings.Join(e.images, ", "))
+}
+
+// IsImagesNotDigestPinned returns true if the error indicates some klusterlet images are not referenced by digest
+func IsImagesNotDigestPinned(err error) bool {
+	var target *imagesNotDigestPinnedError
+	return errors.As(err, &target)
+}
+
+// GetImageDigestPolicyFromManagedClusterAnnotations returns the digest pinning policy of the klusterlet images from
+// the managed cluster annotations, an empty string is returned if the annotation is not set
+func GetImageDigestPolicyFromManagedClusterAnnotations(clusterAnnotations map[string]string) (string, error) {
+	policy, ok := clusterAnnotations[constants.KlusterletImageDigestPolicyAnnotation]
+	if !ok {
+		return "", nil
+	}
+
+	switch policy {
+	case constants.ImageDigestPolicyRequired, constants.ImageDigestPolicyOptional:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("the image digest policy %q should be %s or %s",
+			policy, constants.ImageDigestPolicyRequired, constants.ImageDigestPolicyOptional)
+	}
+}
+
+// ValidateImagesDigestPinned validates the klusterlet images are referenced by digest, the images is a map of the
+// klusterlet component to its image. An image that has both a tag and a digest is pinned by the digest.
+func ValidateImagesDigestPinned(images map[string]string) error {
+	unpinned := []string{}
+	for component, image := range images {
+		if !imageDigestRegexp.MatchString(image) {
+			unpinned = append(unpinned, fmt.Sprintf("%s=%s", component, image))
+		}
+	}
+	if len(unpinned) == 0 {
+		return nil
+	}
+
+	sort.Strings(unpinned)
+	return &imagesNotDigestPinnedError{images: unpinned}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestValidateImagesDigestPinned(t *testing.T) {
+	digest := "@sha256:2d4b8d3b2bc1e4f5a6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9"
+
+	cases := []struct {
+		name              string
+		images            map[string]string
+		expectedNotPinned bool
+	}{
+		{
+			name: "digest pinned images",
+			images: map[string]string{
+				"registration-operator": "quay.io/ocm/registration-operator" + digest,
+				"work":                  "quay.io/ocm/work:v1" + digest,
+			},
+		},
+		{
+			name: "tag based images",
+			images: map[string]string{
+				"registration-operator": "quay.io/ocm/registration-operator" + digest,
+				"work":                  "quay.io/ocm/work:v1",
+			},
+			expectedNotPinned: true,
+		},
+		{
+			name: "images without tag or digest",
+			images: map[string]string{
+				"registration": "quay.io/ocm/registration",
+			},
+			expectedNotPinned: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateImagesDigestPinned(c.images)
+			if IsImagesNotDigestPinned(err) != c.expectedNotPinned {
+				t.Errorf("expected images not digest pinned %v, but got %v", c.expectedNotPinned, err)
+			}
+			if IsImagesNotDigestPinned(fmt.Errorf("wrapped: %w", err)) != c.expectedNotPinned {
+				t.Errorf("expected the wrapped error is recognized, but got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+// KlusterletImporterName is the name of the default importer that imports the klusterlet, it is reserved and
+// cannot be registered by others
+const KlusterletImporterName = "klusterlet"
+
+// Importer imports the agent of a managed cluster with the import secret of the managed cluster. The importer is
+// also used for the managed cluster that is restored from a backup, it is responsible for the backup restore case
+// itself.
+type Importer interface {
+	Import(ctx context.Context, client *ClientHolder, restMapper meta.RESTMapper, importSecret *corev1.Secret) error
+}
+
+// klusterletImporter imports the klusterlet by applying the klusterlet manifests of the import secret
+type klusterletImporter struct {
+	recorder      events.Recorder
+	backupRestore bool
+}
+
+// NewKlusterletImporter returns the default importer that imports the klusterlet, the other importers can
+// delegate to it. If backupRestore is true, the managed cluster is restored from a backup and has the klusterlet
+// already, only the bootstrap hub kubeconfig secret of the klusterlet is updated.
+func NewKlusterletImporter(recorder events.Recorder, backupRestore bool) Importer {
+	return &klusterletImporter{recorder: recorder, backupRestore: backupRestore}
+}
+
+func (i *klusterletImporter) Import(_ context.Context, client *ClientHolder, restMapper meta.RESTMapper,
+	importSecret *corev1.Secret) error {
+	_, err := applyKlusterletResources(i.backupRestore, client, restMapper, i.recorder, importSecret)
+	return err
+}
+
+// applyKlusterletResources applies the klusterlet manifests of the import secret on the managed cluster, only the
+// bootstrap hub kubeconfig secret is updated for the managed cluster that is restored from a backup
+func applyKlusterletResources(backupRestore bool, client *ClientHolder, restMapper meta.RESTMapper,
+	recorder events.Recorder, importSecret *corev1.Secret) (bool, error) {
+	if backupRestore {
+		// only update the bootstrap secret on the managed cluster with the auto-import-secret
+		return UpdateManagedClusterBootstrapSecret(client, importSecret, recorder)
+	}
+	return ImportManagedClusterFromSecret(client, restMapper, recorder, importSecret)
+}
+
+var importers = struct {
+	sync.RWMutex
+	registry map[string]Importer
+}{
+	registry: map[string]Importer{},
+}
+
+// RegisterImporter registers an importer with the name, the managed cluster that has the importer annotation
+// with the name will be imported by the importer instead of the default klusterlet importer. The importers
+// should be registered before the controllers are started.
+func RegisterImporter(name string, importer Importer) error {
+	if len(name) == 0 || name == KlusterletImporterName {
+		return fmt.Errorf("the importer name %q is invalid or reserved", name)
+	}
+	if importer == nil {
+		return fmt.Errorf("the importer %s is nil", name)
+	}
+
+	importers.Lock()
+	defer importers.Unlock()
+
+	if _, ok := importers.registry[name]; ok {
+		return fmt.Errorf("the importer %s is already registered", name)
+	}
+	importers.registry[name] = importer
+	return nil
+}
+
+// GetImporter returns the registered importer that is specified by the importer annotation of the managed
+// cluster. Nil is returned if the managed cluster uses the default klusterlet importer.
+func GetImporter(annotations map[string]string) (Importer, error) {
+	name := annotations[constants.ImporterAnnotation]
+	if len(name) == 0 || name == KlusterletImporterName {
+		return nil, nil
+	}
+
+	importers.RLock()
+	defer importers.RUnlock()
+
+	importer, ok := importers.registry[name]
+	if !ok {
+		return nil, fmt.Errorf("the importer %s specified by the annotation %s is not registered",
+			name, constants.ImporterAnnotation)
+	}
+	return importer, nil
+}
+
+// importerApplyResourcesFunc adapts the importer to the ApplyResourcesFunc. The importer does not report whether
+// the resources on the managed cluster are changed, so the resources are considered as modified once the importer
+// succeeds.
+func importerApplyResourcesFunc(ctx context.Context, importer Importer) ApplyResourcesFunc {
+	return func(_ bool, client *ClientHolder, restMapper meta.RESTMapper,
+		_ events.Recorder, importSecret *corev1.Secret) (bool, error) {
+		if err := importer.Import(ctx, client, restMapper, importSecret); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+}
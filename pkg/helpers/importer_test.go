@@ -0,0 +1,244 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeversion "k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	workinformers "open-cluster-management.io/api/client/work/informers/externalversions"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	testinghelpers "github.com/stolostron/managedcluster-import-controller/pkg/helpers/testing"
+	"github.com/stolostron/managedcluster-import-controller/pkg/source"
+)
+
+type fakeImporter struct {
+	imported bool
+}
+
+func (i *fakeImporter) Import(_ context.Context, _ *ClientHolder, _ meta.RESTMapper, _ *corev1.Secret) error {
+	i.imported = true
+	return nil
+}
+
+func TestRegisterImporter(t *testing.T) {
+	if err := RegisterImporter("", &fakeImporter{}); err == nil {
+		t.Errorf("expected error for the empty importer name, but failed")
+	}
+	if err := RegisterImporter(KlusterletImporterName, &fakeImporter{}); err == nil {
+		t.Errorf("expected error for the reserved importer name, but failed")
+	}
+	if err := RegisterImporter("test-register", nil); err == nil {
+		t.Errorf("expected error for the nil importer, but failed")
+	}
+	if err := RegisterImporter("test-register", &fakeImporter{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := RegisterImporter("test-register", &fakeImporter{}); err == nil {
+		t.Errorf("expected error for the duplicated importer, but failed")
+	}
+
+	cases := []struct {
+		name             string
+		annotations      map[string]string
+		expectedImporter bool
+		expectedErr      bool
+	}{
+		{
+			name: "no annotation",
+		},
+		{
+			name:        "klusterlet importer",
+			annotations: map[string]string{constants.ImporterAnnotation: KlusterletImporterName},
+		},
+		{
+			name:             "registered importer",
+			annotations:      map[string]string{constants.ImporterAnnotation: "test-register"},
+			expectedImporter: true,
+		},
+		{
+			name:        "unregistered importer",
+			annotations: map[string]string{constants.ImporterAnnotation: "test-unregistered"},
+			expectedErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			importer, err := GetImporter(c.annotations)
+			if c.expectedErr != (err != nil) {
+				t.Errorf("expected error %v, but got %v", c.expectedErr, err)
+			}
+			if c.expectedImporter != (importer != nil) {
+				t.Errorf("expected importer %v, but got %v", c.expectedImporter, importer)
+			}
+		})
+	}
+}
+
+func TestImportWithImporter(t *testing.T) {
+	managedClusterName := "test"
+	importer := &fakeImporter{}
+	if err := RegisterImporter("test-import", importer); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	works := []runtime.Object{
+		&workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-klusterlet-crds",
+				Namespace: managedClusterName,
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "true",
+				},
+			},
+		},
+		&workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-klusterlet",
+				Namespace: managedClusterName,
+				Labels: map[string]string{
+					constants.KlusterletWorksLabel: "true",
+				},
+			},
+		},
+	}
+
+	kubeInformerFactory := informers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 10*time.Minute)
+	kubeInformerFactory.Core().V1().Secrets().Informer().GetStore().Add(
+		testinghelpers.GetImportSecret(managedClusterName))
+	workInformerFactory := workinformers.NewSharedInformerFactory(workfake.NewSimpleClientset(works...), 10*time.Minute)
+	workInformer := workInformerFactory.Work().V1().ManifestWorks().Informer()
+	for _, work := range works {
+		workInformer.GetStore().Add(work)
+	}
+
+	spokeKubeClient := kubefake.NewSimpleClientset()
+	spokeKubeClient.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &kubeversion.Info{
+		GitVersion: "v1.27.3",
+	}
+
+	defaultApplied := false
+	importHelper := NewImportHelper(&source.InformerHolder{
+		ImportSecretLister:   kubeInformerFactory.Core().V1().Secrets().Lister(),
+		KlusterletWorkLister: workInformerFactory.Work().V1().ManifestWorks().Lister(),
+	}, eventstesting.NewTestingEventRecorder(t), logf.Log.WithName("import-helper-tester")).
+		WithGenerateClientHolderFunc(func(secret *corev1.Secret) (*ClientHolder, meta.RESTMapper, error) {
+			return &ClientHolder{KubeClient: spokeKubeClient}, nil, nil
+		}).
+		WithApplyResourcesFunc(func(backupRestore bool, client *ClientHolder, restMapper meta.RESTMapper,
+			recorder events.Recorder, importSecret *corev1.Secret) (bool, error) {
+			defaultApplied = true
+			return true, nil
+		})
+
+	managedCluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        managedClusterName,
+			Annotations: map[string]string{constants.ImporterAnnotation: "test-import"},
+		},
+	}
+	_, condition, modified, _, err := importHelper.Import(context.TODO(), false, managedCluster, &corev1.Secret{}, 0, 1)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !importer.imported || defaultApplied {
+		t.Errorf("expected the cluster is imported by the registered importer only")
+	}
+	if !modified || !ImportingResourcesApplied(&condition) {
+		t.Errorf("expected the resources are applied, but got %v", condition)
+	}
+
+	// the restored cluster is imported by the importer too
+	importer.imported = false
+	_, _, _, _, err = importHelper.Import(context.TODO(), true, managedCluster, &corev1.Secret{}, 0, 1)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !importer.imported || defaultApplied {
+		t.Errorf("expected the restored cluster is imported by the registered importer only")
+	}
+
+	// the token auto import strategy cannot be used with the importer
+	importer.imported = false
+	_, condition, _, _, err = importHelper.Import(context.TODO(), false, managedCluster, &corev1.Secret{
+		Data: map[string][]byte{
+			constants.AutoImportStrategyKey: []byte(constants.AutoImportStrategyToken),
+			"server":                        []byte("https://api.test.com:6443"),
+			"token":                         []byte("test-token"),
+		},
+	}, 0, 1)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if importer.imported || condition.Reason != constants.ConditionReasonManagedClusterImportFailed {
+		t.Errorf("expected the token strategy is rejected for the importer, but got %v", condition)
+	}
+
+	managedCluster.Annotations[constants.ImporterAnnotation] = "test-unregistered"
+	_, condition, _, _, err = importHelper.Import(context.TODO(), false, managedCluster, &corev1.Secret{}, 0, 1)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if condition.Reason != constants.ConditionReasonManagedClusterImportFailed {
+		t.Errorf("expected the import is failed with an unregistered importer, but got %v", condition)
+	}
+}
+
+func TestKlusterletImporter(t *testing.T) {
+	cases := []struct {
+		name                    string
+		backupRestore           bool
+		importSecret            *corev1.Secret
+		expectedErr             bool
+		expectedBootstrapSecret bool
+	}{
+		{
+			name:                    "update the bootstrap secret of the restored cluster",
+			backupRestore:           true,
+			importSecret:            testinghelpers.GetImportSecret("test"),
+			expectedBootstrapSecret: true,
+		},
+		{
+			name:         "import the klusterlet with an invalid import secret",
+			importSecret: &corev1.Secret{},
+			expectedErr:  true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clientHolder := &ClientHolder{
+				KubeClient: kubefake.NewSimpleClientset(),
+			}
+
+			importer := NewKlusterletImporter(eventstesting.NewTestingEventRecorder(t), c.backupRestore)
+			err := importer.Import(context.TODO(), clientHolder, nil, c.importSecret)
+			if c.expectedErr != (err != nil) {
+				t.Errorf("expected error %v, but got %v", c.expectedErr, err)
+			}
+
+			_, err = clientHolder.KubeClient.CoreV1().Secrets("open-cluster-management-agent").Get(
+				context.TODO(), "bootstrap-hub-kubeconfig", metav1.GetOptions{})
+			if c.expectedBootstrapSecret != (err == nil) {
+				t.Errorf("expected the bootstrap secret %v, but got %v", c.expectedBootstrapSecret, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// the prefix of the klusterlet namespace
+const klusterletNamespacePrefix = "open-cluster-management-"
+
+// GetImportYaml returns the import.yaml of the import secret, the import.yaml is decompressed if it is
+// compressed because the import secret exceeds the size limit
+func GetImportYaml(importSecret *corev1.Secret) ([]byte, error) {
+	if data, ok := importSecret.Data[constants.ImportSecretImportYamlKey]; ok && len(data) != 0 {
+		return data, nil
+	}
+
+	compressed, ok := importSecret.Data[constants.ImportSecretImportYamlGzipKey]
+	if !ok || len(compressed) == 0 {
+		return nil, fmt.Errorf("the %s is required", constants.ImportSecretImportYamlKey)
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress the %s: %v", constants.ImportSecretImportYamlGzipKey, err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress the %s: %v", constants.ImportSecretImportYamlGzipKey, err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("the %s is required", constants.ImportSecretImportYamlKey)
+	}
+	return data, nil
+}
+
+// CompressImportYaml compresses the import.yaml with gzip, the compressed data can be read by GetImportYaml
+// with the ImportSecretImportYamlGzipKey
+func CompressImportYaml(importYaml []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(importYaml); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ValidateKlusterletNamespace validates the klusterlet namespace, the namespace must be a DNS-1123 label and have
+// a prefix of "open-cluster-management-"
+func ValidateKlusterletNamespace(namespace string) error {
+	if errMsgs := validation.IsDNS1123Label(namespace); len(errMsgs) != 0 {
+		return fmt.Errorf("invalid klusterlet namespace %s: %s", namespace, strings.Join(errMsgs, ";"))
+	}
+
+	if !strings.HasPrefix(namespace, klusterletNamespacePrefix) {
+		return fmt.Errorf("invalid klusterlet namespace %s, the namespace must have a prefix of %s",
+			namespace, klusterletNamespacePrefix)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGetImportYaml(t *testing.T) {
+	importYaml := []byte("apiVersion: v1\nkind: Namespace\n")
+	compressed, err := CompressImportYaml(importYaml)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		name        string
+		data        map[string][]byte
+		expectedErr bool
+	}{
+		{
+			name: "import yaml",
+			data: map[string][]byte{constants.ImportSecretImportYamlKey: importYaml},
+		},
+		{
+			name: "compressed import yaml",
+			data: map[string][]byte{constants.ImportSecretImportYamlGzipKey: compressed},
+		},
+		{
+			name:        "no import yaml",
+			data:        map[string][]byte{},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid compressed import yaml",
+			data:        map[string][]byte{constants.ImportSecretImportYamlGzipKey: importYaml},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			data, err := GetImportYaml(&corev1.Secret{Data: c.data})
+			if c.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, but failed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(data) != string(importYaml) {
+				t.Errorf("expected import yaml %q, but got %q", importYaml, data)
+			}
+		})
+	}
+}
+
+func TestValidateKlusterletNamespace(t *testing.T) {
+	cases := []struct {
+		name        string
+		namespace   string
+		expectedErr bool
+	}{
+		{
+			name:      "default namespace",
+			namespace: "open-cluster-management-agent",
+		},
+		{
+			name:      "tenant namespace",
+			namespace: "open-cluster-management-tenant-a",
+		},
+		{
+			name:        "not a DNS-1123 label",
+			namespace:   "open-cluster-management-Tenant_A",
+			expectedErr: true,
+		},
+		{
+			name:        "no prefix",
+			namespace:   "tenant-a",
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateKlusterletNamespace(c.namespace)
+			if c.expectedErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if !c.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ package helpers
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -29,6 +30,39 @@ import (
 
 type WorkSelector func(string, workv1.ManifestWork) bool
 
+// hubIdentityEnvVarName is the env to specify the identity of this hub, the identity is recorded on the klusterlet
+// manifestworks to distinguish them from the klusterlet manifestworks that are created by other hubs
+const hubIdentityEnvVarName = "HUB_IDENTITY"
+
+// GetHubIdentity returns the identity of this hub, it is empty if the identity is not specified
+func GetHubIdentity() string {
+	return os.Getenv(hubIdentityEnvVarName)
+}
+
+// IsForeignKlusterletWork returns true if the klusterlet manifestwork is created by another hub. The works that do not
+// have the hub identity are considered as created by this hub.
+func IsForeignKlusterletWork(work *workv1.ManifestWork) bool {
+	hubIdentity := GetHubIdentity()
+	if len(hubIdentity) == 0 {
+		return false
+	}
+
+	workHubIdentity, ok := work.Annotations[constants.KlusterletWorksHubIdentityAnnotation]
+	return ok && workHubIdentity != hubIdentity
+}
+
+// IsControllerManaged returns true if the managed cluster is imported by this controller. This controller records
+// the provenance of the managed clusters that it handles in the created-via annotation, so a cluster that does not
+// have a created-via annotation set by this controller is registered by others.
+func IsControllerManaged(cluster *clusterv1.ManagedCluster) bool {
+	switch cluster.GetAnnotations()[constants.CreatedViaAnnotation] {
+	case constants.CreatedViaOther, constants.CreatedViaHive, constants.CreatedViaAI,
+		constants.CreatedViaDiscovery, constants.CreatedViaHypershift:
+		return true
+	}
+	return false
+}
+
 // AssertManifestWorkFinalizer add/remove manifest finalizer for a managed cluster,
 // this func will send request to api server to update managed cluster.
 func AssertManifestWorkFinalizer(ctx context.Context, runtimeClient client.Client, recorder events.Recorder,
@@ -0,0 +1,62 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+func TestIsControllerManaged(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		expected    bool
+	}{
+		{
+			name: "imported by this controller",
+			annotations: map[string]string{
+				constants.CreatedViaAnnotation: constants.CreatedViaOther,
+			},
+			expected: true,
+		},
+		{
+			name: "provisioned by hive",
+			annotations: map[string]string{
+				constants.CreatedViaAnnotation: constants.CreatedViaHive,
+			},
+			expected: true,
+		},
+		{
+			name:     "registered externally",
+			expected: false,
+		},
+		{
+			name: "provisioned by a custom pipeline",
+			annotations: map[string]string{
+				constants.CreatedViaAnnotation:          "custom-pipeline",
+				constants.CreatedViaImmutableAnnotation: "true",
+			},
+			expected: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test",
+					Annotations: c.annotations,
+				},
+			}
+			if managed := IsControllerManaged(cluster); managed != c.expected {
+				t.Errorf("expected %v, but got %v", c.expected, managed)
+			}
+		})
+	}
+}
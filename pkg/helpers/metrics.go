@@ -0,0 +1,140 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+const (
+	importResultSucceeded = "succeeded"
+	importResultFailed    = "failed"
+)
+
+// manifestWorkConflicts counts the conflicts returned when applying the manifestworks, the conflicts indicate
+// that the manifestworks are changed by others at the same time
+var manifestWorkConflicts = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "managedcluster_import_manifestwork_conflicts_total",
+		Help: "Total number of conflicts returned when applying the manifestworks of a managed cluster",
+	},
+	[]string{"cluster"},
+)
+
+// clusterDeploymentInstalledToImportedDuration observes the durations between the clusterdeployments are
+// installed and their managed clusters are imported successfully, it reflects the provisioning-to-managed latency
+var clusterDeploymentInstalledToImportedDuration = prometheus.NewHistogram(
+	prometheus.HistogramOpts{
+		Name:    "managedcluster_import_clusterdeployment_installed_to_imported_seconds",
+		Help:    "Duration in seconds between the clusterdeployment is installed and the managed cluster is imported",
+		Buckets: prometheus.ExponentialBuckets(30, 2, 10),
+	},
+)
+
+// importFailedAttempts records how many reconciles have failed to import a managed cluster since the managed cluster
+// was imported successfully last time, it is removed once the managed cluster is deleted
+var importFailedAttempts = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "managedcluster_import_failed_attempts",
+		Help: "Number of the reconciles that have failed to import a managed cluster until it is imported",
+	},
+	[]string{"cluster"},
+)
+
+// importTotal counts the terminal outcomes of the imports of the managed clusters
+var importTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "import_controller_import_total",
+		Help: "Total number of the imports of the managed clusters that are succeeded or failed",
+	},
+	[]string{"mode", "result"},
+)
+
+// importDuration observes the durations from the start of the reconciles to the terminal import conditions of
+// the managed clusters are written
+var importDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "import_controller_import_duration_seconds",
+		Help:    "Duration in seconds from the start of the reconcile to the terminal import condition is written",
+		Buckets: prometheus.DefBuckets,
+	},
+	[]string{"mode"},
+)
+
+// importedClusters counts the managed clusters that are imported currently in the hosted mode and in the default
+// mode, it reflects the ratio of the hosted imports to the default imports of the fleet
+var importedClusters = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "managedcluster_import_imported_clusters",
+		Help: "Number of the managed clusters that are imported in the hosted mode or in the default mode",
+	},
+	[]string{"mode"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(manifestWorkConflicts)
+	metrics.Registry.MustRegister(clusterDeploymentInstalledToImportedDuration)
+	metrics.Registry.MustRegister(importFailedAttempts)
+	metrics.Registry.MustRegister(importTotal)
+	metrics.Registry.MustRegister(importDuration)
+	metrics.Registry.MustRegister(importedClusters)
+}
+
+// ObserveClusterDeploymentInstalledToImported observes the duration between the installed time of a
+// clusterdeployment and the imported time of its managed cluster
+func ObserveClusterDeploymentInstalledToImported(installed, imported time.Time) {
+	if imported.Before(installed) {
+		return
+	}
+
+	clusterDeploymentInstalledToImportedDuration.Observe(imported.Sub(installed).Seconds())
+}
+
+// ObserveImportResult records the outcome of an import of the managed cluster and its duration from the start
+// of the reconcile once the import condition is written, the condition that is not terminal or is not changed
+// from the current condition of the managed cluster is ignored
+func ObserveImportResult(mode operatorv1.InstallMode, cluster *clusterv1.ManagedCluster,
+	condition *metav1.Condition, start time.Time) {
+	result := importResult(condition)
+	if len(result) == 0 {
+		return
+	}
+
+	current := meta.FindStatusCondition(cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+	if current != nil && current.Status == condition.Status && current.Reason == condition.Reason &&
+		current.Message == RenderConditionMessage(cluster.Name, *condition) {
+		return
+	}
+
+	modeLabel := strings.ToLower(string(mode))
+	importTotal.WithLabelValues(modeLabel, result).Inc()
+	importDuration.WithLabelValues(modeLabel).Observe(time.Since(start).Seconds())
+}
+
+func importResult(condition *metav1.Condition) string {
+	switch {
+	case condition.Status == metav1.ConditionTrue || ImportingResourcesApplied(condition):
+		return importResultSucceeded
+	case IsImportFailed(condition) || condition.Reason == constants.ConditionReasonSpokeVersionUnsupported:
+		return importResultFailed
+	}
+	return ""
+}
+
+// SetImportedClusters sets the numbers of the managed clusters that are imported in the hosted mode and in the
+// default mode
+func SetImportedClusters(hosted, dflt int) {
+	importedClusters.WithLabelValues(strings.ToLower(string(operatorv1.InstallModeHosted))).Set(float64(hosted))
+	importedClusters.WithLabelValues(strings.ToLower(string(operatorv1.InstallModeDefault))).Set(float64(dflt))
+}
@@ -0,0 +1,122 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clienttesting "k8s.io/client-go/testing"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+func TestManifestWorkConflictsMetric(t *testing.T) {
+	existing := &workv1.ManifestWork{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "conflict-klusterlet",
+			Namespace: "conflict",
+		},
+	}
+
+	workClient := workfake.NewSimpleClientset(existing)
+	workClient.PrependReactor("update", "manifestworks",
+		func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+			return true, nil, errors.NewConflict(
+				schema.GroupResource{Group: workv1.GroupName, Resource: "manifestworks"},
+				existing.Name, nil)
+		})
+
+	required := existing.DeepCopy()
+	required.Spec.Workload.Manifests = []workv1.Manifest{
+		{RawExtension: runtime.RawExtension{Raw: []byte("{\"apiVersion\":\"v1\",\"kind\":\"Namespace\"}")}},
+	}
+
+	before := testutil.ToFloat64(manifestWorkConflicts.WithLabelValues("conflict"))
+	if _, err := applyManifestWork(workClient, eventstesting.NewTestingEventRecorder(t), required); !errors.IsConflict(err) {
+		t.Errorf("expected conflict error, but got %v", err)
+	}
+
+	after := testutil.ToFloat64(manifestWorkConflicts.WithLabelValues("conflict"))
+	if after-before != 1 {
+		t.Errorf("expected the conflicts counter is increased by 1, but got %v", after-before)
+	}
+}
+
+func TestObserveImportResult(t *testing.T) {
+	imported := NewManagedClusterImportSucceededCondition(metav1.ConditionTrue,
+		constants.ConditionReasonManagedClusterImported, "Import succeeded")
+	failed := NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+		constants.ConditionReasonManagedClusterImportFailed, "Try to import managed cluster, retry times: 1/1")
+	importing := NewManagedClusterImportSucceededCondition(metav1.ConditionFalse,
+		constants.ConditionReasonManagedClusterImporting, "Wait for import secret")
+
+	cases := []struct {
+		name              string
+		mode              operatorv1.InstallMode
+		currentConditions []metav1.Condition
+		condition         metav1.Condition
+		expectedResult    string
+		expectedIncreased float64
+	}{
+		{
+			name:              "import is not terminal",
+			mode:              operatorv1.InstallModeDefault,
+			condition:         importing,
+			expectedResult:    importResultSucceeded,
+			expectedIncreased: 0,
+		},
+		{
+			name:              "hosted import succeeded",
+			mode:              operatorv1.InstallModeHosted,
+			currentConditions: []metav1.Condition{importing},
+			condition:         imported,
+			expectedResult:    importResultSucceeded,
+			expectedIncreased: 1,
+		},
+		{
+			name:              "hosted import succeeded already",
+			mode:              operatorv1.InstallModeHosted,
+			currentConditions: []metav1.Condition{imported},
+			condition:         imported,
+			expectedResult:    importResultSucceeded,
+			expectedIncreased: 0,
+		},
+		{
+			name:              "default import failed",
+			mode:              operatorv1.InstallModeDefault,
+			condition:         failed,
+			expectedResult:    importResultFailed,
+			expectedIncreased: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Status:     clusterv1.ManagedClusterStatus{Conditions: c.currentConditions},
+			}
+			counter := importTotal.WithLabelValues(strings.ToLower(string(c.mode)), c.expectedResult)
+
+			before := testutil.ToFloat64(counter)
+			ObserveImportResult(c.mode, cluster, &c.condition, time.Now())
+			after := testutil.ToFloat64(counter)
+			if after-before != c.expectedIncreased {
+				t.Errorf("expected the import counter is increased by %v, but got %v", c.expectedIncreased, after-before)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var klusterletGVK = operatorv1.SchemeGroupVersion.WithKind("Klusterlet")
+
+var (
+	// the klusterlet CRDs are just applied on the managed cluster, the managed cluster may take a while to serve
+	// them, so wait for the klusterlet CRDs to be discovered
+	klusterletCRDsPollInterval = 1 * time.Second
+	klusterletCRDsPollTimeout  = 10 * time.Second
+)
+
+// spokeMissingKlusterletCRDsError indicates the klusterlet GVKs cannot be resolved on the managed cluster
+type spokeMissingKlusterletCRDsError struct {
+	err error
+}
+
+func (e *spokeMissingKlusterletCRDsError) Error() string {
+	return fmt.Sprintf("the managed cluster cannot resolve %s, the klusterlet CRDs are missing: %v",
+		klusterletGVK.String(), e.err)
+}
+
+func (e *spokeMissingKlusterletCRDsError) Unwrap() error {
+	return e.err
+}
+
+// IsSpokeMissingKlusterletCRDs returns true if the error (or one of the aggregated errors) indicates the
+// klusterlet GVKs cannot be resolved on the managed cluster
+func IsSpokeMissingKlusterletCRDs(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var target *spokeMissingKlusterletCRDsError
+	if errors.As(err, &target) {
+		return true
+	}
+	if errs, ok := err.(utilerrors.Aggregate); ok {
+		for _, e := range errs.Errors() {
+			if errors.As(e, &target) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkKlusterletCRDs checks the klusterlet GVKs can be resolved on the managed cluster. The RESTMapper that is
+// generated from the managed cluster discovery is static, so if the mapper cannot resolve the klusterlet GVKs,
+// the discovery of the managed cluster is polled to find them.
+func checkKlusterletCRDs(client *ClientHolder, restMapper meta.RESTMapper) error {
+	if restMapper != nil {
+		if _, err := restMapper.RESTMapping(klusterletGVK.GroupKind(), klusterletGVK.Version); err == nil {
+			return nil
+		}
+	}
+
+	var lastErr error
+	err := wait.PollUntilContextTimeout(context.TODO(), klusterletCRDsPollInterval, klusterletCRDsPollTimeout, true,
+		func(ctx context.Context) (bool, error) {
+			resources, err := client.KubeClient.Discovery().ServerResourcesForGroupVersion(
+				klusterletGVK.GroupVersion().String())
+			if err != nil {
+				lastErr = err
+				return false, nil
+			}
+			for _, resource := range resources.APIResources {
+				if resource.Kind == klusterletGVK.Kind {
+					return true, nil
+				}
+			}
+			lastErr = fmt.Errorf("kind %s is not found in %s", klusterletGVK.Kind, klusterletGVK.GroupVersion())
+			return false, nil
+		})
+	if err != nil {
+		if lastErr == nil {
+			lastErr = err
+		}
+		return &spokeMissingKlusterletCRDsError{err: lastErr}
+	}
+	return nil
+}
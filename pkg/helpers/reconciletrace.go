@@ -0,0 +1,121 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+const (
+	// ReconcileTraceConfigMapName is the name of the configmap in the managed cluster namespace to record the
+	// reconcile decisions of the managed cluster
+	ReconcileTraceConfigMapName = "import-controller-reconcile-trace"
+	ReconcileTraceKey           = "trace"
+
+	// maxReconcileTraceRecords is the number of the latest reconcile decisions that are kept in the trace
+	maxReconcileTraceRecords = 50
+)
+
+// Decisions of the reconcile that are recorded in the reconcile trace
+const (
+	ReconcileDecisionSkippedNotInstalled = "skipped-not-installed"
+	ReconcileDecisionSkippedNotClaimed   = "skipped-not-claimed"
+	ReconcileDecisionSkippedAutoImport   = "skipped-auto-import-secret"
+	ReconcileDecisionWaitingImportDelay  = "waiting-import-delay"
+	ReconcileDecisionWaitingWorks        = "waiting-works"
+	ReconcileDecisionApplied             = "applied"
+	ReconcileDecisionFailed              = "failed"
+)
+
+// IsReconcileTraceEnabled returns true if the reconcile trace is enabled on the managed cluster
+func IsReconcileTraceEnabled(cluster *clusterv1.ManagedCluster) bool {
+	return strings.EqualFold(cluster.GetAnnotations()[constants.ReconcileTraceAnnotation], "true")
+}
+
+// RecordReconcileTrace appends a reconcile decision of the controller into the reconcile trace configmap in the
+// managed cluster namespace, only the latest maxReconcileTraceRecords decisions are kept. The decision is recorded
+// only when the reconcile trace is enabled on the managed cluster. The trace is used for debugging, so the failure
+// of recording is logged and ignored.
+func RecordReconcileTrace(ctx context.Context, kubeClient kubernetes.Interface, cluster *clusterv1.ManagedCluster,
+	controllerName, decision, message string) {
+	if !IsReconcileTraceEnabled(cluster) {
+		return
+	}
+
+	if err := recordReconcileTrace(ctx, kubeClient, cluster.Name, controllerName, decision, message); err != nil {
+		klog.Warningf("Failed to record the reconcile trace of the managed cluster %s: %v", cluster.Name, err)
+	}
+}
+
+// CleanupReconcileTrace deletes the reconcile trace configmap from the managed cluster namespace after the reconcile
+// trace is disabled on the managed cluster.
+func CleanupReconcileTrace(ctx context.Context, runtimeClient client.Client, cluster *clusterv1.ManagedCluster) error {
+	if IsReconcileTraceEnabled(cluster) {
+		return nil
+	}
+
+	err := runtimeClient.Delete(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ReconcileTraceConfigMapName,
+			Namespace: cluster.Name,
+		},
+	})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func recordReconcileTrace(ctx context.Context, kubeClient kubernetes.Interface,
+	clusterName, controllerName, decision, message string) error {
+	record := fmt.Sprintf("%s %s: %s", time.Now().UTC().Format(time.RFC3339), controllerName, decision)
+	if len(message) != 0 {
+		record = fmt.Sprintf("%s, %s", record, message)
+	}
+
+	configMap, err := kubeClient.CoreV1().ConfigMaps(clusterName).Get(ctx, ReconcileTraceConfigMapName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = kubeClient.CoreV1().ConfigMaps(clusterName).Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ReconcileTraceConfigMapName,
+				Namespace: clusterName,
+			},
+			Data: map[string]string{ReconcileTraceKey: record},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	records := []string{}
+	if trace := configMap.Data[ReconcileTraceKey]; len(trace) != 0 {
+		records = strings.Split(trace, "\n")
+	}
+	records = append(records, record)
+	if len(records) > maxReconcileTraceRecords {
+		records = records[len(records)-maxReconcileTraceRecords:]
+	}
+
+	configMap = configMap.DeepCopy()
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[ReconcileTraceKey] = strings.Join(records, "\n")
+	_, err = kubeClient.CoreV1().ConfigMaps(clusterName).Update(ctx, configMap, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,165 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+func TestRecordReconcileTrace(t *testing.T) {
+	cases := []struct {
+		name            string
+		annotations     map[string]string
+		decisions       []string
+		expectedRecords []string
+	}{
+		{
+			name:      "trace is not enabled",
+			decisions: []string{ReconcileDecisionSkippedNotInstalled},
+		},
+		{
+			name: "record the decisions",
+			annotations: map[string]string{
+				constants.ReconcileTraceAnnotation: "true",
+			},
+			decisions: []string{ReconcileDecisionSkippedNotInstalled, ReconcileDecisionApplied},
+			expectedRecords: []string{
+				"test-controller: " + ReconcileDecisionSkippedNotInstalled,
+				"test-controller: " + ReconcileDecisionApplied,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			cluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test",
+					Annotations: c.annotations,
+				},
+			}
+
+			for _, decision := range c.decisions {
+				RecordReconcileTrace(context.TODO(), kubeClient, cluster, "test-controller", decision, "")
+			}
+
+			configMap, err := kubeClient.CoreV1().ConfigMaps("test").Get(
+				context.TODO(), ReconcileTraceConfigMapName, metav1.GetOptions{})
+			if len(c.expectedRecords) == 0 {
+				if err == nil {
+					t.Errorf("expected no trace, but got %v", configMap.Data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			records := strings.Split(configMap.Data[ReconcileTraceKey], "\n")
+			if len(records) != len(c.expectedRecords) {
+				t.Fatalf("expected %d records, but got %v", len(c.expectedRecords), records)
+			}
+			for i, record := range records {
+				if !strings.HasSuffix(record, c.expectedRecords[i]) {
+					t.Errorf("expected record %q, but got %q", c.expectedRecords[i], record)
+				}
+			}
+		})
+	}
+}
+
+func TestRecordReconcileTraceRolling(t *testing.T) {
+	kubeClient := kubefake.NewSimpleClientset()
+	cluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+			Annotations: map[string]string{
+				constants.ReconcileTraceAnnotation: "true",
+			},
+		},
+	}
+
+	for i := 0; i < maxReconcileTraceRecords+5; i++ {
+		RecordReconcileTrace(context.TODO(), kubeClient, cluster, "test-controller",
+			ReconcileDecisionWaitingWorks, fmt.Sprintf("retry %d", i))
+	}
+
+	configMap, err := kubeClient.CoreV1().ConfigMaps("test").Get(
+		context.TODO(), ReconcileTraceConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records := strings.Split(configMap.Data[ReconcileTraceKey], "\n")
+	if len(records) != maxReconcileTraceRecords {
+		t.Errorf("expected %d records, but got %d", maxReconcileTraceRecords, len(records))
+	}
+	if !strings.HasSuffix(records[0], "retry 5") {
+		t.Errorf("expected the oldest records are dropped, but got %q", records[0])
+	}
+}
+
+func TestCleanupReconcileTrace(t *testing.T) {
+	cases := []struct {
+		name            string
+		annotations     map[string]string
+		expectedDeleted bool
+	}{
+		{
+			name: "trace is enabled",
+			annotations: map[string]string{
+				constants.ReconcileTraceAnnotation: "true",
+			},
+		},
+		{
+			name:            "trace is disabled",
+			expectedDeleted: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			runtimeClient := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      ReconcileTraceConfigMapName,
+					Namespace: "test",
+				},
+			}).Build()
+			cluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test",
+					Annotations: c.annotations,
+				},
+			}
+
+			if err := CleanupReconcileTrace(context.TODO(), runtimeClient, cluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// the trace is already deleted
+			if err := CleanupReconcileTrace(context.TODO(), runtimeClient, cluster); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			err := runtimeClient.Get(context.TODO(),
+				types.NamespacedName{Namespace: "test", Name: ReconcileTraceConfigMapName}, &corev1.ConfigMap{})
+			if errors.IsNotFound(err) != c.expectedDeleted {
+				t.Errorf("expected deleted %v, but got %v", c.expectedDeleted, err)
+			}
+		})
+	}
+}
@@ -7,10 +7,12 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"time"
 
 	klusterletconfigv1alpha1lister "github.com/stolostron/cluster-lifecycle-api/client/klusterletconfig/listers/klusterletconfig/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	corev1listers "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
@@ -23,6 +25,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/features"
 )
 
 type InformerHolder struct {
@@ -41,6 +45,8 @@ type InformerHolder struct {
 	KlusterletConfigLister klusterletconfigv1alpha1lister.KlusterletConfigLister
 
 	ManagedClusterInformer cache.SharedIndexInformer
+
+	HubRootCAInformer cache.SharedIndexInformer
 }
 
 // NewImportSecretSource return a source only for import secrets
@@ -88,6 +94,15 @@ func NewManagedClusterSource(mcInformer cache.SharedIndexInformer) *Source {
 	}
 }
 
+// NewHubRootCASource return a source only for the hub root CA configmap
+func NewHubRootCASource(configMapInformer cache.SharedIndexInformer) *Source {
+	return &Source{
+		informer:     configMapInformer,
+		expectedType: reflect.TypeOf(&corev1.ConfigMap{}),
+		name:         "hub-root-ca",
+	}
+}
+
 // Source is the event source of specified objects
 type Source struct {
 	informer     cache.SharedIndexInformer
@@ -99,6 +114,12 @@ var _ source.SyncingSource = &Source{}
 
 func (s *Source) Start(ctx context.Context, handler handler.EventHandler,
 	queue workqueue.RateLimitingInterface, predicates ...predicate.Predicate) error {
+	var resyncs *resyncQueue
+	if features.DefaultMutableFeatureGate.Enabled(features.ResyncLowPriorityQueue) {
+		resyncs = newResyncQueue(s.name, queue)
+		go resyncs.run(ctx)
+	}
+
 	_, err := s.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			newObj, ok := obj.(client.Object)
@@ -153,6 +174,12 @@ func (s *Source) Start(ctx context.Context, handler handler.EventHandler,
 				}
 			}
 
+			// the informer resyncs send the update events with the same resource version
+			if resyncs != nil && oldClientObj.GetResourceVersion() == newClientObj.GetResourceVersion() {
+				handler.Update(ctx, updateEvent, resyncs)
+				return
+			}
+
 			handler.Update(ctx, updateEvent, queue)
 		},
 		DeleteFunc: func(obj interface{}) {
@@ -201,6 +228,54 @@ func (s *Source) String() string {
 	return s.name
 }
 
+// resyncQueueIdleCheckPeriod is the period to check whether the controller queue is idle
+const resyncQueueIdleCheckPeriod = 100 * time.Millisecond
+
+// resyncQueue holds the reconcile requests that are triggered by the informer resyncs, the requests are moved
+// to the controller queue only when the controller queue is idle
+type resyncQueue struct {
+	workqueue.RateLimitingInterface
+
+	controllerQueue workqueue.RateLimitingInterface
+	idleCheckPeriod time.Duration
+}
+
+func newResyncQueue(name string, controllerQueue workqueue.RateLimitingInterface) *resyncQueue {
+	return &resyncQueue{
+		RateLimitingInterface: workqueue.NewRateLimitingQueueWithConfig(workqueue.DefaultControllerRateLimiter(),
+			workqueue.RateLimitingQueueConfig{Name: name + "-resync"}),
+		controllerQueue: controllerQueue,
+		idleCheckPeriod: resyncQueueIdleCheckPeriod,
+	}
+}
+
+func (q *resyncQueue) run(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		q.ShutDown()
+	}()
+
+	for q.processNextRequest(ctx) {
+	}
+}
+
+func (q *resyncQueue) processNextRequest(ctx context.Context) bool {
+	request, shutdown := q.Get()
+	if shutdown {
+		return false
+	}
+	defer q.Done(request)
+
+	if err := wait.PollUntilContextCancel(ctx, q.idleCheckPeriod, true, func(ctx context.Context) (bool, error) {
+		return q.controllerQueue.Len() == 0, nil
+	}); err != nil {
+		return false
+	}
+
+	q.controllerQueue.Add(request)
+	return true
+}
+
 // Map a client object to reconcile request
 type MapFunc func(client.Object) reconcile.Request
 
@@ -0,0 +1,61 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestResyncQueue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	controllerQueue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer controllerQueue.ShutDown()
+
+	resyncs := newResyncQueue("test", controllerQueue)
+	resyncs.idleCheckPeriod = 10 * time.Millisecond
+
+	// the controller is under load, there are requests triggered by the changes in the controller queue when
+	// the resyncs happen
+	eventRequests := map[reconcile.Request]bool{}
+	for i := 0; i < 10; i++ {
+		request := reconcile.Request{NamespacedName: types.NamespacedName{Name: fmt.Sprintf("event-%d", i)}}
+		eventRequests[request] = true
+		controllerQueue.Add(request)
+	}
+	for i := 0; i < 5; i++ {
+		resyncs.Add(reconcile.Request{NamespacedName: types.NamespacedName{Name: fmt.Sprintf("resync-%d", i)}})
+	}
+
+	go resyncs.run(ctx)
+
+	processed := []reconcile.Request{}
+	for len(processed) < 15 {
+		item, shutdown := controllerQueue.Get()
+		if shutdown {
+			t.Fatalf("the controller queue is shut down")
+		}
+		processed = append(processed, item.(reconcile.Request))
+		// simulate the reconcile takes time
+		time.Sleep(5 * time.Millisecond)
+		controllerQueue.Done(item)
+	}
+
+	for i, request := range processed {
+		if i < len(eventRequests) && !eventRequests[request] {
+			t.Errorf("expected the event-driven requests are processed first, but got %s at %d", request.Name, i)
+		}
+		if i >= len(eventRequests) && eventRequests[request] {
+			t.Errorf("expected the resync requests are processed last, but got %s at %d", request.Name, i)
+		}
+	}
+}
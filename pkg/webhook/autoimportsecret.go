@@ -0,0 +1,71 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+// AutoImportSecretValidatingPath is the path that the auto import secret validating webhook is served on
+const AutoImportSecretValidatingPath = "/validate-auto-import-secret"
+
+var log = logf.Log.WithName("auto-import-secret-webhook")
+
+// AutoImportSecretValidator rejects the creation of an auto import secret that cannot be used to access the
+// managed cluster, e.g. the keys of the secret are mistyped. Only the auto import secrets in the managed cluster
+// namespaces are validated, the other secrets are always allowed.
+type AutoImportSecretValidator struct {
+	client client.Client
+}
+
+// NewAutoImportSecretValidator returns an AutoImportSecretValidator, the client is used to find the managed
+// cluster of the secret namespace
+func NewAutoImportSecretValidator(client client.Client) *AutoImportSecretValidator {
+	return &AutoImportSecretValidator{client: client}
+}
+
+func (v *AutoImportSecretValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create ||
+		req.Resource.Resource != "secrets" || req.Name != constants.AutoImportSecretName {
+		return admission.Allowed("")
+	}
+
+	managedCluster := &clusterv1.ManagedCluster{}
+	err := v.client.Get(ctx, types.NamespacedName{Name: req.Namespace}, managedCluster)
+	if errors.IsNotFound(err) {
+		// not a managed cluster namespace, the secret is not an auto import secret
+		return admission.Allowed("")
+	}
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	secret := &corev1.Secret{}
+	if err := json.Unmarshal(req.Object.Raw, secret); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	// the name and namespace may be absent from the raw object if they are set by the request
+	secret.Name = req.Name
+	secret.Namespace = req.Namespace
+
+	if err := helpers.ValidateAutoImportSecret(secret); err != nil {
+		log.Info("Reject the auto import secret", "namespace", req.Namespace, "reason", err.Error())
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
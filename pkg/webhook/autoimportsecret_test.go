@@ -0,0 +1,144 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+var testscheme = scheme.Scheme
+
+func init() {
+	testscheme.AddKnownTypes(clusterv1.SchemeGroupVersion, &clusterv1.ManagedCluster{})
+}
+
+func TestAutoImportSecretValidator(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+
+	cases := []struct {
+		name            string
+		operation       admissionv1.Operation
+		secretName      string
+		namespace       string
+		data            map[string][]byte
+		stringData      map[string]string
+		expectedAllowed bool
+	}{
+		{
+			name:            "kubeconfig",
+			operation:       admissionv1.Create,
+			secretName:      constants.AutoImportSecretName,
+			namespace:       "cluster1",
+			data:            map[string][]byte{"kubeconfig": []byte("kubeconfig")},
+			expectedAllowed: true,
+		},
+		{
+			name:            "server and token",
+			operation:       admissionv1.Create,
+			secretName:      constants.AutoImportSecretName,
+			namespace:       "cluster1",
+			data:            map[string][]byte{"server": []byte("https://api.cluster1:6443")},
+			stringData:      map[string]string{"token": "token"},
+			expectedAllowed: true,
+		},
+		{
+			name:            "mistyped kubeconfig key",
+			operation:       admissionv1.Create,
+			secretName:      constants.AutoImportSecretName,
+			namespace:       "cluster1",
+			data:            map[string][]byte{"kubeConfig": []byte("kubeconfig")},
+			expectedAllowed: false,
+		},
+		{
+			name:            "token without server",
+			operation:       admissionv1.Create,
+			secretName:      constants.AutoImportSecretName,
+			namespace:       "cluster1",
+			data:            map[string][]byte{"token": []byte("token")},
+			expectedAllowed: false,
+		},
+		{
+			name:       "both kubeconfig and token",
+			operation:  admissionv1.Create,
+			secretName: constants.AutoImportSecretName,
+			namespace:  "cluster1",
+			data: map[string][]byte{
+				"kubeconfig": []byte("kubeconfig"),
+				"server":     []byte("https://api.cluster1:6443"),
+				"token":      []byte("token"),
+			},
+			expectedAllowed: false,
+		},
+		{
+			name:            "not a managed cluster namespace",
+			operation:       admissionv1.Create,
+			secretName:      constants.AutoImportSecretName,
+			namespace:       "default",
+			data:            map[string][]byte{"foo": []byte("bar")},
+			expectedAllowed: true,
+		},
+		{
+			name:            "not an auto import secret",
+			operation:       admissionv1.Create,
+			secretName:      "foo",
+			namespace:       "cluster1",
+			data:            map[string][]byte{"foo": []byte("bar")},
+			expectedAllowed: true,
+		},
+		{
+			name:            "update",
+			operation:       admissionv1.Update,
+			secretName:      constants.AutoImportSecretName,
+			namespace:       "cluster1",
+			data:            map[string][]byte{"foo": []byte("bar")},
+			expectedAllowed: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      c.secretName,
+					Namespace: c.namespace,
+				},
+				Data:       c.data,
+				StringData: c.stringData,
+			}
+			raw, err := json.Marshal(secret)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			validator := NewAutoImportSecretValidator(
+				fake.NewClientBuilder().WithScheme(testscheme).WithObjects([]client.Object{managedCluster}...).Build())
+			resp := validator.Handle(context.TODO(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Operation: c.operation,
+					Resource:  metav1.GroupVersionResource{Version: "v1", Resource: "secrets"},
+					Name:      c.secretName,
+					Namespace: c.namespace,
+					Object:    runtime.RawExtension{Raw: raw},
+				},
+			})
+			if resp.Allowed != c.expectedAllowed {
+				t.Errorf("expected allowed %v, but got %v: %v", c.expectedAllowed, resp.Allowed, resp.Result)
+			}
+		})
+	}
+}